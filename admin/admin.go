@@ -0,0 +1,131 @@
+// Package admin serves the live topology of a PubSub instance over HTTP
+// for debugging, in the spirit of expvar and pprof: its keys with their
+// subscribers, queued and retained messages and publish statistics, and
+// the use of the memory budget. It renders an HTML page, or JSON when
+// requested with ?format=json or an Accept header of application/json.
+//
+// The handler exposes key names and traffic statistics; mount it on an
+// internal or authenticated endpoint only.
+package admin
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// Handler is an http.Handler rendering the topology of a PubSub instance.
+type Handler[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// Name, if set, returns the display name of a key;
+	// fmt.Sprint is used otherwise.
+	Name func(key K) string
+}
+
+// Topology is the JSON document served by the handler.
+type Topology struct {
+	Time   time.Time          `json:"time"`
+	Keys   []Key              `json:"keys"`
+	Memory pubsub.MemoryUsage `json:"memory"`
+}
+
+// Key is the state of a key, ordered by name in the Topology.
+type Key struct {
+	Name        string    `json:"name"`
+	Subscribers int       `json:"subscribers"`
+	Queued      int       `json:"queued"`
+	Retained    int       `json:"retained"`
+	Published   uint64    `json:"published"`
+	Delivered   uint64    `json:"delivered"`
+	Dropped     uint64    `json:"dropped"`
+	LastPublish time.Time `json:"lastPublish,omitzero"`
+	Rate        float64   `json:"rate"`
+}
+
+// Topology returns the current topology.
+func (h *Handler[K, T]) Topology() Topology {
+	stats := h.PubSub.AllStats()
+	keys := make([]Key, 0, len(stats))
+	for key, s := range stats {
+		keys = append(keys, Key{
+			Name:        h.name(key),
+			Subscribers: s.Subscribers,
+			Queued:      s.Queued,
+			Retained:    s.Retained,
+			Published:   s.Published,
+			Delivered:   s.Delivered,
+			Dropped:     s.Dropped,
+			LastPublish: s.LastPublish,
+			Rate:        s.Rate,
+		})
+	}
+
+	slices.SortFunc(keys, func(a, b Key) int { return cmp.Compare(a.Name, b.Name) })
+
+	return Topology{
+		Time:   h.PubSub.Clock().Now(),
+		Keys:   keys,
+		Memory: h.PubSub.MemoryUsage(),
+	}
+}
+
+// ServeHTTP renders the topology as HTML or JSON.
+func (h *Handler[K, T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	topology := h.Topology()
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(topology)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	page.Execute(w, topology)
+}
+
+// name returns the display name of the key.
+func (h *Handler[K, T]) name(key K) string {
+	if h.Name != nil {
+		return h.Name(key)
+	}
+
+	return fmt.Sprint(key)
+}
+
+var page = template.Must(template.New("admin").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pubsub</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr:nth-child(even) { background: #f4f4f4; }
+</style>
+</head>
+<body>
+<h1>pubsub</h1>
+<p>{{.Time.Format "2006-01-02 15:04:05 MST"}}, {{len .Keys}} keys,
+{{.Memory.Messages}} messages held{{with .Memory.Limit}} of {{.}}{{end}},
+{{.Memory.Evicted}} evicted, {{.Memory.Rejected}} rejected
+(<a href="?format=json">JSON</a>)</p>
+<table>
+<tr><th>Key</th><th>Subscribers</th><th>Queued</th><th>Retained</th><th>Published</th><th>Delivered</th><th>Dropped</th><th>Rate/s</th><th>Last publish</th></tr>
+{{range .Keys}}<tr><td>{{.Name}}</td><td>{{.Subscribers}}</td><td>{{.Queued}}</td><td>{{.Retained}}</td><td>{{.Published}}</td><td>{{.Delivered}}</td><td>{{.Dropped}}</td><td>{{printf "%.2f" .Rate}}</td><td>{{if not .LastPublish.IsZero}}{{.LastPublish.Format "15:04:05.000"}}{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
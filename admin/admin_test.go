@@ -0,0 +1,45 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/admin"
+)
+
+func TestHandler(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithKeyStats(), pubsub.WithRetention(10))
+	ps.Subscribe([]string{"b"}, make(chan int, 10))
+	ps.Subscribe([]string{"<a>"}, make(chan int, 10))
+	ps.Publish(context.Background(), "b", 1)
+	ps.Publish(context.Background(), "b", 2)
+
+	h := &admin.Handler[string, int]{PubSub: ps}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?format=json", nil))
+
+	var topology admin.Topology
+	if err := json.NewDecoder(rec.Body).Decode(&topology); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(topology.Keys) != 2 || topology.Keys[0].Name != "<a>" {
+		t.Fatalf("expected keys ordered by name, got %+v", topology.Keys)
+	}
+
+	if b := topology.Keys[1]; b.Subscribers != 1 || b.Queued != 2 || b.Retained != 2 || b.Published != 2 {
+		t.Errorf("unexpected key %+v", b)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "<td>&lt;a&gt;</td>") {
+		t.Errorf("expected an HTML page with escaped keys, got %s", body)
+	}
+}
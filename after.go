@@ -0,0 +1,104 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrHandlerConflict is returned by AliasKey when the handlers of the two
+// keys can't be merged: two of them have the same name, or their
+// dependencies form a cycle.
+var ErrHandlerConflict = errors.New("pubsub: conflicting handlers")
+
+// SubscribeAfter is like SubscribeFunc, but names the handler and calls
+// it for a message only after the handlers named in after returned nil
+// for it, for consumers that must see the effects of others, such as a
+// reader of a cache after the cache update. Handlers of a key form a
+// dependency graph sequenced by the library: it calls them in an order
+// respecting the dependencies, and otherwise in registration order. If a
+// handler fails or is skipped, the handlers depending on it are skipped
+// for that message and don't count as deliveries.
+//
+// Names are scoped to the key: a dependency on a name without a handler
+// for the key is ignored. It panics if a handler of one of the keys
+// already has the name, or if the dependencies form a cycle.
+func (ps *PubSub[K, T]) SubscribeAfter(keys []K, name string, after []string,
+	fn func(ctx context.Context, key K, msg T) error,
+) (unsubscribe func()) {
+	if name == "" {
+		panic("pubsub: empty handler name")
+	}
+
+	return ps.addHandler(keys, &handler[K, T]{fn: fn, name: name, after: slices.Clone(after)})
+}
+
+// blocked reports whether a dependency of the handler failed.
+func (h *handler[K, T]) blocked(failed map[string]bool) bool {
+	for _, name := range h.after {
+		if failed[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// orderHandlers returns the handlers sorted so that each comes after its
+// dependencies, keeping the registration order otherwise. Lists without
+// dependencies are returned as is. It panics on duplicate names and
+// dependency cycles.
+func orderHandlers[K comparable, T any](list []*handler[K, T]) []*handler[K, T] {
+	ordered, err := sortHandlers(list)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return ordered
+}
+
+// sortHandlers is orderHandlers returning an error wrapping
+// ErrHandlerConflict instead of panicking.
+func sortHandlers[K comparable, T any](list []*handler[K, T]) ([]*handler[K, T], error) {
+	byName := make(map[string]*handler[K, T])
+	deps := false
+	for _, h := range list {
+		if h.name == "" {
+			continue
+		}
+
+		if byName[h.name] != nil {
+			return nil, fmt.Errorf("%w: duplicate handler name %s", ErrHandlerConflict, h.name)
+		}
+		byName[h.name] = h
+		deps = deps || len(h.after) > 0
+	}
+
+	if !deps {
+		return list, nil
+	}
+
+	placed := make(map[*handler[K, T]]bool, len(list))
+	ready := func(h *handler[K, T]) bool {
+		for _, name := range h.after {
+			if dep := byName[name]; dep != nil && !placed[dep] {
+				return false
+			}
+		}
+		return true
+	}
+
+	ordered := make([]*handler[K, T], 0, len(list))
+	for len(ordered) < len(list) {
+		next := slices.IndexFunc(list, func(h *handler[K, T]) bool { return !placed[h] && ready(h) })
+		if next < 0 {
+			return nil, fmt.Errorf("%w: handler dependency cycle", ErrHandlerConflict)
+		}
+
+		placed[list[next]] = true
+		ordered = append(ordered, list[next])
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,79 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubscribeAfter(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var calls []string
+	record := func(name string, err error) func(context.Context, string, int) error {
+		return func(context.Context, string, int) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+
+	keys := []string{"k"}
+	ps.SubscribeAfter(keys, "reader", []string{"cache"}, record("reader", nil))
+	ps.SubscribeFunc(keys, record("plain", nil))
+	ps.SubscribeAfter(keys, "report", []string{"reader", "missing"}, record("report", nil))
+	ps.SubscribeAfter(keys, "cache", nil, record("cache", nil))
+
+	n, err := ps.Publish(context.Background(), "k", 1)
+	if err != nil || n != 4 {
+		t.Fatalf("expected 4 deliveries, got %d, %v", n, err)
+	}
+	if want := []string{"plain", "cache", "reader", "report"}; !slices.Equal(calls, want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestSubscribeAfterFailure(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var calls []string
+	keys := []string{"k"}
+	failure := errors.New("cache down")
+	ps.SubscribeAfter(keys, "cache", nil, func(context.Context, string, int) error {
+		return failure
+	})
+	ps.SubscribeAfter(keys, "reader", []string{"cache"}, func(context.Context, string, int) error {
+		calls = append(calls, "reader")
+		return nil
+	})
+	ps.SubscribeAfter(keys, "report", []string{"reader"}, func(context.Context, string, int) error {
+		calls = append(calls, "report")
+		return nil
+	})
+	ps.SubscribeAfter(keys, "audit", nil, func(context.Context, string, int) error {
+		calls = append(calls, "audit")
+		return nil
+	})
+
+	n, err := ps.Publish(context.Background(), "k", 1)
+	if !errors.Is(err, failure) || n != 1 || !slices.Equal(calls, []string{"audit"}) {
+		t.Errorf("expected the dependents skipped, got %d, %v, %v", n, err, calls)
+	}
+}
+
+func TestSubscribeAfterCycle(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	nop := func(context.Context, string, int) error { return nil }
+	ps.SubscribeAfter([]string{"k"}, "a", []string{"b"}, nop)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a dependency cycle")
+		}
+		if n, _ := ps.Publish(context.Background(), "k", 1); n != 1 {
+			t.Errorf("expected the handlers unchanged, got %d deliveries", n)
+		}
+	}()
+
+	ps.SubscribeAfter([]string{"k"}, "b", []string{"a"}, nop)
+}
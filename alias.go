@@ -0,0 +1,246 @@
+package pubsub
+
+import (
+	"errors"
+	"slices"
+	"sync/atomic"
+)
+
+// ErrAliasCycle is returned by AliasKey when the new key is the old one
+// or an alias of it.
+var ErrAliasCycle = errors.New("pubsub: alias cycle")
+
+// alias is a key standing for another one.
+type alias[K comparable] struct {
+	old  K
+	key  K           // the key it stands for, never an alias itself
+	used atomic.Bool // published to since AliasKey
+}
+
+// AliasKey makes the old key an alias of the new one, to rename a key
+// during a migration without breaking its publishers and subscribers:
+// the channels and handlers subscribed to the old key move to the new
+// one, and later publishes and subscriptions to the old key apply to the
+// new one, so publishes to either key reach the same subscribers. Aliases
+// of the old key follow it to the new one. Reading the retained messages,
+// statistics and consumers of the old key reads those of the new one.
+//
+// The messages retained for the old key move to the new key with their
+// sequence numbers if the new key has none; otherwise they are appended
+// to those of the new key, numbered after them, and the oldest are
+// discarded over the retention limit of the new key.
+//
+// The first publish to the old key after AliasKey sends a KeyAliasUsed
+// event for the old key to the watchers of WatchKeys and is logged, to
+// track the publishers still using it. AliasKey fails with ErrAliasCycle
+// if the new key is the old one or an alias of it, and with an error
+// wrapping ErrHandlerConflict if handlers registered with SubscribeAfter
+// for the two keys have the same name or dependencies forming a cycle.
+func (ps *PubSub[K, T]) AliasKey(oldKey, newKey K) error {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	target := ps.resolve(newKey)
+	if target == oldKey {
+		return ErrAliasCycle
+	}
+
+	// Merge the handlers first, so a conflict fails before any change.
+	// Handlers of both keys are kept once.
+	handlers, moveHandlers := ps.handlers[oldKey]
+	if moveHandlers {
+		merged := slices.Clip(ps.handlers[target])
+		for _, h := range handlers {
+			if !slices.Contains(merged, h) {
+				merged = append(merged, h)
+			}
+		}
+
+		var err error
+		if handlers, err = sortHandlers(merged); err != nil {
+			return err
+		}
+	}
+
+	// Move the subscribers before the old key resolves to the new one,
+	// adding them to the new key first so that the channels keep their
+	// priorities.
+	var moved []chan T
+	for ch := range ps.subscribers[oldKey].channels() {
+		moved = append(moved, ch)
+	}
+	for _, ch := range moved {
+		refs := ps.subscribers[oldKey].refs[ch]
+		prev := ps.subscribers[target].channels()[ch]
+		ps.add([]K{target}, ch, false)
+		ps.subscribers[target].refs[ch] = prev + refs
+
+		ps.subscribers[oldKey].refs[ch] = 1
+		ps.remove([]K{oldKey}, ch)
+	}
+
+	if moveHandlers {
+		ps.handlers[target] = handlers
+		delete(ps.handlers, oldKey)
+	}
+
+	ps.moveRetained(oldKey, target)
+
+	if ps.aliases == nil {
+		ps.aliases = make(map[K]*alias[K])
+	}
+	for _, a := range ps.aliases {
+		if a.key == oldKey {
+			a.key = target
+		}
+	}
+	ps.aliases[oldKey] = &alias[K]{old: oldKey, key: target}
+	ps.opts.logger.Debug("pubsub: key aliased", "key", oldKey, "to", target)
+
+	return nil
+}
+
+// UnaliasKey removes the alias, once the migration is over: publishes
+// and subscriptions to the old key apply to it again. The subscribers
+// moved to the new key stay there: the keys of Subscriptions, of
+// subscriptions made with SubscribeFor and of handlers naming the old
+// key are replaced with the new one, so they are removed from the key
+// they are subscribed to.
+func (ps *PubSub[K, T]) UnaliasKey(oldKey K) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	a, ok := ps.aliases[oldKey]
+	if !ok {
+		return
+	}
+	delete(ps.aliases, oldKey)
+
+	rename := func(keys []K) []K {
+		if !slices.Contains(keys, oldKey) {
+			return keys
+		}
+
+		// Copy on write: the old list may be in use outside the lock.
+		keys = slices.Clone(keys)
+		for i, key := range keys {
+			if key == oldKey {
+				keys[i] = a.key
+			}
+		}
+
+		return keys
+	}
+
+	for _, s := range ps.managed {
+		s.keys = rename(s.keys)
+	}
+	for _, l := range ps.limits {
+		l.keys = rename(l.keys)
+	}
+	for _, h := range ps.handlers[a.key] {
+		h.keys = rename(h.keys)
+	}
+}
+
+// moveRetained moves the messages retained for the old key to the new
+// one, as described by AliasKey. The caller must hold the lock.
+func (ps *PubSub[K, T]) moveRetained(oldKey, newKey K) {
+	from, to := ps.shard(oldKey), ps.shard(newKey)
+	for _, i := range slices.Compact([]int{min(from, to), max(from, to)}) {
+		ps.history[i].mu.Lock()
+		defer ps.history[i].mu.Unlock()
+	}
+
+	s, ok := ps.history[from].keys[oldKey]
+	if !ok {
+		return
+	}
+	delete(ps.history[from].keys, oldKey)
+
+	// Consumers waiting for the old key wait for the new one instead.
+	if s.wake != nil {
+		close(s.wake)
+		s.wake = nil
+	}
+
+	dst := ps.history[to].stream(newKey)
+	if dst.seq == 0 {
+		s.wake = dst.wake
+		ps.history[to].keys[newKey] = s
+		dst = s
+	} else {
+		for _, e := range s.entries {
+			if size := ps.Size(e.Msg); s.quota != dst.quota {
+				if s.quota != nil {
+					s.quota.add(-size)
+				}
+				if dst.quota != nil {
+					dst.quota.add(size)
+				}
+			}
+
+			dst.seq++
+			e.Seq = dst.seq
+			dst.entries = append(dst.entries, e)
+		}
+	}
+
+	for len(dst.entries) > ps.retention(newKey) {
+		ps.dropRetained(dst)
+	}
+
+	if dst.wake != nil && len(dst.entries) > 0 {
+		close(dst.wake)
+		dst.wake = nil
+	}
+}
+
+// resolve returns the key the key stands for if it is an alias, or the
+// key itself. The caller must hold the lock, or the read lock.
+func (ps *PubSub[K, T]) resolve(key K) K {
+	if len(ps.aliases) == 0 {
+		return key
+	}
+
+	if a, ok := ps.aliases[key]; ok {
+		return a.key
+	}
+
+	return key
+}
+
+// resolved is resolve taking the read lock.
+func (ps *PubSub[K, T]) resolved(key K) K {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return ps.resolve(key)
+}
+
+// resolvePublish is resolve for publishes: it also returns the alias if
+// this is its first use. The caller must hold the read lock.
+func (ps *PubSub[K, T]) resolvePublish(key K) (K, *alias[K]) {
+	if len(ps.aliases) == 0 {
+		return key, nil
+	}
+
+	a, ok := ps.aliases[key]
+	if !ok {
+		return key, nil
+	}
+
+	if a.used.Swap(true) {
+		return a.key, nil
+	}
+
+	return a.key, a
+}
+
+// aliasUsed reports the first publish to an alias.
+func (ps *PubSub[K, T]) aliasUsed(a *alias[K], key K) {
+	ps.opts.logger.Warn("pubsub: publish to key alias", "key", a.old, "to", key)
+	ps.watch.notify(a.old, KeyAliasUsed)
+	ps.watch.dispatch()
+}
@@ -0,0 +1,233 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestAliasKey(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	old := make(chan int, 4)
+	current := make(chan int, 4)
+	ps.SubscribeRef([]string{"orders"}, old)
+	ps.SubscribeRef([]string{"orders"}, old)
+	ps.Subscribe([]string{"orders.v2"}, current)
+	var handled int
+	ps.SubscribeFunc([]string{"orders"}, func(context.Context, string, int) error {
+		handled++
+		return nil
+	})
+
+	var events []pubsub.KeyEvent
+	ps.WatchKeys(func(key string, event pubsub.KeyEvent) {
+		if key == "orders" {
+			events = append(events, event)
+		}
+	})
+
+	if err := ps.AliasKey("orders", "orders.v2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// publishes to either key reach the same subscribers
+	for _, key := range []string{"orders", "orders.v2", "orders"} {
+		if n, err := ps.Publish(context.Background(), key, 1); n != 3 || err != nil {
+			t.Errorf("publish to %s: expected 3 deliveries, got %d, %v", key, n, err)
+		}
+	}
+	if len(old) != 3 || len(current) != 3 || handled != 3 {
+		t.Errorf("expected 3 messages each, got %d, %d and %d", len(old), len(current), handled)
+	}
+
+	// the alias was used once, after the old key lost its subscribers
+	if len(events) != 2 || events[0] != pubsub.KeyRemoved || events[1] != pubsub.KeyAliasUsed {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	// the reference counts moved with the subscriptions
+	ps.Unsubscribe([]string{"orders"}, old)
+	if keys := ps.KeysOf(old); len(keys) != 1 || keys[0] != "orders.v2" {
+		t.Errorf("expected old subscribed to orders.v2 once more, got %v", keys)
+	}
+	ps.Unsubscribe([]string{"orders.v2"}, old)
+	if keys := ps.KeysOf(old); len(keys) != 0 {
+		t.Errorf("expected old unsubscribed, got %v", keys)
+	}
+
+	ps.UnaliasKey("orders")
+	if n, _ := ps.Publish(context.Background(), "orders", 2); n != 0 {
+		t.Errorf("expected no subscribers of the old key after unaliasing, got %d", n)
+	}
+}
+
+func TestAliasKeyHandlers(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var calls int
+	ps.SubscribeFunc([]string{"old", "new"}, func(context.Context, string, int) error {
+		calls++
+		return nil
+	})
+
+	if err := ps.AliasKey("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := ps.Publish(context.Background(), "old", 1); n != 1 || calls != 1 {
+		t.Errorf("expected the handler of both keys called once, got %d deliveries, %d calls", n, calls)
+	}
+
+	nop := func(context.Context, string, int) error { return nil }
+	ps.SubscribeAfter([]string{"a"}, "cache", nil, nop)
+	ps.SubscribeAfter([]string{"b"}, "cache", nil, nop)
+	if err := ps.AliasKey("a", "b"); !errors.Is(err, pubsub.ErrHandlerConflict) {
+		t.Errorf("expected ErrHandlerConflict, got %v", err)
+	}
+}
+
+func TestAliasKeyPriority(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	high, low := make(chan int, 8), make(chan int, 8)
+	ps.SubscribePriority([]string{"old"}, high, 10)
+	ps.Subscribe([]string{"new"}, low)
+
+	if err := ps.AliasKey("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 5 {
+		order := make(chan chan int, 2)
+		ps.PublishAsync(context.Background(), "new", 1, func(c pubsub.Confirmation[string, int]) {
+			order <- c.Subscriber
+		})
+		if first, second := <-order, <-order; first != high || second != low {
+			t.Fatal("expected the channel moved by AliasKey to keep its priority")
+		}
+	}
+}
+
+func TestOnDemandAlias(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	if err := ps.AliasKey("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{}, 1)
+	cancel := ps.OnDemand("old", func(ctx context.Context) {
+		started <- struct{}{}
+		<-ctx.Done()
+	}, nil)
+	defer cancel()
+
+	ps.Subscribe([]string{"old"}, make(chan int, 1)) // subscribes to new
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Error("expected the producer of the alias to start")
+	}
+}
+
+func TestAliasKeyChain(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"c"}, ch)
+
+	ps.AliasKey("a", "b")
+	ps.AliasKey("b", "c")
+	if n, _ := ps.Publish(context.Background(), "a", 1); n != 1 {
+		t.Errorf("expected the alias of an alias to follow it, got %d deliveries", n)
+	}
+
+	if err := ps.AliasKey("c", "a"); !errors.Is(err, pubsub.ErrAliasCycle) {
+		t.Errorf("expected ErrAliasCycle, got %v", err)
+	}
+	if err := ps.AliasKey("d", "d"); !errors.Is(err, pubsub.ErrAliasCycle) {
+		t.Errorf("expected ErrAliasCycle, got %v", err)
+	}
+}
+
+func TestAliasKeyRetention(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(3))
+	for i := range 2 {
+		ps.Publish(context.Background(), "old", i)
+	}
+	if err := ps.AliasKey("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	ps.Publish(context.Background(), "new", 2)
+
+	for _, key := range []string{"old", "new"} {
+		got := ps.Retained(key)
+		if len(got) != 3 || got[0].Seq != 1 || got[2].Msg != 2 {
+			t.Errorf("%s: expected the history moved to new, got %v", key, got)
+		}
+		if msg, _ := ps.Latest(key); msg != 2 {
+			t.Errorf("%s: expected latest 2, got %d", key, msg)
+		}
+	}
+
+	ch := make(chan int, 1)
+	history, err := ps.SubscribeWithReplay("old", ch)
+	if err != nil || len(history) != 3 {
+		t.Fatalf("expected 3 replayed messages, got %v, %v", history, err)
+	}
+
+	c, _ := ps.Consumer("reader", "old")
+	if msg, err := c.Next(context.Background()); err != nil || msg.Msg != 0 {
+		t.Errorf("expected the consumer to read the moved history, got %v, %v", msg, err)
+	}
+
+	if s := ps.Stats("old"); s.Subscribers != 1 || s.Retained != 3 {
+		t.Errorf("expected the statistics of new, got %+v", s)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ps.WaitForSubscribers(ctx, "old", 1); err != nil {
+		t.Errorf("expected the subscriber of new counted, got %v", err)
+	}
+}
+
+func TestAliasKeyMergesRetention(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(3))
+	ps.Publish(context.Background(), "new", 0)
+	ps.Publish(context.Background(), "old", 1)
+	ps.Publish(context.Background(), "old", 2)
+	ps.Publish(context.Background(), "old", 3)
+
+	if err := ps.AliasKey("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := ps.Retained("new")
+	if len(got) != 3 || got[0].Msg != 1 || got[0].Seq != 2 || got[2].Seq != 4 {
+		t.Errorf("expected 1, 2 and 3 numbered after 0, got %v", got)
+	}
+}
+
+func TestUnaliasKeyRenamesSubscriptions(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	sub, err := ps.NewSubscription(context.Background(), "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited := make(chan int, 1)
+	_, cancel := ps.SubscribeFor([]string{"old"}, limited, pubsub.WithLimit(10))
+	remove := ps.SubscribeFunc([]string{"old"}, func(context.Context, string, int) error { return nil })
+
+	ps.AliasKey("old", "new")
+	ps.UnaliasKey("old")
+
+	if keys := sub.Keys(); len(keys) != 1 || keys[0] != "new" {
+		t.Errorf("expected the subscription keys renamed, got %v", keys)
+	}
+
+	sub.Close()
+	cancel()
+	remove()
+	if n, _ := ps.Publish(context.Background(), "new", 1); n != 0 {
+		t.Errorf("expected nothing left subscribed to new, got %d deliveries", n)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build !pubsubdebug
+
+// Mutation checks of pubsubdebug builds hash messages with reflection,
+// which allocates.
+
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+// TestPublishAllocs checks the allocations per publish documented on
+// Publish.
+func TestPublishAllocs(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		opts  []pubsub.Option
+		setup func(ps *pubsub.PubSub[string, string])
+	}{
+		{name: "channels"},
+		{name: "drop", opts: []pubsub.Option{pubsub.WithDropPolicy(pubsub.DropNewest)}, setup: func(ps *pubsub.PubSub[string, string]) {
+			ps.Subscribe([]string{"k"}, make(chan string))
+		}},
+		{name: "handler", setup: func(ps *pubsub.PubSub[string, string]) {
+			ps.SubscribeFunc([]string{"k"}, func(context.Context, string, string) error { return nil })
+		}},
+		{name: "tap", setup: func(ps *pubsub.PubSub[string, string]) {
+			ps.Tap(func(string, string) {})
+		}},
+		{name: "stats", opts: []pubsub.Option{pubsub.WithKeyStats()}},
+		{name: "limit", setup: func(ps *pubsub.PubSub[string, string]) {
+			ps.SubscribeFor([]string{"k"}, make(chan string, 1000), pubsub.WithLimit(1000))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := pubsub.New[string, string](tt.opts...)
+			ch := make(chan string, 1)
+			ps.Subscribe([]string{"k"}, ch)
+			if tt.setup != nil {
+				tt.setup(ps)
+			}
+
+			allocs := testing.AllocsPerRun(100, func() {
+				ps.Publish(ctx, "k", "message")
+				<-ch
+			})
+			if allocs != 0 {
+				t.Errorf("expected no allocations, got %.1f per publish", allocs)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package amqpbridge connects a PubSub instance to AMQP brokers such as
+// RabbitMQ, so existing exchanges and queues interoperate with services
+// using PubSub. Messages published to selected keys are published to an
+// exchange with a routing key derived from the key, and deliveries
+// consumed from queues are published to keys and acknowledged once
+// published. The package does not depend on an AMQP client: it works with
+// any channel implementing the Channel interface.
+package amqpbridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Delivery is a message consumed from a queue.
+type Delivery struct {
+	Queue       string
+	Exchange    string
+	RoutingKey  string
+	DeliveryTag uint64
+	ContentType string
+	Body        []byte
+}
+
+// Publishing is a message published to an exchange.
+type Publishing struct {
+	ContentType string
+	Body        []byte
+}
+
+// Channel is the subset of an AMQP channel used by the bridge.
+type Channel interface {
+	// Publish publishes the message to the exchange with the routing key.
+	Publish(ctx context.Context, exchange, routingKey string, msg Publishing) error
+	// Consume starts consuming the queue without automatic
+	// acknowledgements. The channel is closed when consuming stops.
+	Consume(ctx context.Context, queue string) (<-chan Delivery, error)
+	// Ack acknowledges the delivery.
+	Ack(tag uint64) error
+	// Nack rejects the delivery, returning it to the queue if requeue is
+	// set, otherwise discarding or dead-lettering it.
+	Nack(tag uint64, requeue bool) error
+}
+
+// Bridge forwards messages between an AMQP broker and a PubSub instance.
+//
+// Note: a key should not be both consumed and produced, otherwise
+// messages will loop between the broker and PubSub.
+type Bridge[K comparable, T any] struct {
+	PubSub  *pubsub.PubSub[K, T]
+	Channel Channel
+
+	// Queues are consumed, and KeyOf maps their deliveries to the key they
+	// are published to, typically by routing key; deliveries for which it
+	// returns false are acknowledged and skipped.
+	Queues []string
+	KeyOf  func(Delivery) (K, bool)
+
+	// Produce lists the keys whose messages are published to Exchange
+	// with the routing key returned by RoutingKey.
+	Produce    []K
+	Exchange   string
+	RoutingKey func(K) string
+
+	// Codec converts message bodies; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the channels subscribed to Produce keys.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be decoded,
+	// encoded or published. Undecodable deliveries are rejected without
+	// requeueing, so the broker can dead-letter them, and the bridge
+	// continues. If OnError is nil, such errors stop the bridge.
+	OnError func(d Delivery, err error)
+}
+
+// Run starts forwarding in both configured directions and blocks until
+// the context is canceled or an unhandled error occurs. Deliveries are
+// acknowledged after being published to all subscribers of their key and
+// requeued if publishing fails.
+func (b *Bridge[K, T]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for _, queue := range b.Queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.consume(ctx, queue))
+		}()
+	}
+
+	for _, key := range b.Produce {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.produce(ctx, key))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// errConsumerClosed is returned when a queue stops delivering.
+var errConsumerClosed = errors.New("amqpbridge: consumer closed")
+
+// consume publishes the deliveries of the queue to PubSub.
+func (b *Bridge[K, T]) consume(ctx context.Context, queue string) error {
+	deliveries, err := b.Channel.Consume(ctx, queue)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case d, ok := <-deliveries:
+			if !ok {
+				return errConsumerClosed
+			}
+
+			if err := b.forward(ctx, d); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// forward publishes a single delivery and settles it.
+func (b *Bridge[K, T]) forward(ctx context.Context, d Delivery) error {
+	key, ok := b.KeyOf(d)
+	if !ok {
+		return b.Channel.Ack(d.DeliveryTag)
+	}
+
+	msg, err := b.codec().Unmarshal(d.Body)
+	if err != nil {
+		if b.OnError == nil {
+			b.Channel.Nack(d.DeliveryTag, true)
+			return err
+		}
+
+		b.OnError(d, err)
+		return b.Channel.Nack(d.DeliveryTag, false)
+	}
+
+	if _, err := b.PubSub.Publish(ctx, key, msg); err != nil {
+		b.Channel.Nack(d.DeliveryTag, true)
+		return err
+	}
+
+	return b.Channel.Ack(d.DeliveryTag)
+}
+
+// produce subscribes to the key and publishes its messages to the exchange.
+func (b *Bridge[K, T]) produce(ctx context.Context, key K) error {
+	ch := make(chan T, b.Buffer)
+	keys := []K{key}
+	b.PubSub.Subscribe(keys, ch)
+	defer b.PubSub.UnsubscribeAndDrain(keys, ch)
+
+	c := b.codec()
+	routingKey := b.RoutingKey(key)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case value := <-ch:
+			msg := Publishing{ContentType: c.ContentType()}
+			body, err := c.Marshal(value)
+			if err == nil {
+				msg.Body = body
+				err = b.Channel.Publish(ctx, b.Exchange, routingKey, msg)
+			}
+
+			if err != nil {
+				if b.OnError == nil || ctx.Err() != nil {
+					return err
+				}
+
+				b.OnError(Delivery{Exchange: b.Exchange, RoutingKey: routingKey, ContentType: msg.ContentType, Body: msg.Body}, err)
+			}
+		}
+	}
+}
+
+// codec returns the configured codec or the default one.
+func (b *Bridge[K, T]) codec() codec.Codec[T] {
+	if b.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return b.Codec
+}
@@ -0,0 +1,121 @@
+package amqpbridge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/amqpbridge"
+)
+
+type published struct {
+	exchange, routingKey string
+	msg                  amqpbridge.Publishing
+}
+
+type fakeChannel struct {
+	deliveries chan amqpbridge.Delivery
+	published  chan published
+
+	mu     sync.Mutex
+	acked  []uint64
+	nacked []uint64
+}
+
+func (c *fakeChannel) Publish(_ context.Context, exchange, routingKey string, msg amqpbridge.Publishing) error {
+	c.published <- published{exchange, routingKey, msg}
+	return nil
+}
+
+func (c *fakeChannel) Consume(context.Context, string) (<-chan amqpbridge.Delivery, error) {
+	return c.deliveries, nil
+}
+
+func (c *fakeChannel) Ack(tag uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked = append(c.acked, tag)
+	return nil
+}
+
+func (c *fakeChannel) Nack(tag uint64, _ bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nacked = append(c.nacked, tag)
+	return nil
+}
+
+func TestBridgeConsume(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"orders.created"}, ch)
+
+	channel := &fakeChannel{deliveries: make(chan amqpbridge.Delivery, 2)}
+	bridge := &amqpbridge.Bridge[string, int]{
+		PubSub:  ps,
+		Channel: channel,
+		Queues:  []string{"orders"},
+		KeyOf: func(d amqpbridge.Delivery) (string, bool) {
+			return d.RoutingKey, true
+		},
+		OnError: func(amqpbridge.Delivery, error) {},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	channel.deliveries <- amqpbridge.Delivery{RoutingKey: "orders.created", DeliveryTag: 1, Body: []byte("oops")}
+	channel.deliveries <- amqpbridge.Delivery{RoutingKey: "orders.created", DeliveryTag: 2, Body: []byte("42")}
+
+	select {
+	case got := <-ch:
+		if got != 42 {
+			t.Errorf("expected 42, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("delivery not published")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if len(channel.nacked) != 1 || channel.nacked[0] != 1 {
+		t.Errorf("expected the undecodable delivery rejected, got %v", channel.nacked)
+	}
+
+	if len(channel.acked) != 1 || channel.acked[0] != 2 {
+		t.Errorf("expected the published delivery acknowledged, got %v", channel.acked)
+	}
+}
+
+func TestBridgeProduce(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	channel := &fakeChannel{published: make(chan published, 1)}
+	bridge := &amqpbridge.Bridge[string, int]{
+		PubSub:     ps,
+		Channel:    channel,
+		Produce:    []string{"orders/created"},
+		Exchange:   "events",
+		RoutingKey: func(key string) string { return "routing." + key },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	for len(ps.Keys()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ps.Publish(ctx, "orders/created", 7)
+
+	p := <-channel.published
+	if p.exchange != "events" || p.routingKey != "routing.orders/created" || string(p.msg.Body) != "7" || p.msg.ContentType != "application/json" {
+		t.Errorf("unexpected publishing %+v", p)
+	}
+}
@@ -0,0 +1,149 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"reflect"
+)
+
+// PublishAny delivers the message to exactly one channel subscribed to
+// the key or to a pattern matching it, whichever is ready first, and
+// returns it; ties are broken at random. It dispatches a task to any
+// available worker without defining consumer groups: workers subscribe
+// their channels to a queue key and receive each task once among all of
+// them.
+//
+// If no channel is ready, PublishAny waits for the first one that
+// becomes ready, or fails with a *DeliveryError[K] wrapping the context
+// error. With a drop policy other than Block it doesn't wait, and returns
+// a nil channel if the message was dropped. It fails with
+// ErrNoSubscribers if the key has no subscribed channels. Paused channels
+// and subscriptions with credits enabled are skipped, and handlers
+// registered with SubscribeFunc are not called. Messages are authorized,
+// converted, validated, tapped and retained as by Publish.
+func (ps *PubSub[K, T]) PublishAny(ctx context.Context, key K, msg T) (chan T, error) {
+	key, msg, _, err := ps.prepare(ctx, key, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	ps.taps.call(key, msg)
+
+	ch, spent, err := ps.anycast(ctx, key, msg)
+	if spent != nil {
+		ps.removeSpent([]*limit[K, T]{spent})
+	}
+
+	switch {
+	case ch != nil:
+		ps.recordPublish(key, 1, 0)
+	case errors.Is(err, ErrNoSubscribers):
+		ps.recordPublish(key, 0, 0)
+	case !errors.Is(err, ErrClosed):
+		ps.recordPublish(key, 0, 1)
+	}
+
+	return ch, err
+}
+
+// anycast sends the message to one subscribed channel under the read lock.
+// It also returns the limit of the channel if it received its last
+// message.
+func (ps *PubSub[K, T]) anycast(ctx context.Context, key K, msg T) (chan T, *limit[K, T], error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if ps.state != stateOpen {
+		return nil, nil, ErrClosed
+	}
+
+	ps.retain(key, msg)
+	msg = ps.clone(msg)
+
+	// Claim a delivery from every candidate, giving back all but the one
+	// used.
+	type candidate struct {
+		ch   chan T
+		l    *limit[K, T]
+		last bool
+	}
+	var candidates []candidate
+	targets, _ := ps.targets(key)
+	for ch := range targets {
+		if _, paused := ps.paused[ch]; paused {
+			continue
+		}
+		if _, credited := ps.credited[ch]; credited {
+			continue
+		}
+
+		l := ps.limits[ch]
+		if taken, last := l.take(); taken {
+			candidates = append(candidates, candidate{ch, l, last})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil, ErrNoSubscribers
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+
+	chosen, stopped := -1, false
+	start := ps.opts.clock.Now()
+	for i := 0; i < len(candidates) && chosen < 0; i++ {
+		select {
+		case candidates[i].ch <- msg:
+			chosen = i
+		default:
+		}
+	}
+
+	if chosen < 0 && ps.dropPolicy(key) == Block {
+		cases := make([]reflect.SelectCase, len(candidates)+2)
+		for i, c := range candidates {
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(c.ch), Send: reflect.ValueOf(&msg).Elem()}
+		}
+		cases[len(candidates)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+		cases[len(candidates)+1] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ps.stopping)}
+
+		switch i, _, _ := reflect.Select(cases); {
+		case i < len(candidates):
+			chosen = i
+		case i > len(candidates):
+			stopped = true
+		}
+	}
+
+	for i, c := range candidates {
+		if i != chosen {
+			c.l.giveBack()
+		}
+	}
+
+	if chosen < 0 {
+		if ps.dropPolicy(key) != Block {
+			return nil, nil, nil
+		}
+		if stopped {
+			return nil, nil, ErrClosed
+		}
+
+		return nil, nil, &DeliveryError[K]{Key: key, Subscribers: len(candidates), Err: contextErr(ctx)}
+	}
+
+	c := candidates[chosen]
+	if sub, managed := ps.managed[c.ch]; managed {
+		sub.stats.record(true, len(c.ch), ps.Size(msg), ps.opts.clock.Now().Sub(start))
+	}
+
+	var spent *limit[K, T]
+	if c.last {
+		spent = c.l
+	}
+
+	return c.ch, spent, nil
+}
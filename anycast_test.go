@@ -0,0 +1,69 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestPublishAny(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	busy, idle := make(chan int), make(chan int, 1)
+	ps.Subscribe([]string{"tasks"}, busy)
+	ps.Subscribe([]string{"tasks"}, idle)
+
+	ch, err := ps.PublishAny(context.Background(), "tasks", 1)
+	if err != nil || ch != idle || <-idle != 1 {
+		t.Fatalf("expected the ready channel to get the task, got %v, %v", ch, err)
+	}
+
+	idle <- 0 // now only busy can become ready
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-busy
+	}()
+
+	if ch, err := ps.PublishAny(context.Background(), "tasks", 2); err != nil || ch != busy {
+		t.Errorf("expected to wait for the first ready channel, got %v, %v", ch, err)
+	}
+}
+
+func TestPublishAnyOnce(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	workers := make([]chan int, 3)
+	for i := range workers {
+		workers[i] = make(chan int, 10)
+		ps.Subscribe([]string{"tasks"}, workers[i])
+	}
+
+	for i := range 9 {
+		ps.PublishAny(context.Background(), "tasks", i)
+	}
+
+	var total int
+	for _, ch := range workers {
+		total += len(ch)
+	}
+	if total != 9 {
+		t.Errorf("expected each task delivered once, got %d deliveries", total)
+	}
+}
+
+func TestPublishAnyErrors(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	if _, err := ps.PublishAny(context.Background(), "tasks", 1); !errors.Is(err, pubsub.ErrNoSubscribers) {
+		t.Errorf("expected ErrNoSubscribers, got %v", err)
+	}
+
+	ps.Subscribe([]string{"tasks"}, make(chan int))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var derr *pubsub.DeliveryError[string]
+	if _, err := ps.PublishAny(ctx, "tasks", 1); !errors.As(err, &derr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a delivery error, got %v", err)
+	}
+}
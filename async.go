@@ -0,0 +1,125 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+)
+
+// Outcome is the result of the delivery of a message to a subscriber.
+type Outcome int
+
+const (
+	Delivered     Outcome = iota // sent to the channel or its pause buffer
+	Dropped                      // by the drop policy, a full pause buffer or a failed dependency
+	TimedOut                     // the publish context ended first
+	HandlerFailed                // a handler returned an error
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Delivered:
+		return "delivered"
+	case Dropped:
+		return "dropped"
+	case TimedOut:
+		return "timed out"
+	case HandlerFailed:
+		return "handler failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Confirmation reports the outcome of a delivery by PublishAsync.
+type Confirmation[K comparable, T any] struct {
+	Key        K
+	Subscriber chan T // nil for handlers registered with SubscribeFunc
+	Outcome    Outcome
+	Err        error // context error if TimedOut, handler error if HandlerFailed
+}
+
+// PublishAsync is like Publish, but returns without waiting for the
+// deliveries and calls confirm with the outcome of each, for publishers
+// that can't block but still account for every subscriber. It returns
+// the error of the authorizer, converter or validator synchronously; the
+// deliveries then run in a new goroutine, with the context bounding how
+// long they may block. Unlike Publish, a subscriber timing out doesn't
+// stop the deliveries to the others: each is attempted and confirmed.
+//
+// confirm is called from the delivery goroutine, once per subscribed
+// channel and handler and sequentially, and must not block for long: the
+// publish holds the read lock while confirming deliveries to channels.
+// Messages published asynchronously may be delivered out of order.
+func (ps *PubSub[K, T]) PublishAsync(ctx context.Context, key K, msg T, confirm func(Confirmation[K, T])) error {
+	key, msg, handlers, err := ps.prepare(ctx, key, msg)
+	if err != nil {
+		return err
+	}
+
+	ps.mu.RLock()
+	state := ps.state
+	ps.mu.RUnlock()
+	if state != stateOpen {
+		return ErrClosed
+	}
+
+	go func() {
+		ps.taps.call(key, msg)
+
+		delivered, dropped, spent, err := ps.broadcastAsync(ctx, key, msg, confirm)
+		if !errors.Is(err, ErrClosed) {
+			ps.recordPublish(key, delivered, dropped)
+		}
+
+		if len(spent) > 0 {
+			ps.removeSpent(spent)
+		}
+
+		ps.callConfirm(ctx, key, msg, handlers, func(o Outcome, err error) {
+			confirm(Confirmation[K, T]{Key: key, Outcome: o, Err: err})
+		})
+	}()
+
+	return nil
+}
+
+// broadcastAsync sends the message to every subscribed channel under the
+// read lock, confirming each delivery. It returns the number of
+// deliveries and of failed ones, and the spent limited subscriptions.
+func (ps *PubSub[K, T]) broadcastAsync(ctx context.Context, key K, msg T, confirm func(Confirmation[K, T])) (delivered, dropped int, spent []*limit[K, T], err error) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if ps.state != stateOpen {
+		return 0, 0, nil, ErrClosed
+	}
+
+	ps.retain(key, msg)
+
+	t := tally[K, T]{confirm: func(ch chan T, o Outcome, err error) {
+		confirm(Confirmation[K, T]{Key: key, Subscriber: ch, Outcome: o, Err: err})
+	}}
+
+	subs := ps.subscribers[key]
+	channels := subs.channels()
+	var matched []chan T
+	if ps.patterns.n > 0 {
+		matched = ps.matchPatterns(key, channels)
+	}
+
+	n := len(channels) + len(matched)
+	if subs != nil && subs.tiers != nil {
+		for _, ch := range subs.tiers {
+			ps.sendTo(ctx, key, ch, msg, n, &t)
+		}
+	} else {
+		for ch := range channels {
+			ps.sendTo(ctx, key, ch, msg, n, &t)
+		}
+	}
+	for _, ch := range matched {
+		ps.sendTo(ctx, key, ch, msg, n, &t)
+	}
+
+	return t.delivered, t.dropped, t.spent, nil
+}
@@ -0,0 +1,74 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestPublishAsync(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDropPolicy(pubsub.DropNewest))
+	ready, full := make(chan int, 1), make(chan int)
+	ps.Subscribe([]string{"k"}, ready)
+	ps.Subscribe([]string{"k"}, full)
+	failure := errors.New("failed")
+	ps.SubscribeFunc([]string{"k"}, func(context.Context, string, int) error { return failure })
+
+	confirmations := make(chan pubsub.Confirmation[string, int], 3)
+	err := ps.PublishAsync(context.Background(), "k", 1, func(c pubsub.Confirmation[string, int]) {
+		confirmations <- c
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[chan int]pubsub.Outcome)
+	for range 3 {
+		c := <-confirmations
+		if c.Key != "k" {
+			t.Errorf("expected key k, got %q", c.Key)
+		}
+		if c.Subscriber == nil && (c.Outcome != pubsub.HandlerFailed || !errors.Is(c.Err, failure)) {
+			t.Errorf("expected the handler failure, got %v, %v", c.Outcome, c.Err)
+		}
+		got[c.Subscriber] = c.Outcome
+	}
+
+	if got[ready] != pubsub.Delivered || got[full] != pubsub.Dropped {
+		t.Errorf("expected delivered and dropped, got %v", got)
+	}
+}
+
+func TestPublishAsyncTimedOut(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	a, b := make(chan int), make(chan int)
+	ps.Subscribe([]string{"k"}, a)
+	ps.Subscribe([]string{"k"}, b)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	confirmations := make(chan pubsub.Confirmation[string, int], 2)
+	ps.PublishAsync(ctx, "k", 1, func(c pubsub.Confirmation[string, int]) { confirmations <- c })
+
+	for range 2 {
+		if c := <-confirmations; c.Outcome != pubsub.TimedOut || !errors.Is(c.Err, context.DeadlineExceeded) {
+			t.Errorf("expected each subscriber to time out, got %v, %v", c.Outcome, c.Err)
+		}
+	}
+}
+
+func TestPublishAsyncClosed(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.Close()
+
+	err := ps.PublishAsync(context.Background(), "k", 1, func(pubsub.Confirmation[string, int]) {
+		t.Error("unexpected confirmation")
+	})
+	if !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrForbidden is the error for an Authorizer to return when it denies an
+// operation without a more specific reason. The package never returns it
+// on its own: operations fail only with the errors of the authorizer.
+var ErrForbidden = errors.New("pubsub: forbidden")
+
+// Action is an operation checked by an Authorizer.
+type Action int
+
+const (
+	ActionSubscribe Action = iota
+	ActionPublish
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionSubscribe:
+		return "subscribe"
+	case ActionPublish:
+		return "publish"
+	default:
+		return "unknown"
+	}
+}
+
+// Authorizer decides whether the principal of the context may perform
+// the action on the key. A nil error allows the operation. Aliases made by
+// AliasKey are resolved first, so subscribes and publishes are checked
+// against the same key.
+type Authorizer[K comparable] interface {
+	Authorize(ctx context.Context, action Action, key K) error
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc[K comparable] func(ctx context.Context, action Action, key K) error
+
+func (f AuthorizerFunc[K]) Authorize(ctx context.Context, action Action, key K) error {
+	return f(ctx, action, key)
+}
+
+// principalKey is the context key of the principal.
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the principal, for example
+// the authenticated user of a gateway request.
+func WithPrincipal(ctx context.Context, principal any) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal returns the principal carried by the context.
+func Principal(ctx context.Context) (any, bool) {
+	principal := ctx.Value(principalKey{})
+	return principal, principal != nil
+}
+
+// SetAuthorizer sets the authorizer consulted by Publish and
+// SubscribeContext. A nil authorizer allows everything.
+//
+// Subscribe and Unsubscribe are not checked: they are meant for trusted
+// in-process code, while gateways serving remote clients should use
+// SubscribeContext with the request context.
+func (ps *PubSub[K, T]) SetAuthorizer(a Authorizer[K]) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.authorizer = a
+}
+
+// SubscribeContext is like Subscribe, but first asks the authorizer
+// whether the principal of the context may subscribe to every key.
+// If any key is denied, the channel is not subscribed at all.
+func (ps *PubSub[K, T]) SubscribeContext(ctx context.Context, keys []K, ch chan T) error {
+	for _, key := range keys {
+		if err := ps.authorize(ctx, ActionSubscribe, key); err != nil {
+			ps.opts.logger.Warn("pubsub: subscribe denied", "key", key, "error", err)
+			return err
+		}
+	}
+
+	return ps.subscribe(keys, ch, false)
+}
+
+// authorize consults the authorizer, if any, about the resolved key.
+func (ps *PubSub[K, T]) authorize(ctx context.Context, action Action, key K) error {
+	ps.mu.RLock()
+	a := ps.authorizer
+	key = ps.resolve(key)
+	ps.mu.RUnlock()
+
+	if a == nil {
+		return nil
+	}
+
+	return a.Authorize(ctx, action, key)
+}
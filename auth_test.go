@@ -0,0 +1,67 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+// ownKeysOnly allows users to access only keys prefixed with their name.
+var ownKeysOnly = pubsub.AuthorizerFunc[string](func(ctx context.Context, action pubsub.Action, key string) error {
+	user, ok := pubsub.Principal(ctx)
+	if !ok {
+		return nil // trusted in-process caller
+	}
+	if !strings.HasPrefix(key, user.(string)+"/") {
+		return pubsub.ErrForbidden
+	}
+	return nil
+})
+
+func TestAuthorizer(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	ps.SetAuthorizer(ownKeysOnly)
+	alice := pubsub.WithPrincipal(context.Background(), "alice")
+
+	ch := make(chan string, 1)
+	err := ps.SubscribeContext(alice, []string{"alice/inbox", "bob/inbox"}, ch)
+	if !errors.Is(err, pubsub.ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if n, _ := ps.Publish(context.Background(), "alice/inbox", "x"); n != 0 {
+		t.Error("denied subscription must not be partially applied")
+	}
+
+	if err := ps.SubscribeContext(alice, []string{"alice/inbox"}, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ps.Publish(alice, "bob/inbox", "hi"); !errors.Is(err, pubsub.ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+	if n, err := ps.Publish(alice, "alice/inbox", "hi"); err != nil || n != 1 {
+		t.Errorf("expected 1 delivery, got %d, %v", n, err)
+	}
+}
+
+func TestAuthorizerAlias(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	ps.SetAuthorizer(ownKeysOnly)
+	alice := pubsub.WithPrincipal(context.Background(), "alice")
+
+	// the alias looks like alice's key but resolves to bob's
+	if err := ps.AliasKey("alice/old", "bob/inbox"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ps.SubscribeContext(alice, []string{"alice/old"}, make(chan string, 1))
+	if !errors.Is(err, pubsub.ErrForbidden) {
+		t.Errorf("expected ErrForbidden for subscribe, got %v", err)
+	}
+	if _, err := ps.Publish(alice, "alice/old", "hi"); !errors.Is(err, pubsub.ErrForbidden) {
+		t.Errorf("expected ErrForbidden for publish, got %v", err)
+	}
+}
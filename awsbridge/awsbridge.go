@@ -0,0 +1,242 @@
+// Package awsbridge mirrors PubSub keys to AWS: messages published to
+// selected keys are published to SNS topics in batches, and messages
+// received from SQS queues, typically subscribed to SNS topics, are
+// published to keys and deleted in batches, with a limit on messages in
+// flight for flow control. The package does not depend on the AWS SDK: it
+// works with any client implementing the SNS and SQS interfaces, and its
+// Bridge implements bridge.Bridge.
+package awsbridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/bridge"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Limits of the AWS batch APIs.
+const (
+	MaxPublishBatch = 10 // SNS PublishBatch entries
+	MaxReceive      = 10 // SQS ReceiveMessage messages
+)
+
+// DefaultBatchDelay is the time messages are collected into a batch, used
+// when Bridge.BatchDelay is zero.
+const DefaultBatchDelay = 10 * time.Millisecond
+
+// Message is an SNS or SQS message as seen by the bridge. Bodies are
+// strings in both services.
+type Message struct {
+	ID            string
+	ReceiptHandle string // set on received messages
+	Body          string
+	Attributes    map[string]string
+}
+
+// SNS publishes batches of messages to topics.
+type SNS interface {
+	// PublishBatch publishes up to MaxPublishBatch messages to the topic.
+	PublishBatch(ctx context.Context, topicARN string, msgs []Message) error
+}
+
+// SQS receives messages from queues.
+type SQS interface {
+	// ReceiveMessage returns up to max messages of the queue, waiting for
+	// messages with long polling.
+	ReceiveMessage(ctx context.Context, queueURL string, max int) ([]Message, error)
+	// DeleteMessageBatch deletes the received messages with the receipt
+	// handles.
+	DeleteMessageBatch(ctx context.Context, queueURL string, receiptHandles ...string) error
+}
+
+// Bridge forwards messages between AWS and a PubSub instance.
+//
+// Note: a key should not be both consumed and produced, otherwise
+// messages will loop between AWS and PubSub.
+type Bridge[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// SNS, Produce and Topic configure the PubSub to SNS direction:
+	// messages of the keys are published to the topic ARN returned by
+	// Topic, in batches collected for at most BatchDelay.
+	SNS        SNS
+	Produce    []K
+	Topic      func(K) string
+	BatchDelay time.Duration
+
+	// SQS, Queues and KeyOf configure the SQS to PubSub direction. KeyOf
+	// maps a received message to the key it is published to; messages for
+	// which it returns false are deleted and skipped. At most MaxInFlight
+	// messages of a queue, up to MaxReceive, are received and not yet
+	// deleted at a time.
+	SQS         SQS
+	Queues      []string
+	KeyOf       func(Message) (K, bool)
+	MaxInFlight int
+
+	// Codec converts message bodies; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the channels subscribed to Produce keys.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be decoded,
+	// which are deleted and skipped, and for batches that could not be
+	// published, which are dropped. If OnError is nil, such errors stop
+	// the bridge.
+	OnError func(msg Message, err error)
+}
+
+var _ bridge.Bridge = (*Bridge[string, any])(nil)
+
+// Run starts forwarding in both configured directions and blocks until
+// the context is canceled or an unhandled error occurs. Received messages
+// are deleted after being published to all subscribers of their key;
+// messages that failed become visible again in the queue.
+func (b *Bridge[K, T]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for _, queue := range b.Queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.consume(ctx, queue))
+		}()
+	}
+
+	for _, key := range b.Produce {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.produce(ctx, key))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// consume receives messages of the queue and publishes them to PubSub.
+func (b *Bridge[K, T]) consume(ctx context.Context, queue string) error {
+	limit := b.MaxInFlight
+	if limit <= 0 || limit > MaxReceive {
+		limit = MaxReceive
+	}
+
+	for {
+		msgs, err := b.SQS.ReceiveMessage(ctx, queue, limit)
+		if err != nil {
+			return err
+		}
+
+		if len(msgs) == 0 {
+			continue
+		}
+
+		handles := make([]string, 0, len(msgs))
+		for _, msg := range msgs {
+			if err := b.forward(ctx, msg); err != nil {
+				return err
+			}
+
+			handles = append(handles, msg.ReceiptHandle)
+		}
+
+		if err := b.SQS.DeleteMessageBatch(ctx, queue, handles...); err != nil {
+			return err
+		}
+	}
+}
+
+// forward publishes a single received message.
+func (b *Bridge[K, T]) forward(ctx context.Context, msg Message) error {
+	key, ok := b.KeyOf(msg)
+	if !ok {
+		return nil
+	}
+
+	value, err := b.codec().Unmarshal([]byte(msg.Body))
+	if err != nil {
+		if b.OnError == nil {
+			return err
+		}
+
+		b.OnError(msg, err)
+		return nil
+	}
+
+	_, err = b.PubSub.Publish(ctx, key, value)
+	return err
+}
+
+// produce subscribes to the key and publishes its messages in batches.
+func (b *Bridge[K, T]) produce(ctx context.Context, key K) error {
+	ch := make(chan T, b.Buffer)
+	keys := []K{key}
+	b.PubSub.Subscribe(keys, ch)
+	defer b.PubSub.UnsubscribeAndDrain(keys, ch)
+
+	delay := b.BatchDelay
+	if delay <= 0 {
+		delay = DefaultBatchDelay
+	}
+
+	topic := b.Topic(key)
+	msgs := make([]Message, 0, MaxPublishBatch)
+
+	return bridge.Batch(ctx, ch, MaxPublishBatch, delay, func(batch []T) error {
+		msgs = msgs[:0]
+		for _, value := range batch {
+			data, err := b.codec().Marshal(value)
+			if err != nil {
+				if b.OnError == nil {
+					return err
+				}
+
+				b.OnError(Message{}, err)
+				continue
+			}
+
+			msgs = append(msgs, Message{Body: string(data)})
+		}
+
+		if len(msgs) == 0 {
+			return nil
+		}
+
+		err := b.SNS.PublishBatch(ctx, topic, msgs)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		if b.OnError == nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			b.OnError(msg, err)
+		}
+
+		return nil
+	})
+}
+
+// codec returns the configured codec or the default one.
+func (b *Bridge[K, T]) codec() codec.Codec[T] {
+	if b.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return b.Codec
+}
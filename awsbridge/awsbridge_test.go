@@ -0,0 +1,117 @@
+package awsbridge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/awsbridge"
+)
+
+type fakeAWS struct {
+	received  chan []awsbridge.Message
+	published chan []awsbridge.Message
+
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (a *fakeAWS) PublishBatch(_ context.Context, _ string, msgs []awsbridge.Message) error {
+	a.published <- append([]awsbridge.Message(nil), msgs...)
+	return nil
+}
+
+func (a *fakeAWS) ReceiveMessage(ctx context.Context, _ string, _ int) ([]awsbridge.Message, error) {
+	select {
+	case msgs := <-a.received:
+		return msgs, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (a *fakeAWS) DeleteMessageBatch(_ context.Context, _ string, handles ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.deleted = append(a.deleted, handles...)
+	return nil
+}
+
+func TestBridgeConsume(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"orders"}, ch)
+
+	aws := &fakeAWS{received: make(chan []awsbridge.Message, 1)}
+	var reported int
+	bridge := &awsbridge.Bridge[string, int]{
+		PubSub:  ps,
+		SQS:     aws,
+		Queues:  []string{"https://sqs/orders"},
+		KeyOf:   func(awsbridge.Message) (string, bool) { return "orders", true },
+		OnError: func(awsbridge.Message, error) { reported++ },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	aws.received <- []awsbridge.Message{
+		{ReceiptHandle: "r1", Body: "not json"},
+		{ReceiptHandle: "r2", Body: "42"},
+	}
+
+	select {
+	case got := <-ch:
+		if got != 42 {
+			t.Errorf("expected 42, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not published")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if len(aws.deleted) != 2 || reported != 1 {
+		t.Errorf("expected both messages deleted and one reported, got %v, %d", aws.deleted, reported)
+	}
+}
+
+func TestBridgeProduceBatchLimit(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	aws := &fakeAWS{published: make(chan []awsbridge.Message, 10)}
+	bridge := &awsbridge.Bridge[string, int]{
+		PubSub:     ps,
+		SNS:        aws,
+		Produce:    []string{"events"},
+		Topic:      func(string) string { return "arn:aws:sns:events" },
+		BatchDelay: time.Hour,
+		Buffer:     20,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	for len(ps.Keys()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := range awsbridge.MaxPublishBatch {
+		ps.Publish(ctx, "events", i)
+	}
+
+	select {
+	case batch := <-aws.published:
+		if len(batch) != awsbridge.MaxPublishBatch || batch[0].Body != "0" {
+			t.Errorf("unexpected batch %+v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("full batch not published")
+	}
+}
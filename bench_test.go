@@ -0,0 +1,43 @@
+package pubsub_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mdigger/pubsub/pstest"
+)
+
+// BenchmarkFanout measures publishing across subscribers per key, keys,
+// message sizes and concurrent publishers, for example:
+//
+//	go test -run '^$' -bench 'Fanout/subs=10/' -benchmem
+func BenchmarkFanout(b *testing.B) {
+	for _, subs := range []int{1, 10, 100} {
+		for _, keys := range []int{1, 100} {
+			for _, size := range []int{16, 1024} {
+				for _, publishers := range []int{1, 8} {
+					name := fmt.Sprintf("subs=%d/keys=%d/size=%d/publishers=%d", subs, keys, size, publishers)
+					b.Run(name, func(b *testing.B) {
+						pstest.BenchmarkFanout(b, pstest.Fanout[int, []byte]{
+							Keys:        intKeys(keys),
+							Subscribers: subs,
+							Buffer:      64,
+							Publishers:  publishers,
+							Msg:         make([]byte, size),
+						})
+					})
+				}
+			}
+		}
+	}
+}
+
+// intKeys returns the keys 0 to n-1.
+func intKeys(n int) []int {
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i
+	}
+
+	return keys
+}
@@ -0,0 +1,121 @@
+package bridge
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mdigger/pubsub"
+)
+
+// Backfill publishes recent history of bridged keys fetched from the
+// remote system when a key gains its first local subscriber, so late
+// starting services don't begin with an empty view. Bridges that support
+// backfilling call Wait before publishing a live message, so the history
+// of a key is published before live messages that arrive meanwhile.
+//
+// A live message published at the very moment the key gets its first
+// subscriber may still precede the history. Subject patterns, reported
+// with PatternAdded, are not backfilled.
+type Backfill[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// Fetch returns the recent messages of the key, oldest first.
+	Fetch func(ctx context.Context, key K) ([]T, error)
+
+	// Keys reports whether the key is bridged; all keys if nil.
+	Keys func(K) bool
+
+	// OnError, if set, is called when fetching or publishing the history
+	// fails; live delivery continues without it.
+	OnError func(key K, err error)
+
+	mu      sync.Mutex
+	running map[K]chan struct{} // closed when the backfill of a key ends
+}
+
+// Start watches the keys and backfills them until the context is
+// canceled or the returned function is called; the function waits for
+// backfills in progress to finish.
+func (b *Backfill[K, T]) Start(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+
+	unwatch := b.PubSub.WatchKeys(func(key K, event pubsub.KeyEvent) {
+		if event != pubsub.KeyAdded || (b.Keys != nil && !b.Keys(key)) || ctx.Err() != nil {
+			return
+		}
+
+		done := make(chan struct{})
+		b.mu.Lock()
+		if _, ok := b.running[key]; ok {
+			b.mu.Unlock()
+			return
+		}
+		if b.running == nil {
+			b.running = make(map[K]chan struct{})
+		}
+		b.running[key] = done
+		b.mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				b.mu.Lock()
+				delete(b.running, key)
+				b.mu.Unlock()
+				close(done)
+			}()
+
+			if err := b.backfill(ctx, key); err != nil && b.OnError != nil {
+				b.OnError(key, err)
+			}
+		}()
+	})
+
+	return func() {
+		unwatch()
+		cancel()
+		wg.Wait()
+	}
+}
+
+// backfill fetches and publishes the history of the key.
+func (b *Backfill[K, T]) backfill(ctx context.Context, key K) error {
+	msgs, err := b.Fetch(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		if _, err := b.PubSub.Publish(ctx, key, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Wait waits until the backfill of the key in progress, if any, ends.
+// It returns the context error if the context is done first. A nil
+// Backfill returns right away.
+func (b *Backfill[K, T]) Wait(ctx context.Context, key K) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	done, ok := b.running[key]
+	b.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,94 @@
+package bridge_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/bridge"
+)
+
+func TestBackfill(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	fetched := make(chan string, 2)
+	release := make(chan struct{})
+	b := &bridge.Backfill[string, int]{
+		PubSub: ps,
+		Keys:   func(key string) bool { return key != "local" },
+		Fetch: func(_ context.Context, key string) ([]int, error) {
+			fetched <- key
+			<-release
+			return []int{1, 2}, nil
+		},
+	}
+
+	ctx := context.Background()
+	stop := b.Start(ctx)
+	defer stop()
+
+	ps.Subscribe([]string{"local"}, make(chan int))
+	ch := make(chan int, 10)
+	ps.Subscribe([]string{"remote"}, ch)
+
+	if key := <-fetched; key != "remote" {
+		t.Fatalf("expected remote to be backfilled, got %s", key)
+	}
+
+	live := make(chan struct{})
+	go func() {
+		defer close(live)
+		b.Wait(ctx, "remote") // what a bridge does before a live publish
+		ps.Publish(ctx, "remote", 3)
+	}()
+
+	select {
+	case <-live:
+		t.Fatal("expected the live message to wait for the backfill")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-live
+
+	for want := 1; want <= 3; want++ {
+		if got := <-ch; got != want {
+			t.Errorf("expected %d, got %d", want, got)
+		}
+	}
+
+	select {
+	case key := <-fetched:
+		t.Errorf("unexpected backfill of %s", key)
+	default:
+	}
+}
+
+func TestBackfillPattern(t *testing.T) {
+	ps := pubsub.New[pubsub.Subject, int]()
+	fetched := make(chan pubsub.Subject, 2)
+	b := &bridge.Backfill[pubsub.Subject, int]{
+		PubSub: ps,
+		Fetch: func(_ context.Context, key pubsub.Subject) ([]int, error) {
+			fetched <- key
+			return nil, nil
+		},
+	}
+
+	stop := b.Start(context.Background())
+	defer stop()
+
+	ch := make(chan int, 1)
+	pubsub.SubscribePattern(ps, pubsub.ParseSubject("orders.>"), ch)
+	ps.Subscribe([]pubsub.Subject{pubsub.ParseSubject("orders.eu")}, ch)
+
+	if key := <-fetched; key != pubsub.ParseSubject("orders.eu") {
+		t.Errorf("unexpected backfill of %s", key)
+	}
+
+	select {
+	case key := <-fetched:
+		t.Errorf("unexpected backfill of %s", key)
+	default:
+	}
+}
@@ -0,0 +1,112 @@
+// Package bridge defines what the broker bridges of this module have in
+// common: each bridge forwards messages between a PubSub instance and an
+// external system until its Run method returns, so bridges to different
+// systems can be configured separately and run together:
+//
+//	err := bridge.Run(ctx, kafka, nats, sqs)
+//
+// It also provides the batching used by bridges to systems with batch
+// APIs.
+package bridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Bridge forwards messages between a PubSub instance and an external
+// system. Run blocks until the context is canceled or the bridge fails.
+// The Bridge types of kafkabridge, redisbridge, mqttbridge, natsbridge,
+// amqpbridge, gcpbridge and awsbridge implement it.
+type Bridge interface {
+	Run(ctx context.Context) error
+}
+
+// Run runs the bridges until the context is canceled or one of them
+// fails, which stops the others. It returns the first error other than
+// the cancellation of the context.
+func Run(ctx context.Context, bridges ...Bridge) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for _, b := range bridges {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.Run(ctx))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// Batch receives values from the channel and passes them to flush in
+// batches of up to size values, at least one. A batch is flushed when it
+// is full or delay after its first value was received, so values don't
+// wait long when traffic is low; with zero delay, a batch is flushed as
+// soon as no more values are ready. Batch returns the context error or
+// the first error returned by flush. The batch passed to flush is reused
+// after flush returns.
+func Batch[T any](ctx context.Context, ch <-chan T, size int, delay time.Duration, flush func([]T) error) error {
+	size = max(size, 1)
+	batch := make([]T, 0, size)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case v := <-ch:
+			batch = append(batch[:0], v)
+		}
+
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			batch = fill(ctx, ch, batch, size, timer.C)
+			timer.Stop()
+		} else {
+			batch = fill(ctx, ch, batch, size, nil)
+		}
+
+		if err := flush(batch); err != nil {
+			return err
+		}
+	}
+}
+
+// fill appends values from the channel to the batch until it has size
+// values, the timer expires or the context is done. With a nil timer, it
+// only appends the values that are ready.
+func fill[T any](ctx context.Context, ch <-chan T, batch []T, size int, expired <-chan time.Time) []T {
+	for len(batch) < size {
+		select {
+		case v := <-ch:
+			batch = append(batch, v)
+			continue
+		default:
+		}
+
+		if expired == nil {
+			return batch
+		}
+
+		select {
+		case v := <-ch:
+			batch = append(batch, v)
+		case <-expired:
+			return batch
+		case <-ctx.Done():
+			return batch
+		}
+	}
+
+	return batch
+}
@@ -0,0 +1,82 @@
+package bridge_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub/amqpbridge"
+	"github.com/mdigger/pubsub/awsbridge"
+	"github.com/mdigger/pubsub/bridge"
+	"github.com/mdigger/pubsub/gcpbridge"
+	"github.com/mdigger/pubsub/kafkabridge"
+	"github.com/mdigger/pubsub/mqttbridge"
+	"github.com/mdigger/pubsub/natsbridge"
+	"github.com/mdigger/pubsub/redisbridge"
+)
+
+var _ = []bridge.Bridge{
+	(*kafkabridge.Bridge[string, int])(nil),
+	(*redisbridge.Bridge[string, int])(nil),
+	(*mqttbridge.Bridge[string, int])(nil),
+	(*natsbridge.Bridge[string, int])(nil),
+	(*amqpbridge.Bridge[string, int])(nil),
+	(*gcpbridge.Bridge[string, int])(nil),
+	(*awsbridge.Bridge[string, int])(nil),
+}
+
+type runFunc func(ctx context.Context) error
+
+func (f runFunc) Run(ctx context.Context) error { return f(ctx) }
+
+func TestRun(t *testing.T) {
+	failure := errors.New("failure")
+	stopped := make(chan struct{})
+
+	err := bridge.Run(context.Background(),
+		runFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			close(stopped)
+			return ctx.Err()
+		}),
+		runFunc(func(context.Context) error { return failure }),
+	)
+
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the failure, got %v", err)
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("expected the other bridge stopped")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ch := make(chan int, 10)
+	for i := range 5 {
+		ch <- i
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var batches [][]int
+	err := bridge.Batch(ctx, ch, 2, time.Millisecond, func(batch []int) error {
+		batches = append(batches, slices.Clone(batch))
+		if len(batches) == 3 {
+			cancel()
+		}
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	want := [][]int{{0, 1}, {2, 3}, {4}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("unexpected batches %v", batches)
+	}
+}
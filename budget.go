@@ -0,0 +1,132 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrBudgetExceeded is returned when an operation would exceed the memory
+// budget set with WithMemoryBudget.
+var ErrBudgetExceeded = errors.New("pubsub: memory budget exceeded")
+
+// EvictionPolicy defines what happens to a message that doesn't fit into
+// the memory budget.
+type EvictionPolicy int
+
+const (
+	// EvictOldest makes room by discarding the oldest message of the
+	// buffer the new message is added to. If that buffer is empty, the
+	// new message is rejected. This is the default.
+	EvictOldest EvictionPolicy = iota
+	// RejectNew discards the new message.
+	RejectNew
+)
+
+// WithMemoryBudget limits the number of messages held by the library
+// across all its buffers: messages kept for paused channels and the
+// capacity of channels of managed subscriptions. Messages exceeding the
+// budget are handled by the eviction policy; NewSubscription fails with
+// ErrBudgetExceeded. Zero means no limit.
+func WithMemoryBudget(maxMessages int, policy EvictionPolicy) Option {
+	return func(o *options) {
+		o.budget = max(maxMessages, 0)
+		o.eviction = policy
+	}
+}
+
+// WithByteBudget limits the total size, as reported by the Sizer set with
+// WithSizer, of messages held by the library. Channel capacity of managed
+// subscriptions is not counted, as its size is unknown. Zero means no
+// limit; without a Sizer all messages have size zero.
+func WithByteBudget(maxBytes int) Option {
+	return func(o *options) {
+		o.byteBudget = max(maxBytes, 0)
+	}
+}
+
+// MemoryUsage describes the use of the memory budget.
+type MemoryUsage struct {
+	Messages  int    // messages held or reserved
+	Bytes     int    // size of the held messages
+	Limit     int    // message budget, zero if unlimited
+	ByteLimit int    // byte budget, zero if unlimited
+	Evicted   uint64 // messages discarded to make room
+	Rejected  uint64 // messages and subscriptions refused
+}
+
+// MemoryUsage returns the current use of the memory budget.
+func (ps *PubSub[K, T]) MemoryUsage() MemoryUsage {
+	b := &ps.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return MemoryUsage{
+		Messages:  b.used,
+		Bytes:     b.bytes,
+		Limit:     ps.opts.budget,
+		ByteLimit: ps.opts.byteBudget,
+		Evicted:   b.evicted,
+		Rejected:  b.rejected,
+	}
+}
+
+// budget accounts messages held by the library.
+type budget struct {
+	mu       sync.Mutex
+	used     int
+	bytes    int
+	evicted  uint64
+	rejected uint64
+}
+
+// reserve accounts n messages of the given total size if they fit into
+// the limits.
+func (b *budget) reserve(n, size int, o *options) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if o.budget > 0 && b.used+n > o.budget ||
+		o.byteBudget > 0 && b.bytes+size > o.byteBudget {
+		return false
+	}
+
+	b.used += n
+	b.bytes += size
+
+	return true
+}
+
+// fits reports whether n more messages of the given total size would fit
+// into the limits.
+func (b *budget) fits(n, size int, o *options) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return (o.budget == 0 || b.used+n <= o.budget) &&
+		(o.byteBudget == 0 || b.bytes+size <= o.byteBudget)
+}
+
+// release returns n messages of the given total size to the budget.
+func (b *budget) release(n, size int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= n
+	b.bytes -= size
+}
+
+// evict counts a message discarded to make room for another one.
+func (b *budget) evict() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.evicted++
+}
+
+// reject counts a message or subscription refused for lack of budget.
+func (b *budget) reject() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rejected++
+}
@@ -0,0 +1,78 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestMemoryBudgetEvictOldest(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithMemoryBudget(3, pubsub.EvictOldest))
+	a, b := make(chan int), make(chan int)
+	ps.Subscribe([]string{"a"}, a)
+	ps.Subscribe([]string{"b"}, b)
+	ps.Pause(a, 10)
+	ps.Pause(b, 10)
+
+	ctx := context.Background()
+	ps.Publish(ctx, "a", 1)
+	ps.Publish(ctx, "a", 2)
+	ps.Publish(ctx, "b", 1)
+	ps.Publish(ctx, "b", 2) // evicts b's oldest message
+
+	want := pubsub.MemoryUsage{Messages: 3, Limit: 3, Evicted: 1}
+	if got := ps.MemoryUsage(); got != want {
+		t.Errorf("unexpected usage %+v, want %+v", got, want)
+	}
+
+	if got := ps.Resume(b); !slices.Equal(got, []int{2}) {
+		t.Errorf("expected [2] kept for b, got %v", got)
+	}
+
+	ps.Resume(a)
+	if got := ps.MemoryUsage().Messages; got != 0 {
+		t.Errorf("expected budget to be released, got %d messages", got)
+	}
+}
+
+func TestMemoryBudgetRejectNew(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithMemoryBudget(1, pubsub.RejectNew))
+	ch := make(chan int)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.Pause(ch, 10)
+
+	ps.Publish(context.Background(), "k", 1)
+	if n, _ := ps.Publish(context.Background(), "k", 2); n != 0 {
+		t.Errorf("expected the message over budget to be rejected, got %d deliveries", n)
+	}
+
+	if got := ps.Resume(ch); !slices.Equal(got, []int{1}) {
+		t.Errorf("expected [1], got %v", got)
+	}
+}
+
+func TestMemoryBudgetSubscription(t *testing.T) {
+	ps := pubsub.New[string, int](
+		pubsub.WithBufferSize(4),
+		pubsub.WithMemoryBudget(6, pubsub.RejectNew),
+	)
+
+	sub, err := ps.NewSubscription(context.Background(), "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ps.NewSubscription(context.Background(), "k"); !errors.Is(err, pubsub.ErrBudgetExceeded) {
+		t.Errorf("expected ErrBudgetExceeded, got %v", err)
+	}
+
+	sub.Close()
+	if sub, err := ps.NewSubscription(context.Background(), "k"); err != nil {
+		t.Errorf("expected the closed subscription to free the budget, got %v", err)
+	} else {
+		sub.Close()
+	}
+}
@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Clock is the source of time used for timeouts, rate limits and other
+// time-based features, so tests can replace real time with a fake clock.
+type Clock interface {
+	Now() time.Time
+
+	// AfterFunc calls f in its own goroutine after the duration elapses.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending AfterFunc call.
+type Timer interface {
+	// Stop prevents the call, reporting whether it was still pending.
+	Stop() bool
+}
+
+// WithClock sets the clock used by the PubSub instance and the helpers
+// built on it. The default is the system clock.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// Clock returns the clock set with WithClock, for helpers that need to
+// measure time consistently with the PubSub instance.
+func (ps *PubSub[K, T]) Clock() Clock {
+	return ps.opts.clock
+}
+
+// systemClock is the Clock of the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// withTimeout is context.WithTimeout driven by the configured clock.
+// Contexts timed out by a fake clock report context.DeadlineExceeded as
+// their cause, but have no deadline.
+func (ps *PubSub[K, T]) withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ps.opts.clock.(systemClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := ps.opts.clock.AfterFunc(d, func() {
+		cancel(context.DeadlineExceeded)
+	})
+
+	return ctx, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// contextErr returns the error of a done context, reporting contexts
+// timed out by a fake clock as context.DeadlineExceeded.
+func contextErr(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+
+	return ctx.Err()
+}
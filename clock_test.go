@@ -0,0 +1,57 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestClockPublishTimeout(t *testing.T) {
+	clock := pstest.NewClock(time.Unix(0, 0))
+	ps := pubsub.New[string, int](pubsub.WithClock(clock))
+	ps.Subscribe([]string{"k"}, make(chan int)) // never read
+
+	done := make(chan error)
+	go func() {
+		_, err := ps.PublishWithTimeout("k", 1, time.Hour)
+		done <- err
+	}()
+
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("publish returned before the timeout: %v", err)
+	default:
+	}
+
+	clock.Advance(time.Hour)
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestClockTenantRate(t *testing.T) {
+	clock := pstest.NewClock(time.Unix(0, 0))
+	ps := pubsub.New[string, int](pubsub.WithClock(clock))
+	tenant := ps.Scoped(pubsub.Prefix("a/")).Tenant(pubsub.Quota{PublishRate: 1})
+
+	ctx := context.Background()
+	if _, err := tenant.Publish(ctx, "k", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tenant.Publish(ctx, "k", 2); !errors.Is(err, pubsub.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if _, err := tenant.Publish(ctx, "k", 3); err != nil {
+		t.Errorf("expected a token after a second, got %v", err)
+	}
+}
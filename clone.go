@@ -0,0 +1,123 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Cloner returns an independent copy of a message.
+type Cloner[T any] func(msg T) T
+
+// WithCloner sets the function copying messages, so that each subscribed
+// channel and handler receives its own copy and subscribers mutating
+// pointer, slice or map payloads don't race with each other or the
+// publisher. DeepCopy can be used for plain data types. Taps and retained
+// messages see the published message. T must be the message type of the
+// PubSub instance, otherwise New panics.
+//
+// Copying a message for every subscriber costs an allocation or more per
+// delivery; without a Cloner messages are shared and must not be mutated.
+func WithCloner[T any](cloner Cloner[T]) Option {
+	return func(o *options) {
+		o.cloner = cloner
+	}
+}
+
+// clone returns a copy of the message made by the Cloner, or the message
+// itself if no Cloner is set.
+func (ps *PubSub[K, T]) clone(msg T) T {
+	if ps.cloner == nil {
+		return msg
+	}
+
+	return ps.cloner(msg)
+}
+
+// setCloner applies the WithCloner option.
+func (ps *PubSub[K, T]) setCloner() {
+	if ps.opts.cloner == nil {
+		return
+	}
+
+	cloner, ok := ps.opts.cloner.(Cloner[T])
+	if !ok {
+		panic(fmt.Sprintf("pubsub: WithCloner for %T used with message type %v", ps.opts.cloner, reflect.TypeFor[T]()))
+	}
+
+	ps.cloner = cloner
+}
+
+// DeepCopy is a Cloner copying the pointers, slices, maps, arrays,
+// interfaces and exported struct fields reachable from the message.
+// Unexported fields, channels and functions are shared. It uses
+// reflection, so a hand-written Cloner is faster; the message must not
+// contain cycles.
+func DeepCopy[T any](msg T) T {
+	v := reflect.ValueOf(&msg).Elem()
+	return deepCopy(v).Interface().(T)
+}
+
+// deepCopy returns a copy of the value.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := range v.Len() {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			c.SetMapIndex(deepCopy(iter.Key()), deepCopy(iter.Value()))
+		}
+		return c
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := range v.NumField() {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+
+	default:
+		return v
+	}
+}
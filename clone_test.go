@@ -0,0 +1,75 @@
+package pubsub_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestWithCloner(t *testing.T) {
+	ps := pubsub.New[string, []int](pubsub.WithCloner(pubsub.Cloner[[]int](slices.Clone[[]int])))
+	a, b := make(chan []int, 1), make(chan []int, 1)
+	ps.Subscribe([]string{"k"}, a)
+	ps.Subscribe([]string{"k"}, b)
+
+	var handled []int
+	ps.SubscribeFunc([]string{"k"}, func(_ context.Context, _ string, msg []int) error {
+		handled = msg
+		return nil
+	})
+
+	msg := []int{1, 2}
+	ps.Publish(context.Background(), "k", msg)
+	msg[0] = 0
+
+	got := <-a
+	got[1] = 0
+	if other := <-b; !slices.Equal(other, []int{1, 2}) || !slices.Equal(handled, []int{1, 2}) {
+		t.Errorf("expected independent copies, got %v and %v", other, handled)
+	}
+}
+
+func TestWithClonerType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a Cloner of another type")
+		}
+	}()
+
+	pubsub.New[string, int](pubsub.WithCloner(pubsub.Cloner[string](func(s string) string { return s })))
+}
+
+func TestDeepCopy(t *testing.T) {
+	type inner struct{ Tags []string }
+	type event struct {
+		ID     int
+		Attrs  map[string]*inner
+		Items  [2][]int
+		Any    any
+		Nil    *inner
+		secret *inner
+	}
+
+	shared := &inner{Tags: []string{"s"}}
+	msg := event{
+		ID:     1,
+		Attrs:  map[string]*inner{"a": {Tags: []string{"x"}}},
+		Items:  [2][]int{{1}, {2}},
+		Any:    []byte("any"),
+		secret: shared,
+	}
+
+	c := pubsub.DeepCopy(msg)
+	c.Attrs["a"].Tags[0] = "y"
+	c.Items[0][0] = 0
+	c.Any.([]byte)[0] = 'A'
+
+	if msg.Attrs["a"].Tags[0] != "x" || msg.Items[0][0] != 1 || string(msg.Any.([]byte)) != "any" {
+		t.Errorf("expected the original unchanged, got %+v", msg)
+	}
+	if c.ID != 1 || c.Nil != nil || c.secret != shared {
+		t.Errorf("expected values copied and unexported fields shared, got %+v", c)
+	}
+}
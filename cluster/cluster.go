@@ -0,0 +1,398 @@
+// Package cluster implements an experimental peer-to-peer mesh of PubSub
+// instances. Each node tells its peers which keys it has local subscribers
+// for, and forwards publishes only to the peers interested in the key.
+//
+// Nodes form a full mesh: every node connects to every peer it knows
+// about. Forwarded messages are published locally and never forwarded
+// again, which prevents loops. Peers are configured statically with
+// AddPeer, which can also be driven by an external membership or gossip
+// library; a node connecting to another one is added as its peer
+// automatically. Frames are encoded with gob and messages with a codec,
+// gob by default.
+package cluster
+
+import (
+	"context"
+	"encoding/gob"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Default settings used when the corresponding Node fields are zero.
+const (
+	DefaultRetryDelay = time.Second
+	DefaultQueueSize  = 1024
+)
+
+// frame kinds.
+const (
+	frameHello    = iota // Addr identifies the connecting node
+	frameInterest        // Present reports whether Addr has subscribers for Key
+	framePublish         // Data was published to Key
+)
+
+// frame is a single message sent to a peer.
+type frame[K comparable] struct {
+	Kind    int
+	Addr    string
+	Key     K
+	Present bool
+	Data    []byte
+}
+
+// Node is a member of the cluster.
+type Node[K comparable, T any] struct {
+	// RetryDelay is the delay between connection attempts to a peer.
+	RetryDelay time.Duration
+
+	// QueueSize is the capacity of the per-peer queue of forwarded
+	// messages. Messages forwarded to a peer whose queue is full are
+	// dropped; changes of interest never are.
+	QueueSize int
+
+	// Codec encodes forwarded messages; codec.Gob if nil.
+	Codec codec.Codec[T]
+
+	ps   *pubsub.PubSub[K, T]
+	addr string
+
+	mu     sync.Mutex
+	ctx    context.Context
+	local  map[K]map[chan T]struct{} // local subscribed channels per key
+	remote map[string]*remote[K]     // keys with subscribers per peer
+	peers  map[string]*peer[K, T]    // outgoing links per peer address
+	wg     sync.WaitGroup            // peer link goroutines
+}
+
+// remote is the interest of a peer, received on one connection.
+type remote[K comparable] struct {
+	keys map[K]struct{}
+}
+
+// peer is an outgoing link to another node.
+type peer[K comparable, T any] struct {
+	queue    chan frame[K] // forwarded messages
+	interest map[K]bool    // changes of interest not sent yet, guarded by Node.mu
+	changed  chan struct{} // signaled when interest changes
+	cancel   context.CancelFunc
+}
+
+// NewNode returns a node publishing to ps. The address is the one the
+// node listens on; it identifies the node to its peers.
+func NewNode[K comparable, T any](ps *pubsub.PubSub[K, T], addr string) *Node[K, T] {
+	return &Node[K, T]{
+		ps:     ps,
+		addr:   addr,
+		local:  make(map[K]map[chan T]struct{}),
+		remote: make(map[string]*remote[K]),
+		peers:  make(map[string]*peer[K, T]),
+	}
+}
+
+// Run accepts connections from peers on the listener and maintains links
+// to added peers until the context is canceled.
+func (n *Node[K, T]) Run(ctx context.Context, l net.Listener) error {
+	n.mu.Lock()
+	n.ctx = ctx
+	for addr, p := range n.peers {
+		n.start(addr, p)
+	}
+	n.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer n.wg.Wait()
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.serve(ctx, conn)
+		}()
+	}
+}
+
+// AddPeer adds a node to connect to.
+func (n *Node[K, T]) AddPeer(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.peers[addr]; ok || addr == n.addr {
+		return
+	}
+
+	size := n.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+
+	p := &peer[K, T]{
+		queue:    make(chan frame[K], size),
+		interest: make(map[K]bool),
+		changed:  make(chan struct{}, 1),
+	}
+	n.peers[addr] = p
+	if n.ctx != nil {
+		n.start(addr, p)
+	}
+}
+
+// RemovePeer disconnects from the node and forgets about it.
+func (n *Node[K, T]) RemovePeer(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if p, ok := n.peers[addr]; ok {
+		if p.cancel != nil {
+			p.cancel()
+		}
+		delete(n.peers, addr)
+	}
+
+	delete(n.remote, addr)
+}
+
+// Subscribe subscribes the channel to the keys on the local PubSub and
+// advertises interest in them to the peers. Subscribing a channel twice
+// to a key is a no-op.
+func (n *Node[K, T]) Subscribe(keys []K, ch chan T) {
+	n.ps.Subscribe(keys, ch)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, key := range keys {
+		chans, ok := n.local[key]
+		if !ok {
+			chans = make(map[chan T]struct{})
+			n.local[key] = chans
+			n.advertise(key, true)
+		}
+		chans[ch] = struct{}{}
+	}
+}
+
+// Unsubscribe removes the channel subscription and withdraws interest in
+// keys left without local subscribers.
+func (n *Node[K, T]) Unsubscribe(keys []K, ch chan T) {
+	n.ps.Unsubscribe(keys, ch)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, key := range keys {
+		chans, ok := n.local[key]
+		if _, subscribed := chans[ch]; !ok || !subscribed {
+			continue
+		}
+
+		delete(chans, ch)
+		if len(chans) == 0 {
+			delete(n.local, key)
+			n.advertise(key, false)
+		}
+	}
+}
+
+// Publish publishes the message locally and forwards it to the peers
+// with subscribers for the key. It returns the number of local deliveries;
+// forwarding is asynchronous and best-effort.
+func (n *Node[K, T]) Publish(ctx context.Context, key K, msg T) (int, error) {
+	var data []byte
+	n.mu.Lock()
+	for addr, r := range n.remote {
+		if _, ok := r.keys[key]; ok {
+			if p, ok := n.peers[addr]; ok {
+				if data == nil {
+					var err error
+					if data, err = n.codec().Marshal(msg); err != nil {
+						n.mu.Unlock()
+						return 0, err
+					}
+				}
+
+				p.send(frame[K]{Kind: framePublish, Key: key, Data: data})
+			}
+		}
+	}
+	n.mu.Unlock()
+
+	return n.ps.Publish(ctx, key, msg)
+}
+
+// advertise records the change of interest in the key for every peer
+// and wakes their links. Changes are never dropped: only the last one of
+// a key is sent. Must be called with n.mu held.
+func (n *Node[K, T]) advertise(key K, present bool) {
+	for _, p := range n.peers {
+		p.interest[key] = present
+		select {
+		case p.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// start runs the link to the peer. Must be called with n.mu held.
+func (n *Node[K, T]) start(addr string, p *peer[K, T]) {
+	ctx, cancel := context.WithCancel(n.ctx)
+	p.cancel = cancel
+
+	delay := n.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		var d net.Dialer
+		for {
+			if conn, err := d.DialContext(ctx, "tcp", addr); err == nil {
+				n.link(ctx, conn, p)
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// link sends the hello frame and the current interest, then the changes
+// of interest and the queued frames, until the connection fails.
+func (n *Node[K, T]) link(ctx context.Context, conn net.Conn, p *peer[K, T]) {
+	defer conn.Close()
+
+	enc := gob.NewEncoder(conn)
+	n.mu.Lock()
+	frames := []frame[K]{{Kind: frameHello, Addr: n.addr}}
+	for key := range n.local {
+		frames = append(frames, frame[K]{Kind: frameInterest, Key: key, Present: true})
+	}
+	clear(p.interest) // the whole interest is sent
+	n.mu.Unlock()
+
+	for {
+		for _, f := range frames {
+			if err := enc.Encode(f); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case f := <-p.queue:
+			frames = []frame[K]{f}
+		case <-p.changed:
+			n.mu.Lock()
+			frames = frames[:0]
+			for key, present := range p.interest {
+				frames = append(frames, frame[K]{Kind: frameInterest, Key: key, Present: present})
+			}
+			clear(p.interest)
+			n.mu.Unlock()
+		}
+	}
+}
+
+// serve handles frames received from a connected peer.
+func (n *Node[K, T]) serve(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	var (
+		addr string
+		r    *remote[K] // of this connection
+	)
+	defer func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		// A newer connection of the peer may have replaced it.
+		if r != nil && n.remote[addr] == r {
+			delete(n.remote, addr)
+		}
+	}()
+
+	dec := gob.NewDecoder(conn)
+	for {
+		var f frame[K]
+		if err := dec.Decode(&f); err != nil {
+			return
+		}
+
+		switch f.Kind {
+		case frameHello:
+			addr = f.Addr
+			n.AddPeer(addr) // links are symmetric
+			n.mu.Lock()
+			r = &remote[K]{keys: make(map[K]struct{})}
+			n.remote[addr] = r
+			n.mu.Unlock()
+
+		case frameInterest:
+			n.mu.Lock()
+			if r != nil {
+				if f.Present {
+					r.keys[f.Key] = struct{}{}
+				} else {
+					delete(r.keys, f.Key)
+				}
+			}
+			n.mu.Unlock()
+
+		case framePublish:
+			// forwarded messages are published locally only
+			msg, err := n.codec().Unmarshal(f.Data)
+			if err != nil {
+				return
+			}
+
+			if _, err := n.ps.Publish(ctx, f.Key, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// codec returns the configured codec or the default one.
+func (n *Node[K, T]) codec() codec.Codec[T] {
+	if n.Codec == nil {
+		return codec.Gob[T]{}
+	}
+
+	return n.Codec
+}
+
+// send queues the forwarded message, dropping it if the queue is full.
+func (p *peer[K, T]) send(f frame[K]) {
+	select {
+	case p.queue <- f:
+	default:
+	}
+}
@@ -0,0 +1,166 @@
+package cluster_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/cluster"
+)
+
+func startNode(t *testing.T, ctx context.Context) (*cluster.Node[string, int], string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := cluster.NewNode(pubsub.New[string, int](), l.Addr().String())
+	node.RetryDelay = 10 * time.Millisecond
+	node.QueueSize = 1
+	go node.Run(ctx, l)
+	return node, l.Addr().String()
+}
+
+// forwarded publishes to a until ch receives a message, reporting false
+// if it doesn't before the timeout.
+func forwarded(ctx context.Context, a *cluster.Node[string, int], key string, ch chan int, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	for {
+		a.Publish(ctx, key, 1)
+		select {
+		case <-ch:
+			return true
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func TestForwardToInterestedPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, _ := startNode(t, ctx)
+	b, addrB := startNode(t, ctx)
+	c, addrC := startNode(t, ctx)
+	a.AddPeer(addrB)
+	a.AddPeer(addrC)
+
+	chB := make(chan int, 10)
+	b.Subscribe([]string{"k"}, chB)
+	chC := make(chan int, 10)
+	c.Subscribe([]string{"other"}, chC)
+
+	// publish until the interest of b has propagated to a
+	deadline := time.After(2 * time.Second)
+	for received := false; !received; {
+		a.Publish(ctx, "k", 1)
+		select {
+		case msg := <-chB:
+			if msg != 1 {
+				t.Fatalf("expected 1, got %d", msg)
+			}
+			received = true
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("message not forwarded")
+		}
+	}
+
+	select {
+	case msg := <-chC:
+		t.Errorf("uninterested peer received %d", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestNoLoops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, _ := startNode(t, ctx)
+	b, addrB := startNode(t, ctx)
+	a.AddPeer(addrB)
+
+	chA := make(chan int, 10)
+	a.Subscribe([]string{"k"}, chA)
+	chB := make(chan int, 10)
+	b.Subscribe([]string{"k"}, chB)
+
+	deadline := time.After(2 * time.Second)
+	for received := false; !received; {
+		a.Publish(ctx, "k", 1)
+		select {
+		case <-chB:
+			received = true
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("message not forwarded")
+		}
+	}
+
+	// drain and make sure nothing bounces back once publishing stops
+	time.Sleep(50 * time.Millisecond)
+	for len(chA) > 0 {
+		<-chA
+	}
+	for len(chB) > 0 {
+		<-chB
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(chA) != 0 || len(chB) != 0 {
+		t.Errorf("messages bounced: a=%d b=%d", len(chA), len(chB))
+	}
+}
+
+func TestInterestPerChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, _ := startNode(t, ctx)
+	b, addrB := startNode(t, ctx)
+	a.AddPeer(addrB)
+
+	ch := make(chan int, 100)
+	b.Subscribe([]string{"k"}, ch)
+	b.Subscribe([]string{"k"}, ch)               // no-op
+	b.Unsubscribe([]string{"k"}, make(chan int)) // never subscribed
+	if !forwarded(ctx, a, "k", ch, 2*time.Second) {
+		t.Fatal("expected the interest of b kept")
+	}
+
+	b.Unsubscribe([]string{"k"}, ch)
+	time.Sleep(50 * time.Millisecond) // let the withdrawal propagate
+	for len(ch) > 0 {
+		<-ch
+	}
+	if forwarded(ctx, a, "k", ch, 100*time.Millisecond) {
+		t.Error("expected the interest withdrawn after a single unsubscribe")
+	}
+}
+
+func TestInterestNotDropped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, _ := startNode(t, ctx)
+	b, addrB := startNode(t, ctx)
+	a.AddPeer(addrB)
+	time.Sleep(50 * time.Millisecond) // connected
+
+	// Many more changes than the queue of size 1 holds.
+	ch := make(chan int, 100)
+	for i := range 50 {
+		b.Subscribe([]string{fmt.Sprint(i)}, ch)
+	}
+
+	for _, key := range []string{"0", "25", "49"} {
+		if !forwarded(ctx, a, key, ch, 2*time.Second) {
+			t.Errorf("expected %s forwarded", key)
+		}
+	}
+}
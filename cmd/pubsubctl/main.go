@@ -0,0 +1,211 @@
+// Command pubsubctl inspects and interacts with a PubSub instance exposed
+// by a wsgateway.Gateway with string keys and JSON messages.
+//
+// Usage:
+//
+//	pubsubctl [-url ws://localhost:8080/ws] [-timeout 5s] command [arguments]
+//
+// The URL may use the wss scheme for gateways served over TLS.
+//
+// Commands:
+//
+//	keys              list keys with subscribers
+//	stats             print gateway statistics
+//	tail KEY...       print messages published to the keys until interrupted
+//	publish KEY JSON  publish a message and print the number of deliveries
+//
+// The keys and stats commands require the gateway to have Inspect set.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/mdigger/pubsub/internal/websocket"
+	"github.com/mdigger/pubsub/wsgateway"
+)
+
+type frame = wsgateway.Frame[string]
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "pubsubctl:", err)
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned for invalid command lines.
+var errUsage = errors.New("usage: pubsubctl [-url URL] [-timeout DURATION] keys | stats | tail KEY... | publish KEY JSON")
+
+// run executes the command line, writing results to out.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("pubsubctl", flag.ContinueOnError)
+	url := flags.String("url", "ws://localhost:8080/ws", "gateway WebSocket `URL` (ws or wss)")
+	timeout := flags.Duration("timeout", 5*time.Second, "request timeout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, *timeout)
+	conn, err := websocket.Dial(dialCtx, *url)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	c := &client{conn: conn, timeout: *timeout}
+
+	switch cmd, args := args[0], args[1:]; {
+	case cmd == "keys" && len(args) == 0:
+		reply, err := c.request(frame{Op: wsgateway.OpKeys})
+		if err != nil {
+			return err
+		}
+
+		slices.Sort(reply.Keys)
+		for _, key := range reply.Keys {
+			fmt.Fprintln(out, key)
+		}
+		return nil
+
+	case cmd == "stats" && len(args) == 0:
+		reply, err := c.request(frame{Op: wsgateway.OpStats})
+		if err != nil {
+			return err
+		}
+
+		var stats wsgateway.Stats
+		if err := json.Unmarshal(reply.Data, &stats); err != nil {
+			return err
+		}
+
+		data, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Fprintln(out, string(data))
+		return nil
+
+	case cmd == "publish" && len(args) == 2:
+		if !json.Valid([]byte(args[1])) {
+			return errors.New("message is not valid JSON")
+		}
+
+		reply, err := c.request(frame{Op: wsgateway.OpPublish, Keys: args[:1], Data: json.RawMessage(args[1])})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(out, reply.Delivered)
+		return nil
+
+	case cmd == "tail" && len(args) > 0:
+		if _, err := c.request(frame{Op: wsgateway.OpSubscribe, Keys: args}); err != nil {
+			return err
+		}
+
+		return c.tail(ctx, out)
+
+	default:
+		return errUsage
+	}
+}
+
+// client sends requests to the gateway.
+type client struct {
+	conn    *websocket.Conn
+	timeout time.Duration
+	lastID  int
+}
+
+// request sends the frame and waits for its acknowledgement, skipping
+// other frames.
+func (c *client) request(f frame) (frame, error) {
+	c.lastID++
+	f.ID = strconv.Itoa(c.lastID)
+	if err := c.write(f); err != nil {
+		return frame{}, err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	for {
+		reply, err := c.read()
+		if err != nil {
+			return frame{}, err
+		}
+
+		if reply.ID != f.ID {
+			continue
+		}
+
+		if reply.Op == wsgateway.OpError {
+			return frame{}, errors.New(reply.Error)
+		}
+
+		return reply, nil
+	}
+}
+
+// tail prints message frames until the context is canceled or the
+// connection fails.
+func (c *client) tail(ctx context.Context, out io.Writer) error {
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	for {
+		f, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		switch f.Op {
+		case wsgateway.OpMessage:
+			fmt.Fprintf(out, "%s\t%s\n", f.Keys[0], f.Data)
+		case wsgateway.OpError:
+			return errors.New(f.Error)
+		}
+	}
+}
+
+func (c *client) write(f frame) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+
+	return c.conn.WriteMessage(websocket.OpText, data)
+}
+
+func (c *client) read() (frame, error) {
+	_, data, err := c.conn.ReadMessage()
+	if err != nil {
+		return frame{}, err
+	}
+
+	var f frame
+	err = json.Unmarshal(data, &f)
+
+	return f, err
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/wsgateway"
+)
+
+func newGateway(t *testing.T) (*pubsub.PubSub[string, json.RawMessage], string) {
+	t.Helper()
+	ps := pubsub.New[string, json.RawMessage]()
+	srv := httptest.NewServer(&wsgateway.Gateway[string, json.RawMessage]{PubSub: ps, Inspect: true})
+	t.Cleanup(srv.Close)
+
+	return ps, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func runCommand(t *testing.T, url string, args ...string) string {
+	t.Helper()
+	var out strings.Builder
+	if err := run(context.Background(), append([]string{"-url", url}, args...), &out); err != nil {
+		t.Fatalf("%s: %v", args[0], err)
+	}
+
+	return out.String()
+}
+
+func TestCommands(t *testing.T) {
+	ps, url := newGateway(t)
+	ch := make(chan json.RawMessage, 1)
+	ps.Subscribe([]string{"b", "a"}, ch)
+
+	if got := runCommand(t, url, "keys"); got != "a\nb\n" {
+		t.Errorf("keys: unexpected output %q", got)
+	}
+
+	if got := runCommand(t, url, "publish", "a", `{"n":1}`); got != "1\n" {
+		t.Errorf("publish: unexpected output %q", got)
+	}
+	if msg := <-ch; string(msg) != `{"n":1}` {
+		t.Errorf("unexpected message %s", msg)
+	}
+
+	if got := runCommand(t, url, "stats"); !strings.Contains(got, `"keys": 2`) {
+		t.Errorf("stats: unexpected output %q", got)
+	}
+
+	if err := run(context.Background(), []string{"-url", url, "publish", "a"}, io.Discard); err != errUsage {
+		t.Errorf("expected usage error, got %v", err)
+	}
+}
+
+func TestTail(t *testing.T) {
+	ps, url := newGateway(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	r, w := io.Pipe()
+
+	done := make(chan error)
+	go func() { done <- run(ctx, []string{"-url", url, "tail", "events"}, w) }()
+
+	go func() {
+		for ctx.Err() == nil {
+			if n, _ := ps.PublishWithTimeout("events", json.RawMessage(`"hi"`), time.Second); n > 0 {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "events\t\"hi\"\n" {
+		t.Errorf("unexpected line %q", line)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("tail: %v", err)
+	}
+}
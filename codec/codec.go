@@ -0,0 +1,100 @@
+// Package codec defines how messages are converted to bytes at the
+// boundaries of a process: gateways, bridges and other adapters take
+// a Codec instead of hardcoding a serialization format.
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec converts messages of type T to and from bytes.
+type Codec[T any] interface {
+	Marshal(msg T) ([]byte, error)
+	Unmarshal(data []byte) (T, error)
+	// ContentType returns the MIME type of the encoded data.
+	ContentType() string
+}
+
+// JSON encodes messages with encoding/json.
+type JSON[T any] struct{}
+
+func (JSON[T]) Marshal(msg T) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (JSON[T]) Unmarshal(data []byte) (T, error) {
+	var msg T
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+func (JSON[T]) ContentType() string {
+	return "application/json"
+}
+
+// Gob encodes messages with encoding/gob. Each message is encoded
+// separately, so type information is sent with every message.
+type Gob[T any] struct{}
+
+func (Gob[T]) Marshal(msg T) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(msg)
+	return buf.Bytes(), err
+}
+
+func (Gob[T]) Unmarshal(data []byte) (T, error) {
+	var msg T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&msg)
+	return msg, err
+}
+
+func (Gob[T]) ContentType() string {
+	return "application/x-gob"
+}
+
+// Binary encodes messages whose pointer type implements
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.
+type Binary[T any, P interface {
+	*T
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}] struct{}
+
+func (Binary[T, P]) Marshal(msg T) ([]byte, error) {
+	return P(&msg).MarshalBinary()
+}
+
+func (Binary[T, P]) Unmarshal(data []byte) (T, error) {
+	var msg T
+	err := P(&msg).UnmarshalBinary(data)
+	return msg, err
+}
+
+func (Binary[T, P]) ContentType() string {
+	return "application/octet-stream"
+}
+
+// Funcs adapts a pair of functions to the Codec interface, for formats
+// provided by third-party packages such as protobuf or msgpack:
+//
+//	codec.Funcs[T]{MarshalFunc: msgpack.Marshal, ...}
+type Funcs[T any] struct {
+	MarshalFunc   func(T) ([]byte, error)
+	UnmarshalFunc func([]byte) (T, error)
+	Type          string // content type
+}
+
+func (c Funcs[T]) Marshal(msg T) ([]byte, error) {
+	return c.MarshalFunc(msg)
+}
+
+func (c Funcs[T]) Unmarshal(data []byte) (T, error) {
+	return c.UnmarshalFunc(data)
+}
+
+func (c Funcs[T]) ContentType() string {
+	return c.Type
+}
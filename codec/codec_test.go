@@ -0,0 +1,98 @@
+package codec_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub/codec"
+)
+
+type event struct {
+	Name  string
+	Count int
+}
+
+func roundTrip[T comparable](t *testing.T, c codec.Codec[T], msg T) {
+	t.Helper()
+	data, err := c.Marshal(msg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got != msg {
+		t.Errorf("expected %v, got %v", msg, got)
+	}
+	if c.ContentType() == "" {
+		t.Error("empty content type")
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	msg := event{Name: "created", Count: 3}
+	roundTrip[event](t, codec.JSON[event]{}, msg)
+	roundTrip[event](t, codec.Gob[event]{}, msg)
+	roundTrip[time.Time](t, codec.Binary[time.Time, *time.Time]{}, time.Date(2025, 7, 7, 0, 0, 0, 0, time.UTC))
+	roundTrip[int](t, codec.Funcs[int]{
+		MarshalFunc:   func(n int) ([]byte, error) { return []byte(strconv.Itoa(n)), nil },
+		UnmarshalFunc: func(b []byte) (int, error) { return strconv.Atoi(string(b)) },
+		Type:          "text/plain",
+	}, 42)
+}
+
+func TestUnmarshalError(t *testing.T) {
+	if _, err := (codec.JSON[event]{}).Unmarshal([]byte("{")); err == nil {
+		t.Error("expected JSON error")
+	}
+	if _, err := (codec.Gob[event]{}).Unmarshal([]byte("garbage")); err == nil {
+		t.Error("expected gob error")
+	}
+}
+
+func TestCompressed(t *testing.T) {
+	c := codec.Compressed[string]{Codec: codec.JSON[string]{}, MinSize: 64}
+	roundTrip[string](t, c, "short")
+
+	long := strings.Repeat("compressible ", 100)
+	roundTrip[string](t, c, long)
+
+	data, _ := c.Marshal(long)
+	if len(data) >= len(long) {
+		t.Errorf("expected compressed size below %d, got %d", len(long), len(data))
+	}
+
+	if _, err := c.Unmarshal([]byte{9, '"', '"'}); err == nil {
+		t.Error("expected an error for an unknown header")
+	}
+}
+
+func TestCompressedLimits(t *testing.T) {
+	c := codec.Compressed[string]{Codec: codec.JSON[string]{}, MaxSize: 1000}
+	if ct := c.ContentType(); ct != `application/x-pubsub-compressed; codec="application/json"` {
+		t.Errorf("unexpected content type %s", ct)
+	}
+
+	// A small payload decompressing beyond the limit.
+	bomb, _ := (codec.Compressed[string]{Codec: codec.JSON[string]{}}).Marshal(strings.Repeat("a", 100_000))
+	if _, err := c.Unmarshal(bomb); !errors.Is(err, codec.ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+
+	if _, err := c.Unmarshal(mustMarshal(t, c, strings.Repeat("a", 500))); err != nil {
+		t.Errorf("expected a payload within the limit, got %v", err)
+	}
+}
+
+func mustMarshal(t *testing.T, c codec.Codec[string], msg string) []byte {
+	t.Helper()
+	data, err := c.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
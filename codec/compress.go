@@ -0,0 +1,159 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+)
+
+// Header bytes of Compressed payloads.
+const (
+	rawPayload        = 0
+	compressedPayload = 1
+)
+
+// DefaultMaxSize is the decompressed size limit used when
+// Compressed.MaxSize is zero.
+const DefaultMaxSize = 64 << 20
+
+// ErrTooLarge is returned by Compressed.Unmarshal for payloads that
+// decompress beyond the size limit.
+var ErrTooLarge = errors.New("codec: decompressed payload too large")
+
+// Compressed wraps a codec and compresses encoded messages, for adapters
+// carrying large, compressible messages: use it as the Codec of a bridge,
+// gateway or stream.Writer and Reader, with length-prefixed framing as
+// payloads are binary. Compression applies to all the keys of an adapter;
+// keys with different needs use separate adapters. Payloads start with a
+// byte telling whether the rest is compressed, so small messages can be
+// sent as is. Both sides must use the same configuration.
+//
+// DEFLATE from compress/flate is used by default; other algorithms, such
+// as snappy or zstd from third-party packages, plug in with CompressFunc
+// and DecompressFunc. Payloads decompressing beyond MaxSize are rejected,
+// so a peer can't exhaust memory with a small payload.
+type Compressed[T any] struct {
+	Codec Codec[T]
+
+	// MinSize is the encoded size below which messages are not compressed.
+	MinSize int
+
+	// MaxSize is the decompressed size above which payloads are rejected
+	// with ErrTooLarge; DefaultMaxSize if zero.
+	MaxSize int
+
+	// Level is the DEFLATE compression level; flate.DefaultCompression if
+	// zero. Use flate.BestSpeed or flate.BestCompression to trade speed
+	// for ratio.
+	Level int
+
+	CompressFunc   func([]byte) ([]byte, error)
+	DecompressFunc func([]byte) ([]byte, error)
+}
+
+func (c Compressed[T]) Marshal(msg T) ([]byte, error) {
+	data, err := c.Codec.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < c.MinSize {
+		return append([]byte{rawPayload}, data...), nil
+	}
+
+	compressed, err := c.compress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(compressed) >= len(data) {
+		return append([]byte{rawPayload}, data...), nil
+	}
+
+	return append([]byte{compressedPayload}, compressed...), nil
+}
+
+func (c Compressed[T]) Unmarshal(data []byte) (T, error) {
+	if len(data) == 0 {
+		var zero T
+		return zero, errors.New("codec: empty compressed payload")
+	}
+
+	payload := data[1:]
+	switch data[0] {
+	case rawPayload:
+	case compressedPayload:
+		var err error
+		if payload, err = c.decompress(payload); err != nil {
+			var zero T
+			return zero, err
+		}
+	default:
+		var zero T
+		return zero, fmt.Errorf("codec: unknown compressed payload header %#x", data[0])
+	}
+
+	return c.Codec.Unmarshal(payload)
+}
+
+// ContentType returns application/x-pubsub-compressed, with the content
+// type of the wrapped codec as the codec parameter.
+func (c Compressed[T]) ContentType() string {
+	return mime.FormatMediaType("application/x-pubsub-compressed",
+		map[string]string{"codec": c.Codec.ContentType()})
+}
+
+func (c Compressed[T]) compress(data []byte) ([]byte, error) {
+	if c.CompressFunc != nil {
+		return c.CompressFunc(data)
+	}
+
+	level := c.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c Compressed[T]) decompress(data []byte) ([]byte, error) {
+	limit := c.MaxSize
+	if limit <= 0 {
+		limit = DefaultMaxSize
+	}
+
+	if c.DecompressFunc != nil {
+		data, err := c.DecompressFunc(data)
+		if err == nil && len(data) > limit {
+			err = ErrTooLarge
+		}
+		return data, err
+	}
+
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err == nil && len(data) > limit {
+		err = ErrTooLarge
+	}
+
+	return data, err
+}
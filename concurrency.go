@@ -0,0 +1,140 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// WithDeliveryConcurrency makes Publish deliver to the channels of a key
+// concurrently, so a full channel doesn't delay the others: a delivery
+// that would block runs in its own goroutine, with at most global such
+// goroutines in total and perKey per key. Zero leaves a bound unlimited;
+// by default, with both zero, deliveries are sequential. Deliveries over
+// the bounds run in the publishing goroutine, so a hot key with thousands
+// of subscribers can't start thousands of goroutines.
+//
+// Publish still returns after all deliveries, preserving snapshot
+// isolation and per-subscriber ordering. Unlike sequential delivery, a
+// subscriber whose delivery fails when the context ends doesn't stop the
+// deliveries already under way. The bounds only matter with the Block
+// drop policy: the other policies never wait.
+func WithDeliveryConcurrency(global, perKey int) Option {
+	return func(o *options) {
+		o.concurrency = max(global, 0)
+		o.keyConcurrency = max(perKey, 0)
+	}
+}
+
+// semaphores bound the number of delivery goroutines.
+type semaphores[K comparable] struct {
+	global atomic.Int64
+
+	mu   sync.Mutex
+	keys map[K]int
+}
+
+// concurrent reports whether deliveries to the key may run concurrently.
+// The caller must hold the read lock.
+func (ps *PubSub[K, T]) concurrent(key K) bool {
+	return (ps.opts.concurrency > 0 || ps.opts.keyConcurrency > 0) && ps.dropPolicy(key) == Block
+}
+
+// acquire reserves a delivery goroutine for the key, if the bounds allow.
+func (ps *PubSub[K, T]) acquire(key K) bool {
+	s := &ps.sems
+	if limit := int64(ps.opts.concurrency); limit > 0 {
+		if s.global.Add(1) > limit {
+			s.global.Add(-1)
+			return false
+		}
+	}
+
+	if limit := ps.opts.keyConcurrency; limit > 0 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.keys[key] >= limit {
+			if ps.opts.concurrency > 0 {
+				s.global.Add(-1)
+			}
+			return false
+		}
+
+		if s.keys == nil {
+			s.keys = make(map[K]int)
+		}
+		s.keys[key]++
+	}
+
+	return true
+}
+
+// release frees a delivery goroutine reserved by acquire.
+func (ps *PubSub[K, T]) release(key K) {
+	s := &ps.sems
+	if ps.opts.concurrency > 0 {
+		s.global.Add(-1)
+	}
+
+	if ps.opts.keyConcurrency > 0 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.keys[key]--; s.keys[key] == 0 {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// sendConcurrently delivers the message to the channels, in goroutines
+// for those that would block, within the bounds. The caller must hold
+// the read lock.
+func (ps *PubSub[K, T]) sendConcurrently(ctx context.Context, key K, subs map[chan T]int, msg T, t *tally[K, T]) error {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		total tally[K, T] // not t, which would escape to the heap
+		errs  error
+	)
+
+	total.confirm, total.start = t.confirm, t.start
+	send := func(ch chan T) {
+		local := tally[K, T]{confirm: total.confirm, start: total.start}
+		err := ps.sendTo(ctx, key, ch, msg, len(subs), &local)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		total.delivered += local.delivered
+		total.dropped += local.dropped
+		total.spent = append(total.spent, local.spent...)
+		if err != nil && errs == nil {
+			errs = err
+		}
+	}
+
+	for ch := range subs {
+		if _, paused := ps.paused[ch]; paused || len(ch) < cap(ch) || !ps.acquire(key) {
+			send(ch) // won't block, or over the bounds
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer ps.release(key)
+			send(ch)
+		}()
+	}
+	wg.Wait()
+	*t = total
+
+	var derr *DeliveryError[K]
+	if errors.As(errs, &derr) {
+		derr.Delivered = t.delivered
+	}
+
+	return errs
+}
@@ -0,0 +1,85 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestDeliveryConcurrency(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDeliveryConcurrency(10, 0))
+	slow, fast := make(chan int), make(chan int)
+	ps.Subscribe([]string{"k"}, slow)
+	ps.Subscribe([]string{"k"}, fast)
+
+	done := make(chan int)
+	go func() {
+		n, _ := ps.Publish(context.Background(), "k", 1)
+		done <- n
+	}()
+
+	// Either channel may be served first: the other one's delivery runs
+	// concurrently.
+	select {
+	case <-fast:
+		<-slow
+	case <-slow:
+		<-fast
+	}
+
+	if n := <-done; n != 2 {
+		t.Errorf("expected 2 deliveries, got %d", n)
+	}
+}
+
+func TestDeliveryConcurrencyBounds(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDeliveryConcurrency(1, 1))
+	chans := make([]chan int, 5)
+	for i := range chans {
+		chans[i] = make(chan int)
+		ps.Subscribe([]string{"k"}, chans[i])
+	}
+
+	done := make(chan int)
+	go func() {
+		n, _ := ps.Publish(context.Background(), "k", 1)
+		done <- n
+	}()
+
+	// Over the bounds, deliveries run in the publishing goroutine, one at
+	// a time; they complete in whatever order the channels are read.
+	received := 0
+	for received < len(chans) {
+		for _, ch := range chans {
+			select {
+			case <-ch:
+				received++
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+
+	if n := <-done; n != len(chans) {
+		t.Errorf("expected %d deliveries, got %d", len(chans), n)
+	}
+}
+
+func TestDeliveryConcurrencyTimeout(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDeliveryConcurrency(0, 10))
+	ok := make(chan int, 1)
+	ps.Subscribe([]string{"k"}, make(chan int))
+	ps.Subscribe([]string{"k"}, make(chan int))
+	ps.Subscribe([]string{"k"}, ok)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	n, err := ps.Publish(ctx, "k", 1)
+	var derr *pubsub.DeliveryError[string]
+	if n != 1 || !errors.As(err, &derr) || derr.Delivered != 1 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a delivery error after 1 delivery, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,180 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OffsetStore stores the offsets of durable consumers: the sequence number
+// of the last message each consumer acknowledged.
+type OffsetStore interface {
+	// LoadOffset returns the offset of the consumer, or zero if it has none.
+	LoadOffset(name string) (uint64, error)
+	// SaveOffset stores the offset of the consumer.
+	SaveOffset(name string, seq uint64) error
+}
+
+// WithOffsetStore sets the store of durable consumer offsets. By default
+// offsets are kept in memory by the instance, so consumers resume after
+// restarting within the process.
+func WithOffsetStore(store OffsetStore) Option {
+	return func(o *options) {
+		o.offsets = store
+	}
+}
+
+// MemoryOffsets is an OffsetStore keeping offsets in memory.
+type MemoryOffsets struct {
+	mu      sync.Mutex
+	offsets map[string]uint64
+}
+
+// LoadOffset returns the offset of the consumer, or zero if it has none.
+func (m *MemoryOffsets) LoadOffset(name string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.offsets[name], nil
+}
+
+// SaveOffset stores the offset of the consumer.
+func (m *MemoryOffsets) SaveOffset(name string, seq uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.offsets == nil {
+		m.offsets = make(map[string]uint64)
+	}
+	m.offsets[name] = seq
+
+	return nil
+}
+
+// Consumer is a named durable consumer of a key's retained messages. It
+// reads messages in sequence order and remembers the last acknowledged
+// one in the offset store, so a consumer created again with the same name
+// continues after it, at least once. Messages discarded by retention
+// before they were read are skipped.
+//
+// A Consumer is safe for concurrent use, but messages are meant to be
+// read by one goroutine at a time.
+type Consumer[K comparable, T any] struct {
+	ps   *PubSub[K, T]
+	name string
+	key  K
+
+	mu  sync.Mutex
+	pos uint64 // sequence number of the last message read
+}
+
+// Consumer returns the durable consumer of the key with the name,
+// positioned after its stored offset. Durable consumers need retention,
+// see WithRetention.
+func (ps *PubSub[K, T]) Consumer(name string, key K) (*Consumer[K, T], error) {
+	seq, err := ps.opts.offsets.LoadOffset(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Consumer[K, T]{ps: ps, name: name, key: key, pos: seq}, nil
+}
+
+// Name returns the name of the consumer.
+func (c *Consumer[K, T]) Name() string {
+	return c.name
+}
+
+// Next returns the message following the last one read, waiting for it to
+// be published if needed. It fails with ErrClosed once the instance is
+// closed, or with the context error.
+func (c *Consumer[K, T]) Next(ctx context.Context) (Retained[T], error) {
+	for {
+		c.mu.Lock()
+		key := c.ps.resolved(c.key)
+		msg, wake, err := c.ps.historyOf(key).next(c.ps, key, c.pos)
+		if err == nil && wake == nil {
+			c.pos = msg.Seq
+		}
+		c.mu.Unlock()
+
+		if err != nil || wake == nil {
+			return msg, err
+		}
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return Retained[T]{}, contextErr(ctx)
+		}
+	}
+}
+
+// Ack stores seq as the offset of the consumer: the message with the
+// sequence number and all before it are processed and won't be read again
+// by a new consumer with the same name.
+func (c *Consumer[K, T]) Ack(seq uint64) error {
+	return c.ps.opts.offsets.SaveOffset(c.name, seq)
+}
+
+// Seek moves the consumer, so Next returns the message with the sequence
+// number, or the oldest retained one after it. It doesn't change the
+// stored offset.
+func (c *Consumer[K, T]) Seek(seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pos = max(seq, 1) - 1
+}
+
+// SeekTime moves the consumer, so Next returns the first message published
+// at or after t.
+func (c *Consumer[K, T]) SeekTime(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := c.ps.resolved(c.key)
+	c.pos = c.ps.historyOf(key).before(c.ps, key, t)
+}
+
+// next returns the first retained message of the key after seq. If there
+// is none yet, it returns a channel closed when the next message of the
+// key is retained or the instance is closed.
+func (h *history[K, T]) next(ps *PubSub[K, T], key K, seq uint64) (Retained[T], <-chan struct{}, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return Retained[T]{}, nil, ErrClosed
+	}
+
+	s := h.stream(key)
+	ps.expire(s, ps.opts.clock.Now())
+
+	for _, r := range s.entries {
+		if r.Seq > seq {
+			return r, nil, nil
+		}
+	}
+
+	if s.wake == nil {
+		s.wake = make(chan struct{})
+	}
+
+	return Retained[T]{}, s.wake, nil
+}
+
+// before returns the sequence number of the last message of the key
+// published before t.
+func (h *history[K, T]) before(ps *PubSub[K, T], key K, t time.Time) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stream(key)
+	seq := s.seq
+	for i := len(s.entries) - 1; i >= 0 && !s.entries[i].Time.Before(t); i-- {
+		seq = s.entries[i].Seq - 1
+	}
+
+	return seq
+}
@@ -0,0 +1,81 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestConsumerResume(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(100))
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		ps.Publish(ctx, "jobs", i)
+	}
+
+	c, err := ps.Consumer("worker", "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		r, err := c.Next(ctx)
+		if err != nil || r.Msg != want {
+			t.Fatalf("expected %d, got %+v, %v", want, r, err)
+		}
+	}
+	c.Ack(2) // 3 was read but not processed
+
+	c, _ = ps.Consumer("worker", "jobs")
+	if r, _ := c.Next(ctx); r.Msg != 3 {
+		t.Errorf("expected the restarted consumer to resume at 3, got %d", r.Msg)
+	}
+}
+
+func TestConsumerWait(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(10))
+	c, _ := ps.Consumer("c", "k")
+
+	go ps.Publish(context.Background(), "k", 42)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if r, err := c.Next(ctx); err != nil || r.Msg != 42 || r.Seq != 1 {
+		t.Fatalf("expected message 42, got %+v, %v", r, err)
+	}
+
+	go ps.Close()
+	if _, err := c.Next(ctx); !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestConsumerSeek(t *testing.T) {
+	ps, clock := pstest.New[string, int](pubsub.WithRetention(10))
+	ctx := context.Background()
+	for i := 1; i <= 4; i++ {
+		ps.Publish(ctx, "k", i)
+		clock.Advance(time.Minute)
+	}
+
+	c, _ := ps.Consumer("c", "k")
+	c.Seek(3)
+	if r, _ := c.Next(ctx); r.Msg != 3 {
+		t.Errorf("expected 3 after Seek, got %d", r.Msg)
+	}
+
+	c.SeekTime(time.Unix(90, 0))
+	if r, _ := c.Next(ctx); r.Msg != 3 {
+		t.Errorf("expected 3 after SeekTime, got %d", r.Msg)
+	}
+
+	c.SeekTime(time.Unix(0, 0))
+	if r, _ := c.Next(ctx); r.Msg != 1 {
+		t.Errorf("expected 1 after SeekTime to the start, got %d", r.Msg)
+	}
+}
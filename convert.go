@@ -0,0 +1,115 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrConversionLoop is returned by an Upgrader whose converters of a key
+// form a cycle.
+var ErrConversionLoop = errors.New("pubsub: conversion loop")
+
+// Converter converts messages published to a key before they are
+// validated and delivered, for example to upgrade messages of older
+// versions published by lagging services during a rolling deploy.
+type Converter[K comparable, T any] interface {
+	Convert(key K, msg T) (T, error)
+}
+
+// SetConverter sets the converter applied by Publish after authorization.
+// Conversion errors are returned by Publish and the message is not
+// delivered. A nil converter leaves messages unchanged.
+func (ps *PubSub[K, T]) SetConverter(c Converter[K, T]) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.converter = c
+}
+
+// convert applies the converter, if any.
+func (ps *PubSub[K, T]) convert(c Converter[K, T], key K, msg T) (T, error) {
+	if c == nil {
+		return msg, nil
+	}
+
+	out, err := c.Convert(key, msg)
+	if err != nil {
+		ps.opts.logger.Warn("pubsub: conversion failed", "key", key, "error", err)
+		return msg, fmt.Errorf("pubsub: convert message for key %v: %w", key, err)
+	}
+
+	return out, nil
+}
+
+// Upgrader is a Converter applying chains of up-converters registered per
+// key: a message of a version with a registered converter is converted to
+// the next version, again and again, until no converter is registered for
+// its version, which is the latest one. It is safe for concurrent use.
+type Upgrader[K comparable, T any] struct {
+	version func(T) string
+
+	mu     sync.RWMutex
+	chains map[K]map[string]func(T) (T, error)
+}
+
+// NewUpgrader returns an Upgrader reading the version of messages with
+// version, for example from a version field. See TypeVersion for buses
+// carrying a Go type per version.
+func NewUpgrader[K comparable, T any](version func(T) string) *Upgrader[K, T] {
+	return &Upgrader[K, T]{version: version}
+}
+
+// TypeVersion returns the Go type of the message as its version, for
+// PubSub[K, any] instances where each version is its own struct type.
+func TypeVersion(msg any) string {
+	return fmt.Sprintf("%T", msg)
+}
+
+// Register sets the converter of messages of the version published to
+// the key to the next version. Converters must not call Register.
+func (u *Upgrader[K, T]) Register(key K, from string, convert func(T) (T, error)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.chains == nil {
+		u.chains = make(map[K]map[string]func(T) (T, error))
+	}
+
+	chain, ok := u.chains[key]
+	if !ok {
+		chain = make(map[string]func(T) (T, error))
+		u.chains[key] = chain
+	}
+	chain[from] = convert
+}
+
+// Convert upgrades the message to the latest version of the key.
+func (u *Upgrader[K, T]) Convert(key K, msg T) (T, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	chain := u.chains[key]
+	for range len(chain) + 1 {
+		convert, ok := chain[u.version(msg)]
+		if !ok {
+			return msg, nil
+		}
+
+		var err error
+		if msg, err = convert(msg); err != nil {
+			return msg, err
+		}
+	}
+
+	return msg, ErrConversionLoop
+}
+
+// Upgrade registers a converter of messages of type From published to
+// the key to type To, for an Upgrader using TypeVersion.
+func Upgrade[K comparable, From, To any](u *Upgrader[K, any], key K, convert func(From) To) {
+	u.Register(key, reflect.TypeFor[From]().String(), func(msg any) (any, error) {
+		return convert(msg.(From)), nil
+	})
+}
@@ -0,0 +1,82 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+type orderV1 struct{ Amount int }
+
+type orderV2 struct{ Cents int }
+
+type orderV3 struct {
+	Cents    int
+	Currency string
+}
+
+func TestUpgradeTypes(t *testing.T) {
+	u := pubsub.NewUpgrader[string](pubsub.TypeVersion)
+	pubsub.Upgrade(u, "orders", func(o orderV1) orderV2 { return orderV2{Cents: o.Amount * 100} })
+	pubsub.Upgrade(u, "orders", func(o orderV2) orderV3 { return orderV3{Cents: o.Cents, Currency: "EUR"} })
+
+	ps := pubsub.New[string, any]()
+	ps.SetConverter(u)
+	ch := make(chan any, 3)
+	ps.Subscribe([]string{"orders", "other"}, ch)
+
+	ctx := context.Background()
+	ps.Publish(ctx, "orders", orderV1{Amount: 2})
+	ps.Publish(ctx, "orders", orderV3{Cents: 5, Currency: "USD"})
+	ps.Publish(ctx, "other", orderV1{Amount: 1})
+
+	want := []any{orderV3{Cents: 200, Currency: "EUR"}, orderV3{Cents: 5, Currency: "USD"}, orderV1{Amount: 1}}
+	for _, w := range want {
+		if got := <-ch; got != w {
+			t.Errorf("expected %#v, got %#v", w, got)
+		}
+	}
+}
+
+type versioned struct {
+	Version int
+	Data    string
+}
+
+func TestUpgradeField(t *testing.T) {
+	u := pubsub.NewUpgrader[string](func(m versioned) string { return strconv.Itoa(m.Version) })
+	u.Register("k", "1", func(m versioned) (versioned, error) {
+		return versioned{Version: 2, Data: m.Data + "!"}, nil
+	})
+	u.Register("k", "0", func(versioned) (versioned, error) {
+		return versioned{}, errors.New("unsupported")
+	})
+
+	ps := pubsub.New[string, versioned]()
+	ps.SetConverter(u)
+	ch := make(chan versioned, 1)
+	ps.Subscribe([]string{"k"}, ch)
+
+	ctx := context.Background()
+	ps.Publish(ctx, "k", versioned{Version: 1, Data: "hi"})
+	if got := <-ch; got.Version != 2 || got.Data != "hi!" {
+		t.Errorf("unexpected message %+v", got)
+	}
+
+	if n, err := ps.Publish(ctx, "k", versioned{Version: 0}); n != 0 || err == nil {
+		t.Errorf("expected the conversion error, got %d, %v", n, err)
+	}
+}
+
+func TestUpgradeLoop(t *testing.T) {
+	u := pubsub.NewUpgrader[string](func(m versioned) string { return strconv.Itoa(m.Version) })
+	u.Register("k", "1", func(m versioned) (versioned, error) { return versioned{Version: 2}, nil })
+	u.Register("k", "2", func(m versioned) (versioned, error) { return versioned{Version: 1}, nil })
+
+	if _, err := u.Convert("k", versioned{Version: 1}); !errors.Is(err, pubsub.ErrConversionLoop) {
+		t.Errorf("expected ErrConversionLoop, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// credits hold the flow control state of a subscription in credit mode.
+type credits[T any] struct {
+	mu      sync.Mutex // serializes the deliveries to the channel
+	n       int        // messages the consumer is ready for
+	backlog pauseBuffer[T]
+	stats   *subStats
+}
+
+// EnableCredits switches the subscription to credit-based flow control,
+// for consumers signaling demand instead of relying on blind buffering:
+// the consumer grants credits with Grant, each message sent to the
+// channel takes one, and delivery pauses at zero credits. Up to backlog
+// messages published meanwhile are kept, as with Pause, and sent when
+// more credits are granted. The subscription starts with n credits.
+//
+// Kept messages are accounted in the memory budget, and Publish doesn't
+// block on them. Enabling credits again replaces the credits and the
+// backlog limit, keeping the messages already kept.
+func (s *Subscription[K, T]) EnableCredits(n, backlog int) {
+	ps := s.ps
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, managed := ps.managed[s.ch]; !managed {
+		return // closed
+	}
+
+	c, ok := ps.credited[s.ch]
+	if !ok {
+		c = &credits[T]{
+			backlog: pauseBuffer[T]{opts: &ps.opts, budget: &ps.budget, size: ps.Size},
+			stats:   s.stats,
+		}
+		if ps.credited == nil {
+			ps.credited = make(map[chan T]*credits[T])
+		}
+		ps.credited[s.ch] = c
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.n = max(n, 0)
+	c.backlog.limit = max(backlog, 0)
+	for len(c.backlog.msgs) > c.backlog.limit {
+		c.backlog.removeOldest()
+	}
+	c.flush(s.ch)
+}
+
+// Grant gives the subscription n more credits, sending kept messages to
+// the channel as far as the credits and the room in the channel allow.
+// Kept messages that don't fit are sent by later publishes or grants.
+// It does nothing unless credits are enabled.
+func (s *Subscription[K, T]) Grant(n int) {
+	ps := s.ps
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	c, ok := ps.credited[s.ch]
+	if !ok || ps.state == stateClosed {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.n += max(n, 0)
+	c.flush(s.ch)
+}
+
+// Credits returns the credits left and the number of kept messages
+// waiting for credits.
+func (s *Subscription[K, T]) Credits() (credits, backlog int) {
+	ps := s.ps
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	c, ok := ps.credited[s.ch]
+	if !ok {
+		return 0, 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.n, len(c.backlog.msgs)
+}
+
+// deliverCredited sends the message to the channel if a credit is left
+// and no message is kept before it, and keeps it otherwise. The caller
+// must hold the read lock.
+func (ps *PubSub[K, T]) deliverCredited(ctx context.Context, c *credits[T], ch chan T, msg T, policy DropPolicy) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flush(ch)
+	if c.n == 0 || len(c.backlog.msgs) > 0 {
+		return c.backlog.add(msg), nil
+	}
+
+	ok, err := ps.send(ctx, ch, msg, policy)
+	if ok {
+		c.n--
+	}
+	c.stats.record(ok, len(ch), c.backlog.size(msg), 0)
+
+	return ok, err
+}
+
+// flush sends kept messages to the channel while credits and room are
+// left. The caller must hold the lock of the credits and the read lock
+// of the instance.
+func (c *credits[T]) flush(ch chan T) {
+	for c.n > 0 && len(c.backlog.msgs) > 0 {
+		select {
+		case ch <- c.backlog.msgs[0]:
+			c.n--
+			c.stats.record(true, len(ch), c.backlog.size(c.backlog.msgs[0]), 0)
+			c.backlog.removeOldest()
+		default:
+			return
+		}
+	}
+}
+
+// disableCredits ends credit mode for the channel, releasing the budget
+// of the kept messages. The caller must hold the lock.
+func (ps *PubSub[K, T]) disableCredits(ch chan T) {
+	if c, ok := ps.credited[ch]; ok {
+		c.backlog.release()
+		delete(ps.credited, ch)
+	}
+}
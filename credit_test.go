@@ -0,0 +1,63 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubscriptionCredits(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(10), pubsub.WithMemoryBudget(100, pubsub.EvictOldest))
+	sub, err := ps.NewSubscription(context.Background(), "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	sub.EnableCredits(2, 3)
+	for i := range 6 {
+		if n, _ := ps.Publish(context.Background(), "k", i); i < 5 && n != 1 || i == 5 && n != 0 {
+			t.Fatalf("unexpected delivery count %d of message %d", n, i)
+		}
+	}
+
+	if sub.Depth() != 2 {
+		t.Fatalf("expected 2 messages sent with 2 credits, got %d", sub.Depth())
+	}
+	if credits, backlog := sub.Credits(); credits != 0 || backlog != 3 {
+		t.Fatalf("expected 0 credits and 3 kept messages, got %d and %d", credits, backlog)
+	}
+
+	sub.Grant(2)
+	ps.Publish(context.Background(), "k", 6) // after the kept ones
+
+	var got []int
+	for range 4 {
+		got = append(got, <-sub.C())
+	}
+	if credits, backlog := sub.Credits(); len(got) != 4 || got[3] != 3 || credits != 0 || backlog != 2 {
+		t.Errorf("expected messages 0 to 3 and 2 kept, got %v, %d credits and %d kept", got, credits, backlog)
+	}
+
+	sub.Grant(10)
+	if got := []int{<-sub.C(), <-sub.C()}; got[0] != 4 || got[1] != 6 {
+		t.Errorf("expected the kept messages in order, got %v", got)
+	}
+	if stats := sub.Stats(); stats.Delivered != 6 {
+		t.Errorf("expected 6 deliveries in the statistics, got %d", stats.Delivered)
+	}
+}
+
+func TestSubscriptionCreditsClose(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithMemoryBudget(100, pubsub.EvictOldest))
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	sub.EnableCredits(0, 5)
+	ps.Publish(context.Background(), "k", 1)
+	sub.Close()
+
+	if usage := ps.MemoryUsage(); usage.Messages != 0 {
+		t.Errorf("expected the kept messages released, got %+v", usage)
+	}
+	sub.Grant(1) // no-op after Close
+}
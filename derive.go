@@ -0,0 +1,29 @@
+package pubsub
+
+import "context"
+
+// Derive maintains a derived key: while dst has subscribers, the messages
+// of the src keys are transformed with fn and republished to dst;
+// messages for which fn returns false are dropped. The pipeline is
+// demand-driven, like OnDemand: it subscribes to the sources when dst
+// gets its first subscriber, shortly after, and unsubscribes when dst
+// loses its last one, so derived keys nobody listens to cost nothing.
+//
+// Derived keys may be derived from in turn, and subscribing to one starts
+// the whole chain. They must not form a cycle. Channels subscribed to
+// patterns matching dst start the pipeline, handlers registered with
+// SubscribeFunc don't. The returned function removes the derivation and
+// waits for the pipeline to stop.
+func (ps *PubSub[K, T]) Derive(dst K, src []K, fn func(T) (T, bool)) (cancel func()) {
+	remove := ps.Annotate("derive", src, []K{dst})
+	stop := ps.OnDemand(dst, func(ctx context.Context) {
+		stop := Pipe(ctx, ps, ps, src, func(K) K { return dst }, fn)
+		<-ctx.Done()
+		stop()
+	}, nil)
+
+	return func() {
+		stop()
+		remove()
+	}
+}
@@ -0,0 +1,49 @@
+package pubsub_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// waitKeys waits until the subscribed keys of ps satisfy ok.
+func waitKeys(t *testing.T, ps *pubsub.PubSub[string, int], ok func([]string) bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); !ok(ps.Keys()); {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected keys %v", ps.Keys())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	defer ps.Derive("even", []string{"n"}, func(n int) (int, bool) { return n, n%2 == 0 })()
+	defer ps.Derive("double", []string{"even"}, func(n int) (int, bool) { return n * 2, true })()
+
+	if keys := ps.Keys(); len(keys) != 0 {
+		t.Fatalf("expected idle pipelines, got keys %v", keys)
+	}
+
+	ch := make(chan int, 10)
+	ps.Subscribe([]string{"double"}, ch)
+	waitKeys(t, ps, func(keys []string) bool { return slices.Contains(keys, "n") })
+
+	for n := range 5 {
+		ps.Publish(context.Background(), "n", n+1)
+	}
+
+	for _, want := range []int{4, 8} {
+		if got := <-ch; got != want {
+			t.Errorf("expected %d, got %d", want, got)
+		}
+	}
+
+	ps.Unsubscribe([]string{"double"}, ch)
+	waitKeys(t, ps, func(keys []string) bool { return len(keys) == 0 })
+}
@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+)
+
+// Dispatch subscribes to the keys and processes their messages with a
+// pool of workers calling the handler. All messages of a key are handled
+// by the same worker, in order, so ordering is preserved per key while
+// different keys are processed in parallel. Workers is at least one.
+//
+// Each worker has a queue with the buffer size set by WithBufferSize;
+// while the queue of a busy worker is full, messages for other workers
+// wait too. Processing stops when the context is canceled or the returned
+// function is called; the function waits for the workers to exit.
+// Messages still queued at that moment are discarded.
+func (ps *PubSub[K, T]) Dispatch(
+	ctx context.Context, workers int, keys []K, handler func(ctx context.Context, key K, msg T),
+) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	in, unsubscribed := merge(ps, ctx, keys, 0, tag[K, T])
+
+	queues := make([]chan Keyed[K, T], max(workers, 1))
+	var wg sync.WaitGroup
+
+	for i := range queues {
+		queue := make(chan Keyed[K, T], ps.bufferSize(keys...))
+		queues[i] = queue
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case m := <-queue:
+					handler(ctx, m.Key, m.Msg)
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		seed := maphash.MakeSeed()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m := <-in:
+				queue := queues[maphash.Comparable(seed, m.Key)%uint64(len(queues))]
+				select {
+				case queue <- m:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+		unsubscribed()
+	}
+}
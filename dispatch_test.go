@@ -0,0 +1,64 @@
+package pubsub_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestDispatchOrderPerKey(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(8))
+	keys := []string{"a", "b", "c", "d"}
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	var wg sync.WaitGroup
+	wg.Add(len(keys) * 100)
+
+	stop := ps.Dispatch(context.Background(), 3, keys, func(_ context.Context, key string, msg int) {
+		mu.Lock()
+		got[key] = append(got[key], msg)
+		mu.Unlock()
+		wg.Done()
+	})
+	defer stop()
+
+	for i := range 100 {
+		for _, key := range keys {
+			ps.Publish(context.Background(), key, i)
+		}
+	}
+	wg.Wait()
+
+	for _, key := range keys {
+		if !slices.IsSorted(got[key]) || len(got[key]) != 100 {
+			t.Errorf("key %s: messages out of order or missing: %v", key, got[key])
+		}
+	}
+}
+
+func TestDispatchSameWorker(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	block := make(chan struct{})
+	done := make(chan string, 2)
+
+	stop := ps.Dispatch(context.Background(), 4, []string{"k"}, func(_ context.Context, key string, msg int) {
+		if msg == 1 {
+			<-block // the second message must wait for the first
+		}
+		done <- fmt.Sprint(msg)
+	})
+	defer stop()
+
+	ps.Publish(context.Background(), "k", 1)
+	go ps.Publish(context.Background(), "k", 2)
+	close(block)
+
+	if first, second := <-done, <-done; first != "1" || second != "2" {
+		t.Errorf("expected 1 then 2, got %s then %s", first, second)
+	}
+}
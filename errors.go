@@ -0,0 +1,45 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNoSubscribers reports that a message was published to a key without
+// subscribers, by publish functions that treat it as an error.
+var ErrNoSubscribers = errors.New("pubsub: no subscribers")
+
+// SlowConsumerError identifies the subscriber whose channel was still
+// full when publishing was aborted.
+type SlowConsumerError struct {
+	Subscriber any   // the subscriber channel, a chan T
+	Err        error // context error that aborted the delivery
+}
+
+func (e *SlowConsumerError) Error() string {
+	return fmt.Sprintf("pubsub: slow consumer: %v", e.Err)
+}
+
+func (e *SlowConsumerError) Unwrap() error {
+	return e.Err
+}
+
+// DeliveryError is returned by Publish when the message was not
+// delivered to all subscribers of the key. It wraps a SlowConsumerError,
+// which wraps the context error, so errors.Is(err, context.DeadlineExceeded)
+// keeps working.
+type DeliveryError[K comparable] struct {
+	Key         K
+	Delivered   int // subscribers that received the message
+	Subscribers int // subscribers of the key at the time of publishing
+	Err         error
+}
+
+func (e *DeliveryError[K]) Error() string {
+	return fmt.Sprintf("pubsub: delivered to %d of %d subscribers of %v: %v",
+		e.Delivered, e.Subscribers, e.Key, e.Err)
+}
+
+func (e *DeliveryError[K]) Unwrap() error {
+	return e.Err
+}
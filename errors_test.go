@@ -0,0 +1,49 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestDeliveryError(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	fast := make(chan int, 1)
+	slow := make(chan int) // never read
+	ps.Subscribe([]string{"k"}, fast)
+	ps.Subscribe([]string{"k"}, slow)
+
+	_, err := ps.PublishWithTimeout("k", 1, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error to be wrapped, got %v", err)
+	}
+
+	var de *pubsub.DeliveryError[string]
+	if !errors.As(err, &de) {
+		t.Fatalf("expected a DeliveryError, got %T", err)
+	}
+	if de.Key != "k" || de.Subscribers != 2 || de.Delivered > 1 {
+		t.Errorf("unexpected delivery error %+v", de)
+	}
+
+	var sc *pubsub.SlowConsumerError
+	if !errors.As(err, &sc) || sc.Subscriber != any(slow) {
+		t.Errorf("expected the slow channel to be reported, got %v", sc)
+	}
+}
+
+func TestMustPublish(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	if _, err := ps.MustPublish(context.Background(), "cmd", 1); !errors.Is(err, pubsub.ErrNoSubscribers) {
+		t.Errorf("expected ErrNoSubscribers, got %v", err)
+	}
+
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"cmd"}, ch)
+	if n, err := ps.MustPublish(context.Background(), "cmd", 2); err != nil || n != 1 {
+		t.Errorf("expected 1 delivery, got %d, %v", n, err)
+	}
+}
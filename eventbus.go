@@ -0,0 +1,82 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// EventBus is an event emitter built on PubSub: handlers are registered
+// with On and events are sent with Emit.
+// Each handler has its own queue and dispatch goroutine, so a slow
+// handler delays Emit only once its queue is full.
+type EventBus[K comparable, T any] struct {
+	ps     *PubSub[K, T]
+	buffer int
+
+	mu     sync.Mutex
+	nextID int
+	offs   map[int]func() // registered handlers
+}
+
+// NewEventBus creates an event bus; buffer is the queue capacity of
+// each handler.
+func NewEventBus[K comparable, T any](buffer int) *EventBus[K, T] {
+	return &EventBus[K, T]{
+		ps:     New[K, T](),
+		buffer: buffer,
+		offs:   make(map[int]func()),
+	}
+}
+
+// PubSub returns the underlying PubSub instance.
+func (b *EventBus[K, T]) PubSub() *PubSub[K, T] {
+	return b.ps
+}
+
+// On registers a handler for events emitted with the key.
+// The handler is called one event at a time, in emit order.
+// The returned function unregisters the handler; it must not be called
+// from the handler itself.
+func (b *EventBus[K, T]) On(key K, handler func(T)) (off func()) {
+	stop := b.ps.handle([]K{key}, b.buffer, handler)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.offs[id] = stop
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.offs, id)
+		b.mu.Unlock()
+
+		stop()
+	}
+}
+
+// Emit queues the event for every handler of the key and returns the
+// number of handlers it was queued for. It blocks while a handler's queue
+// is full.
+func (b *EventBus[K, T]) Emit(key K, msg T) int {
+	n, _ := b.ps.Publish(context.Background(), key, msg)
+	return n
+}
+
+// EmitContext is like Emit, but gives up when the context is canceled.
+func (b *EventBus[K, T]) EmitContext(ctx context.Context, key K, msg T) (int, error) {
+	return b.ps.Publish(ctx, key, msg)
+}
+
+// Close unregisters all handlers. Queued events that were not handled
+// yet are discarded.
+func (b *EventBus[K, T]) Close() {
+	b.mu.Lock()
+	offs := b.offs
+	b.offs = make(map[int]func())
+	b.mu.Unlock()
+
+	for _, off := range offs {
+		off()
+	}
+}
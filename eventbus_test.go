@@ -0,0 +1,49 @@
+package pubsub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := pubsub.NewEventBus[string, int](10)
+	defer bus.Close()
+
+	got := make(chan int, 10)
+	off := bus.On("tick", func(n int) { got <- n })
+
+	for i := 1; i <= 3; i++ {
+		if n := bus.Emit("tick", i); n != 1 {
+			t.Fatalf("expected 1 handler, got %d", n)
+		}
+	}
+
+	for want := 1; want <= 3; want++ {
+		select {
+		case n := <-got:
+			if n != want {
+				t.Errorf("expected %d, got %d", want, n)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event not handled")
+		}
+	}
+
+	off()
+	if n := bus.Emit("tick", 4); n != 0 {
+		t.Errorf("expected no handlers after off, got %d", n)
+	}
+}
+
+func TestEventBusClose(t *testing.T) {
+	bus := pubsub.NewEventBus[string, int](0)
+	bus.On("a", func(int) {})
+	bus.On("b", func(int) {})
+	bus.Close()
+
+	if bus.Emit("a", 1)+bus.Emit("b", 1) != 0 {
+		t.Error("expected no handlers after Close")
+	}
+}
@@ -0,0 +1,240 @@
+// Package gcpbridge mirrors PubSub keys to Google Cloud Pub/Sub topics.
+// Messages published to selected keys are published to topics in
+// batches, and messages pulled from subscriptions are published to keys
+// and acknowledged in batches, with a limit on outstanding messages for
+// flow control. The package does not depend on the Google Cloud client:
+// it works with any client implementing the Publisher and Subscriber
+// interfaces, and its Bridge implements bridge.Bridge.
+package gcpbridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/bridge"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Default batching and flow control settings used when the corresponding
+// fields are zero.
+const (
+	DefaultMaxBatch       = 100
+	DefaultBatchDelay     = 10 * time.Millisecond
+	DefaultMaxOutstanding = 100
+)
+
+// Message is a Cloud Pub/Sub message as seen by the bridge.
+type Message struct {
+	ID         string
+	AckID      string // set on pulled messages
+	Data       []byte
+	Attributes map[string]string
+}
+
+// Publisher publishes batches of messages to topics.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, msgs []Message) error
+}
+
+// Subscriber pulls messages from subscriptions.
+type Subscriber interface {
+	// Pull returns up to max messages of the subscription, blocking until
+	// at least one is available or the context is done.
+	Pull(ctx context.Context, subscription string, max int) ([]Message, error)
+	// Ack acknowledges the messages with the ack IDs.
+	Ack(ctx context.Context, subscription string, ackIDs ...string) error
+}
+
+// Bridge forwards messages between Cloud Pub/Sub and a PubSub instance.
+//
+// Note: a key should not be both consumed and produced, otherwise
+// messages will loop between Cloud Pub/Sub and PubSub.
+type Bridge[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// Publisher, Produce and Topic configure the PubSub to Cloud Pub/Sub
+	// direction: messages of the keys are published to the topic
+	// returned by Topic, in batches of up to MaxBatch messages collected
+	// for at most BatchDelay.
+	Publisher  Publisher
+	Produce    []K
+	Topic      func(K) string
+	MaxBatch   int
+	BatchDelay time.Duration
+
+	// Subscriber, Subscriptions and KeyOf configure the Cloud Pub/Sub to
+	// PubSub direction. KeyOf maps a pulled message to the key it is
+	// published to; messages for which it returns false are acknowledged
+	// and skipped. At most MaxOutstanding messages of a subscription are
+	// pulled and not yet acknowledged at a time.
+	Subscriber     Subscriber
+	Subscriptions  []string
+	KeyOf          func(Message) (K, bool)
+	MaxOutstanding int
+
+	// Codec converts message data; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the channels subscribed to Produce keys.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be decoded,
+	// which are acknowledged and skipped, and for batches that could not
+	// be published, which are dropped. If OnError is nil, such errors
+	// stop the bridge.
+	OnError func(msg Message, err error)
+}
+
+var _ bridge.Bridge = (*Bridge[string, any])(nil)
+
+// Run starts forwarding in both configured directions and blocks until
+// the context is canceled or an unhandled error occurs. Pulled messages
+// are acknowledged after being published to all subscribers of their
+// key; messages that failed are redelivered by Cloud Pub/Sub.
+func (b *Bridge[K, T]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for _, sub := range b.Subscriptions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.consume(ctx, sub))
+		}()
+	}
+
+	for _, key := range b.Produce {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.produce(ctx, key))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// consume pulls messages of the subscription and publishes them to PubSub.
+func (b *Bridge[K, T]) consume(ctx context.Context, sub string) error {
+	limit := b.MaxOutstanding
+	if limit <= 0 {
+		limit = DefaultMaxOutstanding
+	}
+
+	for {
+		msgs, err := b.Subscriber.Pull(ctx, sub, limit)
+		if err != nil {
+			return err
+		}
+
+		ackIDs := make([]string, 0, len(msgs))
+		for _, msg := range msgs {
+			if err := b.forward(ctx, msg); err != nil {
+				return err
+			}
+
+			ackIDs = append(ackIDs, msg.AckID)
+		}
+
+		if err := b.Subscriber.Ack(ctx, sub, ackIDs...); err != nil {
+			return err
+		}
+	}
+}
+
+// forward publishes a single pulled message.
+func (b *Bridge[K, T]) forward(ctx context.Context, msg Message) error {
+	key, ok := b.KeyOf(msg)
+	if !ok {
+		return nil
+	}
+
+	value, err := b.codec().Unmarshal(msg.Data)
+	if err != nil {
+		if b.OnError == nil {
+			return err
+		}
+
+		b.OnError(msg, err)
+		return nil
+	}
+
+	_, err = b.PubSub.Publish(ctx, key, value)
+	return err
+}
+
+// produce subscribes to the key and publishes its messages in batches.
+func (b *Bridge[K, T]) produce(ctx context.Context, key K) error {
+	ch := make(chan T, b.Buffer)
+	keys := []K{key}
+	b.PubSub.Subscribe(keys, ch)
+	defer b.PubSub.UnsubscribeAndDrain(keys, ch)
+
+	size := b.MaxBatch
+	if size <= 0 {
+		size = DefaultMaxBatch
+	}
+
+	delay := b.BatchDelay
+	if delay <= 0 {
+		delay = DefaultBatchDelay
+	}
+
+	topic := b.Topic(key)
+	msgs := make([]Message, 0, size)
+
+	return bridge.Batch(ctx, ch, size, delay, func(batch []T) error {
+		msgs = msgs[:0]
+		for _, value := range batch {
+			data, err := b.codec().Marshal(value)
+			if err != nil {
+				if b.OnError == nil {
+					return err
+				}
+
+				b.OnError(Message{}, err)
+				continue
+			}
+
+			msgs = append(msgs, Message{Data: data})
+		}
+
+		if len(msgs) == 0 {
+			return nil
+		}
+
+		err := b.Publisher.Publish(ctx, topic, msgs)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		if b.OnError == nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			b.OnError(msg, err)
+		}
+
+		return nil
+	})
+}
+
+// codec returns the configured codec or the default one.
+func (b *Bridge[K, T]) codec() codec.Codec[T] {
+	if b.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return b.Codec
+}
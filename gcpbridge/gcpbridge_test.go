@@ -0,0 +1,125 @@
+package gcpbridge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/gcpbridge"
+)
+
+type fakeCloud struct {
+	pulls     chan []gcpbridge.Message
+	published chan []gcpbridge.Message
+
+	mu    sync.Mutex
+	max   int
+	acked []string
+}
+
+func (c *fakeCloud) Publish(_ context.Context, _ string, msgs []gcpbridge.Message) error {
+	c.published <- append([]gcpbridge.Message(nil), msgs...)
+	return nil
+}
+
+func (c *fakeCloud) Pull(ctx context.Context, _ string, max int) ([]gcpbridge.Message, error) {
+	c.mu.Lock()
+	c.max = max
+	c.mu.Unlock()
+
+	select {
+	case msgs := <-c.pulls:
+		return msgs, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fakeCloud) Ack(_ context.Context, _ string, ackIDs ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked = append(c.acked, ackIDs...)
+	return nil
+}
+
+func TestBridgeConsume(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 2)
+	ps.Subscribe([]string{"orders"}, ch)
+
+	cloud := &fakeCloud{pulls: make(chan []gcpbridge.Message, 1)}
+	bridge := &gcpbridge.Bridge[string, int]{
+		PubSub:         ps,
+		Subscriber:     cloud,
+		Subscriptions:  []string{"orders-sub"},
+		KeyOf:          func(msg gcpbridge.Message) (string, bool) { return msg.Attributes["key"], true },
+		MaxOutstanding: 5,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	key := map[string]string{"key": "orders"}
+	cloud.pulls <- []gcpbridge.Message{
+		{AckID: "a", Data: []byte("1"), Attributes: key},
+		{AckID: "b", Data: []byte("2"), Attributes: key},
+	}
+
+	for _, want := range []int{1, 2} {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("expected %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message not published")
+		}
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if len(cloud.acked) != 2 || cloud.max != 5 {
+		t.Errorf("expected both messages acknowledged with 5 outstanding, got %v, %d", cloud.acked, cloud.max)
+	}
+}
+
+func TestBridgeProduceBatches(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	cloud := &fakeCloud{published: make(chan []gcpbridge.Message, 10)}
+	bridge := &gcpbridge.Bridge[string, int]{
+		PubSub:     ps,
+		Publisher:  cloud,
+		Produce:    []string{"events"},
+		Topic:      func(key string) string { return "projects/p/topics/" + key },
+		MaxBatch:   3,
+		BatchDelay: time.Hour,
+		Buffer:     10,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	for len(ps.Keys()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := range 3 {
+		ps.Publish(ctx, "events", i)
+	}
+
+	select {
+	case batch := <-cloud.published:
+		if len(batch) != 3 || string(batch[2].Data) != "2" {
+			t.Errorf("unexpected batch %+v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("full batch not published")
+	}
+}
@@ -0,0 +1,171 @@
+// Package grpc exposes a PubSub instance as a gRPC service, so one broker
+// can be shared across services. The service is defined in
+// pubsubpb/pubsub.proto: Subscribe streams messages of the requested keys
+// and Publish publishes a message to a key.
+//
+// Keys travel as strings and messages as bytes encoded with a codec.
+// ProtoCodec encodes protocol buffer messages.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+	"github.com/mdigger/pubsub/grpc/pubsubpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Server implements the PubSub gRPC service over a PubSub instance.
+type Server[K comparable, T any] struct {
+	pubsubpb.UnimplementedPubSubServer
+
+	PubSub *pubsub.PubSub[K, T]
+
+	// ParseKey converts a key from its wire form.
+	ParseKey func(string) (K, error)
+
+	// Codec converts messages to and from their wire form;
+	// codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the per-key subscription channels.
+	Buffer int
+}
+
+// Register registers the service with the gRPC server.
+func (s *Server[K, T]) Register(r grpc.ServiceRegistrar) {
+	pubsubpb.RegisterPubSubServer(r, s)
+}
+
+// Subscribe streams messages of the requested keys until the call ends.
+func (s *Server[K, T]) Subscribe(req *pubsubpb.SubscribeRequest, stream grpc.ServerStreamingServer[pubsubpb.Message]) error {
+	keys := make([]K, len(req.GetKeys()))
+	for i, name := range req.GetKeys() {
+		key, err := s.ParseKey(name)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "key %q: %v", name, err)
+		}
+		keys[i] = key
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	out := make(chan *pubsubpb.Message)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	for i, key := range keys {
+		name := req.GetKeys()[i]
+		ch := make(chan T, s.Buffer)
+		sub := []K{key}
+		if err := s.PubSub.SubscribeContext(ctx, sub, ch); err != nil {
+			return statusError(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.PubSub.UnsubscribeAndDrain(sub, ch)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					data, err := s.codec().Marshal(msg)
+					if err != nil {
+						continue
+					}
+
+					select {
+					case out <- &pubsubpb.Message{Key: name, Data: data}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case msg := <-out:
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Publish publishes the message and returns the number of deliveries.
+func (s *Server[K, T]) Publish(ctx context.Context, req *pubsubpb.PublishRequest) (*pubsubpb.PublishResponse, error) {
+	key, err := s.ParseKey(req.GetKey())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "key %q: %v", req.GetKey(), err)
+	}
+
+	msg, err := s.codec().Unmarshal(req.GetData())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "message: %v", err)
+	}
+
+	delivered, err := s.PubSub.Publish(ctx, key, msg)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pubsubpb.PublishResponse{Delivered: int64(delivered)}, nil
+}
+
+// statusError converts PubSub errors to gRPC status errors.
+func statusError(err error) error {
+	if errors.Is(err, pubsub.ErrForbidden) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if errors.Is(err, pubsub.ErrClosed) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return status.FromContextError(err).Err()
+}
+
+// codec returns the configured codec or the default one.
+func (s *Server[K, T]) codec() codec.Codec[T] {
+	if s.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return s.Codec
+}
+
+// ProtoCodec encodes protocol buffer messages. T is a generated message
+// struct type and P is its pointer type:
+//
+//	grpc.ProtoCodec[examplepb.Event, *examplepb.Event]{}
+type ProtoCodec[T any, P interface {
+	*T
+	proto.Message
+}] struct{}
+
+func (ProtoCodec[T, P]) Marshal(msg *T) ([]byte, error) {
+	return proto.Marshal(P(msg))
+}
+
+func (ProtoCodec[T, P]) Unmarshal(data []byte) (*T, error) {
+	msg := P(new(T))
+	err := proto.Unmarshal(data, msg)
+	return (*T)(msg), err
+}
+
+func (ProtoCodec[T, P]) ContentType() string {
+	return "application/protobuf"
+}
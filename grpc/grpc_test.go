@@ -0,0 +1,105 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+	pubsubgrpc "github.com/mdigger/pubsub/grpc"
+	"github.com/mdigger/pubsub/grpc/pubsubpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newClient(t *testing.T, ps *pubsub.PubSub[string, string]) pubsubpb.PubSubClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer()
+	(&pubsubgrpc.Server[string, string]{
+		PubSub:   ps,
+		ParseKey: func(s string) (string, error) { return s, nil },
+		Codec: codec.Funcs[string]{
+			MarshalFunc:   func(s string) ([]byte, error) { return []byte(s), nil },
+			UnmarshalFunc: func(b []byte) (string, error) { return string(b), nil },
+		},
+	}).Register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pubsubpb.NewPubSubClient(conn)
+}
+
+func TestSubscribeAndPublish(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	client := newClient(t, ps)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Subscribe(ctx, &pubsubpb.SubscribeRequest{Keys: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wait until the stream is subscribed
+	for {
+		resp, err := client.Publish(ctx, &pubsubpb.PublishRequest{Key: "b", Data: []byte("hello")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetDelivered() == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	msg, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetKey() != "b" || string(msg.GetData()) != "hello" {
+		t.Errorf("unexpected message %v", msg)
+	}
+}
+
+func TestPublishTimeout(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	ps.Subscribe([]string{"stuck"}, make(chan string))
+	client := newClient(t, ps)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Publish(ctx, &pubsubpb.PublishRequest{Key: "stuck", Data: []byte("x")})
+	if code := status.Code(err); code != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestProtoCodec(t *testing.T) {
+	var c codec.Codec[*pubsubpb.Message] = pubsubgrpc.ProtoCodec[pubsubpb.Message, *pubsubpb.Message]{}
+	data, err := c.Marshal(&pubsubpb.Message{Key: "k", Data: []byte("v")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.GetKey() != "k" || string(msg.GetData()) != "v" {
+		t.Errorf("unexpected message %v", msg)
+	}
+}
@@ -0,0 +1,5 @@
+// Package pubsubpb contains the protocol buffer definitions of the gRPC
+// gateway service.
+package pubsubpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pubsub.proto
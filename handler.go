@@ -0,0 +1,230 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// HandlerError is returned by Publish when handlers registered with
+// SubscribeFunc fail. Err joins their errors.
+type HandlerError[K comparable] struct {
+	Key K
+	Err error
+}
+
+func (e *HandlerError[K]) Error() string {
+	return fmt.Sprintf("pubsub: handlers of %v failed: %v", e.Key, e.Err)
+}
+
+func (e *HandlerError[K]) Unwrap() error {
+	return e.Err
+}
+
+// handler is a subscriber called inline by Publish.
+type handler[K comparable, T any] struct {
+	fn     func(ctx context.Context, key K, msg T) error
+	ctx    context.Context // canceled when the handler is removed
+	keys   []K             // replaced under the lock by UnaliasKey
+	limit  *limit[K, T]    // nil if unlimited
+	remove func()
+	name   string   // set by SubscribeAfter
+	after  []string // names of the handlers called first
+}
+
+// SubscribeFunc registers a handler called synchronously by Publish for
+// messages of the keys, in the publisher's goroutine: no channels and no
+// concurrency, for deterministic tests and simple single-threaded
+// pipelines. Each call gets a context carrying the values and deadline of
+// the publish context, canceled also when the handler is removed or the
+// instance closed, so long-running handlers can abort during shutdown.
+// Handlers of a key are called in registration order, unless reordered
+// by the dependencies of SubscribeAfter, after the message was sent to the
+// subscribed channels. Each handler that returns nil counts as a delivery;
+// the errors of the others are joined into a *HandlerError[K] returned by
+// Publish. Handlers may publish and unsubscribe.
+//
+// Handlers are not reported by Keys nor key events. The returned function
+// removes the handler.
+func (ps *PubSub[K, T]) SubscribeFunc(keys []K, fn func(ctx context.Context, key K, msg T) error) (unsubscribe func()) {
+	return ps.addHandler(keys, &handler[K, T]{fn: fn})
+}
+
+// addHandler registers the handler for the keys and returns the function
+// removing it.
+func (ps *PubSub[K, T]) addHandler(keys []K, h *handler[K, T]) (remove func()) {
+	ctx, cancel := context.WithCancel(ps.life)
+	h.ctx = ctx
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	keys = slices.Clone(keys)
+	for i, key := range keys {
+		keys[i] = ps.resolve(key)
+	}
+
+	// Order all the lists first: a dependency cycle panics before any
+	// change.
+	lists := make([][]*handler[K, T], len(keys))
+	for i, key := range keys {
+		// Copy on write: Publish calls a snapshot of the list.
+		lists[i] = orderHandlers(append(slices.Clip(ps.handlers[key]), h))
+	}
+
+	if ps.handlers == nil {
+		ps.handlers = make(map[K][]*handler[K, T])
+	}
+
+	for i, key := range keys {
+		ps.handlers[key] = lists[i]
+	}
+	h.keys = keys
+	ps.subscribersChanged()
+
+	h.remove = func() {
+		cancel()
+
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+
+		for _, key := range h.keys {
+			key = ps.resolve(key) // moved by AliasKey
+			list := slices.DeleteFunc(slices.Clone(ps.handlers[key]), func(x *handler[K, T]) bool { return x == h })
+			if len(list) == 0 {
+				delete(ps.handlers, key)
+			} else {
+				ps.handlers[key] = list
+			}
+		}
+		ps.subscribersChanged()
+	}
+
+	return h.remove
+}
+
+// PublishWait is like Publish, but lets the handlers registered with
+// SubscribeFunc veto the message, for validation-style listeners: it
+// calls all of them first, and if any fails, the message is not sent to
+// the subscribed channels and the errors of all failed handlers are
+// returned joined with errors.Join, in a *HandlerError[K]. Otherwise the
+// message is sent to the channels as by Publish. The returned count
+// includes the handlers.
+func (ps *PubSub[K, T]) PublishWait(ctx context.Context, key K, msg T) (int, error) {
+	key, msg, handlers, err := ps.prepare(ctx, key, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	tr := ps.trace(key, msg)
+	n, err := ps.callConfirm(ctx, key, msg, handlers, tr.handler())
+	if err != nil {
+		tr.end(err)
+		return n, err
+	}
+
+	delivered, err := ps.fanout(ctx, key, msg, false, tr)
+	tr.end(err)
+
+	return n + delivered, err
+}
+
+// call calls the handlers with the message and returns the number of
+// handlers that succeeded and their joined errors.
+func (ps *PubSub[K, T]) call(ctx context.Context, key K, msg T, handlers []*handler[K, T]) (int, error) {
+	return ps.callConfirm(ctx, key, msg, handlers, nil)
+}
+
+// callConfirm is like call, but also reports the outcome of each handler
+// to confirm, if not nil.
+func (ps *PubSub[K, T]) callConfirm(ctx context.Context, key K, msg T, handlers []*handler[K, T],
+	confirm func(o Outcome, err error),
+) (int, error) {
+	var (
+		n      int
+		errs   []error
+		failed map[string]bool // named handlers that failed or were skipped
+	)
+
+	var sum uint64
+	if debugMutations {
+		sum = checksum(msg)
+	}
+
+	start := ps.latencyStart()
+	for _, h := range handlers {
+		if failed != nil && h.blocked(failed) {
+			if h.name != "" {
+				failed[h.name] = true
+			}
+			if confirm != nil {
+				confirm(Dropped, nil)
+			}
+			continue
+		}
+
+		taken, last := h.limit.take()
+		if !taken {
+			continue
+		}
+
+		hctx, done := h.context(ctx)
+		err := h.fn(hctx, key, ps.clone(msg))
+		done()
+		if last {
+			h.remove()
+		}
+
+		if debugMutations {
+			ps.checkMutation(key, msg, &sum, "handler")
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+			if h.name != "" {
+				if failed == nil {
+					failed = make(map[string]bool)
+				}
+				failed[h.name] = true
+			}
+		} else {
+			n++
+			ps.observeSince(key, start)
+		}
+
+		if confirm != nil {
+			if err != nil {
+				confirm(HandlerFailed, err)
+			} else {
+				confirm(Delivered, nil)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return n, &HandlerError[K]{Key: key, Err: errors.Join(errs...)}
+	}
+
+	return n, nil
+}
+
+// context returns the context of a call of the handler: the publish
+// context, also canceled when the handler is removed. The returned
+// function releases it.
+func (h *handler[K, T]) context(ctx context.Context) (context.Context, func()) {
+	if ctx == context.Background() || ctx == context.TODO() {
+		return h.ctx, nop // nothing to merge, without allocating
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(h.ctx, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// nop does nothing.
+func nop() {}
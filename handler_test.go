@@ -0,0 +1,152 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubscribeFunc(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var calls []string
+
+	ps.SubscribeFunc([]string{"k"}, func(_ context.Context, key string, msg int) error {
+		calls = append(calls, "first")
+		ps.Publish(context.Background(), "derived", msg*2) // handlers may publish
+		return nil
+	})
+	ps.SubscribeFunc([]string{"derived"}, func(_ context.Context, _ string, msg int) error {
+		calls = append(calls, "derived")
+		return nil
+	})
+	off := ps.SubscribeFunc([]string{"k"}, func(context.Context, string, int) error {
+		calls = append(calls, "second")
+		return nil
+	})
+
+	n, err := ps.Publish(context.Background(), "k", 1)
+	if n != 2 || err != nil {
+		t.Errorf("expected 2 deliveries, got %d, %v", n, err)
+	}
+
+	if want := []string{"first", "derived", "second"}; !slices.Equal(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+
+	off()
+	calls = nil
+	ps.Publish(context.Background(), "k", 1)
+	if want := []string{"first", "derived"}; !slices.Equal(calls, want) {
+		t.Errorf("expected calls %v after removal, got %v", want, calls)
+	}
+}
+
+func TestSubscribeFuncErrors(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	errA, errB := errors.New("a"), errors.New("b")
+	for _, err := range []error{errA, nil, errB} {
+		ps.SubscribeFunc([]string{"k"}, func(context.Context, string, int) error { return err })
+	}
+
+	n, err := ps.Publish(context.Background(), "k", 1)
+	var herr *pubsub.HandlerError[string]
+	if n != 1 || !errors.As(err, &herr) || !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("expected joined handler errors, got %d, %v", n, err)
+	}
+
+	if _, err := ps.MustPublish(context.Background(), "k", 1); errors.Is(err, pubsub.ErrNoSubscribers) {
+		t.Error("expected handlers to count as subscribers")
+	}
+}
+
+func TestSubscribeFuncUnsubscribeItself(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var calls int
+	var off func()
+	off = ps.SubscribeFunc([]string{"k"}, func(context.Context, string, int) error {
+		calls++
+		off()
+		return nil
+	})
+
+	ps.Publish(context.Background(), "k", 1)
+	ps.Publish(context.Background(), "k", 2)
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestPublishWaitVeto(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 2)
+	ps.Subscribe([]string{"k"}, ch)
+
+	errNegative, errOdd := errors.New("negative"), errors.New("odd")
+	ps.SubscribeFunc([]string{"k"}, func(_ context.Context, _ string, msg int) error {
+		if msg < 0 {
+			return errNegative
+		}
+		return nil
+	})
+	ps.SubscribeFunc([]string{"k"}, func(_ context.Context, _ string, msg int) error {
+		if msg%2 != 0 {
+			return errOdd
+		}
+		return nil
+	})
+
+	_, err := ps.PublishWait(context.Background(), "k", -3)
+	if !errors.Is(err, errNegative) || !errors.Is(err, errOdd) {
+		t.Errorf("expected both vetoes, got %v", err)
+	}
+
+	if len(ch) != 0 {
+		t.Error("expected a vetoed message not to be sent")
+	}
+
+	if n, err := ps.PublishWait(context.Background(), "k", 2); n != 3 || err != nil || <-ch != 2 {
+		t.Errorf("expected an accepted message delivered 3 times, got %d, %v", n, err)
+	}
+}
+
+func TestSubscribeFuncContext(t *testing.T) {
+	type ctxKey struct{}
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		stop func(ps *pubsub.PubSub[string, int], unsubscribe func())
+	}{
+		{"unsubscribe", context.Background(), func(_ *pubsub.PubSub[string, int], unsubscribe func()) { unsubscribe() }},
+		{"close", context.Background(), func(ps *pubsub.PubSub[string, int], _ func()) { ps.Close() }},
+		{"values", context.WithValue(context.Background(), ctxKey{}, "v"), func(_ *pubsub.PubSub[string, int], unsubscribe func()) { unsubscribe() }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ps := pubsub.New[string, int]()
+			started := make(chan struct{})
+			unsubscribe := ps.SubscribeFunc([]string{"k"}, func(ctx context.Context, _ string, _ int) error {
+				if tc.ctx.Value(ctxKey{}) != nil && ctx.Value(ctxKey{}) != "v" {
+					t.Error("expected the values of the publish context")
+				}
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			})
+
+			result := make(chan error, 1)
+			go func() {
+				_, err := ps.Publish(tc.ctx, "k", 1)
+				result <- err
+			}()
+
+			<-started
+			tc.stop(ps, unsubscribe)
+			if err := <-result; !errors.Is(err, context.Canceled) {
+				t.Errorf("expected the handler canceled, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// File is a Store persisting IDs in a file, one per line, so handled
+// messages are remembered across restarts. All IDs are also kept in
+// memory; the file grows with every ID added.
+type File struct {
+	mu  sync.Mutex
+	f   *os.File
+	ids map[string]struct{}
+}
+
+// OpenFile opens or creates the file and loads the IDs it contains.
+// IDs must not contain newlines.
+func OpenFile(name string) (*File, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id := scanner.Text(); id != "" {
+			ids[id] = struct{}{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &File{f: f, ids: ids}, nil
+}
+
+// Contains reports whether the ID was added.
+func (s *File) Contains(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.ids[id]
+
+	return ok, nil
+}
+
+// Add appends the ID to the file and syncs it to stable storage.
+func (s *File) Add(id string) error {
+	if strings.ContainsAny(id, "\r\n") {
+		return errors.New("idempotency: ID contains a newline")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ids[id]; ok {
+		return nil
+	}
+
+	if _, err := s.f.WriteString(id + "\n"); err != nil {
+		return err
+	}
+
+	if err := s.f.Sync(); err != nil {
+		return err
+	}
+
+	s.ids[id] = struct{}{}
+
+	return nil
+}
+
+// Close closes the file.
+func (s *File) Close() error {
+	return s.f.Close()
+}
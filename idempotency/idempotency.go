@@ -0,0 +1,105 @@
+// Package idempotency deduplicates message handling by message ID, so a
+// message delivered more than once, for example after a retry or a
+// restart of a bridge, is processed only once.
+//
+// A Store records the IDs of handled messages. Memory keeps a bounded
+// number of recent IDs, File persists them across restarts.
+package idempotency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Store records IDs of handled messages.
+type Store interface {
+	// Contains reports whether the ID was added.
+	Contains(id string) (bool, error)
+	// Add records the ID.
+	Add(id string) error
+}
+
+// Handler wraps the handler so it is called only for messages whose ID,
+// returned by id, is not in the store. The ID is added after the handler
+// succeeds, so a failed message is handled again when redelivered.
+//
+// Concurrent deliveries of the same ID are not deduplicated against
+// each other; deliver messages of an ID from a single goroutine, for
+// example with PubSub.Dispatch.
+func Handler[T any](
+	store Store, id func(T) string, handler func(context.Context, T) error,
+) func(context.Context, T) error {
+	return func(ctx context.Context, msg T) error {
+		key := id(msg)
+		seen, err := store.Contains(key)
+		if err != nil || seen {
+			return err
+		}
+
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+
+		return store.Add(key)
+	}
+}
+
+// Memory is an in-memory Store keeping the most recently added IDs.
+type Memory struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List               // IDs, most recent first
+	ids   map[string]*list.Element // elements of order by ID
+}
+
+// NewMemory returns a Store remembering up to size IDs; the least
+// recently added or looked up ID is evicted first. Size is at least one.
+func NewMemory(size int) *Memory {
+	return &Memory{
+		size:  max(size, 1),
+		order: list.New(),
+		ids:   make(map[string]*list.Element),
+	}
+}
+
+// Contains reports whether the ID is remembered.
+func (m *Memory) Contains(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.ids[id]
+	if ok {
+		m.order.MoveToFront(e)
+	}
+
+	return ok, nil
+}
+
+// Add remembers the ID, evicting the least recently used one if full.
+func (m *Memory) Add(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.ids[id]; ok {
+		m.order.MoveToFront(e)
+		return nil
+	}
+
+	m.ids[id] = m.order.PushFront(id)
+	if m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.ids, oldest.Value.(string))
+	}
+
+	return nil
+}
+
+// Len returns the number of remembered IDs.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
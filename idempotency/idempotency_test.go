@@ -0,0 +1,83 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mdigger/pubsub/idempotency"
+)
+
+type order struct {
+	ID    string
+	Total int
+}
+
+func TestHandler(t *testing.T) {
+	store := idempotency.NewMemory(10)
+	var handled []string
+	fail := true
+
+	handle := idempotency.Handler(store, func(o order) string { return o.ID },
+		func(_ context.Context, o order) error {
+			if o.ID == "2" && fail {
+				fail = false
+				return errors.New("temporary failure")
+			}
+			handled = append(handled, o.ID)
+			return nil
+		})
+
+	ctx := context.Background()
+	for _, id := range []string{"1", "1", "2", "2", "2"} {
+		handle(ctx, order{ID: id})
+	}
+
+	if len(handled) != 2 || handled[0] != "1" || handled[1] != "2" {
+		t.Errorf("expected each order handled once, got %v", handled)
+	}
+}
+
+func TestMemoryEviction(t *testing.T) {
+	store := idempotency.NewMemory(2)
+	store.Add("a")
+	store.Add("b")
+	store.Contains("a") // a is now more recent than b
+	store.Add("c")
+
+	for id, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if got, _ := store.Contains(id); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", id, got, want)
+		}
+	}
+	if store.Len() != 2 {
+		t.Errorf("expected 2 IDs, got %d", store.Len())
+	}
+}
+
+func TestFileRestart(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "ids")
+
+	store, err := idempotency.OpenFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add("bad\nid"); err == nil {
+		t.Error("expected an error for an ID with a newline")
+	}
+	store.Close()
+
+	store, err = idempotency.OpenFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if ok, _ := store.Contains("a"); !ok {
+		t.Error("ID not remembered after reopening")
+	}
+}
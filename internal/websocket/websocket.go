@@ -0,0 +1,418 @@
+// Package websocket implements the subset of the WebSocket protocol
+// (RFC 6455) needed by the gateway: the opening handshake on both sides,
+// text and binary messages, fragmentation and control frames.
+// Extensions and subprotocols are not supported.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message opcodes.
+const (
+	OpText   = 0x1
+	OpBinary = 0x2
+
+	opContinuation = 0x0
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// DefaultReadLimit is the default maximum size of a received message.
+const DefaultReadLimit = 1 << 20
+
+var (
+	// ErrBadHandshake is returned when the opening handshake fails.
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	// ErrMessageTooLarge is returned when a message exceeds the read limit.
+	ErrMessageTooLarge = errors.New("websocket: message too large")
+	// ErrProtocol is returned on malformed frames.
+	ErrProtocol = errors.New("websocket: protocol error")
+	// ErrBadOrigin is returned when the origin of a handshake is denied.
+	ErrBadOrigin = errors.New("websocket: origin not allowed")
+)
+
+// SameOrigin reports whether the Origin header of the request, if any,
+// has the host of the request. Clients other than browsers usually don't
+// send the header and are allowed.
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// acceptGUID is used to compute the Sec-WebSocket-Accept header.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Conn is a WebSocket connection.
+// Reads must be made from a single goroutine; writes are safe for
+// concurrent use.
+type Conn struct {
+	conn      net.Conn
+	br        *bufio.Reader
+	client    bool // client connections mask outgoing frames
+	readLimit int64
+
+	wmu sync.Mutex // serializes frame writes
+}
+
+// Upgrade performs the server side of the opening handshake and returns
+// the hijacked connection. On failure an HTTP error is sent to the client.
+// checkOrigin reports whether the request may be upgraded; if nil,
+// SameOrigin is used, so that browsers can't open connections carrying
+// the cookies of the user from other sites.
+func Upgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(*http.Request) bool) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "websocket handshake expected", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+
+	if checkOrigin == nil {
+		checkOrigin = SameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "websocket origin not allowed", http.StatusForbidden)
+		return nil, ErrBadOrigin
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, err
+	}
+
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	if err := brw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &Conn{conn: conn, br: brw.Reader, readLimit: DefaultReadLimit}, nil
+}
+
+// Dial opens a client connection to the ws:// or wss:// URL. Secure
+// connections verify the server certificate against the system roots.
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	return DialTLS(ctx, rawURL, nil)
+}
+
+// DialTLS is like Dial, but uses the TLS configuration for wss:// URLs.
+// The server name is taken from the URL if the configuration has none.
+func DialTLS(ctx context.Context, rawURL string, config *tls.Config) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var port, scheme string
+	switch u.Scheme {
+	case "ws":
+		port, scheme = "80", "http"
+	case "wss":
+		port, scheme = "443", "https"
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var conn net.Conn
+	if scheme == "https" {
+		d := tls.Dialer{Config: config}
+		conn, err = d.DialContext(ctx, "tcp", host)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [16]byte
+	rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	u.Scheme = scheme
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Host:       u.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-Websocket-Key":     {key},
+			"Sec-Websocket-Version": {"13"},
+		},
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, ErrBadHandshake
+	}
+
+	return &Conn{conn: conn, br: br, client: true, readLimit: DefaultReadLimit}, nil
+}
+
+// SetReadLimit sets the maximum size of a received message.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline for future reads.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for future writes.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// ReadMessage returns the next text or binary message.
+// Ping frames are answered automatically. When the peer closes the
+// connection io.EOF is returned.
+func (c *Conn) ReadMessage() (op int, data []byte, err error) {
+	for {
+		fin, frameOp, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frameOp {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return 0, nil, io.EOF
+		case opContinuation:
+			if op == 0 {
+				return 0, nil, ErrProtocol
+			}
+		case OpText, OpBinary:
+			if op != 0 {
+				return 0, nil, ErrProtocol
+			}
+			op = frameOp
+		default:
+			return 0, nil, ErrProtocol
+		}
+
+		if int64(len(data)+len(payload)) > c.readLimit {
+			return 0, nil, ErrMessageTooLarge
+		}
+
+		data = append(data, payload...)
+		if fin {
+			return op, data, nil
+		}
+	}
+}
+
+// WriteMessage sends data as a single message frame.
+func (c *Conn) WriteMessage(op int, data []byte) error {
+	return c.writeFrame(op, data)
+}
+
+// Ping sends a ping control frame.
+func (c *Conn) Ping() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Close sends a close frame and closes the underlying connection.
+func (c *Conn) Close() error {
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.writeFrame(opClose, []byte{0x03, 0xE8}) // 1000: normal closure
+	return c.conn.Close()
+}
+
+// readFrame reads a single frame and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, op int, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = head[0]&0x80 != 0
+	op = int(head[0] & 0x0F)
+	masked := head[1]&0x80 != 0
+	size := uint64(head[1] & 0x7F)
+
+	// Clients must mask their frames and servers must not (RFC 6455 5.1).
+	if masked == c.client {
+		return false, 0, nil, ErrProtocol
+	}
+
+	// No extension is negotiated, so the reserved bits must be clear
+	// (RFC 6455 5.2).
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, ErrProtocol
+	}
+
+	// Control frames must not be fragmented and their payload fits in
+	// the 7-bit length (RFC 6455 5.5).
+	if op&0x8 != 0 && (!fin || size > 125) {
+		return false, 0, nil, ErrProtocol
+	}
+
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if size > uint64(c.readLimit) {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		maskBytes(mask, payload)
+	}
+
+	return fin, op, payload, nil
+}
+
+// writeFrame writes a single final frame, masking it on client connections.
+func (c *Conn) writeFrame(op int, data []byte) error {
+	buf := make([]byte, 0, len(data)+14)
+	buf = append(buf, 0x80|byte(op))
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	switch size := len(data); {
+	case size < 126:
+		buf = append(buf, maskBit|byte(size))
+	case size <= 0xFFFF:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(size))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(size))
+	}
+
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		buf = append(buf, mask[:]...)
+		start := len(buf)
+		buf = append(buf, data...)
+		maskBytes(mask, buf[start:])
+	} else {
+		buf = append(buf, data...)
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// maskBytes applies the masking key to the payload in place.
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for the key.
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains reports whether the comma-separated header contains
+// the token, case-insensitively.
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
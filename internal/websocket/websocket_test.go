@@ -0,0 +1,215 @@
+package websocket_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mdigger/pubsub/internal/websocket"
+)
+
+func TestEcho(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			op, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(op, data)
+		}
+	}))
+	defer srv.Close()
+
+	conn, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 125, 126, 1 << 16, 1<<16 + 1} {
+		msg := bytes.Repeat([]byte{'a'}, size)
+		if err := conn.WriteMessage(websocket.OpBinary, msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.Ping(); err != nil {
+			t.Fatal(err)
+		}
+		op, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op != websocket.OpBinary || !bytes.Equal(data, msg) {
+			t.Errorf("size %d: echo mismatch", size)
+		}
+	}
+
+	conn.Close()
+}
+
+func TestUpgradeRejectsPlainRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		websocket.Upgrade(w, r, nil)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestUpgradeChecksOrigin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	handshake := func(origin string) int {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := handshake("http://evil.example"); code != http.StatusForbidden {
+		t.Errorf("cross-origin: expected 403, got %d", code)
+	}
+	if code := handshake(srv.URL); code != http.StatusSwitchingProtocols {
+		t.Errorf("same origin: expected 101, got %d", code)
+	}
+	if code := handshake(""); code != http.StatusSwitchingProtocols {
+		t.Errorf("no origin: expected 101, got %d", code)
+	}
+}
+
+func TestServerRejectsMalformedFrames(t *testing.T) {
+	mask := []byte{1, 2, 3, 4}
+	masked := func(head ...byte) []byte {
+		return append(append(head, mask...), bytes.Repeat([]byte{0}, int(head[len(head)-1]&0x7F))...)
+	}
+
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"unmasked", []byte{0x82, 0x02, 'h', 'i'}},
+		{"reserved bits", masked(0xC2, 0x82)},
+		{"fragmented ping", masked(0x09, 0x80)},
+		{"long ping", append(append([]byte{0x89, 0xFE, 0x00, 0x7E}, mask...), make([]byte, 126)...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := serverRead(t, tt.frame); !errors.Is(err, websocket.ErrProtocol) {
+				t.Errorf("expected ErrProtocol, got %v", err)
+			}
+		})
+	}
+}
+
+// serverRead performs the handshake with a raw connection, sends the
+// frame and returns the error of the server reading it.
+func serverRead(t *testing.T, frame []byte) error {
+	t.Helper()
+	result := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			result <- err
+			return
+		}
+		defer conn.Close()
+		_, _, err = conn.ReadMessage()
+		result <- err
+	}))
+	defer srv.Close()
+
+	nc, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	io.WriteString(nc, "GET / HTTP/1.1\r\nHost: "+nc.RemoteAddr().String()+
+		"\r\nConnection: Upgrade\r\nUpgrade: websocket"+
+		"\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(nc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+
+	nc.Write(frame)
+
+	return <-result
+}
+
+func TestDialTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		op, data, err := conn.ReadMessage()
+		if err == nil {
+			conn.WriteMessage(op, data)
+		}
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	url := "wss" + strings.TrimPrefix(srv.URL, "https")
+
+	if _, err := websocket.Dial(context.Background(), url); err == nil {
+		t.Error("expected an untrusted certificate to fail")
+	}
+
+	conn, err := websocket.DialTLS(context.Background(), url, &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.OpText, []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "hi" {
+		t.Errorf("expected echo, got %q, %v", data, err)
+	}
+}
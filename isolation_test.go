@@ -0,0 +1,82 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// blockPublish starts publishing the message to the key "k" and returns
+// once the publish is in flight, blocked on a subscriber nobody reads.
+func blockPublish(ps *pubsub.PubSub[string, int], msg int) (done chan struct{}) {
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		ps.Publish(context.Background(), "k", msg)
+	}()
+
+	time.Sleep(10 * time.Millisecond) // let the publish block
+	return done
+}
+
+func TestSubscribeDuringPublish(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	slow := make(chan int)
+	ps.Subscribe([]string{"k"}, slow)
+
+	published := blockPublish(ps, 1)
+
+	late := make(chan int, 2)
+	subscribed := make(chan struct{})
+	go func() {
+		ps.Subscribe([]string{"k"}, late)
+		close(subscribed)
+	}()
+
+	select {
+	case <-subscribed:
+		t.Fatal("expected Subscribe to wait for the in-flight publish")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	<-slow
+	<-published
+	<-subscribed
+
+	if len(late) != 0 {
+		t.Error("expected a channel subscribed during a publish not to receive it")
+	}
+
+	go func() { <-slow }()
+	ps.Publish(context.Background(), "k", 2)
+	if got := <-late; got != 2 {
+		t.Errorf("expected the next message, got %d", got)
+	}
+}
+
+func TestUnsubscribeOnReceipt(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	self := make(chan int, 1)
+	slow := make(chan int)
+	ps.Subscribe([]string{"k"}, self)
+	ps.Subscribe([]string{"k"}, slow)
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		<-self
+		ps.Unsubscribe([]string{"k"}, self) // waits for the publish
+		close(unsubscribed)
+	}()
+
+	published := blockPublish(ps, 1)
+	<-slow
+	<-published
+	<-unsubscribed
+
+	ps.Unsubscribe([]string{"k"}, slow)
+	if n, _ := ps.Publish(context.Background(), "k", 2); n != 0 || len(self) != 0 {
+		t.Error("expected the unsubscribed channel not to receive the next message")
+	}
+}
@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"context"
+	"iter"
+)
+
+// Messages returns an iterator over messages published to any of the keys:
+//
+//	for key, msg := range ps.Messages(ctx, "a", "b") {
+//		...
+//	}
+//
+// The keys are subscribed when the iteration starts and unsubscribed when
+// the loop exits or the context is canceled. Messages of a key are yielded
+// in order; the order of messages of different keys is unspecified.
+func (ps *PubSub[K, T]) Messages(ctx context.Context, keys ...K) iter.Seq2[K, T] {
+	return func(yield func(K, T) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		events, wait := merge(ps, ctx, keys, 0, tag[K, T])
+		defer wait()
+		defer cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-events:
+				if !yield(ev.Key, ev.Msg) {
+					return
+				}
+			}
+		}
+	}
+}
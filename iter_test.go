@@ -0,0 +1,54 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestMessages(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	go func() {
+		// publish once the loop has subscribed
+		for {
+			n, _ := ps.Publish(ctx, "b", 1)
+			if n > 0 || ctx.Err() != nil {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		ps.Publish(ctx, "a", 2)
+	}()
+
+	got := map[string]int{}
+	for key, msg := range ps.Messages(ctx, "a", "b") {
+		got[key] = msg
+		if len(got) == 2 {
+			break
+		}
+	}
+
+	if got["a"] != 2 || got["b"] != 1 {
+		t.Errorf("unexpected messages %v", got)
+	}
+
+	// breaking out of the loop removes the subscriptions
+	if n, _ := ps.PublishWithTimeout("a", 3, 10*time.Millisecond); n != 0 {
+		t.Errorf("expected no subscribers after the loop, got %d", n)
+	}
+}
+
+func TestMessagesCanceled(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	for range ps.Messages(ctx, "a") {
+		t.Fatal("unexpected message")
+	}
+}
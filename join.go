@@ -0,0 +1,183 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pair is a message joined by Join.
+type Pair[L, R any] struct {
+	Left  L
+	Right R
+}
+
+// JoinSide is an input of Join: the messages published to the keys of a
+// PubSub instance, with the join key extracted by By.
+type JoinSide[K comparable, T any, J comparable] struct {
+	PubSub *PubSub[K, T]
+	Keys   []K
+	By     func(T) J
+}
+
+// Join correlates two streams, for example requests and their responses
+// or events and the records enriching them: whenever a message of one
+// side is received within the duration of a message of the other side
+// with the same join key, the pair is published to the key of dst. A
+// message joins with every matching message of the other side, so a
+// message is paired once per match. Messages that don't match within the
+// duration are discarded.
+//
+// Messages are collected by handlers registered with SubscribeFunc and
+// times measured by the clock of the left instance. Pairs are published
+// with ctx, by the publisher of the message completing them. Joining
+// stops when ctx is canceled or the returned function is called. It
+// panics if the duration is not positive.
+func Join[KL, KR, K comparable, L, R any, J comparable](
+	ctx context.Context,
+	left JoinSide[KL, L, J], right JoinSide[KR, R, J], within time.Duration,
+	dst *PubSub[K, Pair[L, R]], key K,
+) (stop func()) {
+	if within <= 0 {
+		panic("pubsub: invalid join window")
+	}
+
+	j := &join[J, L, R]{
+		clock:  left.PubSub.opts.clock,
+		within: within,
+		left:   make(map[J][]timed[L]),
+		right:  make(map[J][]timed[R]),
+	}
+
+	emit := func(pairs []Pair[L, R]) {
+		for _, p := range pairs {
+			dst.Publish(ctx, key, p)
+		}
+	}
+
+	removeLeft := left.PubSub.SubscribeFunc(left.Keys, func(_ context.Context, _ KL, msg L) error {
+		emit(j.addLeft(left.By(msg), msg))
+		return nil
+	})
+	removeRight := right.PubSub.SubscribeFunc(right.Keys, func(_ context.Context, _ KR, msg R) error {
+		emit(j.addRight(right.By(msg), msg))
+		return nil
+	})
+
+	j.mu.Lock()
+	j.timer = j.clock.AfterFunc(within, j.sweep)
+	j.mu.Unlock()
+
+	halt := func() {
+		removeLeft()
+		removeRight()
+		j.stop()
+	}
+	stopped := context.AfterFunc(ctx, halt)
+
+	return func() {
+		stopped()
+		halt()
+	}
+}
+
+// join is the state of a Join call.
+type join[J comparable, L, R any] struct {
+	clock  Clock
+	within time.Duration
+
+	mu      sync.Mutex
+	left    map[J][]timed[L]
+	right   map[J][]timed[R]
+	timer   Timer
+	stopped bool
+}
+
+// addLeft buffers a left message and returns its pairs.
+func (j *join[J, L, R]) addLeft(key J, msg L) []Pair[L, R] {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stopped {
+		return nil
+	}
+
+	now := j.clock.Now()
+	j.left[key] = append(j.left[key], timed[L]{now, msg})
+
+	var pairs []Pair[L, R]
+	for _, r := range recent(j.right, key, now.Add(-j.within)) {
+		pairs = append(pairs, Pair[L, R]{msg, r.msg})
+	}
+
+	return pairs
+}
+
+// addRight buffers a right message and returns its pairs.
+func (j *join[J, L, R]) addRight(key J, msg R) []Pair[L, R] {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stopped {
+		return nil
+	}
+
+	now := j.clock.Now()
+	j.right[key] = append(j.right[key], timed[R]{now, msg})
+
+	var pairs []Pair[L, R]
+	for _, l := range recent(j.left, key, now.Add(-j.within)) {
+		pairs = append(pairs, Pair[L, R]{l.msg, msg})
+	}
+
+	return pairs
+}
+
+// recent drops the messages of the key received before the time and
+// returns the others.
+func recent[J comparable, T any](buf map[J][]timed[T], key J, since time.Time) []timed[T] {
+	entries := buf[key]
+	i := 0
+	for i < len(entries) && entries[i].time.Before(since) {
+		i++
+	}
+
+	entries = entries[i:]
+	if len(entries) == 0 {
+		delete(buf, key)
+	} else {
+		buf[key] = entries
+	}
+
+	return entries
+}
+
+// sweep discards the messages that can't match anymore.
+func (j *join[J, L, R]) sweep() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.stopped {
+		return
+	}
+
+	since := j.clock.Now().Add(-j.within)
+	for key := range j.left {
+		recent(j.left, key, since)
+	}
+	for key := range j.right {
+		recent(j.right, key, since)
+	}
+
+	j.timer = j.clock.AfterFunc(j.within, j.sweep)
+}
+
+// stop stops the join.
+func (j *join[J, L, R]) stop() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stopped = true
+	j.left, j.right = nil, nil
+	j.timer.Stop()
+}
@@ -0,0 +1,56 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+type request struct{ ID, Path string }
+
+type response struct {
+	ID     string
+	Status int
+}
+
+func TestJoin(t *testing.T) {
+	requests, clock := pstest.New[string, request]()
+	responses := pubsub.New[string, response](pubsub.WithClock(clock))
+	pairs := pubsub.New[string, pubsub.Pair[request, response]]()
+	ch := make(chan pubsub.Pair[request, response], 10)
+	pairs.Subscribe([]string{"exchanges"}, ch)
+
+	stop := pubsub.Join(context.Background(),
+		pubsub.JoinSide[string, request, string]{
+			PubSub: requests, Keys: []string{"req"}, By: func(r request) string { return r.ID },
+		},
+		pubsub.JoinSide[string, response, string]{
+			PubSub: responses, Keys: []string{"resp"}, By: func(r response) string { return r.ID },
+		},
+		time.Second, pairs, "exchanges")
+	defer stop()
+
+	ctx := context.Background()
+	requests.Publish(ctx, "req", request{"1", "/a"})
+	requests.Publish(ctx, "req", request{"2", "/b"})
+	responses.Publish(ctx, "resp", response{"2", 404})
+
+	if p := <-ch; p.Left.Path != "/b" || p.Right.Status != 404 {
+		t.Errorf("unexpected pair %+v", p)
+	}
+
+	clock.Advance(2 * time.Second)
+	responses.Publish(ctx, "resp", response{"1", 200}) // too late
+	if len(ch) != 0 {
+		t.Errorf("expected no pair outside the window, got %+v", <-ch)
+	}
+
+	responses.Publish(ctx, "resp", response{"3", 200})
+	requests.Publish(ctx, "req", request{"3", "/c"})
+	if p := <-ch; p.Left.Path != "/c" || p.Right.Status != 200 {
+		t.Errorf("unexpected pair %+v", p)
+	}
+}
@@ -0,0 +1,210 @@
+// Package kafkabridge connects a PubSub instance to Kafka topics.
+// Messages consumed from Kafka are published to PubSub keys, and messages
+// published to selected keys are produced to Kafka. The package does not
+// depend on any particular Kafka client: it works with any reader and writer
+// that implement the small Reader and Writer interfaces, so local components
+// stay broker-agnostic.
+package kafkabridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Message is a Kafka record as seen by the bridge.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Time      time.Time
+}
+
+// ErrInvalidConfig is returned by Run when a configured direction lacks
+// the function mapping its keys.
+var ErrInvalidConfig = errors.New("kafkabridge: invalid configuration")
+
+// Reader consumes messages from Kafka.
+// FetchMessage must not commit the offset of the returned message:
+// the bridge commits it with CommitMessages once the message was published.
+type Reader interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	CommitMessages(ctx context.Context, msgs ...Message) error
+}
+
+// Writer produces messages to Kafka.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...Message) error
+}
+
+// Bridge forwards messages between Kafka and a PubSub instance.
+// Either direction is optional: a Bridge without a Reader only produces,
+// and a Bridge without a Writer only consumes.
+//
+// Note: a key should not be both a destination of consumed messages and
+// a source of produced ones, otherwise messages will loop between Kafka
+// and PubSub.
+type Bridge[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// Reader and KeyOf configure the Kafka to PubSub direction.
+	// KeyOf maps a consumed message, usually by its topic or record key,
+	// to the key it is published to; messages for which it returns false
+	// are committed and skipped. KeyFromRecord reverses the default
+	// RecordKey for string keys.
+	Reader Reader
+	KeyOf  func(Message) (K, bool)
+
+	// Writer, Keys and Topic configure the PubSub to Kafka direction.
+	// Topic returns the Kafka topic messages of the key are produced to.
+	Writer Writer
+	Keys   []K
+	Topic  func(K) string
+
+	// RecordKey returns the Kafka record key of messages produced for the
+	// key, so the messages of a key go to one partition and keep their
+	// order; the key formatted with fmt.Sprint if nil.
+	RecordKey func(K) []byte
+
+	// Codec converts message values; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the channels subscribed to Keys.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be decoded,
+	// encoded or written. The message is skipped and the bridge continues.
+	// If OnError is nil, such errors stop the bridge.
+	OnError func(msg Message, err error)
+}
+
+// Run starts forwarding in both configured directions and blocks until
+// the context is canceled or an unhandled error occurs.
+// Offsets of consumed messages are committed only after the message has
+// been published to all subscribers of its key.
+func (b *Bridge[K, T]) Run(ctx context.Context) error {
+	if b.Reader != nil && b.KeyOf == nil {
+		return fmt.Errorf("%w: Reader without KeyOf", ErrInvalidConfig)
+	}
+
+	if len(b.Keys) > 0 && (b.Writer == nil || b.Topic == nil) {
+		return fmt.Errorf("%w: Keys without Writer or Topic", ErrInvalidConfig)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	if b.Reader != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.consume(ctx))
+		}()
+	}
+
+	for _, key := range b.Keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.produce(ctx, key))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// consume reads messages from Kafka and publishes them to PubSub.
+func (b *Bridge[K, T]) consume(ctx context.Context) error {
+	for {
+		msg, err := b.Reader.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		if key, ok := b.KeyOf(msg); ok {
+			value, err := b.codec().Unmarshal(msg.Value)
+			if err != nil {
+				if b.OnError == nil {
+					return err
+				}
+
+				b.OnError(msg, err)
+			} else if _, err := b.PubSub.Publish(ctx, key, value); err != nil {
+				return err // not committed: will be redelivered
+			}
+		}
+
+		if err := b.Reader.CommitMessages(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// produce subscribes to the key and writes its messages to Kafka.
+func (b *Bridge[K, T]) produce(ctx context.Context, key K) error {
+	ch := make(chan T, b.Buffer)
+	keys := []K{key}
+	b.PubSub.Subscribe(keys, ch)
+	defer b.PubSub.UnsubscribeAndDrain(keys, ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case value := <-ch:
+			msg := Message{Topic: b.Topic(key), Key: b.recordKey(key)}
+			data, err := b.codec().Marshal(value)
+			if err == nil {
+				msg.Value = data
+				err = b.Writer.WriteMessages(ctx, msg)
+			}
+
+			if err != nil {
+				if b.OnError == nil || ctx.Err() != nil {
+					return err
+				}
+
+				b.OnError(msg, err)
+			}
+		}
+	}
+}
+
+// recordKey returns the Kafka record key of the key.
+func (b *Bridge[K, T]) recordKey(key K) []byte {
+	if b.RecordKey == nil {
+		return []byte(fmt.Sprint(key))
+	}
+
+	return b.RecordKey(key)
+}
+
+// KeyFromRecord is a KeyOf function publishing messages to the key named
+// by their record key, as produced by the default RecordKey. Messages
+// without a record key are skipped.
+func KeyFromRecord[K ~string](msg Message) (K, bool) {
+	return K(msg.Key), len(msg.Key) > 0
+}
+
+// codec returns the configured codec or the default one.
+func (b *Bridge[K, T]) codec() codec.Codec[T] {
+	if b.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return b.Codec
+}
@@ -0,0 +1,178 @@
+package kafkabridge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/kafkabridge"
+)
+
+type fakeReader struct {
+	msgs      chan kafkabridge.Message
+	mu        sync.Mutex
+	committed []int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafkabridge.Message, error) {
+	select {
+	case msg := <-r.msgs:
+		return msg, nil
+	case <-ctx.Done():
+		return kafkabridge.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafkabridge.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range msgs {
+		r.committed = append(r.committed, msg.Offset)
+	}
+	return nil
+}
+
+type fakeWriter chan kafkabridge.Message
+
+func (w fakeWriter) WriteMessages(_ context.Context, msgs ...kafkabridge.Message) error {
+	for _, msg := range msgs {
+		w <- msg
+	}
+	return nil
+}
+
+func TestBridgeConsume(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"orders"}, ch)
+
+	reader := &fakeReader{msgs: make(chan kafkabridge.Message, 2)}
+	bridge := &kafkabridge.Bridge[string, int]{
+		PubSub: ps,
+		Reader: reader,
+		KeyOf: func(msg kafkabridge.Message) (string, bool) {
+			return msg.Topic, msg.Topic == "orders"
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	reader.msgs <- kafkabridge.Message{Topic: "ignored", Offset: 1, Value: []byte("1")}
+	reader.msgs <- kafkabridge.Message{Topic: "orders", Offset: 2, Value: []byte("42")}
+
+	select {
+	case got := <-ch:
+		if got != 42 {
+			t.Errorf("expected 42, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not published")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	reader.mu.Lock()
+	defer reader.mu.Unlock()
+	if len(reader.committed) != 2 {
+		t.Errorf("expected 2 commits, got %v", reader.committed)
+	}
+}
+
+func TestBridgeProduce(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	writer := make(fakeWriter, 1)
+	bridge := &kafkabridge.Bridge[string, int]{
+		PubSub: ps,
+		Writer: writer,
+		Keys:   []string{"events"},
+		Topic:  func(key string) string { return "kafka-" + key },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	// wait for the bridge to subscribe
+	for {
+		n, err := ps.PublishWithTimeout("events", 7, 10*time.Millisecond)
+		if err == nil && n == 1 {
+			break
+		}
+	}
+
+	select {
+	case msg := <-writer:
+		if msg.Topic != "kafka-events" || string(msg.Key) != "events" || string(msg.Value) != "7" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not produced")
+	}
+}
+
+func TestBridgeDecodeError(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	reader := &fakeReader{msgs: make(chan kafkabridge.Message, 1)}
+	bridge := &kafkabridge.Bridge[string, int]{
+		PubSub: ps,
+		Reader: reader,
+		KeyOf:  func(msg kafkabridge.Message) (string, bool) { return msg.Topic, true },
+	}
+
+	reader.msgs <- kafkabridge.Message{Topic: "orders", Value: []byte("bad")}
+	if err := bridge.Run(context.Background()); err == nil {
+		t.Error("expected decode error")
+	}
+}
+
+func TestBridgeRecordKey(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"orders"}, ch)
+
+	reader := &fakeReader{msgs: make(chan kafkabridge.Message, 2)}
+	bridge := &kafkabridge.Bridge[string, int]{
+		PubSub: ps,
+		Reader: reader,
+		KeyOf:  kafkabridge.KeyFromRecord[string],
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	reader.msgs <- kafkabridge.Message{Topic: "shared", Offset: 1, Value: []byte("1")}
+	reader.msgs <- kafkabridge.Message{Topic: "shared", Offset: 2, Key: []byte("orders"), Value: []byte("2")}
+
+	select {
+	case got := <-ch:
+		if got != 2 {
+			t.Errorf("expected the keyed record, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not published")
+	}
+}
+
+func TestBridgeInvalidConfig(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	bridges := []*kafkabridge.Bridge[string, int]{
+		{PubSub: ps, Reader: &fakeReader{}},
+		{PubSub: ps, Writer: make(fakeWriter), Keys: []string{"events"}},
+		{PubSub: ps, Keys: []string{"events"}, Topic: func(key string) string { return key }},
+	}
+
+	for i, bridge := range bridges {
+		if err := bridge.Run(context.Background()); !errors.Is(err, kafkabridge.ErrInvalidConfig) {
+			t.Errorf("bridge %d: expected ErrInvalidConfig, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package pubsub
+
+import "sync"
+
+// KeyOption overrides a setting of a PubSub instance for one key, see
+// ConfigureKey.
+type KeyOption func(*keyConfig)
+
+// KeyBufferSize overrides WithBufferSize for the channels the library
+// creates for the key, for example by Next, Messages, Pipe and
+// NewSubscription. A channel for several keys gets the largest of their
+// buffer sizes.
+func KeyBufferSize(size int) KeyOption {
+	return func(c *keyConfig) {
+		c.bufferSize = override[int]{max(size, 0), true}
+	}
+}
+
+// KeyDropPolicy overrides WithDropPolicy for the messages published to
+// the key.
+func KeyDropPolicy(policy DropPolicy) KeyOption {
+	return func(c *keyConfig) {
+		c.dropPolicy = override[DropPolicy]{policy, true}
+	}
+}
+
+// KeyRetention overrides WithRetention for the key; zero disables
+// retention of its messages.
+func KeyRetention(n int) KeyOption {
+	return func(c *keyConfig) {
+		c.retention = override[int]{max(n, 0), true}
+	}
+}
+
+// KeyRateLimit limits the publishes to the key to rate per second on
+// average, with bursts of up to burst publishes, as measured by the
+// clock of the instance. Publishes over the limit fail with
+// ErrRateLimited.
+func KeyRateLimit(rate float64, burst int) KeyOption {
+	return func(c *keyConfig) {
+		c.rate, c.burst = max(rate, 0), max(burst, 1)
+	}
+}
+
+// override is a setting that may be overridden.
+type override[V any] struct {
+	value V
+	set   bool
+}
+
+// or returns the overriding value if set, or def.
+func (o override[V]) or(def V) V {
+	if o.set {
+		return o.value
+	}
+
+	return def
+}
+
+// keyConfig holds the overrides of a key.
+type keyConfig struct {
+	bufferSize override[int]
+	dropPolicy override[DropPolicy]
+	retention  override[int]
+
+	rate  float64 // publishes per second, zero if unlimited
+	burst int
+
+	mu      sync.Mutex // protects limiter
+	limiter bucket
+}
+
+// ConfigureKey overrides settings of the instance for the key at
+// runtime, so hot or critical keys can be tuned individually without
+// separate instances. The options replace the previous overrides of the
+// key; without options, the key uses the settings of the instance again.
+// Overrides apply to publishes and channels created after the call.
+//
+//	ps.ConfigureKey("audit", pubsub.KeyDropPolicy(pubsub.Block), pubsub.KeyRetention(1000))
+//	ps.ConfigureKey("metrics", pubsub.KeyDropPolicy(pubsub.DropOldest), pubsub.KeyRateLimit(100, 10))
+func (ps *PubSub[K, T]) ConfigureKey(key K, opts ...KeyOption) {
+	var c *keyConfig
+	if len(opts) > 0 {
+		c = new(keyConfig)
+		for _, opt := range opts {
+			opt(c)
+		}
+
+		if c.rate > 0 {
+			c.limiter = newBucket(c.rate, c.burst, ps.opts.clock.Now())
+		}
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if c == nil {
+		delete(ps.keyConfigs, key)
+		return
+	}
+
+	if ps.keyConfigs == nil {
+		ps.keyConfigs = make(map[K]*keyConfig)
+	}
+	ps.keyConfigs[key] = c
+}
+
+// keyConfig returns the overrides of the key, or nil. The caller must
+// hold the read lock.
+func (ps *PubSub[K, T]) keyConfig(key K) *keyConfig {
+	if len(ps.keyConfigs) == 0 {
+		return nil
+	}
+
+	return ps.keyConfigs[key]
+}
+
+// allowPublish takes a token from the rate limit of the key, reporting
+// whether the publish is allowed. c may be nil.
+func (ps *PubSub[K, T]) allowPublish(c *keyConfig) bool {
+	if c == nil || c.rate == 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.limiter.take(ps.opts.clock.Now())
+}
+
+// dropPolicy returns the drop policy of the key. The caller must hold
+// the read lock.
+func (ps *PubSub[K, T]) dropPolicy(key K) DropPolicy {
+	if c := ps.keyConfig(key); c != nil {
+		return c.dropPolicy.or(ps.opts.dropPolicy)
+	}
+
+	return ps.opts.dropPolicy
+}
+
+// retention returns the number of messages of the key to retain. The
+// caller must hold the read lock.
+func (ps *PubSub[K, T]) retention(key K) int {
+	if c := ps.keyConfig(key); c != nil {
+		return c.retention.or(ps.opts.retention)
+	}
+
+	return ps.opts.retention
+}
+
+// bufferSize returns the largest buffer size of the keys.
+func (ps *PubSub[K, T]) bufferSize(keys ...K) int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	size := ps.opts.bufferSize
+	if len(ps.keyConfigs) == 0 || len(keys) == 0 {
+		return size
+	}
+
+	size = 0
+	for _, key := range keys {
+		if c := ps.keyConfig(key); c != nil {
+			size = max(size, c.bufferSize.or(ps.opts.bufferSize))
+		} else {
+			size = max(size, ps.opts.bufferSize)
+		}
+	}
+
+	return size
+}
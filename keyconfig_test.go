@@ -0,0 +1,96 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestConfigureKeyDropPolicy(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.ConfigureKey("metrics", pubsub.KeyDropPolicy(pubsub.DropNewest))
+	ch := make(chan int)
+	ps.Subscribe([]string{"metrics", "orders"}, ch)
+
+	if n, err := ps.Publish(context.Background(), "metrics", 1); n != 0 || err != nil {
+		t.Errorf("expected the message dropped, got %d, %v", n, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ps.Publish(ctx, "orders", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected other keys to block, got %v", err)
+	}
+}
+
+func TestConfigureKeyRetention(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(1))
+	ps.ConfigureKey("audit", pubsub.KeyRetention(3))
+	ps.ConfigureKey("noise", pubsub.KeyRetention(0))
+
+	ctx := context.Background()
+	for i := range 5 {
+		ps.Publish(ctx, "audit", i)
+		ps.Publish(ctx, "noise", i)
+		ps.Publish(ctx, "other", i)
+	}
+
+	if got := len(ps.Retained("audit")); got != 3 {
+		t.Errorf("expected 3 retained messages of audit, got %d", got)
+	}
+	if got := len(ps.Retained("noise")); got != 0 {
+		t.Errorf("expected no retained messages of noise, got %d", got)
+	}
+	if got := len(ps.Retained("other")); got != 1 {
+		t.Errorf("expected 1 retained message of other, got %d", got)
+	}
+}
+
+func TestConfigureKeyRateLimit(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	ps.ConfigureKey("k", pubsub.KeyRateLimit(1, 2))
+	ctx := context.Background()
+
+	for i := range 2 {
+		if _, err := ps.Publish(ctx, "k", i); err != nil {
+			t.Fatalf("expected the burst allowed, got %v", err)
+		}
+	}
+	if _, err := ps.Publish(ctx, "k", 3); !errors.Is(err, pubsub.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+	if _, err := ps.Publish(ctx, "other", 3); err != nil {
+		t.Fatalf("expected other keys unlimited, got %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if _, err := ps.Publish(ctx, "k", 4); err != nil {
+		t.Fatalf("expected a token after a second, got %v", err)
+	}
+
+	ps.ConfigureKey("k") // remove the overrides
+	for i := range 5 {
+		if _, err := ps.Publish(ctx, "k", i); err != nil {
+			t.Fatalf("expected no limit after reset, got %v", err)
+		}
+	}
+}
+
+func TestConfigureKeyBufferSize(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(4))
+	ps.ConfigureKey("hot", pubsub.KeyBufferSize(64))
+
+	sub, err := ps.NewSubscription(context.Background(), "cold", "hot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	if stats := sub.Stats(); stats.Capacity != 64 {
+		t.Errorf("expected a buffer of 64, got %d", stats.Capacity)
+	}
+}
@@ -0,0 +1,122 @@
+package pubsub
+
+import "sync"
+
+// KeyEvent is a change of the subscription state of a key.
+type KeyEvent int
+
+const (
+	// KeyAdded reports that the key got its first subscriber.
+	KeyAdded KeyEvent = iota
+	// KeyRemoved reports that the last subscriber of the key left.
+	KeyRemoved
+	// KeyAliasUsed reports the first publish to a key made an alias by
+	// AliasKey.
+	KeyAliasUsed
+	// PatternAdded reports that a subject pattern got its first channel.
+	PatternAdded
+	// PatternRemoved reports that a subject pattern lost its last channel.
+	PatternRemoved
+)
+
+func (e KeyEvent) String() string {
+	switch e {
+	case KeyAdded:
+		return "added"
+	case KeyRemoved:
+		return "removed"
+	case KeyAliasUsed:
+		return "alias used"
+	case PatternAdded:
+		return "pattern added"
+	case PatternRemoved:
+		return "pattern removed"
+	default:
+		return "unknown"
+	}
+}
+
+// keyWatch dispatches key events to watchers. Events are queued while
+// the subscription lock is held, so they are observed in the order the
+// changes happened, and delivered after it is released, so watchers may
+// subscribe, unsubscribe and publish.
+type keyWatch[K comparable] struct {
+	mu          sync.Mutex
+	watchers    map[int]func(K, KeyEvent)
+	nextID      int
+	queue       []keyChange[K]
+	dispatching bool
+}
+
+type keyChange[K comparable] struct {
+	key   K
+	event KeyEvent
+}
+
+// WatchKeys calls fn whenever a key gets its first subscriber or loses
+// its last one, for example to start and stop an upstream feed on demand.
+// Events are delivered one at a time, in order, by the goroutine that
+// caused them or by a goroutine delivering earlier events, after the
+// change is visible. Subject patterns are reported with PatternAdded and
+// PatternRemoved. The returned function stops the watching.
+func (ps *PubSub[K, T]) WatchKeys(fn func(key K, event KeyEvent)) (stop func()) {
+	w := &ps.watch
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.watchers == nil {
+		w.watchers = make(map[int]func(K, KeyEvent))
+	}
+
+	id := w.nextID
+	w.nextID++
+	w.watchers[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.watchers, id)
+	}
+}
+
+// notify queues the event if anyone is watching.
+func (w *keyWatch[K]) notify(key K, event KeyEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.watchers) > 0 {
+		w.queue = append(w.queue, keyChange[K]{key, event})
+	}
+}
+
+// dispatch delivers queued events unless another goroutine is already
+// doing it, including this one in a watcher callback.
+func (w *keyWatch[K]) dispatch() {
+	w.mu.Lock()
+	if w.dispatching || len(w.queue) == 0 {
+		w.mu.Unlock()
+		return
+	}
+
+	w.dispatching = true
+	for len(w.queue) > 0 {
+		change := w.queue[0]
+		w.queue = w.queue[1:]
+
+		watchers := make([]func(K, KeyEvent), 0, len(w.watchers))
+		for _, fn := range w.watchers {
+			watchers = append(watchers, fn)
+		}
+		w.mu.Unlock()
+
+		for _, fn := range watchers {
+			fn(change.key, change.event)
+		}
+
+		w.mu.Lock()
+	}
+
+	w.dispatching = false
+	w.mu.Unlock()
+}
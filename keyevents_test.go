@@ -0,0 +1,51 @@
+package pubsub_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestWatchKeys(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var events []string
+	stop := ps.WatchKeys(func(key string, event pubsub.KeyEvent) {
+		events = append(events, fmt.Sprint(key, " ", event))
+	})
+
+	a, b := make(chan int), make(chan int)
+	ps.Subscribe([]string{"x", "y"}, a)
+	ps.Subscribe([]string{"x"}, b) // x already has a subscriber
+	ps.Unsubscribe([]string{"x"}, a)
+	ps.Unsubscribe([]string{"x", "y"}, b)
+	ps.Unsubscribe([]string{"y"}, a)
+
+	stop()
+	ps.Subscribe([]string{"z"}, a)
+
+	want := []string{"x added", "y added", "x removed", "y removed"}
+	if !slices.Equal(events, want) {
+		t.Errorf("unexpected events %q, want %q", events, want)
+	}
+}
+
+func TestWatchKeysReentrant(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	mirror := make(chan int)
+	var events []string
+	ps.WatchKeys(func(key string, event pubsub.KeyEvent) {
+		events = append(events, fmt.Sprint(key, " ", event))
+		if key == "src" && event == pubsub.KeyAdded {
+			ps.Subscribe([]string{"mirror"}, mirror) // must not deadlock
+		}
+	})
+
+	ps.Subscribe([]string{"src"}, make(chan int))
+
+	want := []string{"src added", "mirror added"}
+	if !slices.Equal(events, want) {
+		t.Errorf("unexpected events %q, want %q", events, want)
+	}
+}
@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"iter"
+	"path"
+)
+
+// Keys returns the keys that currently have subscribers, in unspecified
+// order.
+func (ps *PubSub[K, T]) Keys() []K {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	keys := make([]K, 0, len(ps.subscribers))
+	for key := range ps.subscribers {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// KeysOf returns the keys the channel is subscribed to, in unspecified
+// order.
+func (ps *PubSub[K, T]) KeysOf(ch chan T) []K {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	keys := make([]K, 0, len(ps.channelKeys[ch]))
+	for key := range ps.channelKeys[ch] {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// KeysMatching returns the keys with subscribers for which match reports
+// true, in unspecified order.
+func (ps *PubSub[K, T]) KeysMatching(match func(K) bool) []K {
+	var keys []K
+	for key := range ps.AllKeys(match) {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// AllKeys returns an iterator over the keys with subscribers for which
+// match reports true, or all of them if match is nil, without copying the
+// registry. The iteration holds a read lock: the loop body must not
+// subscribe or unsubscribe, and a slow body delays those calls.
+func (ps *PubSub[K, T]) AllKeys(match func(K) bool) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		ps.mu.RLock()
+		defer ps.mu.RUnlock()
+
+		for key := range ps.subscribers {
+			if match != nil && !match(key) {
+				continue
+			}
+
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Pattern returns a match function for KeysMatching and AllKeys that
+// reports whether a string key matches the shell pattern, with the syntax
+// of path.Match: "orders/*" matches "orders/1" but not "orders/1/items".
+// A malformed pattern matches nothing.
+func Pattern[K ~string](pattern string) func(K) bool {
+	return func(key K) bool {
+		ok, _ := path.Match(pattern, string(key))
+		return ok
+	}
+}
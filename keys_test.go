@@ -0,0 +1,77 @@
+package pubsub_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestKeys(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int)
+	ps.Subscribe([]string{"b", "a"}, ch)
+	ps.Subscribe([]string{"c"}, ch)
+	ps.Unsubscribe([]string{"c"}, ch)
+
+	keys := ps.Keys()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+}
+
+func TestKeysMatching(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int)
+	ps.Subscribe([]string{"orders/1", "orders/2", "orders/1/items", "users/1"}, ch)
+
+	keys := ps.KeysMatching(pubsub.Pattern[string]("orders/*"))
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"orders/1", "orders/2"}) {
+		t.Errorf("unexpected keys %v", keys)
+	}
+
+	if keys := ps.KeysMatching(pubsub.Pattern[string]("[")); len(keys) != 0 {
+		t.Errorf("expected a malformed pattern to match nothing, got %v", keys)
+	}
+}
+
+func TestAllKeys(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int)
+	ps.Subscribe([]string{"a", "b", "c"}, ch)
+
+	n := 0
+	for range ps.AllKeys(nil) {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+
+	if n != 2 {
+		t.Errorf("expected the iteration to stop after 2 keys, got %d", n)
+	}
+
+	// The lock is released after the loop exits early.
+	ps.Unsubscribe([]string{"a"}, ch)
+}
+
+func TestKeysOf(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int)
+	ps.Subscribe([]string{"a", "b"}, ch)
+	ps.Subscribe([]string{"c"}, make(chan int))
+
+	keys := ps.KeysOf(ch)
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "b"}) {
+		t.Errorf("expected [a b], got %v", keys)
+	}
+
+	ps.Unsubscribe([]string{"a"}, ch)
+	if keys := ps.KeysOf(ch); !slices.Equal(keys, []string{"b"}) {
+		t.Errorf("expected [b], got %v", keys)
+	}
+}
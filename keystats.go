@@ -0,0 +1,178 @@
+package pubsub
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// rateWindow is the time constant of the publish rate average.
+const rateWindow = time.Minute
+
+// WithKeyStats enables the per-key publish counters reported by Stats and
+// AllStats. They cost a lock per publish, so they are off by default.
+func WithKeyStats() Option {
+	return func(o *options) {
+		o.keyStats = true
+	}
+}
+
+// KeyStats are the statistics of a key.
+type KeyStats struct {
+	Subscribers int       // channels subscribed to the key
+	Queued      int       // messages waiting in the subscribed channels
+	Retained    int       // messages kept by retention
+	Published   uint64    // messages published to the key
+	Delivered   uint64    // deliveries to subscribed channels
+	Dropped     uint64    // deliveries that failed because of the drop policy or a timeout
+	LastPublish time.Time // time of the last publish, zero if none
+	Rate        float64   // publishes per second, averaged over about a minute
+}
+
+// Stats returns the statistics of the key. Unless WithKeyStats is set,
+// the publish counters are not tracked and stay zero.
+func (ps *PubSub[K, T]) Stats(key K) KeyStats {
+	var stats KeyStats
+	now := ps.opts.clock.Now()
+
+	ps.mu.RLock()
+	key = ps.resolve(key)
+	queued(&stats, ps.subscribers[key])
+	ps.mu.RUnlock()
+
+	h := ps.historyOf(key)
+	h.mu.Lock()
+	if s, ok := h.keys[key]; ok {
+		ps.expire(s, now)
+		stats.Retained = len(s.entries)
+	}
+	h.mu.Unlock()
+
+	ks := ps.statsOf(key)
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if c, ok := ks.keys[key]; ok {
+		c.fill(&stats, now)
+	}
+
+	return stats
+}
+
+// AllStats returns the statistics of all keys that have subscribers,
+// retained messages or, with WithKeyStats, were published to, to find
+// hot and dead keys.
+func (ps *PubSub[K, T]) AllStats() map[K]KeyStats {
+	now := ps.opts.clock.Now()
+
+	ps.mu.RLock()
+	all := make(map[K]KeyStats, len(ps.subscribers))
+	for key, subs := range ps.subscribers {
+		var stats KeyStats
+		queued(&stats, subs)
+		all[key] = stats
+	}
+	ps.mu.RUnlock()
+
+	for i := range ps.history {
+		h := &ps.history[i]
+		h.mu.Lock()
+		for key, s := range h.keys {
+			if ps.expire(s, now); len(s.entries) > 0 {
+				stats := all[key]
+				stats.Retained = len(s.entries)
+				all[key] = stats
+			}
+		}
+		h.mu.Unlock()
+	}
+
+	for i := range ps.keyStats {
+		ks := &ps.keyStats[i]
+		ks.mu.Lock()
+		for key, c := range ks.keys {
+			stats := all[key]
+			c.fill(&stats, now)
+			all[key] = stats
+		}
+		ks.mu.Unlock()
+	}
+
+	return all
+}
+
+// queued fills the number of subscribers and queued messages. The caller
+// must hold the read lock.
+func queued[T any](stats *KeyStats, subs *keySubs[T]) {
+	stats.Subscribers = len(subs.channels())
+	for ch := range subs.channels() {
+		stats.Queued += len(ch)
+	}
+}
+
+// keyStats holds the counters of the keys of a shard.
+type keyStats[K comparable] struct {
+	mu   sync.Mutex
+	keys map[K]*keyCounters
+}
+
+// keyCounters are the counters of a key.
+type keyCounters struct {
+	published uint64
+	delivered uint64
+	dropped   uint64
+	last      time.Time
+	rate      float64 // at the time of the last publish
+}
+
+// recordPublish accounts a publish to the key in the metrics and, if
+// enabled, the key statistics.
+func (ps *PubSub[K, T]) recordPublish(key K, delivered, dropped int) {
+	if m := ps.opts.metrics; m != nil {
+		m.Published(delivered, dropped)
+	}
+
+	if !ps.opts.keyStats {
+		return
+	}
+
+	now := ps.opts.clock.Now()
+	s := ps.statsOf(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.keys == nil {
+		s.keys = make(map[K]*keyCounters)
+	}
+
+	c, ok := s.keys[key]
+	if !ok {
+		c = new(keyCounters)
+		s.keys[key] = c
+	}
+
+	c.rate = c.rateAt(now) + 1/rateWindow.Seconds()
+	c.last = now
+	c.published++
+	c.delivered += uint64(delivered)
+	c.dropped += uint64(dropped)
+}
+
+// rateAt returns the publish rate decayed to the time.
+func (c *keyCounters) rateAt(now time.Time) float64 {
+	if c.last.IsZero() {
+		return 0
+	}
+
+	elapsed := max(now.Sub(c.last), 0)
+	return c.rate * math.Exp(-elapsed.Seconds()/rateWindow.Seconds())
+}
+
+// fill copies the counters into the statistics.
+func (c *keyCounters) fill(stats *KeyStats, now time.Time) {
+	stats.Published = c.published
+	stats.Delivered = c.delivered
+	stats.Dropped = c.dropped
+	stats.LastPublish = c.last
+	stats.Rate = c.rateAt(now)
+}
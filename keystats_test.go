@@ -0,0 +1,52 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestStats(t *testing.T) {
+	ps, clock := pstest.New[string, int](pubsub.WithKeyStats(), pubsub.WithDropPolicy(pubsub.DropNewest), pubsub.WithRetention(2))
+	full := make(chan int)
+	ps.Subscribe([]string{"hot"}, full)
+	ps.Subscribe([]string{"hot"}, make(chan int, 10))
+	ps.Subscribe([]string{"dead"}, make(chan int))
+
+	for range 3 {
+		ps.Publish(context.Background(), "hot", 1)
+	}
+	ps.Publish(context.Background(), "unheard", 1)
+
+	stats := ps.Stats("hot")
+	if stats.Subscribers != 2 || stats.Queued != 3 || stats.Retained != 2 ||
+		stats.Published != 3 || stats.Delivered != 3 || stats.Dropped != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if !stats.LastPublish.Equal(clock.Now()) || stats.Rate <= 0 {
+		t.Errorf("expected the last publish time and a rate, got %+v", stats)
+	}
+
+	clock.Advance(time.Hour)
+	if rate := ps.Stats("hot").Rate; rate > 0.001 {
+		t.Errorf("expected the rate to decay, got %f", rate)
+	}
+
+	all := ps.AllStats()
+	if len(all) != 3 || all["dead"].Published != 0 || all["dead"].Subscribers != 1 || all["unheard"].Published != 1 {
+		t.Errorf("unexpected snapshot %+v", all)
+	}
+}
+
+func TestStatsDisabled(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.Subscribe([]string{"k"}, make(chan int, 1))
+	ps.Publish(context.Background(), "k", 1)
+
+	if stats := ps.Stats("k"); stats != (pubsub.KeyStats{Subscribers: 1, Queued: 1}) {
+		t.Errorf("expected no publish counters, got %+v", stats)
+	}
+}
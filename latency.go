@@ -0,0 +1,152 @@
+package pubsub
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WithLatencySampling enables the measurement of delivery latencies,
+// reported per key by Latency alongside Stats, to monitor SLOs on event
+// delivery. A delivery to a channel is measured from the start of the
+// fan-out of the message to its sending to the channel; a call of a
+// handler, from the start of the calls of the handlers of the publish to
+// its successful return. Consumers can add the latencies they see up to
+// receiving or acknowledging messages with ObserveLatency.
+//
+// Each key keeps a uniform sample of up to size latencies, chosen by
+// reservoir sampling, at least one. Measuring costs a lock per delivery,
+// so it is off by default.
+func WithLatencySampling(size int) Option {
+	return func(o *options) {
+		o.latencySamples = max(size, 1)
+	}
+}
+
+// Latency is the delivery latency sample of a key.
+type Latency struct {
+	Count   uint64          // latencies measured
+	Samples []time.Duration // uniform sample of them, in ascending order
+}
+
+// Quantile returns the latency below which the fraction q of the sampled
+// latencies fall, such as 0.99 for the 99th percentile, or zero if there
+// are no samples.
+func (l Latency) Quantile(q float64) time.Duration {
+	if len(l.Samples) == 0 {
+		return 0
+	}
+
+	i := int(q * float64(len(l.Samples)))
+	return l.Samples[min(max(i, 0), len(l.Samples)-1)]
+}
+
+// Histogram returns the number of sampled latencies in each bucket
+// delimited by the ascending upper bounds: the first count is of the
+// latencies up to bounds[0], and the last one, after the counts of all
+// bounds, of the latencies above the last bound.
+func (l Latency) Histogram(bounds ...time.Duration) []int {
+	counts := make([]int, len(bounds)+1)
+	for _, d := range l.Samples {
+		i, _ := slices.BinarySearch(bounds, d)
+		counts[i]++
+	}
+
+	return counts
+}
+
+// latencies holds the latency samples of the keys.
+type latencies[K comparable] struct {
+	mu   sync.Mutex
+	keys map[K]*reservoir
+}
+
+// reservoir is a uniform sample of latencies.
+type reservoir struct {
+	count   uint64
+	samples []time.Duration
+}
+
+// add offers a latency to the sample of up to size latencies.
+func (r *reservoir) add(d time.Duration, size int) {
+	r.count++
+	if len(r.samples) < size {
+		r.samples = append(r.samples, d)
+		return
+	}
+
+	if i := rand.Uint64N(r.count); i < uint64(len(r.samples)) {
+		r.samples[i] = d
+	}
+}
+
+// latency returns the sample of the reservoir.
+func (r *reservoir) latency() Latency {
+	l := Latency{Count: r.count, Samples: slices.Clone(r.samples)}
+	slices.Sort(l.Samples)
+
+	return l
+}
+
+// Latency returns the delivery latency sample of the key. It is empty
+// unless WithLatencySampling is set.
+func (ps *PubSub[K, T]) Latency(key K) Latency {
+	key = ps.resolved(key)
+	ps.latencies.mu.Lock()
+	defer ps.latencies.mu.Unlock()
+
+	r, ok := ps.latencies.keys[key]
+	if !ok {
+		return Latency{}
+	}
+
+	return r.latency()
+}
+
+// latencyStart returns the start of a measured delivery, or the zero time
+// if latencies are not sampled.
+func (ps *PubSub[K, T]) latencyStart() time.Time {
+	if ps.opts.latencySamples == 0 {
+		return time.Time{}
+	}
+
+	return ps.opts.clock.Now()
+}
+
+// observeSince records the latency of a delivery started at start, unless
+// start is zero.
+func (ps *PubSub[K, T]) observeSince(key K, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+
+	ps.ObserveLatency(key, ps.opts.clock.Now().Sub(start))
+}
+
+// ObserveLatency adds a delivery latency of the key to its sample, for
+// consumers measuring up to the receipt or acknowledgement of a message,
+// for example from the Time of a retained message. It does nothing unless
+// WithLatencySampling is set.
+func (ps *PubSub[K, T]) ObserveLatency(key K, d time.Duration) {
+	size := ps.opts.latencySamples
+	if size == 0 {
+		return
+	}
+
+	l := &ps.latencies
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.keys == nil {
+		l.keys = make(map[K]*reservoir)
+	}
+
+	r, ok := l.keys[key]
+	if !ok {
+		r = new(reservoir)
+		l.keys[key] = r
+	}
+
+	r.add(d, size)
+}
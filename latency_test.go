@@ -0,0 +1,79 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestLatency(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithLatencySampling(100))
+	ch := make(chan int, 10)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.SubscribeFunc([]string{"k"}, func(context.Context, string, int) error { return nil })
+
+	for i := range 5 {
+		ps.Publish(context.Background(), "k", i)
+	}
+	for i := range 5 {
+		ps.ObserveLatency("k", time.Duration(i+1)*time.Second)
+	}
+
+	l := ps.Latency("k")
+	if l.Count != 15 || len(l.Samples) != 15 {
+		t.Fatalf("expected 15 latencies, got %d sampled of %d", len(l.Samples), l.Count)
+	}
+	if q := l.Quantile(1); q != 5*time.Second {
+		t.Errorf("expected the maximum 5s, got %v", q)
+	}
+	if q := l.Quantile(0.5); q >= time.Second {
+		t.Errorf("expected a median below 1s, got %v", q)
+	}
+
+	h := l.Histogram(time.Second, 3*time.Second)
+	if h[0] != 11 || h[1] != 2 || h[2] != 2 {
+		t.Errorf("unexpected histogram %v", h)
+	}
+
+	if l := ps.Latency("other"); l.Count != 0 {
+		t.Errorf("expected no latencies of another key, got %+v", l)
+	}
+}
+
+func TestLatencyReservoir(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithLatencySampling(10))
+	for i := range 1000 {
+		ps.ObserveLatency("k", time.Duration(i))
+	}
+
+	l := ps.Latency("k")
+	if l.Count != 1000 || len(l.Samples) != 10 {
+		t.Errorf("expected 10 samples of 1000, got %d of %d", len(l.Samples), l.Count)
+	}
+}
+
+func TestLatencyMeasured(t *testing.T) {
+	ps, clock := pstest.New[string, int](pubsub.WithLatencySampling(10))
+	ps.SubscribeFunc([]string{"k"}, func(context.Context, string, int) error {
+		clock.Advance(time.Second) // slow handler
+		return nil
+	})
+
+	ps.Publish(context.Background(), "k", 1)
+
+	if l := ps.Latency("k"); l.Quantile(1) != time.Second {
+		t.Errorf("expected a latency of 1s, got %+v", l)
+	}
+}
+
+func TestLatencyDisabled(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.ObserveLatency("k", time.Second)
+
+	if l := ps.Latency("k"); l.Count != 0 {
+		t.Errorf("expected no latencies, got %+v", l)
+	}
+}
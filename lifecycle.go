@@ -0,0 +1,190 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrClosed is returned by operations on a PubSub instance that is
+// draining or closed.
+var ErrClosed = errors.New("pubsub: closed")
+
+// Lifecycle states of a PubSub instance.
+const (
+	stateOpen = iota
+	stateDraining
+	stateClosed
+)
+
+// drainPoll is how often Drain checks the queues of managed subscriptions.
+const drainPoll = 10 * time.Millisecond
+
+// Drain prepares the instance for shutdown without losing messages: it
+// stops accepting publishes and subscriptions, which fail with ErrClosed,
+// waits until the consumers of managed subscriptions have received all
+// queued messages and the messages kept for paused channels and credit
+// backlogs have been taken, then closes the instance, calling the OnClose
+// hooks. If the context is done first, the instance is closed anyway and
+// the context error returned.
+//
+// Publishes blocked on a full channel when Drain is called fail with
+// ErrClosed. Channels passed to Subscribe are owned by the caller; Drain
+// doesn't wait for them.
+func (ps *PubSub[K, T]) Drain(ctx context.Context) error {
+	ps.stop()
+
+	// Blocked publishes return once stopped, but may still hold the read
+	// lock for a while.
+	locked := make(chan struct{})
+	go func() {
+		ps.mu.Lock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		go func() {
+			<-locked
+			ps.mu.Unlock()
+			ps.Close()
+		}()
+		return ctx.Err()
+	}
+
+	if ps.state == stateOpen {
+		ps.state = stateDraining
+		ps.subscribersChanged()
+	}
+	ps.mu.Unlock()
+
+	defer ps.Close()
+
+	for ps.queued() > 0 {
+		elapsed := make(chan struct{})
+		timer := ps.opts.clock.AfterFunc(drainPoll, func() { close(elapsed) })
+
+		select {
+		case <-elapsed:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// queued returns the number of messages waiting in the channels of
+// managed subscriptions, in pause buffers and in credit backlogs.
+func (ps *PubSub[K, T]) queued() int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	var n int
+	for ch := range ps.managed {
+		n += len(ch)
+	}
+	for _, b := range ps.paused {
+		n += b.len()
+	}
+	for _, c := range ps.credited {
+		c.mu.Lock()
+		n += len(c.backlog.msgs)
+		c.mu.Unlock()
+	}
+
+	return n
+}
+
+// stop aborts the blocked sends of publishes, so that Drain and Close
+// can take the lock.
+func (ps *PubSub[K, T]) stop() {
+	ps.stopOnce.Do(func() { close(ps.stopping) })
+}
+
+// Close closes the instance immediately: publishes and new subscriptions
+// fail with ErrClosed, including those blocked on full channels, and the
+// channels of managed subscriptions are
+// closed, so consumers ranging over them exit. Messages still queued in
+// them can be received until the channels are empty. The done channels
+// of SubscribeFor are closed too, then the OnClose hooks are called.
+// Closing a closed instance does nothing.
+func (ps *PubSub[K, T]) Close() {
+	ps.stop()
+	hooks := ps.close()
+
+	// In reverse order of registration, like deferred calls.
+	for _, h := range slices.Backward(hooks) {
+		h.fn()
+	}
+}
+
+// close closes the instance and returns its OnClose hooks, unless it was
+// already closed.
+func (ps *PubSub[K, T]) close() []*closeHook {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.state == stateClosed {
+		return nil
+	}
+
+	ps.state = stateClosed
+	ps.endLife()
+	for i := range ps.history {
+		ps.history[i].close()
+	}
+	ps.subscribersChanged()
+	for ch := range ps.managed {
+		close(ch)
+	}
+
+	for _, l := range ps.limits {
+		if l.timer != nil {
+			l.timer.Stop()
+		}
+		close(l.done)
+	}
+	ps.limits = nil
+
+	hooks := ps.closeHooks
+	ps.closeHooks = nil
+
+	return hooks
+}
+
+// closeHook is a function registered with OnClose.
+type closeHook struct {
+	fn func()
+}
+
+// OnClose registers fn to be called when the instance is closed, by Close
+// or at the end of Drain, so dependent resources such as bridges,
+// producers or derived keys can tear down. Hooks are called in the
+// reverse order of their registration, like deferred calls, by the
+// goroutine closing the instance after the closing is complete. If the
+// instance is already closed, fn is called right away. The returned
+// function unregisters the hook.
+func (ps *PubSub[K, T]) OnClose(fn func()) (remove func()) {
+	h := &closeHook{fn: fn}
+
+	ps.mu.Lock()
+	if ps.state == stateClosed {
+		ps.mu.Unlock()
+		fn()
+		return nop
+	}
+
+	ps.closeHooks = append(ps.closeHooks, h)
+	ps.mu.Unlock()
+
+	return func() {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+
+		ps.closeHooks = slices.DeleteFunc(ps.closeHooks, func(x *closeHook) bool { return x == h })
+	}
+}
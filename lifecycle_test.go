@@ -0,0 +1,149 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestDrain(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(3))
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	defer sub.Close()
+
+	for i := range 3 {
+		ps.Publish(context.Background(), "k", i)
+	}
+
+	received := make(chan int, 3)
+	go func() {
+		time.Sleep(20 * time.Millisecond) // slow consumer
+		for msg := range sub.C() {
+			received <- msg
+		}
+		close(received)
+	}()
+
+	if err := ps.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	for range received {
+		n++
+	}
+	if n != 3 {
+		t.Errorf("expected 3 messages received before close, got %d", n)
+	}
+
+	if _, err := ps.Publish(context.Background(), "k", 4); !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if err := ps.SubscribeContext(context.Background(), []string{"k"}, make(chan int)); !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+	if _, err := ps.NewSubscription(context.Background(), "k"); !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(1))
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	ps.Publish(context.Background(), "k", 1) // never received
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := ps.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// closed anyway; the queued message is still readable
+	if msg, ok := <-sub.C(); !ok || msg != 1 {
+		t.Errorf("expected the queued message, got %d, %v", msg, ok)
+	}
+	if _, ok := <-sub.C(); ok {
+		t.Error("expected the channel to be closed")
+	}
+
+	sub.Close() // must not hang on the closed channel
+}
+
+func TestCloseBlockedPublish(t *testing.T) {
+	for _, drain := range []bool{false, true} {
+		ps := pubsub.New[string, int]()
+		ps.Subscribe([]string{"k"}, make(chan int)) // never read
+
+		published := make(chan error, 1)
+		go func() {
+			_, err := ps.Publish(context.Background(), "k", 1)
+			published <- err
+		}()
+		time.Sleep(10 * time.Millisecond) // let Publish block
+
+		if drain {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			if err := ps.Drain(ctx); err != nil {
+				t.Errorf("Drain: %v", err)
+			}
+			cancel()
+		} else {
+			ps.Close()
+		}
+
+		if err := <-published; !errors.Is(err, pubsub.ErrClosed) {
+			t.Errorf("expected ErrClosed, got %v", err)
+		}
+	}
+}
+
+func TestDrainPaused(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(1))
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	defer sub.Close()
+
+	sub.Pause(2)
+	ps.Publish(context.Background(), "k", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := ps.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Drain to wait for the paused message, got %v", err)
+	}
+}
+
+func TestOnClose(t *testing.T) {
+	ps := pubsub.New[string, int]()
+
+	var calls []string
+	ps.OnClose(func() { calls = append(calls, "bridge") })
+	remove := ps.OnClose(func() { calls = append(calls, "removed") })
+	ps.OnClose(func() {
+		// the instance is closed when the hooks are called
+		if _, err := ps.Publish(context.Background(), "k", 1); !errors.Is(err, pubsub.ErrClosed) {
+			t.Errorf("expected ErrClosed, got %v", err)
+		}
+		calls = append(calls, "producer")
+	})
+	remove()
+
+	if err := ps.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ps.Close()
+
+	if want := []string{"producer", "bridge"}; !slices.Equal(calls, want) {
+		t.Errorf("expected hooks %v, got %v", want, calls)
+	}
+
+	var late bool
+	ps.OnClose(func() { late = true })
+	if !late {
+		t.Error("expected a hook registered after close to be called right away")
+	}
+}
@@ -0,0 +1,166 @@
+package pubsub
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// SubscribeOption limits a subscription made with SubscribeFor.
+type SubscribeOption func(*subscribeOptions)
+
+// subscribeOptions holds the limits of a subscription.
+type subscribeOptions struct {
+	limit int           // messages, zero if unlimited
+	ttl   time.Duration // zero if unlimited
+}
+
+// WithLimit removes the subscription after n messages were delivered to
+// it.
+func WithLimit(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.limit = max(n, 0)
+	}
+}
+
+// WithTTL removes the subscription once the duration elapses, as measured
+// by the clock of the PubSub instance.
+func WithTTL(d time.Duration) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.ttl = max(d, 0)
+	}
+}
+
+// SubscribeFor subscribes the channel to the keys until a limit set by
+// the options is reached, for example:
+//
+//	done, _ := ps.SubscribeFor(keys, ch, pubsub.WithLimit(100), pubsub.WithTTL(time.Minute))
+//
+// The subscription is removed when the last allowed message was delivered,
+// before that Publish returns, or when the time to live elapses, whichever
+// comes first; then done is closed. Concurrent publishes never deliver
+// more messages than the limit. Failed deliveries, because the publish
+// context ended or the drop policy dropped the message, don't count.
+//
+// The channel must not be subscribed otherwise. The returned cancel
+// function removes the subscription early, which also closes done.
+// Subscribing to a draining or closed instance does nothing and done is
+// closed right away.
+func (ps *PubSub[K, T]) SubscribeFor(keys []K, ch chan T, opts ...SubscribeOption) (done <-chan struct{}, cancel func()) {
+	var o subscribeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	n := o.limit
+	if n == 0 {
+		n = math.MaxInt64
+	}
+
+	l := newLimit(keys, ch, n)
+	ps.subscribeLimited(l, o.ttl)
+
+	return l.done, func() {
+		defer ps.watch.dispatch()
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+
+		ps.expireLimit(l)
+	}
+}
+
+// limit caps the number of messages delivered to a subscription.
+type limit[K comparable, T any] struct {
+	keys  []K
+	ch    chan T // nil for handlers
+	left  atomic.Int64
+	timer Timer         // nil without a time to live
+	done  chan struct{} // closed when the subscription is removed
+}
+
+// newLimit returns a limit of n messages.
+func newLimit[K comparable, T any](keys []K, ch chan T, n int) *limit[K, T] {
+	l := &limit[K, T]{keys: keys, ch: ch, done: make(chan struct{})}
+	l.left.Store(int64(n))
+
+	return l
+}
+
+// take claims a delivery. It reports whether one was left, and whether it
+// was the last one. A nil limit is unlimited.
+func (l *limit[K, T]) take() (taken, last bool) {
+	if l == nil {
+		return true, false
+	}
+
+	for {
+		n := l.left.Load()
+		if n <= 0 {
+			return false, false
+		}
+
+		if l.left.CompareAndSwap(n, n-1) {
+			return true, n == 1
+		}
+	}
+}
+
+// giveBack returns a delivery claimed by take that failed.
+func (l *limit[K, T]) giveBack() {
+	if l != nil {
+		l.left.Add(1)
+	}
+}
+
+// subscribeLimited subscribes the channel of the limit, removing it after
+// the time to live if it's not zero.
+func (ps *PubSub[K, T]) subscribeLimited(l *limit[K, T], ttl time.Duration) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.state != stateOpen {
+		close(l.done)
+		return
+	}
+
+	if ps.limits == nil {
+		ps.limits = make(map[chan T]*limit[K, T])
+	}
+
+	ps.limits[l.ch] = l
+	ps.add(l.keys, l.ch, false)
+
+	if ttl > 0 {
+		l.timer = ps.opts.clock.AfterFunc(ttl, func() {
+			ps.removeSpent([]*limit[K, T]{l})
+		})
+	}
+}
+
+// removeSpent removes the subscriptions whose limits are exhausted.
+func (ps *PubSub[K, T]) removeSpent(spent []*limit[K, T]) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for _, l := range spent {
+		ps.expireLimit(l)
+	}
+}
+
+// expireLimit removes the subscription of the limit, unless it was already
+// removed. The caller must hold the lock.
+func (ps *PubSub[K, T]) expireLimit(l *limit[K, T]) {
+	if ps.limits[l.ch] != l {
+		return
+	}
+
+	delete(ps.limits, l.ch)
+	ps.remove(l.keys, l.ch)
+
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	close(l.done)
+}
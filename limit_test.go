@@ -0,0 +1,77 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestSubscribeForLimit(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 10)
+	done, _ := ps.SubscribeFor([]string{"k"}, ch, pubsub.WithLimit(3))
+
+	for i := range 5 {
+		ps.Publish(context.Background(), "k", i)
+	}
+
+	select {
+	case <-done:
+	default:
+		t.Fatal("expected done closed after the limit")
+	}
+
+	if len(ch) != 3 || len(ps.Keys()) != 0 {
+		t.Errorf("expected 3 messages and no keys, got %d and %v", len(ch), ps.Keys())
+	}
+}
+
+func TestSubscribeForTTL(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	ch := make(chan int, 10)
+	done, _ := ps.SubscribeFor([]string{"k"}, ch, pubsub.WithTTL(time.Minute))
+
+	ps.Publish(context.Background(), "k", 1)
+	clock.Advance(time.Minute)
+	<-done
+
+	if n, _ := ps.Publish(context.Background(), "k", 2); n != 0 {
+		t.Errorf("expected no deliveries after the TTL, got %d", n)
+	}
+}
+
+func TestSubscribeForCancel(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	done, cancel := ps.SubscribeFor([]string{"k"}, make(chan int), pubsub.WithTTL(time.Minute))
+
+	cancel()
+	<-done
+	cancel() // no-op
+
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("expected the TTL timer stopped, got %d timers", n)
+	}
+}
+
+func TestSubscribeForClosed(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.Close()
+
+	done, _ := ps.SubscribeFor([]string{"k"}, make(chan int), pubsub.WithLimit(1))
+	<-done
+}
+
+func TestSubscribeForInstanceClose(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	done, _ := ps.SubscribeFor([]string{"k"}, make(chan int), pubsub.WithTTL(time.Minute))
+
+	ps.Close()
+	<-done
+
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("expected the TTL timer stopped, got %d timers", n)
+	}
+}
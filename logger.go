@@ -0,0 +1,39 @@
+package pubsub
+
+import "log/slog"
+
+// Logger receives diagnostic events: subscriptions at debug level,
+// dropped messages, slow subscribers and denied operations at warning
+// level. Arguments are alternating keys and values, as in log/slog.
+//
+// *slog.Logger implements Logger, so slog.Default() or any structured
+// logger built on slog can be passed to WithLogger directly.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+var _ Logger = (*slog.Logger)(nil)
+
+// WithLogger sets the logger of diagnostic events. By default nothing
+// is logged.
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// nopLogger discards all events.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// logging reports whether a logger is set, so hot paths can skip building
+// the arguments of events nobody receives.
+func (ps *PubSub[K, T]) logging() bool {
+	_, nop := ps.opts.logger.(nopLogger)
+	return !nop
+}
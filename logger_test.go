@@ -0,0 +1,36 @@
+package pubsub_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	ps := pubsub.New[string, int](
+		pubsub.WithLogger(logger),
+		pubsub.WithDropPolicy(pubsub.DropNewest),
+	)
+
+	ch := make(chan int)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.Publish(context.Background(), "k", 1)
+	ps.Unsubscribe([]string{"k"}, ch)
+
+	out := buf.String()
+	for _, want := range []string{
+		`level=DEBUG msg="pubsub: subscribed" key=k`,
+		`level=WARN msg="pubsub: message dropped" key=k`,
+		`level=DEBUG msg="pubsub: unsubscribed" key=k`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Keyed is a message together with the key it was published to.
+type Keyed[K comparable, T any] struct {
+	Key K
+	Msg T
+}
+
+// Merge subscribes to the keys and returns a single channel receiving
+// their messages. The channel has the buffer size set by WithBufferSize,
+// in addition to the buffers of the per-key subscriptions. It is closed
+// after the context is done and the keys are unsubscribed.
+//
+// Messages of a key arrive in order; the order of messages of different
+// keys is unspecified.
+func (ps *PubSub[K, T]) Merge(ctx context.Context, keys ...K) <-chan T {
+	return mergeChan(ps, ctx, keys, func(_ K, msg T) T { return msg })
+}
+
+// MergeKeyed is like Merge, but tags messages with the key they were
+// published to.
+func (ps *PubSub[K, T]) MergeKeyed(ctx context.Context, keys ...K) <-chan Keyed[K, T] {
+	return mergeChan(ps, ctx, keys, tag[K, T])
+}
+
+// tag returns the message tagged with the key.
+func tag[K comparable, T any](key K, msg T) Keyed[K, T] {
+	return Keyed[K, T]{Key: key, Msg: msg}
+}
+
+// mergeChan merges the keys into a buffered channel closed when the
+// context is done.
+func mergeChan[K comparable, T, U any](
+	ps *PubSub[K, T], ctx context.Context, keys []K, wrap func(K, T) U,
+) <-chan U {
+	out, wait := merge(ps, ctx, keys, ps.bufferSize(keys...), wrap)
+	go func() {
+		wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// merge subscribes a channel with the configured buffer size to each key
+// and forwards their messages, converted with wrap, to a single channel
+// with the given buffer until the context is done. The returned function
+// waits for the subscriptions to be removed.
+func merge[K comparable, T, U any](
+	ps *PubSub[K, T], ctx context.Context, keys []K, buffer int, wrap func(K, T) U,
+) (chan U, func()) {
+	out := make(chan U, buffer)
+	var wg sync.WaitGroup
+
+	for _, key := range keys {
+		sub := []K{key}
+		ch := make(chan T, ps.bufferSize(key))
+		ps.Subscribe(sub, ch)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer ps.UnsubscribeAndDrain(sub, ch)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					select {
+					case out <- wrap(key, msg):
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	return out, wg.Wait
+}
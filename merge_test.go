@@ -0,0 +1,51 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestMerge(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(4))
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ps.Merge(ctx, "a", "b")
+
+	ps.Publish(ctx, "a", 1)
+	ps.Publish(ctx, "b", 2)
+	ps.Publish(ctx, "c", 3)
+
+	sum := <-ch + <-ch
+	if sum != 3 {
+		t.Errorf("expected messages 1 and 2, got sum %d", sum)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("unexpected message after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+
+	if n, _ := ps.Publish(context.Background(), "a", 4); n != 0 {
+		t.Errorf("expected keys to be unsubscribed, got %d deliveries", n)
+	}
+}
+
+func TestMergeKeyed(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := ps.MergeKeyed(ctx, "a", "b")
+
+	go ps.Publish(ctx, "b", 2)
+
+	if got := <-ch; got != (pubsub.Keyed[string, int]{Key: "b", Msg: 2}) {
+		t.Errorf("unexpected message %+v", got)
+	}
+}
@@ -0,0 +1,19 @@
+package pubsub
+
+// Metrics receives the outcome of every publish, to export counters to a
+// monitoring system without polling Stats. Published is called by the
+// publishing goroutine once the deliveries are done, so it must be fast
+// and safe for concurrent use.
+type Metrics interface {
+	// Published is called with the number of deliveries of a message
+	// and of the deliveries that failed because of the drop policy or a
+	// timeout.
+	Published(delivered, dropped int)
+}
+
+// WithMetrics sets the sink of the publish metrics.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
@@ -0,0 +1,44 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+// counters is a Metrics sink summing the publishes.
+type counters struct {
+	mu                            sync.Mutex
+	published, delivered, dropped int
+}
+
+func (c *counters) Published(delivered, dropped int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.published++
+	c.delivered += delivered
+	c.dropped += dropped
+}
+
+func TestMetrics(t *testing.T) {
+	var m counters
+	ps := pubsub.New[string, int](pubsub.WithMetrics(&m), pubsub.WithDropPolicy(pubsub.DropNewest))
+	ch1, ch2 := make(chan int, 1), make(chan int)
+	ps.Subscribe([]string{"a"}, ch1)
+	ps.Subscribe([]string{"a"}, ch2)
+
+	ps.Publish(context.Background(), "a", 1)
+	ps.Publish(context.Background(), "b", 2)
+
+	if m.published != 2 || m.delivered != 1 || m.dropped != 1 {
+		t.Errorf("expected 2 publishes, 1 delivery and 1 drop, got %+v", &m)
+	}
+
+	if err := ps.Reconfigure(pubsub.WithMetrics(&m)); !errors.Is(err, pubsub.ErrNotReconfigurable) {
+		t.Errorf("expected ErrNotReconfigurable, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+// Package mqttbridge connects a PubSub instance to an MQTT broker, for
+// deployments where devices speak MQTT while services use PubSub.
+// Hierarchical keys map to MQTT topics, and the MQTT quality of service
+// maps to delivery guarantees: QoS 0 messages are forwarded best-effort,
+// QoS 1 messages are acknowledged only after being published to all
+// local subscribers. The package does not depend on an MQTT client: it
+// works with any client implementing the Client interface.
+package mqttbridge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// QoS is the MQTT quality of service level. QoS 2 is not supported.
+type QoS byte
+
+const (
+	AtMostOnce  QoS = 0 // best-effort
+	AtLeastOnce QoS = 1 // acknowledged
+)
+
+// Message is an MQTT message as seen by the bridge.
+type Message struct {
+	Topic   string
+	QoS     QoS
+	ID      uint16 // packet identifier of QoS 1 messages
+	Payload []byte
+}
+
+// Client is the subset of an MQTT client used by the bridge.
+type Client interface {
+	// Subscribe subscribes to the topic filter, which may contain
+	// wildcards, with the maximum QoS.
+	Subscribe(ctx context.Context, filter string, qos QoS) error
+	// Receive returns the next message received on the subscriptions.
+	Receive(ctx context.Context) (Message, error)
+	// Ack acknowledges a received QoS 1 message (PUBACK). The client must
+	// not acknowledge messages on its own.
+	Ack(ctx context.Context, msg Message) error
+	// Publish publishes the message, waiting for the broker to
+	// acknowledge it for QoS 1.
+	Publish(ctx context.Context, msg Message) error
+}
+
+// Bridge forwards messages between an MQTT broker and a PubSub instance.
+//
+// Note: a key should not be both received and published, otherwise
+// messages will loop between MQTT and PubSub.
+type Bridge[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+	Client Client
+
+	// Filters are the topic filters subscribed to, with their QoS.
+	// KeyOf maps the topic of a received message to the key it is
+	// published to; messages for which it returns false are skipped.
+	Filters map[string]QoS
+	KeyOf   func(topic string) (K, bool)
+
+	// Produce lists the keys whose messages are published to MQTT,
+	// to the topic returned by Topic with the QoS returned by QoS
+	// (AtMostOnce if nil).
+	Produce []K
+	Topic   func(K) string
+	QoS     func(K) QoS
+
+	// Codec converts payloads; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the channels subscribed to Produce keys.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be decoded,
+	// encoded or published, and for failed best-effort deliveries; they
+	// are skipped and the bridge continues. If OnError is nil, such
+	// errors stop the bridge, except for best-effort ones.
+	OnError func(msg Message, err error)
+}
+
+// Topics returns key and topic mappings for string keys whose levels are
+// separated with sep, such as "devices.42.temp" for the MQTT topic
+// "devices/42/temp". An optional prefix is added to topics; topics
+// without it are not mapped to keys.
+func Topics(sep, prefix string) (topic func(string) string, keyOf func(string) (string, bool)) {
+	topic = func(key string) string {
+		return prefix + strings.ReplaceAll(key, sep, "/")
+	}
+
+	keyOf = func(topic string) (string, bool) {
+		rest, ok := strings.CutPrefix(topic, prefix)
+		return strings.ReplaceAll(rest, "/", sep), ok
+	}
+
+	return topic, keyOf
+}
+
+// Run subscribes to the filters and starts forwarding in both configured
+// directions. It blocks until the context is canceled or an unhandled
+// error occurs.
+func (b *Bridge[K, T]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	for filter, qos := range b.Filters {
+		if err := b.Client.Subscribe(ctx, filter, qos); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	if len(b.Filters) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.receive(ctx))
+		}()
+	}
+
+	for _, key := range b.Produce {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.produce(ctx, key))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// receive publishes received messages to PubSub.
+func (b *Bridge[K, T]) receive(ctx context.Context) error {
+	for {
+		msg, err := b.Client.Receive(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := b.forward(ctx, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// forward publishes a received message and acknowledges QoS 1 messages
+// once published. Failed QoS 1 messages are not acknowledged, so the
+// broker redelivers them.
+func (b *Bridge[K, T]) forward(ctx context.Context, msg Message) error {
+	key, ok := b.KeyOf(msg.Topic)
+	if ok {
+		value, err := b.codec().Unmarshal(msg.Payload)
+		if err == nil {
+			_, err = b.PubSub.Publish(ctx, key, value)
+		}
+
+		switch {
+		case err == nil:
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case b.OnError != nil:
+			b.OnError(msg, err)
+		case msg.QoS != AtMostOnce:
+			return err
+		}
+	}
+
+	if msg.QoS == AtMostOnce {
+		return nil
+	}
+
+	return b.Client.Ack(ctx, msg)
+}
+
+// produce subscribes to the key and publishes its messages to MQTT.
+func (b *Bridge[K, T]) produce(ctx context.Context, key K) error {
+	ch := make(chan T, b.Buffer)
+	keys := []K{key}
+	b.PubSub.Subscribe(keys, ch)
+	defer b.PubSub.UnsubscribeAndDrain(keys, ch)
+
+	msg := Message{Topic: b.Topic(key)}
+	if b.QoS != nil {
+		msg.QoS = b.QoS(key)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case value := <-ch:
+			payload, err := b.codec().Marshal(value)
+			if err == nil {
+				msg.Payload = payload
+				err = b.Client.Publish(ctx, msg)
+			}
+
+			if err != nil {
+				if ctx.Err() != nil {
+					return err
+				}
+
+				if b.OnError != nil {
+					b.OnError(msg, err)
+				} else if msg.QoS != AtMostOnce {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// codec returns the configured codec or the default one.
+func (b *Bridge[K, T]) codec() codec.Codec[T] {
+	if b.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return b.Codec
+}
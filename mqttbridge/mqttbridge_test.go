@@ -0,0 +1,147 @@
+package mqttbridge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/mqttbridge"
+)
+
+type fakeClient struct {
+	mu        sync.Mutex
+	filters   map[string]mqttbridge.QoS
+	incoming  chan mqttbridge.Message
+	acked     []uint16
+	published chan mqttbridge.Message
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		filters:   make(map[string]mqttbridge.QoS),
+		incoming:  make(chan mqttbridge.Message, 10),
+		published: make(chan mqttbridge.Message, 10),
+	}
+}
+
+func (c *fakeClient) Subscribe(_ context.Context, filter string, qos mqttbridge.QoS) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters[filter] = qos
+	return nil
+}
+
+func (c *fakeClient) Receive(ctx context.Context) (mqttbridge.Message, error) {
+	select {
+	case msg := <-c.incoming:
+		return msg, nil
+	case <-ctx.Done():
+		return mqttbridge.Message{}, ctx.Err()
+	}
+}
+
+func (c *fakeClient) Ack(_ context.Context, msg mqttbridge.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acked = append(c.acked, msg.ID)
+	return nil
+}
+
+func (c *fakeClient) Publish(_ context.Context, msg mqttbridge.Message) error {
+	c.published <- msg
+	return nil
+}
+
+func TestTopics(t *testing.T) {
+	topic, keyOf := mqttbridge.Topics(".", "site/")
+	if got := topic("devices.42.temp"); got != "site/devices/42/temp" {
+		t.Errorf("unexpected topic %q", got)
+	}
+
+	if key, ok := keyOf("site/devices/42/temp"); !ok || key != "devices.42.temp" {
+		t.Errorf("unexpected key %q, %v", key, ok)
+	}
+
+	if _, ok := keyOf("other/devices"); ok {
+		t.Error("expected a topic without the prefix not to be mapped")
+	}
+}
+
+func TestBridgeReceive(t *testing.T) {
+	ps := pubsub.New[string, float64]()
+	ch := make(chan float64, 2)
+	ps.Subscribe([]string{"devices.1.temp"}, ch)
+
+	client := newFakeClient()
+	_, keyOf := mqttbridge.Topics(".", "")
+	bridge := &mqttbridge.Bridge[string, float64]{
+		PubSub:  ps,
+		Client:  client,
+		Filters: map[string]mqttbridge.QoS{"devices/+/temp": mqttbridge.AtLeastOnce},
+		KeyOf:   keyOf,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	client.incoming <- mqttbridge.Message{Topic: "devices/1/temp", QoS: mqttbridge.AtMostOnce, Payload: []byte("bad")}
+	client.incoming <- mqttbridge.Message{Topic: "devices/1/temp", QoS: mqttbridge.AtLeastOnce, ID: 7, Payload: []byte("21.5")}
+
+	select {
+	case got := <-ch:
+		if got != 21.5 {
+			t.Errorf("expected 21.5, got %v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not published")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if len(client.acked) != 1 || client.acked[0] != 7 {
+		t.Errorf("expected only the QoS 1 message acknowledged, got %v", client.acked)
+	}
+	if client.filters["devices/+/temp"] != mqttbridge.AtLeastOnce {
+		t.Errorf("expected the filter subscribed, got %v", client.filters)
+	}
+}
+
+func TestBridgeProduce(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	client := newFakeClient()
+	topic, _ := mqttbridge.Topics(".", "")
+	bridge := &mqttbridge.Bridge[string, int]{
+		PubSub:  ps,
+		Client:  client,
+		Produce: []string{"cmd.reboot"},
+		Topic:   topic,
+		QoS:     func(string) mqttbridge.QoS { return mqttbridge.AtLeastOnce },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	for len(ps.Keys()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ps.Publish(ctx, "cmd.reboot", 1)
+
+	select {
+	case msg := <-client.published:
+		if msg.Topic != "cmd/reboot" || msg.QoS != mqttbridge.AtLeastOnce || string(msg.Payload) != "1" {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not published to MQTT")
+	}
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Builds with the pubsubdebug tag detect subscribers mutating shared
+// messages: Publish takes a checksum of the message before delivering it
+// and compares it after the deliveries to the channels and after each
+// handler, logging a changed message as an error with the key and
+// reporting it to the observer set by WithMutationObserver. Checksums
+// walk the message with reflection, so the tag is meant for tests and
+// debugging, not production; without it the checks compile to nothing.
+//
+//	go test -tags pubsubdebug ./...
+//
+// Only handlers registered with SubscribeFunc are checked reliably, as
+// they run inside Publish. Channel consumers usually receive the message
+// after the checks of Publish are done, so their mutations go undetected
+// unless they happen while the deliveries are still in progress.
+
+// MutationObserver is called with the key of a message found mutated
+// after publish and with who mutated it, "subscriber" for the channel
+// deliveries or "handler" for a SubscribeFunc handler.
+type MutationObserver[K comparable] func(key K, by string)
+
+// WithMutationObserver sets the function called for each mutation
+// detected in builds with the pubsubdebug tag, so tests can assert there
+// is none; the mutation is still logged. K must be the key type of the
+// PubSub instance, otherwise New panics. Without the tag it is never
+// called.
+func WithMutationObserver[K comparable](fn MutationObserver[K]) Option {
+	return func(o *options) {
+		o.mutations = fn
+	}
+}
+
+// setMutationObserver applies the WithMutationObserver option.
+func (ps *PubSub[K, T]) setMutationObserver() {
+	if ps.opts.mutations == nil {
+		return
+	}
+
+	fn, ok := ps.opts.mutations.(MutationObserver[K])
+	if !ok {
+		panic(fmt.Sprintf("pubsub: WithMutationObserver for %T used with key type %v", ps.opts.mutations, reflect.TypeFor[K]()))
+	}
+
+	ps.mutated = fn
+}
+
+// checkMutation reports the message if it no longer matches the checksum
+// taken when it was published, and updates the checksum so each change
+// is reported once.
+func (ps *PubSub[K, T]) checkMutation(key K, msg T, sum *uint64, by string) {
+	if s := checksum(msg); s != *sum {
+		*sum = s
+		ps.opts.logger.Error("pubsub: message mutated after publish", "key", key, "by", by)
+		if ps.mutated != nil {
+			ps.mutated(key, by)
+		}
+	}
+}
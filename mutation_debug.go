@@ -0,0 +1,76 @@
+//go:build pubsubdebug
+
+package pubsub
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"reflect"
+)
+
+// debugMutations enables the checks of mutation.go.
+const debugMutations = true
+
+// checksum returns a hash of the data reachable from the message.
+func checksum[T any](msg T) uint64 {
+	return hashValue(reflect.ValueOf(&msg).Elem(), make(map[uintptr]bool))
+}
+
+// hashValue hashes the value, following pointers once each.
+func hashValue(v reflect.Value, seen map[uintptr]bool) uint64 {
+	h := fnv.New64a()
+	word := func(x uint64) {
+		h.Write(binary.LittleEndian.AppendUint64(nil, x))
+	}
+
+	word(uint64(v.Kind()))
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			word(1)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		word(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		word(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		word(math.Float64bits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		word(math.Float64bits(real(v.Complex())))
+		word(math.Float64bits(imag(v.Complex())))
+	case reflect.String:
+		h.Write([]byte(v.String()))
+	case reflect.Pointer:
+		if v.IsNil() || seen[v.Pointer()] {
+			break
+		}
+		seen[v.Pointer()] = true
+		word(hashValue(v.Elem(), seen))
+	case reflect.Interface:
+		if !v.IsNil() {
+			word(hashValue(v.Elem(), seen))
+		}
+	case reflect.Slice, reflect.Array:
+		word(uint64(v.Len()))
+		for i := range v.Len() {
+			word(hashValue(v.Index(i), seen))
+		}
+	case reflect.Map:
+		// Entries are combined in any order, as iteration order varies.
+		var sum uint64
+		for iter := v.MapRange(); iter.Next(); {
+			sum += hashValue(iter.Key(), seen)*31 + hashValue(iter.Value(), seen)
+		}
+		word(uint64(v.Len()))
+		word(sum)
+	case reflect.Struct:
+		for i := range v.NumField() {
+			word(hashValue(v.Field(i), seen))
+		}
+	default: // channels, functions and unsafe pointers
+		word(uint64(v.Pointer()))
+	}
+
+	return h.Sum64()
+}
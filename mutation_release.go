@@ -0,0 +1,8 @@
+//go:build !pubsubdebug
+
+package pubsub
+
+// debugMutations enables the checks of mutation.go.
+const debugMutations = false
+
+func checksum[T any](T) uint64 { return 0 }
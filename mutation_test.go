@@ -0,0 +1,60 @@
+//go:build pubsubdebug
+
+package pubsub_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestMutationDetected(t *testing.T) {
+	var buf bytes.Buffer
+	ps := pubsub.New[string, map[string][]int](pubsub.WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	ps.SubscribeFunc([]string{"k"}, func(_ context.Context, _ string, msg map[string][]int) error {
+		msg["a"][0] = 0
+		return nil
+	})
+	ps.SubscribeFunc([]string{"k"}, func(context.Context, string, map[string][]int) error { return nil })
+
+	ps.Publish(context.Background(), "k", map[string][]int{"a": {1}, "b": {2}})
+
+	if out := buf.String(); strings.Count(out, `msg="pubsub: message mutated after publish" key=k by=handler`) != 1 {
+		t.Errorf("expected the mutation reported once, got:\n%s", out)
+	}
+}
+
+func TestMutationNotDetected(t *testing.T) {
+	var buf bytes.Buffer
+	ps := pubsub.New[string, map[string][]int](pubsub.WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	ch := make(chan map[string][]int, 1)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.SubscribeFunc([]string{"k"}, func(context.Context, string, map[string][]int) error { return nil })
+
+	ps.Publish(context.Background(), "k", map[string][]int{"a": {1}, "b": {2}, "c": nil})
+
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing reported, got:\n%s", buf.String())
+	}
+}
+
+func TestMutationObserver(t *testing.T) {
+	var reported []string
+	ps := pubsub.New[string, []int](
+		pubsub.WithLogger(slog.New(slog.DiscardHandler)),
+		pubsub.WithMutationObserver(func(key, by string) { reported = append(reported, key+" "+by) }))
+	ps.SubscribeFunc([]string{"k"}, func(_ context.Context, _ string, msg []int) error {
+		msg[0]++
+		return nil
+	})
+
+	ps.Publish(context.Background(), "k", []int{1})
+
+	if len(reported) != 1 || reported[0] != "k handler" {
+		t.Errorf("expected the handler mutation observed, got %v", reported)
+	}
+}
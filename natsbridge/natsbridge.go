@@ -0,0 +1,266 @@
+// Package natsbridge connects a PubSub instance to NATS. Keys map to
+// subjects in both directions, with a configurable prefix and separator,
+// so wildcard keys subscribe to wildcard subjects. Subscriptions are
+// restored after connection failures, and messages of durable keys can be
+// published through JetStream. The package does not depend on the NATS
+// client: it works with any connection implementing the Conn interface.
+package natsbridge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// DefaultReconnect is the delay before resubscribing after a failure,
+// used when Bridge.Reconnect is zero.
+const DefaultReconnect = time.Second
+
+// Msg is a NATS message as seen by the bridge.
+type Msg struct {
+	Subject string
+	Data    []byte
+}
+
+// Conn is the subset of a NATS connection used by the bridge.
+type Conn interface {
+	// Publish publishes the data to the subject.
+	Publish(ctx context.Context, subject string, data []byte) error
+	// Subscribe subscribes to the subject, which may contain wildcards.
+	Subscribe(ctx context.Context, subject string) (Subscription, error)
+}
+
+// Subscription is a NATS subscription.
+type Subscription interface {
+	// Next returns the next message. An error other than the context
+	// error makes the bridge unsubscribe and subscribe again.
+	Next(ctx context.Context) (Msg, error)
+	Unsubscribe() error
+}
+
+// JetStream publishes messages to streams, waiting for the server to
+// acknowledge they are stored.
+type JetStream interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+}
+
+// Bridge forwards messages between NATS and a PubSub instance.
+//
+// Note: a key should not be both consumed and produced, otherwise
+// messages will loop between NATS and PubSub.
+type Bridge[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+	Conn   Conn
+
+	// Subject maps a key to its subject and KeyOf maps the subject of a
+	// received message back to a key; messages for which it returns
+	// false are skipped. See Subjects.
+	Subject func(K) string
+	KeyOf   func(subject string) (K, bool)
+
+	// Consume lists the keys, possibly wildcards, whose subjects are
+	// subscribed to and published to PubSub.
+	Consume []K
+
+	// Produce lists the keys whose messages are published to NATS.
+	Produce []K
+
+	// JetStream, if set, publishes the messages of keys for which Durable
+	// reports true, instead of core NATS.
+	JetStream JetStream
+	Durable   func(K) bool
+
+	// Reconnect is the delay before resubscribing after a subscription
+	// fails; DefaultReconnect if zero.
+	Reconnect time.Duration
+
+	// Codec converts message data; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the channels subscribed to Produce keys.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be decoded,
+	// encoded or published, which are skipped, and for subscription
+	// failures, which are retried. If OnError is nil, message errors stop
+	// the bridge.
+	OnError func(msg Msg, err error)
+}
+
+// Subjects returns key and subject mappings for string keys whose levels
+// are separated with sep, such as "orders/eu/1" for the subject
+// "app.orders.eu.1" with the prefix "app.". Wildcard levels "*" and a
+// final ">" are kept, so they have the same meaning in keys and
+// subjects. Subjects without the prefix are not mapped to keys.
+func Subjects(sep, prefix string) (subject func(string) string, keyOf func(string) (string, bool)) {
+	subject = func(key string) string {
+		return prefix + strings.ReplaceAll(key, sep, ".")
+	}
+
+	keyOf = func(subject string) (string, bool) {
+		rest, ok := strings.CutPrefix(subject, prefix)
+		return strings.ReplaceAll(rest, ".", sep), ok
+	}
+
+	return subject, keyOf
+}
+
+// Run starts forwarding in both configured directions and blocks until
+// the context is canceled or an unhandled error occurs.
+func (b *Bridge[K, T]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for _, key := range b.Consume {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.consume(ctx, b.Subject(key)))
+		}()
+	}
+
+	for _, key := range b.Produce {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.produce(ctx, key))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// consume subscribes to the subject and publishes its messages to PubSub,
+// subscribing again after failures.
+func (b *Bridge[K, T]) consume(ctx context.Context, subject string) error {
+	for {
+		err := b.receive(ctx, subject)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		var fatal *messageError
+		if errors.As(err, &fatal) {
+			return fatal.err
+		}
+
+		if b.OnError != nil {
+			b.OnError(Msg{Subject: subject}, err)
+		}
+
+		delay := b.Reconnect
+		if delay <= 0 {
+			delay = DefaultReconnect
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// messageError wraps message errors that stop the bridge, as opposed to
+// subscription errors that are retried.
+type messageError struct {
+	err error
+}
+
+func (e *messageError) Error() string {
+	return e.err.Error()
+}
+
+// receive forwards messages of a single subscription until it fails.
+func (b *Bridge[K, T]) receive(ctx context.Context, subject string) error {
+	sub, err := b.Conn.Subscribe(ctx, subject)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		msg, err := sub.Next(ctx)
+		if err != nil {
+			return err
+		}
+
+		key, ok := b.KeyOf(msg.Subject)
+		if !ok {
+			continue
+		}
+
+		value, err := b.codec().Unmarshal(msg.Data)
+		if err != nil {
+			if b.OnError == nil {
+				return &messageError{err}
+			}
+
+			b.OnError(msg, err)
+			continue
+		}
+
+		if _, err := b.PubSub.Publish(ctx, key, value); err != nil {
+			return &messageError{err}
+		}
+	}
+}
+
+// produce subscribes to the key and publishes its messages to NATS.
+func (b *Bridge[K, T]) produce(ctx context.Context, key K) error {
+	ch := make(chan T, b.Buffer)
+	keys := []K{key}
+	b.PubSub.Subscribe(keys, ch)
+	defer b.PubSub.UnsubscribeAndDrain(keys, ch)
+
+	publish := b.Conn.Publish
+	if b.JetStream != nil && b.Durable != nil && b.Durable(key) {
+		publish = b.JetStream.Publish
+	}
+
+	subject := b.Subject(key)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case value := <-ch:
+			msg := Msg{Subject: subject}
+			data, err := b.codec().Marshal(value)
+			if err == nil {
+				msg.Data = data
+				err = publish(ctx, subject, data)
+			}
+
+			if err != nil {
+				if b.OnError == nil || ctx.Err() != nil {
+					return err
+				}
+
+				b.OnError(msg, err)
+			}
+		}
+	}
+}
+
+// codec returns the configured codec or the default one.
+func (b *Bridge[K, T]) codec() codec.Codec[T] {
+	if b.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return b.Codec
+}
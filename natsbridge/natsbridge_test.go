@@ -0,0 +1,152 @@
+package natsbridge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/natsbridge"
+)
+
+var errDisconnected = errors.New("disconnected")
+
+// fakeConn fails the first subscription and delivers messages to the
+// following ones.
+type fakeConn struct {
+	mu         sync.Mutex
+	subscribed []string
+	msgs       chan natsbridge.Msg
+	published  chan natsbridge.Msg
+}
+
+func (c *fakeConn) Publish(_ context.Context, subject string, data []byte) error {
+	c.published <- natsbridge.Msg{Subject: subject, Data: data}
+	return nil
+}
+
+func (c *fakeConn) Subscribe(_ context.Context, subject string) (natsbridge.Subscription, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribed = append(c.subscribed, subject)
+	return &fakeSub{conn: c, broken: len(c.subscribed) == 1}, nil
+}
+
+type fakeSub struct {
+	conn   *fakeConn
+	broken bool
+}
+
+func (s *fakeSub) Next(ctx context.Context) (natsbridge.Msg, error) {
+	if s.broken {
+		return natsbridge.Msg{}, errDisconnected
+	}
+
+	select {
+	case msg := <-s.conn.msgs:
+		return msg, nil
+	case <-ctx.Done():
+		return natsbridge.Msg{}, ctx.Err()
+	}
+}
+
+func (s *fakeSub) Unsubscribe() error { return nil }
+
+type fakeJetStream chan natsbridge.Msg
+
+func (js fakeJetStream) Publish(_ context.Context, subject string, data []byte) error {
+	js <- natsbridge.Msg{Subject: subject, Data: data}
+	return nil
+}
+
+func TestSubjects(t *testing.T) {
+	subject, keyOf := natsbridge.Subjects("/", "app.")
+	if got := subject("orders/*/>"); got != "app.orders.*.>" {
+		t.Errorf("unexpected subject %q", got)
+	}
+
+	if key, ok := keyOf("app.orders.eu.1"); !ok || key != "orders/eu/1" {
+		t.Errorf("unexpected key %q, %v", key, ok)
+	}
+}
+
+func TestBridgeConsumeReconnect(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"orders/eu"}, ch)
+
+	conn := &fakeConn{msgs: make(chan natsbridge.Msg, 1)}
+	subject, keyOf := natsbridge.Subjects("/", "app.")
+	var reported []error
+	bridge := &natsbridge.Bridge[string, int]{
+		PubSub:    ps,
+		Conn:      conn,
+		Subject:   subject,
+		KeyOf:     keyOf,
+		Consume:   []string{"orders/*"},
+		Reconnect: time.Millisecond,
+		OnError:   func(_ natsbridge.Msg, err error) { reported = append(reported, err) },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	conn.msgs <- natsbridge.Msg{Subject: "app.orders.eu", Data: []byte("5")}
+	select {
+	case got := <-ch:
+		if got != 5 {
+			t.Errorf("expected 5, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not published after reconnecting")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if len(reported) != 1 || !errors.Is(reported[0], errDisconnected) {
+		t.Errorf("expected the failure reported, got %v", reported)
+	}
+
+	if len(conn.subscribed) != 2 || conn.subscribed[1] != "app.orders.*" {
+		t.Errorf("expected the wildcard subject subscribed twice, got %v", conn.subscribed)
+	}
+}
+
+func TestBridgeProduceJetStream(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	conn := &fakeConn{published: make(chan natsbridge.Msg, 1)}
+	js := make(fakeJetStream, 1)
+	subject, _ := natsbridge.Subjects("/", "")
+	bridge := &natsbridge.Bridge[string, int]{
+		PubSub:    ps,
+		Conn:      conn,
+		Subject:   subject,
+		Produce:   []string{"events", "orders/eu"},
+		JetStream: js,
+		Durable:   func(key string) bool { return key == "orders/eu" },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	for len(ps.Keys()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	ps.Publish(ctx, "orders/eu", 1)
+	ps.Publish(ctx, "events", 2)
+
+	if msg := <-js; msg.Subject != "orders.eu" || string(msg.Data) != "1" {
+		t.Errorf("unexpected JetStream message %+v", msg)
+	}
+
+	if msg := <-conn.published; msg.Subject != "events" || string(msg.Data) != "2" {
+		t.Errorf("unexpected core NATS message %+v", msg)
+	}
+}
@@ -0,0 +1,292 @@
+// Package netbridge links PubSub instances of different processes over
+// a stream connection such as a Unix socket or TCP.
+// Each side forwards the messages of its selected keys to the other side,
+// where they are published to the same keys if that side accepts them.
+// Frames are encoded with gob and messages with a codec, gob by default;
+// the peers should be trusted or checked with Authorize.
+//
+// Messages published while the link is down are not forwarded.
+// A key should be forwarded in one direction only, otherwise its messages
+// will bounce between the processes.
+package netbridge
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// DefaultRetryDelay is the reconnection delay used when RetryDelay is zero.
+const DefaultRetryDelay = time.Second
+
+// ErrRejected is reported for received messages of keys not accepted.
+var ErrRejected = errors.New("netbridge: key not accepted")
+
+// frame is a message forwarded over the link.
+type frame[K comparable] struct {
+	Key  K
+	Data []byte
+}
+
+// Server accepts links from clients.
+type Server[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// Keys are forwarded to every connected client.
+	Keys []K
+
+	// Accept are the keys published when received from a client; the
+	// messages of other keys are rejected with ErrRejected.
+	Accept []K
+
+	// Authorize, if set, is called for each accepted connection before
+	// anything is read from it; an error closes it.
+	Authorize func(conn net.Conn) error
+
+	// Codec encodes messages; codec.Gob if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the subscription channels.
+	Buffer int
+
+	// OnError, if set, is called when a connection is refused or breaks,
+	// and for received messages that could not be published. These don't
+	// break the link.
+	OnError func(err error)
+}
+
+// Serve accepts connections on the listener and links each one until
+// the context is canceled. The listener is closed on return.
+func (s *Server[K, T]) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if s.Authorize != nil {
+				if err := s.Authorize(conn); err != nil {
+					conn.Close()
+					report(s.OnError, err)
+					return
+				}
+			}
+
+			err := s.side().link(ctx, conn)
+			if ctx.Err() == nil {
+				report(s.OnError, err)
+			}
+		}()
+	}
+}
+
+// Client links to a server, reconnecting when the link fails.
+type Client[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// Keys are forwarded to the server.
+	Keys []K
+
+	// Accept are the keys published when received from the server; the
+	// messages of other keys are rejected with ErrRejected.
+	Accept []K
+
+	// Codec encodes messages; codec.Gob if nil.
+	Codec codec.Codec[T]
+
+	// Network and Address of the server, as accepted by net.Dial.
+	Network string
+	Address string
+
+	// RetryDelay is the delay between connection attempts.
+	RetryDelay time.Duration
+
+	// Buffer is the capacity of the subscription channels.
+	Buffer int
+
+	// OnError, if set, is called when connecting fails or the link
+	// breaks, and for received messages that could not be published.
+	// These don't break the link.
+	OnError func(err error)
+}
+
+// Run keeps the link up until the context is canceled.
+func (c *Client[K, T]) Run(ctx context.Context) error {
+	delay := c.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, c.Network, c.Address)
+		if err == nil {
+			err = c.side().link(ctx, conn)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if c.OnError != nil {
+			c.OnError(err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// side is the configuration of one end of a link.
+type side[K comparable, T any] struct {
+	ps      *pubsub.PubSub[K, T]
+	keys    []K
+	accept  map[K]struct{}
+	buffer  int
+	codec   codec.Codec[T]
+	onError func(err error)
+}
+
+func (s *Server[K, T]) side() *side[K, T] {
+	return newSide(s.PubSub, s.Keys, s.Accept, s.Buffer, s.Codec, s.OnError)
+}
+
+func (c *Client[K, T]) side() *side[K, T] {
+	return newSide(c.PubSub, c.Keys, c.Accept, c.Buffer, c.Codec, c.OnError)
+}
+
+func newSide[K comparable, T any](ps *pubsub.PubSub[K, T], keys, accept []K, buffer int, c codec.Codec[T], onError func(error)) *side[K, T] {
+	s := &side[K, T]{ps: ps, keys: keys, accept: make(map[K]struct{}, len(accept)), buffer: buffer, codec: orGob(c), onError: onError}
+	for _, key := range accept {
+		s.accept[key] = struct{}{}
+	}
+
+	return s
+}
+
+// link forwards messages of the keys to the connection and publishes
+// the accepted messages received from it, until either direction fails.
+// The connection is closed on return.
+func (s *side[K, T]) link(ctx context.Context, conn net.Conn) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	go func() {
+		<-ctx.Done()
+		conn.Close() // unblock the decoder
+	}()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex // serializes frame encoding
+	enc := gob.NewEncoder(conn)
+
+	for _, key := range s.keys {
+		sub := []K{key}
+		ch := make(chan T, s.buffer)
+		s.ps.Subscribe(sub, ch)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.ps.UnsubscribeAndDrain(sub, ch)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					data, err := s.codec.Marshal(msg)
+					if err == nil {
+						mu.Lock()
+						err = enc.Encode(frame[K]{Key: key, Data: data})
+						mu.Unlock()
+					}
+
+					if err != nil {
+						cancel(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	dec := gob.NewDecoder(conn)
+	for {
+		var f frame[K]
+		if err := dec.Decode(&f); err != nil {
+			cancel(err)
+			break
+		}
+
+		if err := s.publish(ctx, f); err != nil {
+			report(s.onError, err)
+		}
+	}
+
+	wg.Wait()
+
+	return context.Cause(ctx)
+}
+
+// publish publishes the message of the received frame if its key is
+// accepted.
+func (s *side[K, T]) publish(ctx context.Context, f frame[K]) error {
+	if _, ok := s.accept[f.Key]; !ok {
+		return fmt.Errorf("%w: %v", ErrRejected, f.Key)
+	}
+
+	msg, err := s.codec.Unmarshal(f.Data)
+	if err != nil {
+		return fmt.Errorf("netbridge: decode %v: %w", f.Key, err)
+	}
+
+	if _, err := s.ps.Publish(ctx, f.Key, msg); err != nil {
+		return fmt.Errorf("netbridge: publish %v: %w", f.Key, err)
+	}
+
+	return nil
+}
+
+// report calls the error callback if it is set.
+func report(onError func(error), err error) {
+	if onError != nil {
+		onError(err)
+	}
+}
+
+// orGob returns the codec or the default one if it is nil.
+func orGob[T any](c codec.Codec[T]) codec.Codec[T] {
+	if c == nil {
+		return codec.Gob[T]{}
+	}
+
+	return c
+}
@@ -0,0 +1,190 @@
+package netbridge_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/netbridge"
+)
+
+// publish retries until the message is delivered to a subscriber.
+func publish(t *testing.T, ps *pubsub.PubSub[string, int], key string, msg int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if n, _ := ps.PublishWithTimeout(key, msg, 10*time.Millisecond); n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("no subscribers for %q", key)
+}
+
+func expect(t *testing.T, ch chan int, want int) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Errorf("expected %d, got %d", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message %d not forwarded", want)
+	}
+}
+
+func TestBridge(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverPS := pubsub.New[string, int]()
+	server := &netbridge.Server[string, int]{PubSub: serverPS, Keys: []string{"down"}, Accept: []string{"up"}}
+	go server.Serve(ctx, l)
+
+	clientPS := pubsub.New[string, int]()
+	client := &netbridge.Client[string, int]{
+		PubSub:     clientPS,
+		Keys:       []string{"up"},
+		Accept:     []string{"down"},
+		Network:    "tcp",
+		Address:    l.Addr().String(),
+		RetryDelay: 10 * time.Millisecond,
+	}
+	go client.Run(ctx)
+
+	up := make(chan int, 1)
+	serverPS.Subscribe([]string{"up"}, up)
+	down := make(chan int, 1)
+	clientPS.Subscribe([]string{"down"}, down)
+
+	publish(t, clientPS, "up", 1)
+	expect(t, up, 1)
+
+	publish(t, serverPS, "down", 2)
+	expect(t, down, 2)
+}
+
+func TestClientReconnects(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close() // the server is not running yet
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failed := make(chan struct{}, 1)
+	clientPS := pubsub.New[string, int]()
+	client := &netbridge.Client[string, int]{
+		PubSub:     clientPS,
+		Keys:       []string{"up"},
+		Network:    "tcp",
+		Address:    addr,
+		RetryDelay: 10 * time.Millisecond,
+		OnError: func(error) {
+			select {
+			case failed <- struct{}{}:
+			default:
+			}
+		},
+	}
+	go client.Run(ctx)
+	<-failed
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("address reused:", err)
+	}
+
+	serverPS := pubsub.New[string, int]()
+	go (&netbridge.Server[string, int]{PubSub: serverPS, Accept: []string{"up"}}).Serve(ctx, l)
+
+	up := make(chan int, 1)
+	serverPS.Subscribe([]string{"up"}, up)
+
+	publish(t, clientPS, "up", 3)
+	expect(t, up, 3)
+}
+
+func TestServerRejects(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 10)
+	serverPS := pubsub.New[string, int]()
+	go (&netbridge.Server[string, int]{
+		PubSub:  serverPS,
+		Accept:  []string{"up"},
+		OnError: func(err error) { errs <- err },
+	}).Serve(ctx, l)
+
+	clientPS := pubsub.New[string, int]()
+	go (&netbridge.Client[string, int]{
+		PubSub:     clientPS,
+		Keys:       []string{"secret", "up"},
+		Network:    "tcp",
+		Address:    l.Addr().String(),
+		RetryDelay: 10 * time.Millisecond,
+	}).Run(ctx)
+
+	up := make(chan int, 1)
+	serverPS.Subscribe([]string{"up"}, up)
+	secret := make(chan int, 1)
+	serverPS.Subscribe([]string{"secret"}, secret)
+
+	publish(t, clientPS, "secret", 1)
+	if err := <-errs; !errors.Is(err, netbridge.ErrRejected) {
+		t.Errorf("expected ErrRejected, got %v", err)
+	}
+
+	// the link survives the rejected message
+	publish(t, clientPS, "up", 2)
+	expect(t, up, 2)
+	if len(secret) != 0 {
+		t.Error("expected the secret key not published")
+	}
+}
+
+func TestServerAuthorize(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	denied := errors.New("denied")
+	errs := make(chan error, 10)
+	go (&netbridge.Server[string, int]{
+		PubSub:    pubsub.New[string, int](),
+		Accept:    []string{"up"},
+		Authorize: func(net.Conn) error { return denied },
+		OnError:   func(err error) { errs <- err },
+	}).Serve(ctx, l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := <-errs; !errors.Is(err, denied) {
+		t.Errorf("expected the authorization error, got %v", err)
+	}
+}
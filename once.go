@@ -0,0 +1,27 @@
+package pubsub
+
+import "context"
+
+// SubscribeOnce subscribes the channel to the keys for a single message:
+// the first message published to any of them is sent to the channel, and
+// the subscription is removed before that Publish returns. Concurrent
+// publishes race for the message, but only one of them delivers it; the
+// others skip the channel. If the delivery fails, because the publish
+// context ends or the drop policy drops the message, the subscription
+// stays for the next one.
+//
+// The channel must not be subscribed otherwise. The returned function
+// cancels the subscription if no message was delivered yet.
+func (ps *PubSub[K, T]) SubscribeOnce(keys []K, ch chan T) (cancel func()) {
+	_, cancel = ps.SubscribeFor(keys, ch, WithLimit(1))
+	return cancel
+}
+
+// SubscribeOnceFunc is like SubscribeFunc, but the handler is called for
+// a single message and then removed, even if it fails. Concurrent
+// publishes race for the call, but only one of them makes it. The
+// returned function removes the handler if it wasn't called yet.
+func (ps *PubSub[K, T]) SubscribeOnceFunc(keys []K, fn func(ctx context.Context, key K, msg T) error) (cancel func()) {
+	h := &handler[K, T]{fn: fn, limit: newLimit[K, T](nil, nil, 1)}
+	return ps.addHandler(keys, h)
+}
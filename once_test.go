@@ -0,0 +1,104 @@
+package pubsub_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubscribeOnce(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 10)
+	ps.SubscribeOnce([]string{"a", "b"}, ch)
+
+	if n, _ := ps.Publish(context.Background(), "b", 1); n != 1 {
+		t.Errorf("expected 1 delivery, got %d", n)
+	}
+
+	if keys := ps.Keys(); len(keys) != 0 {
+		t.Errorf("expected the subscription removed when Publish returns, got keys %v", keys)
+	}
+
+	ps.Publish(context.Background(), "a", 2)
+	if len(ch) != 1 || <-ch != 1 {
+		t.Error("expected only the first message")
+	}
+}
+
+func TestSubscribeOnceConcurrent(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 100)
+	ps.SubscribeOnce([]string{"k"}, ch)
+
+	var (
+		wg        sync.WaitGroup
+		delivered atomic.Int64
+	)
+	for i := range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, _ := ps.Publish(context.Background(), "k", i)
+			delivered.Add(int64(n))
+		}()
+	}
+	wg.Wait()
+
+	if delivered.Load() != 1 || len(ch) != 1 {
+		t.Errorf("expected exactly one delivery, got %d", delivered.Load())
+	}
+}
+
+func TestSubscribeOnceRetry(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDropPolicy(pubsub.DropNewest))
+	ch := make(chan int, 1)
+	ch <- 0
+	ps.SubscribeOnce([]string{"k"}, ch)
+
+	if n, _ := ps.Publish(context.Background(), "k", 1); n != 0 {
+		t.Errorf("expected the message dropped, got %d deliveries", n)
+	}
+
+	<-ch
+	ps.Publish(context.Background(), "k", 2)
+	if got := <-ch; got != 2 {
+		t.Errorf("expected the next message after a failed delivery, got %d", got)
+	}
+}
+
+func TestSubscribeOnceCancel(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	cancel := ps.SubscribeOnce([]string{"k"}, make(chan int, 1))
+	cancel()
+
+	if n, _ := ps.Publish(context.Background(), "k", 1); n != 0 {
+		t.Errorf("expected no deliveries after cancel, got %d", n)
+	}
+}
+
+func TestSubscribeOnceFunc(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var calls atomic.Int64
+	ps.SubscribeOnceFunc([]string{"k"}, func(ctx context.Context, _ string, msg int) error {
+		calls.Add(1)
+		ps.Publish(ctx, "k", msg+1) // already removed
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ps.Publish(context.Background(), "k", 0)
+		}()
+	}
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected one call, got %d", calls.Load())
+	}
+}
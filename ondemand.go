@@ -0,0 +1,83 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// OnDemand runs a producer for the key only while the key has
+// subscribers, counting the channels subscribed to patterns matching it.
+// When the key gets its first subscriber, start is called in a new
+// goroutine with a context that is canceled when the last subscriber
+// leaves; then stop, if not nil, is called. If the key already has
+// subscribers, start is called right away.
+//
+// The returned function unregisters the producer, stopping it if it is
+// running, and waits for start to return.
+func (ps *PubSub[K, T]) OnDemand(key K, start func(ctx context.Context), stop func()) (cancel func()) {
+	var (
+		mu      sync.Mutex
+		running context.CancelFunc
+		wg      sync.WaitGroup
+	)
+
+	run := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if running != nil {
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		running = cancel
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start(ctx)
+		}()
+	}
+
+	halt := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if running == nil {
+			return
+		}
+
+		running()
+		running = nil
+
+		if stop != nil {
+			stop()
+		}
+	}
+
+	unwatch := ps.WatchKeys(func(k K, event KeyEvent) {
+		if !keyMatches(ps.resolved(key), k) {
+			return
+		}
+
+		switch event {
+		case KeyAdded, PatternAdded:
+			run()
+		case KeyRemoved, PatternRemoved:
+			// Other subscriptions, to the key or to patterns, may remain.
+			if !ps.subscribed(key) {
+				halt()
+			}
+		}
+	})
+
+	if ps.subscribed(key) {
+		run()
+	}
+
+	return func() {
+		unwatch()
+		halt()
+		wg.Wait()
+	}
+}
@@ -0,0 +1,62 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestOnDemand(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	started := make(chan struct{}, 2)
+	stopped := make(chan struct{}, 2)
+
+	cancel := ps.OnDemand("ticks", func(ctx context.Context) {
+		started <- struct{}{}
+		for i := 0; ctx.Err() == nil; i++ {
+			ps.Publish(ctx, "ticks", i)
+		}
+	}, func() { stopped <- struct{}{} })
+	defer cancel()
+
+	select {
+	case <-started:
+		t.Fatal("producer started without subscribers")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	ch := make(chan int)
+	ps.Subscribe([]string{"ticks"}, ch)
+	<-started
+	if msg := <-ch; msg < 0 {
+		t.Errorf("unexpected message %d", msg)
+	}
+
+	ps.UnsubscribeAndDrain([]string{"ticks"}, ch)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("producer not stopped after the last subscriber left")
+	}
+}
+
+func TestOnDemandActiveKey(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.Subscribe([]string{"k"}, make(chan int))
+
+	started := make(chan struct{})
+	cancel := ps.OnDemand("k", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	}, nil)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("producer not started for a key with subscribers")
+	}
+
+	cancel() // waits for the producer to return
+}
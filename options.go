@@ -0,0 +1,67 @@
+package pubsub
+
+import "time"
+
+// DropPolicy defines what Publish does when a subscriber's channel is full.
+type DropPolicy int
+
+const (
+	// Block waits until the subscriber receives the message or the
+	// context is done. This is the default.
+	Block DropPolicy = iota
+	// DropNewest skips subscribers whose channel is full: the published
+	// message is not delivered to them.
+	DropNewest
+	// DropOldest discards the oldest buffered message of a full channel
+	// to make room for the published one. Unbuffered channels behave as
+	// with DropNewest.
+	DropOldest
+)
+
+// options holds the configuration of a PubSub instance.
+type options struct {
+	bufferSize int
+	dropPolicy DropPolicy
+	logger     Logger
+	clock      Clock
+	budget     int // messages, zero if unlimited
+	byteBudget int // bytes, zero if unlimited
+	sizer      any // func(T) int
+	cloner     any // func(T) T
+	tracer     any // func(T) string
+	traceSize  int
+	mutations  any // MutationObserver[K]
+	eviction   EvictionPolicy
+
+	retention    int           // messages per key, zero if disabled
+	retentionAge time.Duration // zero if unlimited
+	offsets      OffsetStore
+
+	metrics        Metrics
+	shards         int // of the per-key state, at least one
+	keyStats       bool
+	latencySamples int // per key, zero if not sampled
+	concurrency    int // delivery goroutines, zero if unlimited
+	keyConcurrency int // delivery goroutines per key, zero if unlimited
+}
+
+// Option configures a PubSub instance created with New.
+type Option func(*options)
+
+// WithBufferSize sets the capacity of channels created by the library on
+// behalf of subscribers, for example by Messages, Next or Pipe.
+// Channels passed to Subscribe are not affected.
+func WithBufferSize(size int) Option {
+	return func(o *options) {
+		o.bufferSize = max(size, 0)
+	}
+}
+
+// WithDropPolicy sets what Publish does when a subscriber's channel is full.
+// With a policy other than Block, Publish never waits for slow subscribers
+// and reports only the messages actually delivered.
+func WithDropPolicy(policy DropPolicy) Option {
+	return func(o *options) {
+		o.dropPolicy = policy
+	}
+}
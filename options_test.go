@@ -0,0 +1,51 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestDropNewest(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDropPolicy(pubsub.DropNewest))
+	full := make(chan int, 1)
+	free := make(chan int, 1)
+	ps.Subscribe([]string{"k"}, full)
+	ps.Subscribe([]string{"k"}, free)
+	full <- 0
+
+	// does not block on the full channel, even without a deadline
+	n, err := ps.Publish(context.Background(), "k", 1)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 delivery, got %d, %v", n, err)
+	}
+	if msg := <-full; msg != 0 {
+		t.Errorf("expected the old message to be kept, got %d", msg)
+	}
+	if msg := <-free; msg != 1 {
+		t.Errorf("expected 1, got %d", msg)
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDropPolicy(pubsub.DropOldest))
+	ch := make(chan int, 2)
+	ps.Subscribe([]string{"k"}, ch)
+
+	for i := 1; i <= 3; i++ {
+		if n, _ := ps.Publish(context.Background(), "k", i); n != 1 {
+			t.Fatalf("publish %d: expected 1 delivery, got %d", i, n)
+		}
+	}
+
+	if a, b := <-ch, <-ch; a != 2 || b != 3 {
+		t.Errorf("expected the newest messages 2 and 3, got %d and %d", a, b)
+	}
+
+	// unbuffered channels without a reader can't make room
+	ps.Subscribe([]string{"u"}, make(chan int))
+	if n, _ := ps.Publish(context.Background(), "u", 1); n != 0 {
+		t.Errorf("expected no delivery, got %d", n)
+	}
+}
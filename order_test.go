@@ -0,0 +1,64 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+const orderedCount = 1000
+
+// expectOrdered reads orderedCount messages and checks they are in order.
+func expectOrdered(t *testing.T, ch <-chan int) {
+	t.Helper()
+	for want := 0; want < orderedCount; want++ {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Fatalf("out of order: expected %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %d not received", want)
+		}
+	}
+}
+
+func TestEventBusOrdering(t *testing.T) {
+	bus := pubsub.NewEventBus[string, int](16)
+	defer bus.Close()
+
+	got1 := make(chan int, orderedCount)
+	got2 := make(chan int, orderedCount)
+	bus.On("k", func(n int) { got1 <- n })
+	bus.On("k", func(n int) {
+		time.Sleep(time.Microsecond) // a slower subscriber
+		got2 <- n
+	})
+
+	for i := 0; i < orderedCount; i++ {
+		bus.Emit("k", i)
+	}
+
+	expectOrdered(t, got1)
+	expectOrdered(t, got2)
+}
+
+func TestPipeOrdering(t *testing.T) {
+	src := pubsub.New[string, int]()
+	dst := pubsub.New[string, int]()
+	stop := pubsub.Pipe(context.Background(), src, dst, []string{"k"},
+		func(k string) string { return k },
+		func(n int) (int, bool) { return n, true })
+	defer stop()
+
+	got := make(chan int, orderedCount)
+	dst.Subscribe([]string{"k"}, got)
+
+	for i := 0; i < orderedCount; i++ {
+		src.Publish(context.Background(), "k", i)
+	}
+
+	expectOrdered(t, got)
+}
@@ -0,0 +1,169 @@
+// Package outbox implements the relay of the transactional outbox
+// pattern: services write messages into an outbox table in the same
+// database transaction as their state changes, and a Relay publishes the
+// rows to a PubSub instance and marks them done, retrying failures, so a
+// message is published if and only if its transaction committed.
+//
+// The package does not depend on a database: the outbox is read through
+// the Source interface.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// Default relay settings used when the corresponding fields are zero.
+const (
+	DefaultBatchSize   = 100
+	DefaultInterval    = time.Second
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 100 * time.Millisecond
+	DefaultMaxBackoff  = 10 * time.Second
+)
+
+// Record is a row of the outbox.
+type Record[K comparable, T any] struct {
+	ID  string
+	Key K
+	Msg T
+}
+
+// Source reads the outbox.
+type Source[K comparable, T any] interface {
+	// Fetch returns up to limit pending records, oldest first.
+	Fetch(ctx context.Context, limit int) ([]Record[K, T], error)
+	// MarkDone marks the records as published, so they are not fetched
+	// again, for example by deleting them.
+	MarkDone(ctx context.Context, ids ...string) error
+}
+
+// Relay publishes the records of an outbox.
+type Relay[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+	Source Source[K, T]
+
+	// BatchSize limits the records fetched at once.
+	BatchSize int
+
+	// Interval is the delay between polls of an empty outbox, and before
+	// retrying a batch that failed.
+	Interval time.Duration
+
+	// MaxAttempts limits publish attempts per record in a poll. Backoff
+	// is the delay before the first retry; it doubles on each following
+	// retry up to MaxBackoff.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+
+	// OnError, if set, is called for records that could not be published
+	// after all attempts, and with a zero record for fetch and mark
+	// errors. The record stays in the outbox and is retried at the next
+	// poll; records after it wait, to keep their order.
+	OnError func(rec Record[K, T], err error)
+}
+
+// Run relays records until the context is canceled and returns the
+// context error. Time is measured with the clock of the PubSub instance.
+// Records are published at least once: a record published but not marked
+// done because of a failure is published again.
+func (r *Relay[K, T]) Run(ctx context.Context) error {
+	for {
+		more, err := r.relay(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil && r.OnError != nil {
+			r.OnError(Record[K, T]{}, err)
+		}
+
+		if more && err == nil {
+			continue
+		}
+
+		if err := r.sleep(ctx, withDefault(r.Interval, DefaultInterval)); err != nil {
+			return err
+		}
+	}
+}
+
+// relay publishes one batch of records and marks the published ones done.
+// It reports whether more records may be pending: the batch was not
+// empty and all its records were published.
+func (r *Relay[K, T]) relay(ctx context.Context) (bool, error) {
+	recs, err := r.Source.Fetch(ctx, withDefault(r.BatchSize, DefaultBatchSize))
+	if err != nil {
+		return false, err
+	}
+
+	ids := make([]string, 0, len(recs))
+	var failed bool
+	for _, rec := range recs {
+		if err := r.publish(ctx, rec); err != nil {
+			if ctx.Err() == nil && r.OnError != nil {
+				r.OnError(rec, err)
+			}
+
+			failed = true
+			break
+		}
+
+		ids = append(ids, rec.ID)
+	}
+
+	if len(ids) > 0 {
+		if err := r.Source.MarkDone(ctx, ids...); err != nil {
+			return false, err
+		}
+	}
+
+	return len(recs) > 0 && !failed, nil
+}
+
+// publish publishes a single record, retrying as configured.
+func (r *Relay[K, T]) publish(ctx context.Context, rec Record[K, T]) error {
+	attempts := withDefault(r.MaxAttempts, DefaultMaxAttempts)
+	backoff := withDefault(r.Backoff, DefaultBackoff)
+	maxBackoff := withDefault(r.MaxBackoff, DefaultMaxBackoff)
+
+	for attempt := 1; ; attempt++ {
+		_, err := r.PubSub.Publish(ctx, rec.Key, rec.Msg)
+		if err == nil || attempt >= attempts {
+			return err
+		}
+
+		if err := r.sleep(ctx, backoff); err != nil {
+			return err
+		}
+
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// sleep waits for the duration on the clock of the PubSub instance or
+// until the context is canceled.
+func (r *Relay[K, T]) sleep(ctx context.Context, d time.Duration) error {
+	elapsed := make(chan struct{})
+	timer := r.PubSub.Clock().AfterFunc(d, func() { close(elapsed) })
+	defer timer.Stop()
+
+	select {
+	case <-elapsed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// withDefault returns v, or def if v isn't positive.
+func withDefault[N int | time.Duration](v, def N) N {
+	if v <= 0 {
+		return def
+	}
+
+	return v
+}
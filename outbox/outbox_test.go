@@ -0,0 +1,119 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/outbox"
+)
+
+// table is an in-memory outbox.
+type table struct {
+	mu   sync.Mutex
+	rows []outbox.Record[string, int]
+	done []string
+}
+
+func (t *table) Fetch(_ context.Context, limit int) ([]outbox.Record[string, int], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.rows[:min(limit, len(t.rows))]), nil
+}
+
+func (t *table) MarkDone(_ context.Context, ids ...string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = slices.DeleteFunc(t.rows, func(r outbox.Record[string, int]) bool { return slices.Contains(ids, r.ID) })
+	t.done = append(t.done, ids...)
+	return nil
+}
+
+func (t *table) pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.rows)
+}
+
+func TestRelay(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 10)
+	ps.Subscribe([]string{"orders"}, ch)
+
+	src := &table{rows: []outbox.Record[string, int]{
+		{ID: "1", Key: "orders", Msg: 1},
+		{ID: "2", Key: "orders", Msg: 2},
+		{ID: "3", Key: "orders", Msg: 3},
+	}}
+	relay := &outbox.Relay[string, int]{PubSub: ps, Source: src, BatchSize: 2, Interval: time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- relay.Run(ctx) }()
+
+	for want := 1; want <= 3; want++ {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("expected %d, got %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("record not published")
+		}
+	}
+
+	for src.pending() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if !slices.Equal(src.done, []string{"1", "2", "3"}) {
+		t.Errorf("unexpected records marked done %v", src.done)
+	}
+}
+
+func TestRelayRetry(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	denied := errors.New("denied")
+	var attempts int
+	ps.SetAuthorizer(pubsub.AuthorizerFunc[string](func(context.Context, pubsub.Action, string) error {
+		attempts++
+		if attempts < 3 {
+			return denied
+		}
+		return nil
+	}))
+
+	src := &table{rows: []outbox.Record[string, int]{{ID: "1", Key: "k", Msg: 1}}}
+	var reported []error
+	relay := &outbox.Relay[string, int]{
+		PubSub:      ps,
+		Source:      src,
+		Interval:    time.Millisecond,
+		MaxAttempts: 2,
+		Backoff:     time.Microsecond,
+		OnError:     func(_ outbox.Record[string, int], err error) { reported = append(reported, err) },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- relay.Run(ctx) }()
+
+	for src.pending() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if attempts != 3 || len(reported) != 1 || !errors.Is(reported[0], denied) {
+		t.Errorf("expected one exhausted poll and a retry, got %d attempts, %v", attempts, reported)
+	}
+}
@@ -0,0 +1,90 @@
+package pubsub
+
+import (
+	"context"
+	"hash/maphash"
+)
+
+// Partition is the key of one partition of a partitioned key.
+type Partition[K comparable] struct {
+	Key   K
+	Index int
+}
+
+// Partitioned splits each key of a PubSub into a fixed number of
+// partitions. Publishers pick the partition by hashing the message, so
+// related messages, for example of one customer, always go to the same
+// partition and keep their order, while subscribers attach to the
+// partitions they consume and scale out horizontally.
+type Partitioned[K comparable, T any] struct {
+	ps    *PubSub[Partition[K], T]
+	count int
+	hash  func(T) uint64
+}
+
+// NewPartitioned returns a view of the PubSub splitting keys into count
+// partitions, at least one, chosen with hash. It panics if hash is nil.
+func NewPartitioned[K comparable, T any](ps *PubSub[Partition[K], T], count int, hash func(T) uint64) *Partitioned[K, T] {
+	if hash == nil {
+		panic("pubsub: nil partition hash")
+	}
+
+	return &Partitioned[K, T]{ps: ps, count: max(count, 1), hash: hash}
+}
+
+// HashBy returns a partition hash of a comparable part of the message,
+// such as an ID field.
+func HashBy[T any, P comparable](part func(T) P) func(T) uint64 {
+	seed := maphash.MakeSeed()
+	return func(msg T) uint64 {
+		return maphash.Comparable(seed, part(msg))
+	}
+}
+
+// Partitions returns the number of partitions of each key.
+func (p *Partitioned[K, T]) Partitions() int {
+	return p.count
+}
+
+// Partition returns the index of the partition the message is published to.
+func (p *Partitioned[K, T]) Partition(msg T) int {
+	return int(p.hash(msg) % uint64(p.count))
+}
+
+// Publish publishes the message to its partition of the key.
+func (p *Partitioned[K, T]) Publish(ctx context.Context, key K, msg T) (int, error) {
+	return p.ps.Publish(ctx, Partition[K]{Key: key, Index: p.Partition(msg)}, msg)
+}
+
+// Subscribe subscribes the channel to the partitions of the key, or to all
+// of them if none are given. Indexes out of range are ignored.
+func (p *Partitioned[K, T]) Subscribe(key K, ch chan T, partitions ...int) {
+	p.ps.Subscribe(p.keys(key, partitions), ch)
+}
+
+// Unsubscribe unsubscribes the channel from the partitions of the key, or
+// from all of them if none are given.
+func (p *Partitioned[K, T]) Unsubscribe(key K, ch chan T, partitions ...int) {
+	p.ps.Unsubscribe(p.keys(key, partitions), ch)
+}
+
+// keys returns the partition keys of the key.
+func (p *Partitioned[K, T]) keys(key K, partitions []int) []Partition[K] {
+	if len(partitions) == 0 {
+		keys := make([]Partition[K], p.count)
+		for i := range keys {
+			keys[i] = Partition[K]{Key: key, Index: i}
+		}
+
+		return keys
+	}
+
+	keys := make([]Partition[K], 0, len(partitions))
+	for _, i := range partitions {
+		if i >= 0 && i < p.count {
+			keys = append(keys, Partition[K]{Key: key, Index: i})
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,71 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+type order struct {
+	Customer string
+	Seq      int
+}
+
+func TestPartitioned(t *testing.T) {
+	ps := pubsub.New[pubsub.Partition[string], order]()
+	p := pubsub.NewPartitioned(ps, 4, pubsub.HashBy(func(o order) string { return o.Customer }))
+
+	customers := []string{"alice", "bob", "carol", "dave", "erin"}
+	chans := make([]chan order, p.Partitions())
+	for i := range chans {
+		chans[i] = make(chan order, 100)
+		p.Subscribe("orders", chans[i], i)
+	}
+
+	ctx := context.Background()
+	for seq := range 10 {
+		for _, c := range customers {
+			if n, _ := p.Publish(ctx, "orders", order{Customer: c, Seq: seq}); n != 1 {
+				t.Fatalf("expected delivery to one partition, got %d", n)
+			}
+		}
+	}
+
+	for i, ch := range chans {
+		last := map[string]int{}
+		for len(ch) > 0 {
+			o := <-ch
+			if p.Partition(o) != i {
+				t.Errorf("message of %s received by partition %d", o.Customer, i)
+			}
+			if prev, ok := last[o.Customer]; ok && o.Seq != prev+1 {
+				t.Errorf("out of order message of %s: %d after %d", o.Customer, o.Seq, prev)
+			}
+			last[o.Customer] = o.Seq
+		}
+	}
+}
+
+func TestPartitionedSubscribeAll(t *testing.T) {
+	ps := pubsub.New[pubsub.Partition[string], order]()
+	p := pubsub.NewPartitioned(ps, 3, pubsub.HashBy(func(o order) int { return o.Seq }))
+
+	ch := make(chan order, 10)
+	p.Subscribe("k", ch)
+	if got := len(ps.Keys()); got != 3 {
+		t.Errorf("expected all 3 partitions subscribed, got %d", got)
+	}
+
+	for seq := range 6 {
+		p.Publish(context.Background(), "k", order{Seq: seq})
+	}
+	if len(ch) != 6 {
+		t.Errorf("expected all 6 messages, got %d", len(ch))
+	}
+
+	p.Unsubscribe("k", ch)
+	if got := len(ps.Keys()); got != 0 {
+		t.Errorf("expected no subscribed partitions, got %d", got)
+	}
+}
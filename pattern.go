@@ -0,0 +1,364 @@
+package pubsub
+
+import (
+	"iter"
+	"slices"
+	"strings"
+)
+
+// subjectTrie holds the pattern subscriptions by segment, so matching a
+// subject visits only the nodes of its segments and of the wildcards
+// along them, whatever the number of patterns.
+type subjectTrie[T any] struct {
+	root trieNode[T]
+	n    int // subscriptions
+}
+
+// trieNode is the node of a pattern prefix.
+type trieNode[T any] struct {
+	children map[string]*trieNode[T] // by literal segment
+	any      *trieNode[T]            // AnySegment child
+	tail     []chan T                // subscribed to the prefix followed by AnyTail
+	chans    []chan T                // subscribed to the prefix
+}
+
+// add subscribes the channel to the pattern, reporting false if it
+// already was.
+func (t *subjectTrie[T]) add(pattern Subject, ch chan T) bool {
+	n := &t.root
+	rest := pattern.path
+	for rest != "" {
+		seg, tail, _ := strings.Cut(rest, subjectSep)
+		if seg == AnyTail && tail == "" {
+			return t.insert(&n.tail, ch)
+		}
+
+		n = n.child(seg)
+		rest = tail
+	}
+
+	return t.insert(&n.chans, ch)
+}
+
+// insert adds the channel to the list unless it is in it.
+func (t *subjectTrie[T]) insert(list *[]chan T, ch chan T) bool {
+	if slices.Contains(*list, ch) {
+		return false
+	}
+
+	*list = append(*list, ch)
+	t.n++
+
+	return true
+}
+
+// subscribers returns the number of channels subscribed to the pattern.
+func (t *subjectTrie[T]) subscribers(pattern Subject) int {
+	n := &t.root
+	rest := pattern.path
+	for rest != "" {
+		seg, tail, _ := strings.Cut(rest, subjectSep)
+		if seg == AnyTail && tail == "" {
+			return len(n.tail)
+		}
+
+		if seg == AnySegment {
+			n = n.any
+		} else {
+			n = n.children[seg]
+		}
+		if n == nil {
+			return 0
+		}
+		rest = tail
+	}
+
+	return len(n.chans)
+}
+
+// child returns the child of the segment, creating it if needed.
+func (n *trieNode[T]) child(seg string) *trieNode[T] {
+	if seg == AnySegment {
+		if n.any == nil {
+			n.any = new(trieNode[T])
+		}
+		return n.any
+	}
+
+	c, ok := n.children[seg]
+	if !ok {
+		if n.children == nil {
+			n.children = make(map[string]*trieNode[T])
+		}
+		c = new(trieNode[T])
+		n.children[seg] = c
+	}
+
+	return c
+}
+
+// drop unsubscribes the channel from the rest of a pattern path, from
+// the node, pruning the nodes left empty. It reports whether the channel
+// was subscribed.
+func (n *trieNode[T]) drop(rest string, ch chan T) bool {
+	if rest == "" {
+		return deleteChan(&n.chans, ch)
+	}
+
+	seg, tail, _ := strings.Cut(rest, subjectSep)
+	if seg == AnyTail && tail == "" {
+		return deleteChan(&n.tail, ch)
+	}
+
+	c := n.children[seg]
+	if seg == AnySegment {
+		c = n.any
+	}
+	if c == nil || !c.drop(tail, ch) {
+		return false
+	}
+
+	if c.empty() {
+		if seg == AnySegment {
+			n.any = nil
+		} else {
+			delete(n.children, seg)
+		}
+	}
+
+	return true
+}
+
+// dropAll unsubscribes the channel from all the patterns, from the node
+// of the path, pruning the nodes left empty. It returns the number of
+// subscriptions removed and appends the patterns left without channels
+// to emptied.
+func (n *trieNode[T]) dropAll(path string, ch chan T, emptied *[]Subject) int {
+	var removed int
+	if deleteChan(&n.chans, ch) {
+		removed++
+		if len(n.chans) == 0 {
+			*emptied = append(*emptied, Subject{path: path})
+		}
+	}
+	if deleteChan(&n.tail, ch) {
+		removed++
+		if len(n.tail) == 0 {
+			*emptied = append(*emptied, Subject{path: path + AnyTail + subjectSep})
+		}
+	}
+
+	for seg, c := range n.children {
+		if removed += c.dropAll(path+seg+subjectSep, ch, emptied); c.empty() {
+			delete(n.children, seg)
+		}
+	}
+	if n.any != nil {
+		if removed += n.any.dropAll(path+AnySegment+subjectSep, ch, emptied); n.any.empty() {
+			n.any = nil
+		}
+	}
+
+	return removed
+}
+
+// deleteChan removes the channel from the list, reporting whether it was
+// in it.
+func deleteChan[T any](list *[]chan T, ch chan T) bool {
+	i := slices.Index(*list, ch)
+	if i < 0 {
+		return false
+	}
+
+	*list = slices.Delete(*list, i, i+1)
+
+	return true
+}
+
+// has reports whether the channel is subscribed to a pattern, from the
+// node.
+func (n *trieNode[T]) has(ch chan T) bool {
+	if slices.Contains(n.chans, ch) || slices.Contains(n.tail, ch) {
+		return true
+	}
+
+	for _, c := range n.children {
+		if c.has(ch) {
+			return true
+		}
+	}
+
+	return n.any != nil && n.any.has(ch)
+}
+
+// empty reports whether the node has no subscriptions nor children.
+func (n *trieNode[T]) empty() bool {
+	return len(n.chans) == 0 && len(n.tail) == 0 && len(n.children) == 0 && n.any == nil
+}
+
+// match calls fn with the channels subscribed to patterns matching the
+// rest of a subject path, from the node. Channels subscribed to several
+// matching patterns are passed as many times.
+func (n *trieNode[T]) match(rest string, fn func(ch chan T)) {
+	if rest == "" {
+		for _, ch := range n.chans {
+			fn(ch)
+		}
+		return
+	}
+
+	for _, ch := range n.tail {
+		fn(ch)
+	}
+
+	seg, tail, _ := strings.Cut(rest, subjectSep)
+	if c, ok := n.children[seg]; ok {
+		c.match(tail, fn)
+	}
+	if n.any != nil {
+		n.any.match(tail, fn)
+	}
+}
+
+// SubscribePattern subscribes the channel to the subjects matching the
+// pattern, which may contain the wildcards of Subject, including subjects
+// nobody publishes to yet. A message matching several patterns of the
+// channel, or also published to a subject the channel is subscribed to,
+// is sent to it once. Subscribing a channel twice to a pattern is a
+// no-op, as subscribing to a draining or closed instance.
+//
+// Patterns are kept in a trie of segments, so matching a published
+// subject takes time in the number of its segments, not of the patterns.
+// Every publish method delivers to pattern subscriptions as to the
+// subscriptions of the subject. They are not reported by Keys; key
+// events report them under the pattern itself: PatternAdded when it gets
+// its first channel, PatternRemoved when it loses its last one, so watchers
+// feeding keys from upstream don't take a pattern for a key. OnDemand, Derive
+// and WaitForSubscribers count the pattern subscriptions matching their
+// key.
+func SubscribePattern[T any](ps *PubSub[Subject, T], pattern Subject, ch chan T) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.state != stateOpen {
+		return
+	}
+
+	if ps.patterns.add(pattern, ch) {
+		if ps.patterns.subscribers(pattern) == 1 {
+			ps.watch.notify(pattern, PatternAdded)
+		}
+		ps.subscribersChanged()
+	}
+}
+
+// UnsubscribePattern removes the subscription of the channel to the
+// pattern. UnsubscribeAll removes all the pattern subscriptions of the
+// channel too.
+func UnsubscribePattern[T any](ps *PubSub[Subject, T], pattern Subject, ch chan T) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.patterns.root.drop(pattern.path, ch) {
+		ps.patterns.n--
+		if ps.patterns.subscribers(pattern) == 0 {
+			ps.watch.notify(pattern, PatternRemoved)
+		}
+		ps.dropPaused(ch)
+		ps.subscribersChanged()
+	}
+}
+
+// removePatterns removes all the pattern subscriptions of the channel.
+// The caller must hold the lock.
+func (ps *PubSub[K, T]) removePatterns(ch chan T) {
+	if ps.patterns.n == 0 {
+		return
+	}
+
+	var emptied []Subject
+	if removed := ps.patterns.root.dropAll("", ch, &emptied); removed > 0 {
+		ps.patterns.n -= removed
+		for _, pattern := range emptied {
+			ps.watch.notify(any(pattern).(K), PatternRemoved) // only subjects have patterns
+		}
+		ps.subscribersChanged()
+	}
+}
+
+// matchPatterns returns the channels subscribed to patterns matching the
+// key, but not to the key itself. The caller must hold the read lock.
+func (ps *PubSub[K, T]) matchPatterns(key K, exact map[chan T]int) []chan T {
+	subject, ok := any(key).(Subject)
+	if !ok {
+		return nil
+	}
+
+	var matched []chan T
+	ps.patterns.root.match(subject.path, func(ch chan T) {
+		if _, dup := exact[ch]; !dup && !slices.Contains(matched, ch) {
+			matched = append(matched, ch)
+		}
+	})
+
+	return matched
+}
+
+// targets returns an iterator over the channels subscribed to the key or
+// to patterns matching it, each once, and their number. The caller must
+// hold the read lock.
+func (ps *PubSub[K, T]) targets(key K) (iter.Seq[chan T], int) {
+	exact := ps.subscribers[key].channels()
+	var matched []chan T
+	if ps.patterns.n > 0 {
+		matched = ps.matchPatterns(key, exact)
+	}
+
+	return func(yield func(chan T) bool) {
+		for ch := range exact {
+			if !yield(ch) {
+				return
+			}
+		}
+		for _, ch := range matched {
+			if !yield(ch) {
+				return
+			}
+		}
+	}, len(exact) + len(matched)
+}
+
+// isTarget reports whether the channel is subscribed to the key or to a
+// pattern matching it. The caller must hold the read lock.
+func (ps *PubSub[K, T]) isTarget(key K, ch chan T) bool {
+	if _, ok := ps.subscribers[key].channels()[ch]; ok {
+		return true
+	}
+
+	return ps.patterns.n > 0 && slices.Contains(ps.matchPatterns(key, nil), ch)
+}
+
+// subscribed reports whether channels are subscribed to the key, or to
+// the key it is an alias of, or to patterns matching it.
+func (ps *PubSub[K, T]) subscribed(key K) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	_, n := ps.targets(ps.resolve(key))
+
+	return n > 0
+}
+
+// keyMatches reports whether the key is the other key or, for subjects,
+// matches it as a pattern.
+func keyMatches[K comparable](key, pattern K) bool {
+	if key == pattern {
+		return true
+	}
+
+	subject, ok := any(key).(Subject)
+
+	return ok && subject.Match(any(pattern).(Subject))
+}
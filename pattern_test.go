@@ -0,0 +1,204 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubscribePattern(t *testing.T) {
+	ps := pubsub.New[pubsub.Subject, string]()
+	eu := make(chan string, 4)
+	all := make(chan string, 4)
+	exact := make(chan string, 4)
+
+	pubsub.SubscribePattern(ps, pubsub.ParseSubject("orders.eu.*"), eu)
+	pubsub.SubscribePattern(ps, pubsub.ParseSubject("orders.>"), all)
+	pubsub.SubscribePattern(ps, pubsub.ParseSubject("orders.*.created"), all) // overlapping
+	ps.Subscribe([]pubsub.Subject{pubsub.ParseSubject("orders.eu.created")}, exact)
+	pubsub.SubscribePattern(ps, pubsub.ParseSubject("orders.>"), exact) // also subscribed exactly
+
+	n, err := ps.Publish(context.Background(), pubsub.ParseSubject("orders.eu.created"), "m1")
+	if n != 3 || err != nil {
+		t.Errorf("expected 3 deliveries, got %d, %v", n, err)
+	}
+	if n, _ := ps.Publish(context.Background(), pubsub.ParseSubject("orders.us.created"), "m2"); n != 2 {
+		t.Errorf("expected 2 deliveries, got %d", n)
+	}
+	if len(eu) != 1 || len(all) != 2 || len(exact) != 2 {
+		t.Errorf("expected 1, 2 and 2 messages, got %d, %d and %d", len(eu), len(all), len(exact))
+	}
+
+	if _, err := ps.MustPublish(context.Background(), pubsub.ParseSubject("users.created"), "m3"); !errors.Is(err, pubsub.ErrNoSubscribers) {
+		t.Errorf("expected ErrNoSubscribers, got %v", err)
+	}
+
+	pubsub.UnsubscribePattern(ps, pubsub.ParseSubject("orders.eu.*"), eu)
+	ps.UnsubscribeAll(all)
+	if n, _ := ps.Publish(context.Background(), pubsub.ParseSubject("orders.eu.deleted"), "m4"); n != 1 {
+		t.Errorf("expected only exact's pattern left, got %d deliveries", n)
+	}
+}
+
+func TestUnsubscribePatternWildcards(t *testing.T) {
+	ps := pubsub.New[pubsub.Subject, string]()
+	ch := make(chan string, 8)
+	patterns := []string{"a.*", "a.>", "a.*.c", "a.b.c", "a.>.c"}
+	for _, p := range patterns {
+		pubsub.SubscribePattern(ps, pubsub.ParseSubject(p), ch)
+		pubsub.SubscribePattern(ps, pubsub.ParseSubject(p), ch) // no-op
+	}
+
+	// ">" in the middle is a literal segment
+	if n, _ := ps.Publish(context.Background(), pubsub.ParseSubject("a.>.c"), "m"); n != 1 {
+		t.Errorf("expected a single delivery, got %d", n)
+	}
+	if len(ch) != 1 {
+		t.Errorf("expected one message, got %d", len(ch))
+	}
+
+	for _, p := range patterns {
+		pubsub.UnsubscribePattern(ps, pubsub.ParseSubject(p), ch)
+	}
+	for _, s := range []string{"a.b", "a.b.c", "a.>.c", "a.x.y.z"} {
+		if n, _ := ps.Publish(context.Background(), pubsub.ParseSubject(s), "m"); n != 0 {
+			t.Errorf("%s: expected no deliveries after unsubscribing, got %d", s, n)
+		}
+	}
+}
+
+func TestPatternPublishPaths(t *testing.T) {
+	ps := pubsub.New[pubsub.Subject, string]()
+	ch := make(chan string, 8)
+	pubsub.SubscribePattern(ps, pubsub.ParseSubject("orders.>"), ch)
+	subject := pubsub.ParseSubject("orders.eu")
+	ctx := context.Background()
+
+	if got, err := ps.PublishAny(ctx, subject, "any"); got != ch || err != nil {
+		t.Errorf("PublishAny: expected the pattern channel, got %v, %v", got, err)
+	}
+	if n, err := ps.PublishTx(ctx, pubsub.Keyed[pubsub.Subject, string]{Key: subject, Msg: "tx"}); n != 1 || err != nil {
+		t.Errorf("PublishTx: expected 1 delivery, got %d, %v", n, err)
+	}
+	if n, err := ps.PublishQuorum(ctx, subject, "quorum", pubsub.AtLeast(1)); n != 1 || err != nil {
+		t.Errorf("PublishQuorum: expected 1 delivery, got %d, %v", n, err)
+	}
+
+	confirmed := make(chan pubsub.Confirmation[pubsub.Subject, string], 1)
+	if err := ps.PublishAsync(ctx, subject, "async", func(c pubsub.Confirmation[pubsub.Subject, string]) {
+		confirmed <- c
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if c := <-confirmed; c.Subscriber != ch || c.Outcome != pubsub.Delivered {
+		t.Errorf("PublishAsync: unexpected confirmation %+v", c)
+	}
+
+	for _, want := range []string{"any", "tx", "quorum", "async"} {
+		if got := <-ch; got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestPatternSubscribers(t *testing.T) {
+	ps := pubsub.New[pubsub.Subject, string]()
+	pattern := pubsub.ParseSubject("orders.*")
+	subject := pubsub.ParseSubject("orders.eu")
+
+	var events []string
+	stop := ps.WatchKeys(func(key pubsub.Subject, event pubsub.KeyEvent) {
+		events = append(events, fmt.Sprint(key, " ", event))
+	})
+	defer stop()
+
+	started := make(chan struct{}, 1)
+	stopped := make(chan struct{}, 1)
+	cancel := ps.OnDemand(subject, func(ctx context.Context) {
+		started <- struct{}{}
+		<-ctx.Done()
+	}, func() { stopped <- struct{}{} })
+	defer cancel()
+
+	waited := make(chan error, 1)
+	go func() { waited <- ps.WaitForSubscribers(context.Background(), subject, 2) }()
+
+	ch1, ch2 := make(chan string, 1), make(chan string, 1)
+	pubsub.SubscribePattern(ps, pattern, ch1)
+	<-started
+	pubsub.SubscribePattern(ps, pattern, ch2)
+	if err := <-waited; err != nil {
+		t.Errorf("WaitForSubscribers: %v", err)
+	}
+
+	pubsub.UnsubscribePattern(ps, pattern, ch1)
+	select {
+	case <-stopped:
+		t.Error("expected the producer to keep running")
+	default:
+	}
+	ps.UnsubscribeAll(ch2)
+	<-stopped
+
+	want := []string{"orders.* pattern added", "orders.* pattern removed"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("expected events %v, got %v", want, events)
+	}
+}
+
+// BenchmarkPatternMatch compares matching a published subject against
+// 100k patterns with the trie of SubscribePattern and with a scan of all
+// patterns:
+//
+//	go test -run '^$' -bench PatternMatch -benchmem
+func BenchmarkPatternMatch(b *testing.B) {
+	const n = 100_000
+	patterns := make([]pubsub.Subject, n)
+	for i := range patterns {
+		switch i % 4 {
+		case 0:
+			patterns[i] = pubsub.NewSubject("orders", fmt.Sprint(i), "created")
+		case 1:
+			patterns[i] = pubsub.NewSubject("orders", fmt.Sprint(i), "*")
+		case 2:
+			patterns[i] = pubsub.NewSubject("users", fmt.Sprint(i), ">")
+		default:
+			patterns[i] = pubsub.NewSubject("*", fmt.Sprint(i), "deleted")
+		}
+	}
+	subject := pubsub.NewSubject("orders", "4", "created")
+
+	b.Run("trie", func(b *testing.B) {
+		ps := pubsub.New[pubsub.Subject, int](pubsub.WithDropPolicy(pubsub.DropNewest))
+		ch := make(chan int, 1)
+		for _, p := range patterns {
+			pubsub.SubscribePattern(ps, p, ch)
+		}
+
+		b.ResetTimer()
+		for range b.N {
+			ps.Publish(context.Background(), subject, 1)
+			select {
+			case <-ch:
+			default:
+			}
+		}
+	})
+
+	b.Run("scan", func(b *testing.B) {
+		for range b.N {
+			var matched int
+			for _, p := range patterns {
+				if subject.Match(p) {
+					matched++
+				}
+			}
+			if matched != 1 {
+				b.Fatalf("expected 1 match, got %d", matched)
+			}
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package pubsub
+
+import "sync"
+
+// pauseBuffer holds the messages published to a paused channel.
+type pauseBuffer[T any] struct {
+	mu     sync.Mutex
+	limit  int
+	msgs   []T
+	opts   *options
+	budget *budget
+	size   func(T) int
+}
+
+// add buffers the message, reporting whether it was kept. When the
+// buffer is full, the DropOldest policy evicts the oldest message and
+// the other policies drop the new one. Kept messages are accounted in
+// the memory budget.
+func (b *pauseBuffer[T]) add(msg T) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.msgs) >= b.limit {
+		if b.opts.dropPolicy != DropOldest || b.limit == 0 {
+			return false
+		}
+
+		b.removeOldest()
+	}
+
+	size := b.size(msg)
+	for !b.budget.reserve(1, size, b.opts) {
+		if b.opts.eviction != EvictOldest || len(b.msgs) == 0 {
+			b.budget.reject()
+			return false
+		}
+
+		b.removeOldest()
+		b.budget.evict()
+	}
+
+	b.msgs = append(b.msgs, msg)
+
+	return true
+}
+
+// removeOldest discards the oldest message, releasing its budget.
+func (b *pauseBuffer[T]) removeOldest() {
+	b.budget.release(1, b.size(b.msgs[0]))
+	b.msgs = b.msgs[1:]
+}
+
+// removeNewest discards the n newest messages, releasing their budget.
+func (b *pauseBuffer[T]) removeNewest(n int) {
+	i := len(b.msgs) - n
+	var size int
+	for _, msg := range b.msgs[i:] {
+		size += b.size(msg)
+	}
+
+	b.budget.release(n, size)
+	clear(b.msgs[i:])
+	b.msgs = b.msgs[:i]
+}
+
+// release returns the budget of all buffered messages.
+func (b *pauseBuffer[T]) release() {
+	var size int
+	for _, msg := range b.msgs {
+		size += b.size(msg)
+	}
+
+	b.budget.release(len(b.msgs), size)
+}
+
+// len returns the number of buffered messages.
+func (b *pauseBuffer[T]) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.msgs)
+}
+
+// Pause stops sending messages to the channel, for all keys it is
+// subscribed to, without removing its subscriptions. Up to limit messages
+// published while paused are kept for Resume; with the DropOldest policy
+// further messages replace the oldest kept ones, otherwise they are
+// dropped. A limit of zero drops all messages while paused.
+//
+// Publish doesn't block on paused channels and counts kept messages as
+// delivered. Pausing a paused channel changes its limit. Unsubscribing
+// the channel from all its keys and patterns resumes it and discards the
+// kept messages.
+func (ps *PubSub[K, T]) Pause(ch chan T, limit int) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.pause(ch, limit)
+}
+
+// pause pauses the channel. The caller must hold the lock.
+func (ps *PubSub[K, T]) pause(ch chan T, limit int) {
+	limit = max(limit, 0)
+	if b, ok := ps.paused[ch]; ok {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.limit = limit
+		for len(b.msgs) > limit {
+			b.removeOldest()
+		}
+		return
+	}
+
+	if ps.paused == nil {
+		ps.paused = make(map[chan T]*pauseBuffer[T])
+	}
+
+	ps.paused[ch] = &pauseBuffer[T]{
+		limit:  limit,
+		opts:   &ps.opts,
+		budget: &ps.budget,
+		size:   ps.Size,
+	}
+}
+
+// Resume resumes sending messages to a paused channel and returns the
+// messages kept while it was paused, oldest first. They precede any
+// message sent to the channel after Resume returns, so a consumer
+// handles the returned messages before reading the channel again.
+func (ps *PubSub[K, T]) Resume(ch chan T) []T {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	return ps.resume(ch)
+}
+
+// resume resumes the channel and returns its kept messages. The caller
+// must hold the lock.
+func (ps *PubSub[K, T]) resume(ch chan T) []T {
+	b, ok := ps.paused[ch]
+	if !ok {
+		return nil
+	}
+
+	delete(ps.paused, ch)
+	b.release()
+
+	return b.msgs
+}
+
+// dropPaused discards the pause buffer of a channel left without
+// subscriptions, returning its memory budget. The caller must hold the
+// lock.
+func (ps *PubSub[K, T]) dropPaused(ch chan T) {
+	b, ok := ps.paused[ch]
+	if !ok || len(ps.channelKeys[ch]) > 0 || ps.patterns.n > 0 && ps.patterns.root.has(ch) {
+		return
+	}
+
+	delete(ps.paused, ch)
+	b.release()
+}
+
+// Paused reports whether the channel is paused.
+func (ps *PubSub[K, T]) Paused(ch chan T) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	_, ok := ps.paused[ch]
+
+	return ok
+}
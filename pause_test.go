@@ -0,0 +1,87 @@
+package pubsub_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestPauseResume(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.Pause(ch, 2)
+
+	for i := 1; i <= 3; i++ {
+		// doesn't block on the paused channel
+		n, err := ps.Publish(context.Background(), "k", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 1
+		if i > 2 {
+			want = 0 // buffer full
+		}
+		if n != want {
+			t.Errorf("publish %d: expected %d deliveries, got %d", i, want, n)
+		}
+	}
+
+	if len(ch) != 0 {
+		t.Fatal("paused channel received a message")
+	}
+	if !ps.Paused(ch) {
+		t.Error("expected the channel to be paused")
+	}
+
+	if got := ps.Resume(ch); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("expected kept messages [1 2], got %v", got)
+	}
+
+	ps.Publish(context.Background(), "k", 4)
+	if msg := <-ch; msg != 4 {
+		t.Errorf("expected 4 after resume, got %d", msg)
+	}
+}
+
+func TestPauseDropOldest(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithDropPolicy(pubsub.DropOldest))
+	ch := make(chan int)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.Pause(ch, 2)
+
+	for i := 1; i <= 4; i++ {
+		ps.Publish(context.Background(), "k", i)
+	}
+
+	if got := ps.Resume(ch); !slices.Equal(got, []int{3, 4}) {
+		t.Errorf("expected the newest messages [3 4], got %v", got)
+	}
+}
+
+func TestPauseUnsubscribe(t *testing.T) {
+	for _, unsubscribe := range []func(ps *pubsub.PubSub[string, int], ch chan int){
+		func(ps *pubsub.PubSub[string, int], ch chan int) { ps.Unsubscribe([]string{"a", "b"}, ch) },
+		func(ps *pubsub.PubSub[string, int], ch chan int) { ps.UnsubscribeAll(ch) },
+		func(ps *pubsub.PubSub[string, int], ch chan int) { ps.UnsubscribeAndDrain([]string{"a", "b"}, ch) },
+	} {
+		ps := pubsub.New[string, int](pubsub.WithMemoryBudget(10, pubsub.EvictOldest))
+		ch := make(chan int)
+		ps.Subscribe([]string{"a", "b"}, ch)
+		ps.Pause(ch, 5)
+		ps.Publish(context.Background(), "a", 1)
+		ps.Publish(context.Background(), "b", 2)
+
+		ps.Unsubscribe([]string{"a"}, ch)
+		if !ps.Paused(ch) || ps.MemoryUsage().Messages != 2 {
+			t.Error("expected the buffer kept while subscribed to b")
+		}
+
+		unsubscribe(ps, ch)
+		if ps.Paused(ch) || ps.MemoryUsage().Messages != 0 {
+			t.Errorf("expected the buffer released, got %+v", ps.MemoryUsage())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// Pipe subscribes to the keys of src and republishes their messages to dst.
+// Each key is mapped with mapKey and each message with mapMsg; messages for
+// which mapMsg returns false are dropped. Messages of a key are republished
+// in order, with ctx used for publishing to dst.
+//
+// The subscription is made before Pipe returns. Piping stops when ctx is
+// canceled or the returned function is called; the function waits for
+// the piping goroutines to finish.
+func Pipe[K, K2 comparable, A, B any](
+	ctx context.Context,
+	src *PubSub[K, A], dst *PubSub[K2, B], keys []K,
+	mapKey func(K) K2, mapMsg func(A) (B, bool),
+) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+
+	for _, key := range keys {
+		sub := []K{key}
+		ch := make(chan A, src.bufferSize(key))
+		src.Subscribe(sub, ch)
+
+		dstKey := mapKey(key)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer src.UnsubscribeAndDrain(sub, ch)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					if out, ok := mapMsg(msg); ok {
+						if _, err := dst.Publish(ctx, dstKey, out); err != nil {
+							return
+						}
+					}
+				}
+			}
+		}()
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
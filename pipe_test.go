@@ -0,0 +1,59 @@
+package pubsub_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestPipe(t *testing.T) {
+	src := pubsub.New[string, int]()
+	dst := pubsub.New[int, string]()
+
+	stop := pubsub.Pipe(context.Background(), src, dst, []string{"a"},
+		func(key string) int { return len(key) },
+		func(n int) (string, bool) { return strconv.Itoa(n * 2), n%2 == 1 },
+	)
+	defer stop()
+
+	ch := make(chan string, 2)
+	dst.Subscribe([]int{1}, ch)
+
+	for i := 1; i <= 3; i++ {
+		if n, err := src.PublishWithTimeout("a", i, time.Second); err != nil || n != 1 {
+			t.Fatalf("publish %d: delivered %d, error %v", i, n, err)
+		}
+	}
+
+	for _, want := range []string{"2", "6"} {
+		select {
+		case got := <-ch:
+			if got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %q not piped", want)
+		}
+	}
+}
+
+func TestPipeStop(t *testing.T) {
+	src := pubsub.New[string, int]()
+	dst := pubsub.New[string, int]()
+	identity := func(k string) string { return k }
+	pass := func(n int) (int, bool) { return n, true }
+
+	// the destination is never read, so the pipe blocks on publishing
+	dst.Subscribe([]string{"a"}, make(chan int))
+
+	stop := pubsub.Pipe(context.Background(), src, dst, []string{"a"}, identity, pass)
+	src.PublishWithTimeout("a", 1, time.Second)
+	stop()
+
+	if n, _ := src.PublishWithTimeout("a", 2, 10*time.Millisecond); n != 0 {
+		t.Errorf("expected no subscribers after stop, got %d", n)
+	}
+}
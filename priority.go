@@ -0,0 +1,39 @@
+package pubsub
+
+// SubscribePriority is like Subscribe, but sets the priority of the
+// channel: Publish sends each message to the channels of higher priority
+// before those of lower priority, for example so a subscriber updating a
+// cache gets a message before the subscribers reading the cache on
+// receipt. The default priority is zero; channels of equal priority are
+// sent to in unspecified order. Sends are ordered, not processing: a
+// lower-priority send starts once the higher ones completed, which for a
+// buffered channel doesn't mean its reader has seen the message.
+//
+// The priority applies to all the keys of the channel and is forgotten
+// when the channel is unsubscribed from all of them. Keys whose channels
+// all have the default priority are delivered as usual; the others are
+// delivered sequentially, even with WithDeliveryConcurrency.
+func (ps *PubSub[K, T]) SubscribePriority(keys []K, ch chan T, priority int) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.state != stateOpen {
+		return
+	}
+
+	if priority != 0 {
+		if ps.priorities == nil {
+			ps.priorities = make(map[chan T]int)
+		}
+		ps.priorities[ch] = priority
+	} else {
+		delete(ps.priorities, ch)
+	}
+
+	ps.add(keys, ch, false)
+
+	for key := range ps.channelKeys[ch] {
+		ps.subscribers[key].update(ps.priorities)
+	}
+}
@@ -0,0 +1,56 @@
+package pubsub_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubscribePriority(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	cache, reader, audit := make(chan int), make(chan int), make(chan int)
+	ps.Subscribe([]string{"k"}, reader)
+	ps.SubscribePriority([]string{"k"}, audit, 5)
+	ps.SubscribePriority([]string{"k", "other"}, cache, 10)
+
+	order := make(chan []string)
+	go func() {
+		for range 3 {
+			var got []string
+			for range 3 {
+				select {
+				case <-cache:
+					got = append(got, "cache")
+				case <-reader:
+					got = append(got, "reader")
+				case <-audit:
+					got = append(got, "audit")
+				}
+			}
+			order <- got
+		}
+	}()
+
+	for i := range 3 {
+		go ps.Publish(context.Background(), "k", i)
+		if got := <-order; !slices.Equal(got, []string{"cache", "audit", "reader"}) {
+			t.Fatalf("expected delivery by priority, got %v", got)
+		}
+	}
+}
+
+func TestSubscribePriorityReset(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	high, low := make(chan int, 1), make(chan int, 1)
+	ps.SubscribePriority([]string{"k"}, high, 1)
+	ps.Subscribe([]string{"k"}, low)
+
+	ps.Unsubscribe([]string{"k"}, high)
+	ps.Subscribe([]string{"k"}, high) // priority forgotten
+
+	if n, _ := ps.Publish(context.Background(), "k", 1); n != 2 {
+		t.Errorf("expected 2 deliveries, got %d", n)
+	}
+}
@@ -0,0 +1,93 @@
+package pstest
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+// Fanout describes a fan-out benchmark run by BenchmarkFanout.
+type Fanout[K comparable, T any] struct {
+	Keys        []K // keys published to in turn; at least one
+	Subscribers int // channels subscribed to each key
+	Buffer      int // capacity of the subscriber channels
+	Publishers  int // concurrent publishing goroutines; one if zero
+	Msg         T   // message published
+
+	// Options configure the benchmarked PubSub instance.
+	Options []pubsub.Option
+}
+
+// BenchmarkFanout measures publishing the message to the keys, one
+// publish per iteration, with readers draining the subscriber channels
+// concurrently. Besides the time and allocations per publish, it reports
+// the deliveries per second. Use it to compare topologies, options and
+// message types of your own:
+//
+//	func BenchmarkOrders(b *testing.B) {
+//		pstest.BenchmarkFanout(b, pstest.Fanout[string, Order]{
+//			Keys: []string{"orders"}, Subscribers: 10, Buffer: 64, Msg: Order{},
+//		})
+//	}
+func BenchmarkFanout[K comparable, T any](b *testing.B, f Fanout[K, T]) {
+	ps := pubsub.New[K, T](f.Options...)
+
+	var (
+		readers sync.WaitGroup
+		chans   []chan T
+	)
+	for _, key := range f.Keys {
+		for range f.Subscribers {
+			ch := make(chan T, f.Buffer)
+			ps.Subscribe([]K{key}, ch)
+			chans = append(chans, ch)
+
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for range ch {
+				}
+			}()
+		}
+	}
+
+	var (
+		next       atomic.Int64
+		deliveries atomic.Int64
+		publishers sync.WaitGroup
+	)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range max(f.Publishers, 1) {
+		publishers.Add(1)
+		go func() {
+			defer publishers.Done()
+
+			var n int
+			for i := next.Add(1); i <= int64(b.N); i = next.Add(1) {
+				delivered, err := ps.Publish(context.Background(), f.Keys[int(i)%len(f.Keys)], f.Msg)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				n += delivered
+			}
+			deliveries.Add(int64(n))
+		}()
+	}
+	publishers.Wait()
+
+	b.StopTimer()
+	b.ReportMetric(float64(deliveries.Load())/b.Elapsed().Seconds(), "deliveries/s")
+
+	ps.Close()
+	for _, ch := range chans {
+		close(ch)
+	}
+	readers.Wait()
+}
@@ -0,0 +1,174 @@
+package pstest
+
+import (
+	"math/rand/v2"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// Chaos are the probabilities, between 0 and 1, of the faults Chaotic
+// injects into each message.
+type Chaos struct {
+	Drop      float64       // the message is lost
+	Duplicate float64       // the message is received twice
+	Delay     float64       // the message is received up to MaxDelay later, possibly after later ones
+	Reorder   float64       // the message is held until the next one of its key was received
+	MaxDelay  time.Duration // of delays, and of holds if not zero
+	Seed      uint64        // makes the faults reproducible if not zero
+}
+
+// Chaotic subscribes to the keys and returns a channel receiving their
+// messages with faults injected according to the probabilities, so
+// consumer code can be hardened against lost, duplicated, late and
+// reordered messages before it meets them with drop policies, bridges or
+// redeliveries. Delays are measured in real time. A message held for
+// reordering is released after the next message of its key or, if
+// MaxDelay is not zero, once MaxDelay has passed; without either, it
+// stays held.
+//
+// The subscriptions are removed when the test finishes; messages still
+// delayed or held then are discarded.
+func Chaotic[K comparable, T any](tb testing.TB, ps *pubsub.PubSub[K, T], chaos Chaos, keys ...K) <-chan pubsub.Keyed[K, T] {
+	tb.Helper()
+
+	c := &chaotic[K, T]{
+		Chaos: chaos,
+		out:   make(chan pubsub.Keyed[K, T]),
+		done:  make(chan struct{}),
+	}
+	if chaos.Seed != 0 {
+		c.rng = rand.New(rand.NewPCG(chaos.Seed, chaos.Seed))
+	} else {
+		c.rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	for _, key := range keys {
+		sub := []K{key}
+		ch := make(chan T)
+		ps.Subscribe(sub, ch)
+
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			defer ps.UnsubscribeAndDrain(sub, ch)
+
+			c.relay(key, ch)
+		}()
+	}
+
+	tb.Cleanup(func() {
+		close(c.done)
+		c.wg.Wait()
+	})
+
+	return c.out
+}
+
+// chaotic relays messages with faults.
+type chaotic[K comparable, T any] struct {
+	Chaos
+	mu   sync.Mutex // protects rng
+	rng  *rand.Rand
+	out  chan pubsub.Keyed[K, T]
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// relay forwards the messages of the key until the test finishes.
+func (c *chaotic[K, T]) relay(key K, ch chan T) {
+	var (
+		held    *pubsub.Keyed[K, T]
+		timer   *time.Timer
+		release <-chan time.Time
+	)
+
+	for {
+		select {
+		case <-c.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+
+		case msg := <-ch:
+			if c.hit(c.Drop) {
+				continue
+			}
+
+			m := pubsub.Keyed[K, T]{Key: key, Msg: msg}
+			if held == nil && c.hit(c.Reorder) {
+				held = &m
+				if c.MaxDelay > 0 {
+					timer = time.NewTimer(c.MaxDelay)
+					release = timer.C
+				}
+				continue
+			}
+
+			c.send(m)
+			if held == nil {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+
+		case <-release:
+		}
+
+		c.send(*held)
+		held, timer, release = nil, nil, nil
+	}
+}
+
+// send emits the message, duplicated or delayed by chance.
+func (c *chaotic[K, T]) send(m pubsub.Keyed[K, T]) {
+	n := 1
+	if c.hit(c.Duplicate) {
+		n = 2
+	}
+
+	for range n {
+		if c.MaxDelay <= 0 || !c.hit(c.Delay) {
+			c.emit(m)
+			continue
+		}
+
+		c.wg.Add(1)
+		time.AfterFunc(c.delay(), func() {
+			defer c.wg.Done()
+			c.emit(m)
+		})
+	}
+}
+
+// emit sends the message to the output unless the test finished.
+func (c *chaotic[K, T]) emit(m pubsub.Keyed[K, T]) {
+	select {
+	case c.out <- m:
+	case <-c.done:
+	}
+}
+
+// hit reports whether a fault of probability p happens.
+func (c *chaotic[K, T]) hit(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.rng.Float64() < p
+}
+
+// delay returns a random delay up to MaxDelay.
+func (c *chaotic[K, T]) delay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Duration(c.rng.Int64N(int64(c.MaxDelay) + 1))
+}
@@ -0,0 +1,84 @@
+package pstest_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+// receive publishes the messages to the key and returns the first n
+// received from the channel, failing after a second without one.
+func receive(t *testing.T, ps *pubsub.PubSub[string, int], out <-chan pubsub.Keyed[string, int], n int, msgs ...int) []int {
+	t.Helper()
+
+	go func() {
+		for _, msg := range msgs {
+			ps.Publish(context.Background(), "k", msg)
+		}
+	}()
+
+	var got []int
+	for range n {
+		select {
+		case m := <-out:
+			got = append(got, m.Msg)
+		case <-time.After(time.Second):
+			t.Fatalf("received only %v", got)
+		}
+	}
+
+	return got
+}
+
+func TestChaoticNoFaults(t *testing.T) {
+	ps, _ := pstest.New[string, int]()
+	out := pstest.Chaotic(t, ps, pstest.Chaos{}, "k")
+
+	if got := receive(t, ps, out, 3, 1, 2, 3); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected the messages unchanged, got %v", got)
+	}
+}
+
+func TestChaoticDropAndDuplicate(t *testing.T) {
+	ps, _ := pstest.New[string, int]()
+	lost := pstest.Chaotic(t, ps, pstest.Chaos{Drop: 1}, "k")
+	twice := pstest.Chaotic(t, ps, pstest.Chaos{Duplicate: 1}, "k")
+
+	go func() {
+		for range lost {
+			t.Error("expected all messages dropped")
+		}
+	}()
+
+	if got := receive(t, ps, twice, 4, 1, 2); !slices.Equal(got, []int{1, 1, 2, 2}) {
+		t.Errorf("expected each message twice, got %v", got)
+	}
+}
+
+func TestChaoticReorder(t *testing.T) {
+	ps, _ := pstest.New[string, int]()
+	out := pstest.Chaotic(t, ps, pstest.Chaos{Reorder: 1}, "k")
+
+	if got := receive(t, ps, out, 4, 1, 2, 3, 4); !slices.Equal(got, []int{2, 1, 4, 3}) {
+		t.Errorf("expected pairs swapped, got %v", got)
+	}
+}
+
+func TestChaoticDelay(t *testing.T) {
+	ps, _ := pstest.New[string, int]()
+	out := pstest.Chaotic(t, ps, pstest.Chaos{
+		Delay: 0.5, Reorder: 0.2, MaxDelay: 20 * time.Millisecond, Seed: 1,
+	}, "k")
+
+	got := receive(t, ps, out, 20, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19)
+	slices.Sort(got)
+	for i, msg := range got {
+		if msg != i {
+			t.Fatalf("expected every message once, got %v", got)
+		}
+	}
+}
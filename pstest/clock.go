@@ -0,0 +1,100 @@
+package pstest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// Clock is a fake pubsub.Clock that only moves when advanced.
+// Timers fire synchronously in Advance, in the order they are due.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+var _ pubsub.Clock = (*Clock)(nil)
+
+// NewClock returns a fake clock set to the time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the current fake time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// AfterFunc schedules f to be called when the clock is advanced past d.
+func (c *Clock) AfterFunc(d time.Duration, f func()) pubsub.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &timer{clock: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+
+	return t
+}
+
+// Advance moves the clock forward and calls the functions of the timers
+// that became due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	var due, pending []*timer
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+
+	c.timers = pending
+	c.mu.Unlock()
+
+	slices.SortStableFunc(due, func(a, b *timer) int {
+		return a.at.Compare(b.at)
+	})
+
+	for _, t := range due {
+		t.f()
+	}
+}
+
+// Timers returns the number of timers waiting to fire. Tests use it to
+// wait until the code under test has started a timer before advancing.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// timer is a pending AfterFunc call of the fake clock.
+type timer struct {
+	clock *Clock
+	at    time.Time
+	f     func()
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = slices.Delete(t.clock.timers, i, i+1)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+// Package pstest provides utilities for testing code built on pubsub:
+// a PubSub instance with synchronous delivery and a fake clock, a
+// Recorder of published messages with assertion helpers, Clock, a fake
+// pubsub.Clock, and Chaotic, which injects delivery faults.
+package pstest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// DefaultTimeout is how long ExpectPublished waits for a message.
+var DefaultTimeout = time.Second
+
+// New returns a PubSub instance for tests together with its fake clock,
+// set to the Unix epoch. Helper subscriptions are unbuffered, so Publish
+// returns only after every subscriber has taken the message; Recorders
+// record it within Publish. Additional options are applied after the
+// defaults.
+func New[K comparable, T any](opts ...pubsub.Option) (*pubsub.PubSub[K, T], *Clock) {
+	clock := NewClock(time.Unix(0, 0))
+	opts = append([]pubsub.Option{
+		pubsub.WithBufferSize(0),
+		pubsub.WithClock(clock),
+	}, opts...)
+
+	return pubsub.New[K, T](opts...), clock
+}
+
+// Message is a message recorded with the key it was published to.
+type Message[K comparable, T any] struct {
+	Key K
+	Msg T
+}
+
+func (m Message[K, T]) String() string {
+	return fmt.Sprintf("%v: %+v", m.Key, m.Msg)
+}
+
+// Recorder records messages published to a set of keys. The Expect
+// methods consume recorded messages in the order they were received.
+type Recorder[K comparable, T any] struct {
+	tb     testing.TB
+	mu     sync.Mutex
+	msgs   []Message[K, T]
+	next   int           // index of the first message not yet expected
+	notify chan struct{} // closed and replaced on every message
+}
+
+// Record subscribes a Recorder to the keys with a handler, so a message
+// is recorded before Publish returns and messages are recorded in publish
+// order across all the keys. It is unsubscribed when the test finishes.
+// Like every handler, the Recorder counts as a delivery but is not
+// reported by Keys nor key events.
+func Record[K comparable, T any](tb testing.TB, ps *pubsub.PubSub[K, T], keys ...K) *Recorder[K, T] {
+	tb.Helper()
+
+	r := &Recorder[K, T]{tb: tb, notify: make(chan struct{})}
+	unsubscribe := ps.SubscribeFunc(keys, func(_ context.Context, key K, msg T) error {
+		r.add(Message[K, T]{Key: key, Msg: msg})
+		return nil
+	})
+	tb.Cleanup(unsubscribe)
+
+	return r
+}
+
+// add appends the message and wakes up waiting expectations.
+func (r *Recorder[K, T]) add(msg Message[K, T]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.msgs = append(r.msgs, msg)
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// Messages returns all recorded messages.
+func (r *Recorder[K, T]) Messages() []Message[K, T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Message[K, T](nil), r.msgs...)
+}
+
+// Reset discards the recorded messages.
+func (r *Recorder[K, T]) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.msgs, r.next = nil, 0
+}
+
+// wait returns the next unexpected message, waiting for it at most the
+// timeout.
+func (r *Recorder[K, T]) wait(timeout time.Duration) (Message[K, T], bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		r.mu.Lock()
+		if r.next < len(r.msgs) {
+			msg := r.msgs[r.next]
+			r.next++
+			r.mu.Unlock()
+			return msg, true
+		}
+		notify := r.notify
+		r.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-deadline.C:
+			return Message[K, T]{}, false
+		}
+	}
+}
+
+// ExpectPublished fails the test unless the next recorded message,
+// received within DefaultTimeout, was published to the key and is deeply
+// equal to msg.
+func (r *Recorder[K, T]) ExpectPublished(key K, msg T) {
+	r.tb.Helper()
+
+	got, ok := r.wait(DefaultTimeout)
+	if !ok {
+		r.tb.Fatalf("pstest: no message published, want %v", Message[K, T]{key, msg})
+		return
+	}
+
+	want := Message[K, T]{Key: key, Msg: msg}
+	if !reflect.DeepEqual(got, want) {
+		r.tb.Errorf("pstest: unexpected message %v, want %v", got, want)
+	}
+}
+
+// ExpectNoMessage fails the test if a message not yet expected is
+// recorded within the duration.
+func (r *Recorder[K, T]) ExpectNoMessage(within time.Duration) {
+	r.tb.Helper()
+
+	if got, ok := r.wait(within); ok {
+		r.tb.Errorf("pstest: unexpected message %v", got)
+	}
+}
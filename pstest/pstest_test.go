@@ -0,0 +1,115 @@
+package pstest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestRecorder(t *testing.T) {
+	ps, _ := pstest.New[string, int]()
+	rec := pstest.Record(t, ps, "a", "b")
+
+	ps.Publish(context.Background(), "a", 1)
+	ps.Publish(context.Background(), "b", 2)
+	ps.Publish(context.Background(), "c", 3)
+
+	rec.ExpectPublished("a", 1)
+	rec.ExpectPublished("b", 2)
+	rec.ExpectNoMessage(10 * time.Millisecond)
+
+	if n := len(rec.Messages()); n != 2 {
+		t.Errorf("expected 2 recorded messages, got %d", n)
+	}
+}
+
+func TestRecorderOrder(t *testing.T) {
+	ps, _ := pstest.New[string, int]()
+	rec := pstest.Record(t, ps, "a", "b")
+
+	for i := range 100 {
+		ps.Publish(context.Background(), []string{"a", "b"}[i%2], i)
+		if n := len(rec.Messages()); n != i+1 {
+			t.Fatalf("expected %d recorded messages right after Publish, got %d", i+1, n)
+		}
+	}
+
+	for i, m := range rec.Messages() {
+		if m.Msg != i {
+			t.Fatalf("expected message %d at %d, got %v", i, i, m)
+		}
+	}
+}
+
+func TestRecorderMismatch(t *testing.T) {
+	ps, _ := pstest.New[string, int]()
+	ft := &fakeT{TB: t}
+	rec := pstest.Record(ft, ps, "a")
+
+	ps.Publish(context.Background(), "a", 1)
+	rec.ExpectPublished("a", 2)
+
+	if !ft.failed {
+		t.Error("expected a failure for a different message")
+	}
+}
+
+func TestClock(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	ps.Subscribe([]string{"k"}, make(chan int)) // never read
+
+	done := make(chan error)
+	go func() {
+		_, err := ps.PublishWithTimeout("k", 1, time.Minute)
+		done <- err
+	}()
+
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("publish returned before the timeout: %v", err)
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if !clock.Now().Equal(time.Unix(61, 0)) {
+		t.Errorf("unexpected time %v", clock.Now())
+	}
+}
+
+func TestClockStop(t *testing.T) {
+	clock := pstest.NewClock(time.Unix(0, 0))
+	fired := false
+	timer := clock.AfterFunc(time.Second, func() { fired = true })
+
+	if !timer.Stop() {
+		t.Error("expected a pending timer to stop")
+	}
+	if timer.Stop() {
+		t.Error("expected a stopped timer not to stop again")
+	}
+
+	clock.Advance(time.Hour)
+	if fired {
+		t.Error("stopped timer fired")
+	}
+}
+
+// fakeT records failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *fakeT) Errorf(string, ...any) { t.failed = true }
+func (t *fakeT) Fatalf(string, ...any) { t.failed = true }
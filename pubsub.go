@@ -3,10 +3,56 @@
 // based on topic keys, with thread-safe operations.
 // The implementation is generic, supporting any comparable key type
 // and any message type.
+//
+// # Ordering
+//
+// Publish returns only after the message was handed to every subscriber,
+// so messages published to a key by one goroutine reach each subscriber in
+// publish order. Helpers that deliver asynchronously (handlers registered
+// with On and EventBus.On, Messages, Pipe) keep a serial queue per
+// subscription and preserve that per-key, per-subscriber FIFO order.
+// There is no ordering between different keys, nor between messages
+// published concurrently by different goroutines.
+//
+// # Subscription changes during Publish
+//
+// Each Publish delivers to the subscribers of the key at the moment it
+// starts: subscriptions are snapshot-isolated from in-flight publishes.
+// Subscribe and Unsubscribe calls made while a publish is delivering wait
+// until it returns and apply to later publishes only, so a channel
+// subscribed meanwhile doesn't receive the message, and a channel
+// unsubscribed meanwhile still does. While such a call waits, new
+// publishes wait for it too.
+//
+// A subscriber that unsubscribes itself when it receives a message thus
+// waits for the publish to complete delivery to the other subscribers.
+// If it stops reading meanwhile and the publish blocks on its channel,
+// they deadlock: use UnsubscribeAndDrain, which keeps reading, or
+// SubscribeFunc handlers, which may unsubscribe themselves. Handlers of
+// SubscribeFunc are called after the channel deliveries, with a snapshot
+// of the handlers taken when the publish starts.
+//
+// # Guarantees
+//
+// The tests check these invariants under random concurrent operations:
+//
+//   - A message is sent at most once to each channel subscribed to its
+//     key, and the count returned by Publish is the number of channels
+//     and handlers that received it.
+//   - Keys reports exactly the keys with at least one subscribed channel.
+//   - Once Close returns, no message is sent to any channel, Publish
+//     fails with ErrClosed and new subscriptions are ignored or fail.
+//   - Each subscriber receives the messages of a publishing goroutine in
+//     publish order, whatever the keys they were published to.
 package pubsub
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"hash/maphash"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 )
@@ -16,16 +62,117 @@ import (
 // allowing efficient message distribution.
 // K is the key type (must be comparable), T is the message type.
 type PubSub[K comparable, T any] struct {
-	mu          sync.RWMutex // protects subscribers map
-	subscribers map[K]map[chan T]struct{}
+	mu            sync.RWMutex // protects subscribers map
+	subscribers   map[K]*keySubs[T]
+	channelKeys   map[chan T]map[K]struct{} // reverse index of subscribers
+	priorities    map[chan T]int            // delivery tiers, see SubscribePriority
+	keyConfigs    map[K]*keyConfig          // overrides set by ConfigureKey
+	aliases       map[K]*alias[K]           // set by AliasKey
+	patterns      subjectTrie[T]            // subject patterns, see SubscribePattern
+	paused        map[chan T]*pauseBuffer[T]
+	managed       map[chan T]*Subscription[K, T]
+	credited      map[chan T]*credits[T] // Subscription channels with flow control
+	watch         keyWatch[K]
+	settingsWatch settingsWatch
+	budget        budget
+	sizer         Sizer[T]
+	cloner        Cloner[T]
+	tracer        *tracer[K, T] // nil unless WithTracer is set
+	mutated       MutationObserver[K]
+	taps          taps[K, T]
+	state         int           // lifecycle state, see Close
+	stopping      chan struct{} // closed by Drain and Close, see stop
+	stopOnce      sync.Once
+	life          context.Context    // canceled by Close
+	endLife       context.CancelFunc // cancels life
+	history       []history[K, T]    // shards, see WithShards
+	seed          maphash.Seed       // of the shards
+	authorizer    Authorizer[K]
+	validation    validation[K, T]
+	converter     Converter[K, T]
+	handlers      map[K][]*handler[K, T] // inline subscribers
+	limits        map[chan T]*limit[K, T]
+	keyStats      []keyStats[K] // shards, see WithShards
+	latencies     latencies[K]
+	components    []*component[K] // drawn by WriteTopology
+	closeHooks    []*closeHook    // called by Close
+	warmups       []*warmup       // replays of workers, see Sync
+	sems          semaphores[K]
+	changed       chan struct{} // closed on subscription changes, if waited for
+	opts          options
+}
+
+// keySubs are the channels subscribed to a key.
+type keySubs[T any] struct {
+	refs  map[chan T]int // subscription reference counts
+	one   chan T         // the only channel, if there is exactly one
+	tiers []chan T       // by descending priority, if any channel has one
+}
+
+// channels returns the subscribed channels; s may be nil.
+func (s *keySubs[T]) channels() map[chan T]int {
+	if s == nil {
+		return nil
+	}
+
+	return s.refs
 }
 
-// New creates and returns a new PubSub instance.
+// update sets the only channel and the tiers after a change.
+func (s *keySubs[T]) update(priorities map[chan T]int) {
+	s.one, s.tiers = nil, nil
+	if len(s.refs) == 1 {
+		for ch := range s.refs {
+			s.one = ch
+		}
+		return
+	}
+
+	for ch := range s.refs {
+		if priorities[ch] != 0 {
+			s.tiers = slices.Collect(maps.Keys(s.refs))
+			slices.SortStableFunc(s.tiers, func(a, b chan T) int {
+				return cmp.Compare(priorities[b], priorities[a])
+			})
+			return
+		}
+	}
+}
+
+// New creates and returns a new PubSub instance configured with the options.
 // The returned PubSub is ready to use with zero values initialized.
-func New[K comparable, T any]() *PubSub[K, T] {
-	return &PubSub[K, T]{
-		subscribers: make(map[K]map[chan T]struct{}),
+func New[K comparable, T any](opts ...Option) *PubSub[K, T] {
+	ps := &PubSub[K, T]{
+		subscribers: make(map[K]*keySubs[T]),
+		channelKeys: make(map[chan T]map[K]struct{}),
+		stopping:    make(chan struct{}),
 	}
+	ps.life, ps.endLife = context.WithCancel(context.Background())
+
+	for _, opt := range opts {
+		opt(&ps.opts)
+	}
+
+	if ps.opts.logger == nil {
+		ps.opts.logger = nopLogger{}
+	}
+
+	if ps.opts.clock == nil {
+		ps.opts.clock = systemClock{}
+	}
+
+	if ps.opts.offsets == nil {
+		ps.opts.offsets = new(MemoryOffsets)
+	}
+
+	ps.setShards()
+
+	ps.setSizer()
+	ps.setCloner()
+	ps.setTracer()
+	ps.setMutationObserver()
+
+	return ps
 }
 
 // Subscribe adds a channel to receive messages for the specified keys.
@@ -34,33 +181,200 @@ func New[K comparable, T any]() *PubSub[K, T] {
 //
 // Note: The channel should have sufficient buffer space or active readers
 // to prevent indefinite blocking in the Publish method.
+// Subscribing to a draining or closed instance does nothing; use
+// SubscribeContext to get ErrClosed.
 func (ps *PubSub[K, T]) Subscribe(keys []K, ch chan T) {
+	ps.subscribe(keys, ch, false)
+}
+
+// SubscribeRef is like Subscribe, but counts references: subscribing a
+// channel to a key it is already subscribed to increments the count, and
+// the subscription is removed only after as many Unsubscribe calls.
+// It lets independent components sharing a channel pair their subscribe
+// and unsubscribe calls safely.
+func (ps *PubSub[K, T]) SubscribeRef(keys []K, ch chan T) {
+	ps.subscribe(keys, ch, true)
+}
+
+// subscribe adds the subscriptions, incrementing the reference counts of
+// existing ones if ref is set. It fails if the instance is not open.
+func (ps *PubSub[K, T]) subscribe(keys []K, ch chan T, ref bool) error {
+	defer ps.watch.dispatch()
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
+	if ps.state != stateOpen {
+		return ErrClosed
+	}
+
+	ps.add(keys, ch, ref)
+
+	return nil
+}
+
+// add subscribes the channel to the keys. The caller must hold the lock.
+func (ps *PubSub[K, T]) add(keys []K, ch chan T, ref bool) {
 	for _, key := range keys {
-		if _, exists := ps.subscribers[key]; !exists {
-			ps.subscribers[key] = make(map[chan T]struct{})
+		key = ps.resolve(key)
+		subs, exists := ps.subscribers[key]
+		if !exists {
+			subs = &keySubs[T]{refs: make(map[chan T]int)}
+			ps.subscribers[key] = subs
+			ps.watch.notify(key, KeyAdded)
 		}
 
-		ps.subscribers[key][ch] = struct{}{}
+		if refs := subs.refs[ch]; refs == 0 {
+			subs.refs[ch] = 1
+			subs.update(ps.priorities)
+			if ps.channelKeys[ch] == nil {
+				ps.channelKeys[ch] = make(map[K]struct{})
+			}
+			ps.channelKeys[ch][key] = struct{}{}
+			ps.opts.logger.Debug("pubsub: subscribed", "key", key)
+		} else if ref {
+			subs.refs[ch] = refs + 1
+		}
 	}
+
+	ps.subscribersChanged()
 }
 
 // Unsubscribe removes a channel from receiving messages for the specified keys.
-// After this call, the channel will no longer receive messages for these keys.
+// After this call, the channel will no longer receive messages for these keys,
+// unless its subscription to a key was referenced more than once with
+// SubscribeRef; then only the reference count is decremented.
 // If the channel wasn't subscribed to a key, that key is skipped.
 // If all channels are unsubscribed from a key, the key is removed from the registry.
 func (ps *PubSub[K, T]) Unsubscribe(keys []K, ch chan T) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.remove(keys, ch)
+}
+
+// UnsubscribeAll removes the channel from every key it is subscribed to,
+// whatever the reference counts, so a connection manager can clean up all
+// the subscriptions of a disconnecting client in one call. A limit set by
+// SubscribeFor ends, closing its done channel.
+func (ps *PubSub[K, T]) UnsubscribeAll(ch chan T) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.removeAll(ch)
+}
+
+// PurgeKey removes all the channels and handlers subscribed to the key,
+// whatever the reference counts, and discards its retained messages.
+// Sequence numbers of the key continue where they were, so consumers
+// don't receive new messages as old ones.
+func (ps *PubSub[K, T]) PurgeKey(key K) {
+	defer ps.watch.dispatch()
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
+	key = ps.resolve(key)
+	keys := []K{key}
+	for ch := range ps.subscribers[key].channels() {
+		ps.subscribers[key].refs[ch] = 1
+		ps.remove(keys, ch)
+	}
+
+	if _, ok := ps.handlers[key]; ok {
+		delete(ps.handlers, key)
+		ps.subscribersChanged()
+	}
+
+	ps.purgeRetained(key)
+}
+
+// removeAll unsubscribes the channel from all keys. The caller must hold
+// the lock.
+func (ps *PubSub[K, T]) removeAll(ch chan T) {
+	if l := ps.limits[ch]; l != nil {
+		ps.expireLimit(l)
+	}
+
+	keys := make([]K, 0, len(ps.channelKeys[ch]))
+	for key := range ps.channelKeys[ch] {
+		ps.subscribers[key].refs[ch] = 1
+		keys = append(keys, key)
+	}
+	ps.remove(keys, ch)
+	ps.removePatterns(ch)
+	ps.dropPaused(ch)
+}
+
+// remove unsubscribes the channel from the keys. The caller must hold the
+// lock.
+func (ps *PubSub[K, T]) remove(keys []K, ch chan T) {
 	for _, key := range keys {
-		if subs, exists := ps.subscribers[key]; exists {
-			delete(subs, ch)
+		key = ps.resolve(key)
+		subs, exists := ps.subscribers[key]
+		if !exists {
+			continue
+		}
 
-			if len(subs) == 0 {
-				delete(ps.subscribers, key)
+		switch refs := subs.refs[ch]; refs {
+		case 0:
+			continue
+		case 1:
+			delete(subs.refs, ch)
+			subs.update(ps.priorities)
+			if delete(ps.channelKeys[ch], key); len(ps.channelKeys[ch]) == 0 {
+				delete(ps.channelKeys, ch)
+				delete(ps.priorities, ch)
+				ps.dropPaused(ch)
+			}
+			ps.opts.logger.Debug("pubsub: unsubscribed", "key", key)
+		default:
+			subs.refs[ch] = refs - 1
+			continue
+		}
+
+		if len(subs.refs) == 0 {
+			delete(ps.subscribers, key)
+			ps.watch.notify(key, KeyRemoved)
+		}
+	}
+
+	ps.subscribersChanged()
+}
+
+// UnsubscribeAndDrain removes the channel subscription from the keys
+// while receiving from the channel, so a publisher blocked on sending to
+// it can't prevent the unsubscribe, and then discards the messages left
+// in the channel buffer. After it returns, no more messages are sent to
+// the channel for these keys and the buffer is empty.
+//
+// Use it instead of Unsubscribe when nobody reads the channel anymore,
+// for example when a consumer goroutine exits. Like Unsubscribe, it only
+// decrements the count of subscriptions referenced with SubscribeRef.
+func (ps *PubSub[K, T]) UnsubscribeAndDrain(keys []K, ch chan T) {
+	done := make(chan struct{})
+	go func() {
+		ps.Unsubscribe(keys, ch)
+		close(done)
+	}()
+
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok { // closed by Close, nothing more to drain
+				<-done
+				return
+			}
+		case <-done:
+			for {
+				select {
+				case _, ok := <-ch:
+					if !ok {
+						return
+					}
+				default:
+					return
+				}
 			}
 		}
 	}
@@ -70,32 +384,314 @@ func (ps *PubSub[K, T]) Unsubscribe(keys []K, ch chan T) {
 // The operation will block until all subscribers receive the message or until:
 // - The context is canceled
 // - The timeout expires (if context has a deadline)
-// Returns the number of successful deliveries and any context error encountered.
+// Returns the number of successful deliveries and, if the context ended
+// first, a *DeliveryError[K] wrapping the context error.
+// With a drop policy other than Block, full channels are handled by the
+// policy instead of blocking.
+// If an authorizer is set and denies the publish, its error is returned.
+// Publishing to a draining or closed instance fails with ErrClosed.
+//
+// Publish doesn't allocate when the message is delivered or dropped,
+// including with handlers, taps and key statistics, unless a logger is
+// set. Retention, the last delivery of a SubscribeFor subscription and
+// errors allocate, as do converters, validators and cloners that do.
 func (ps *PubSub[K, T]) Publish(ctx context.Context, key K, msg T) (int, error) {
+	return ps.publish(ctx, key, msg, false)
+}
+
+// MustPublish is like Publish, but fails with ErrNoSubscribers if the key
+// has no subscribers, for command-style keys where a message nobody
+// listens to is an error. It doesn't panic.
+func (ps *PubSub[K, T]) MustPublish(ctx context.Context, key K, msg T) (int, error) {
+	return ps.publish(ctx, key, msg, true)
+}
+
+// publish implements Publish; if required is set, a key without
+// subscribers is an error.
+func (ps *PubSub[K, T]) publish(ctx context.Context, key K, msg T, required bool) (int, error) {
+	key, msg, handlers, err := ps.prepare(ctx, key, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	var sum uint64
+	if debugMutations {
+		sum = checksum(msg)
+	}
+
+	tr := ps.trace(key, msg)
+	delivered, err := ps.fanout(ctx, key, msg, required && len(handlers) == 0, tr)
+	if debugMutations {
+		ps.checkMutation(key, msg, &sum, "subscriber")
+	}
+	if err != nil || len(handlers) == 0 {
+		tr.end(err)
+		return delivered, err
+	}
+
+	n, err := ps.callConfirm(ctx, key, msg, handlers, tr.handler())
+	tr.end(err)
+
+	return delivered + n, err
+}
+
+// prepare resolves the key if it is an alias, authorizes, converts and
+// validates a message to publish, and returns them with the handlers of
+// the key. The hooks are read under a single read lock, which matters to
+// concurrent publishers.
+func (ps *PubSub[K, T]) prepare(ctx context.Context, key K, msg T) (K, T, []*handler[K, T], error) {
+	ps.mu.RLock()
+	key, firstUse := ps.resolvePublish(key)
+	a, c, v, handlers := ps.authorizer, ps.converter, ps.validation, ps.handlers[key]
+	kc := ps.keyConfig(key)
+	ps.mu.RUnlock()
+
+	if firstUse != nil {
+		ps.aliasUsed(firstUse, key)
+	}
+
+	if a != nil {
+		if err := a.Authorize(ctx, ActionPublish, key); err != nil {
+			ps.opts.logger.Warn("pubsub: publish denied", "key", key, "error", err)
+			return key, msg, nil, err
+		}
+	}
+
+	if !ps.allowPublish(kc) {
+		ps.opts.logger.Warn("pubsub: publish rate exceeded", "key", key)
+		return key, msg, nil, ErrRateLimited
+	}
+
+	msg, err := ps.convert(c, key, msg)
+	if err != nil {
+		return key, msg, nil, err
+	}
+
+	return key, msg, handlers, ps.validate(ctx, v, key, msg)
+}
+
+// fanout delivers an authorized and valid message to the subscribers of
+// the key, recording the deliveries in the trace, if not nil.
+func (ps *PubSub[K, T]) fanout(ctx context.Context, key K, msg T, required bool, tr *trace[K, T]) (int, error) {
+	ps.taps.call(key, msg)
+
+	delivered, dropped, spent, err := ps.broadcast(ctx, key, msg, required, tr.channel())
+	if !errors.Is(err, ErrClosed) {
+		ps.recordPublish(key, delivered, dropped)
+	}
+
+	if len(spent) > 0 {
+		ps.removeSpent(spent)
+	}
+
+	return delivered, err
+}
+
+// broadcast sends the message to the subscribed channels under the read
+// lock and returns the number of deliveries and of failed ones. It also
+// returns the limited subscriptions that received their last message, for
+// the caller to remove once the lock is released. confirm, if not nil, is
+// called with the outcome of each delivery.
+func (ps *PubSub[K, T]) broadcast(ctx context.Context, key K, msg T, required bool,
+	confirm func(ch chan T, o Outcome, err error),
+) (delivered, dropped int, spent []*limit[K, T], err error) {
 	ps.mu.RLock()
 	defer ps.mu.RUnlock()
 
+	if ps.state != stateOpen {
+		return 0, 0, nil, ErrClosed
+	}
+
+	ps.retain(key, msg)
+
 	subs, exists := ps.subscribers[key]
-	if !exists {
-		return 0, nil
+	var matched []chan T
+	if ps.patterns.n > 0 {
+		matched = ps.matchPatterns(key, subs.channels())
+	}
+	if !exists && len(matched) == 0 {
+		if required {
+			return 0, 0, nil, ErrNoSubscribers
+		}
+		return 0, 0, nil, nil
+	}
+
+	t := tally[K, T]{confirm: confirm, start: ps.latencyStart()}
+	switch {
+	case !exists: // only pattern subscribers
+	case subs.one != nil: // fast path of the common single subscriber
+		err = ps.sendTo(ctx, key, subs.one, msg, 1, &t)
+	case subs.tiers != nil:
+		for _, ch := range subs.tiers {
+			if err = ps.sendTo(ctx, key, ch, msg, len(subs.tiers), &t); err != nil {
+				break
+			}
+		}
+	case ps.concurrent(key):
+		err = ps.sendConcurrently(ctx, key, subs.refs, msg, &t)
+	default:
+		for ch := range subs.refs {
+			if err = ps.sendTo(ctx, key, ch, msg, len(subs.refs), &t); err != nil {
+				break
+			}
+		}
+	}
+
+	for _, ch := range matched {
+		if err != nil {
+			break
+		}
+		err = ps.sendTo(ctx, key, ch, msg, len(subs.channels())+len(matched), &t)
+	}
+
+	return t.delivered, t.dropped, t.spent, err
+}
+
+// tally counts the deliveries of a broadcast.
+type tally[K comparable, T any] struct {
+	delivered int
+	dropped   int
+	spent     []*limit[K, T]                        // limited subscriptions that got their last message
+	confirm   func(ch chan T, o Outcome, err error) // set by PublishAsync and tracing
+	start     time.Time                             // of the fan-out, if latencies are sampled
+}
+
+// sendTo delivers the message to a channel among the given number of
+// subscribers of the key and counts it. It returns a *DeliveryError[K] if
+// the context ended first. The caller must hold the read lock.
+func (ps *PubSub[K, T]) sendTo(ctx context.Context, key K, ch chan T, msg T, subscribers int, t *tally[K, T]) error {
+	var l *limit[K, T]
+	if len(ps.limits) > 0 {
+		l = ps.limits[ch]
+	}
+
+	taken, last := l.take()
+	if !taken {
+		return nil
+	}
+
+	ok, err := ps.deliver(ctx, ch, msg, ps.dropPolicy(key))
+	if err != nil {
+		l.giveBack()
+		t.dropped++
+		if t.confirm != nil {
+			t.confirm(ch, TimedOut, err)
+		}
+		ps.opts.logger.Warn("pubsub: slow subscriber, publish aborted",
+			"key", key, "delivered", t.delivered, "subscribers", subscribers, "error", err)
+		return &DeliveryError[K]{
+			Key:         key,
+			Delivered:   t.delivered,
+			Subscribers: subscribers,
+			Err:         &SlowConsumerError{Subscriber: ch, Err: err},
+		}
+	}
+
+	switch {
+	case !ok:
+		l.giveBack()
+		t.dropped++
+		if ps.logging() {
+			ps.opts.logger.Warn("pubsub: message dropped", "key", key)
+		}
+	case last:
+		t.spent = append(t.spent, l)
+		fallthrough
+	default:
+		t.delivered++
+		ps.observeSince(key, t.start)
+	}
+
+	if t.confirm != nil {
+		if ok {
+			t.confirm(ch, Delivered, nil)
+		} else {
+			t.confirm(ch, Dropped, nil)
+		}
 	}
 
-	var delivered int
-	for ch := range subs {
+	return nil
+}
+
+// deliver sends the message to the channel according to the drop policy,
+// or buffers it if the channel is paused. It reports whether the message
+// was delivered or buffered. The caller must hold the read lock.
+func (ps *PubSub[K, T]) deliver(ctx context.Context, ch chan T, msg T, policy DropPolicy) (bool, error) {
+	if ps.cloner != nil {
+		msg = ps.cloner(msg)
+	}
+
+	if len(ps.paused) > 0 {
+		if b, paused := ps.paused[ch]; paused {
+			return b.add(msg), nil
+		}
+	}
+
+	if len(ps.managed) == 0 {
+		return ps.send(ctx, ch, msg, policy)
+	}
+
+	if len(ps.credited) > 0 {
+		if c, credited := ps.credited[ch]; credited {
+			return ps.deliverCredited(ctx, c, ch, msg, policy)
+		}
+	}
+
+	sub, managed := ps.managed[ch]
+	if !managed {
+		return ps.send(ctx, ch, msg, policy)
+	}
+
+	start := ps.opts.clock.Now()
+	ok, err := ps.send(ctx, ch, msg, policy)
+	sub.stats.record(ok, len(ch), ps.Size(msg), ps.opts.clock.Now().Sub(start))
+
+	return ok, err
+}
+
+// send delivers the message to a single channel according to the drop
+// policy. It reports whether the message was delivered.
+func (ps *PubSub[K, T]) send(ctx context.Context, ch chan T, msg T, policy DropPolicy) (bool, error) {
+	switch policy {
+	case DropNewest:
 		select {
 		case ch <- msg:
-			delivered++
+			return true, nil
+		default:
+			return false, nil
+		}
+
+	case DropOldest:
+		for range 2 {
+			select {
+			case ch <- msg:
+				return true, nil
+			default:
+			}
+
+			select {
+			case <-ch: // make room
+			default:
+			}
+		}
+		return false, nil
+
+	default:
+		select {
+		case ch <- msg:
+			return true, nil
 		case <-ctx.Done():
-			return delivered, ctx.Err()
+			return false, contextErr(ctx)
+		case <-ps.stopping:
+			return false, ErrClosed
 		}
 	}
-
-	return delivered, nil
 }
 
 // PublishWithTimeout is a convenience method that creates a context with timeout.
+// The timeout is measured by the configured clock.
 func (ps *PubSub[K, T]) PublishWithTimeout(key K, msg T, timeout time.Duration) (int, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := ps.withTimeout(context.Background(), timeout)
 	defer cancel()
 
 	return ps.Publish(ctx, key, msg)
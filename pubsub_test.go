@@ -2,6 +2,7 @@ package pubsub_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -34,7 +35,7 @@ func TestPublishWithContext(t *testing.T) {
 		cancel() // cancel immediately
 
 		delivered, err := ps.Publish(ctx, "topic", "msg")
-		if err != context.Canceled {
+		if !errors.Is(err, context.Canceled) {
 			t.Errorf("expected context.Canceled, got %v", err)
 		}
 		if delivered != 0 {
@@ -47,7 +48,7 @@ func TestPublishWithContext(t *testing.T) {
 		defer cancel()
 
 		delivered, err := ps.Publish(ctx, "topic", "msg")
-		if err != context.DeadlineExceeded {
+		if !errors.Is(err, context.DeadlineExceeded) {
 			t.Errorf("expected DeadlineExceeded, got %v", err)
 		}
 		if delivered != 0 {
@@ -62,10 +63,103 @@ func TestPublishWithTimeout(t *testing.T) {
 	ps.Subscribe([]string{"topic"}, ch)
 
 	delivered, err := ps.PublishWithTimeout("topic", "msg", 10*time.Millisecond)
-	if err != context.DeadlineExceeded {
+	if !errors.Is(err, context.DeadlineExceeded) {
 		t.Errorf("expected DeadlineExceeded, got %v", err)
 	}
 	if delivered != 0 {
 		t.Errorf("expected 0 deliveries, got %d", delivered)
 	}
 }
+
+func TestUnsubscribeAndDrain(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"k"}, ch)
+	ch <- 0 // full buffer
+
+	published := make(chan error)
+	go func() {
+		_, err := ps.Publish(context.Background(), "k", 1) // blocks
+		published <- err
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	ps.UnsubscribeAndDrain([]string{"k"}, ch)
+
+	if err := <-published; err != nil {
+		t.Errorf("unexpected publish error: %v", err)
+	}
+	if len(ch) != 0 {
+		t.Errorf("expected an empty buffer, got %d messages", len(ch))
+	}
+	if n, _ := ps.Publish(context.Background(), "k", 2); n != 0 {
+		t.Errorf("expected no deliveries after unsubscribe, got %d", n)
+	}
+}
+
+func TestSubscribeRef(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	keys := []string{"k"}
+
+	ps.SubscribeRef(keys, ch)
+	ps.SubscribeRef(keys, ch)
+	ps.Subscribe(keys, ch) // no-op for an existing subscription
+
+	ps.Unsubscribe(keys, ch)
+	if n, _ := ps.Publish(context.Background(), "k", 1); n != 1 {
+		t.Fatalf("expected the subscription to survive one unsubscribe, got %d deliveries", n)
+	}
+	<-ch
+
+	ps.Unsubscribe(keys, ch)
+	if n, _ := ps.Publish(context.Background(), "k", 2); n != 0 {
+		t.Errorf("expected no deliveries after the last unsubscribe, got %d", n)
+	}
+}
+
+func TestSingleSubscriberTransitions(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	a, b := make(chan int, 10), make(chan int, 10)
+
+	ps.Subscribe([]string{"k"}, a)
+	ps.Publish(context.Background(), "k", 1)
+	ps.Subscribe([]string{"k"}, b)
+	ps.Publish(context.Background(), "k", 2)
+	ps.Unsubscribe([]string{"k"}, a)
+	ps.Publish(context.Background(), "k", 3)
+
+	if len(a) != 2 || len(b) != 2 {
+		t.Fatalf("expected 2 messages on each channel, got %d and %d", len(a), len(b))
+	}
+	if <-b != 2 || <-b != 3 {
+		t.Error("expected the remaining subscriber to get the later messages")
+	}
+}
+
+func TestUnsubscribeAll(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	client, other := make(chan int, 10), make(chan int, 10)
+	ps.Subscribe([]string{"a", "b"}, client)
+	ps.SubscribeRef([]string{"b"}, client)
+	ps.Subscribe([]string{"b"}, other)
+	done, _ := ps.SubscribeFor([]string{"c"}, client, pubsub.WithLimit(5))
+
+	ps.UnsubscribeAll(client)
+
+	if keys := ps.KeysOf(client); len(keys) != 0 {
+		t.Errorf("expected no keys left, got %v", keys)
+	}
+	if keys := ps.Keys(); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("expected only the key of the other channel, got %v", keys)
+	}
+	select {
+	case <-done:
+	default:
+		t.Error("expected the limited subscription to end")
+	}
+
+	if n, _ := ps.Publish(context.Background(), "b", 1); n != 1 || len(client) != 0 {
+		t.Errorf("expected delivery to the other channel only, got %d", n)
+	}
+}
@@ -0,0 +1,180 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrQuorum is matched by errors.Is for a *QuorumError.
+var ErrQuorum = errors.New("pubsub: quorum not reached")
+
+// QuorumError is returned by PublishQuorum when not enough subscribers
+// could take the message before the context ended. The message was not
+// delivered to any of them.
+type QuorumError[K comparable] struct {
+	Key   K
+	Need  int   // subscribers required
+	Ready int   // subscribers ready at the last check
+	Err   error // context error
+}
+
+func (e *QuorumError[K]) Error() string {
+	return fmt.Sprintf("pubsub: quorum of %d subscribers of %v not reached, %d ready: %v",
+		e.Need, e.Key, e.Ready, e.Err)
+}
+
+func (e *QuorumError[K]) Unwrap() []error {
+	return []error{ErrQuorum, e.Err}
+}
+
+// Quorum is the number of subscribers required by PublishQuorum.
+type Quorum struct {
+	n       int
+	percent float64
+}
+
+// AtLeast requires at least n subscribers.
+func AtLeast(n int) Quorum {
+	return Quorum{n: max(n, 1)}
+}
+
+// Percent requires at least the percentage of the subscribers of the key,
+// rounded up, and at least one.
+func Percent(p float64) Quorum {
+	return Quorum{percent: min(max(p, 0), 100)}
+}
+
+// need returns the number of subscribers required out of the total.
+func (q Quorum) need(subscribers int) int {
+	if q.n > 0 {
+		return q.n
+	}
+
+	return max(int(math.Ceil(q.percent*float64(subscribers)/100)), 1)
+}
+
+// quorumPoll is how often PublishQuorum checks the subscribers again.
+const quorumPoll = drainPoll
+
+// PublishQuorum is like Publish, but delivers the message only once a
+// quorum of the subscribed channels can take it without blocking, for
+// replication where best-effort delivery counts are not enough. Until
+// then it checks again periodically; if the context ends first, it
+// returns a *QuorumError[K] and the message is delivered to nobody, so
+// there is nothing to roll back. Channels of the quorum receive the
+// message at once; the others as by Publish, and PublishQuorum then
+// returns the number of deliveries.
+//
+// Publishes to the key and subscription changes wait while the quorum
+// receives the message; the other channels are sent to as by Publish,
+// concurrently with them. Handlers registered with SubscribeFunc don't
+// count toward the quorum and are called after the deliveries.
+func (ps *PubSub[K, T]) PublishQuorum(ctx context.Context, key K, msg T, q Quorum) (int, error) {
+	key, msg, handlers, err := ps.prepare(ctx, key, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		delivered, need, ready, err := ps.quorum(ctx, key, msg, q)
+		if ready >= need {
+			if err != nil || len(handlers) == 0 {
+				return delivered, err
+			}
+
+			n, err := ps.call(ctx, key, msg, handlers)
+			return delivered + n, err
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		elapsed := make(chan struct{})
+		timer := ps.opts.clock.AfterFunc(quorumPoll, func() { close(elapsed) })
+
+		select {
+		case <-elapsed:
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, &QuorumError[K]{Key: key, Need: need, Ready: ready, Err: contextErr(ctx)}
+		}
+	}
+}
+
+// quorum delivers the message if enough subscribers are ready, and
+// returns the number of deliveries, of subscribers needed and ready.
+// Nobody else can send to the channels while the lock is held, so the
+// subscribers found ready can't block. The others are sent to after the
+// lock is released, so that they block only this publish.
+func (ps *PubSub[K, T]) quorum(ctx context.Context, key K, msg T, q Quorum) (delivered, need, ready int, err error) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+
+	if ps.state != stateOpen {
+		ps.mu.Unlock()
+		return 0, 1, 0, ErrClosed
+	}
+
+	subs, n := ps.targets(key)
+	need = q.need(n)
+
+	var quorum, waiting []chan T
+	for ch := range subs {
+		if ps.room(ch) > 0 {
+			quorum = append(quorum, ch)
+		} else {
+			waiting = append(waiting, ch)
+		}
+	}
+
+	if ready = len(quorum); ready < need {
+		ps.mu.Unlock()
+		return 0, need, ready, nil
+	}
+
+	ps.retain(key, msg)
+
+	var t tally[K, T]
+	for _, ch := range quorum {
+		ps.sendTo(ctx, key, ch, msg, n, &t)
+	}
+	ps.mu.Unlock()
+
+	ps.taps.call(key, msg)
+	if len(waiting) > 0 {
+		err = ps.sendWaiting(ctx, key, msg, waiting, n, &t)
+	}
+
+	if len(t.spent) > 0 {
+		ps.removeSpent(t.spent)
+	}
+	ps.recordPublish(key, t.delivered, t.dropped)
+
+	return t.delivered, need, ready, err
+}
+
+// sendWaiting delivers the message to the channels that were not ready
+// for the quorum, among the given number of subscribers, skipping those
+// unsubscribed meanwhile.
+func (ps *PubSub[K, T]) sendWaiting(ctx context.Context, key K, msg T, waiting []chan T, subscribers int, t *tally[K, T]) error {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if ps.state != stateOpen {
+		return nil
+	}
+
+	for _, ch := range waiting {
+		if !ps.isTarget(key, ch) {
+			continue
+		}
+		if err := ps.sendTo(ctx, key, ch, msg, subscribers, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
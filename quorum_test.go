@@ -0,0 +1,105 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestPublishQuorum(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ready, full := make(chan int, 1), make(chan int, 1)
+	ps.Subscribe([]string{"log"}, ready)
+	ps.Subscribe([]string{"log"}, full)
+	full <- 0
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-full
+	}()
+
+	n, err := ps.PublishQuorum(context.Background(), "log", 1, pubsub.AtLeast(1))
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 deliveries, got %d, %v", n, err)
+	}
+	if <-ready != 1 || <-full != 1 {
+		t.Error("expected both channels to get the message")
+	}
+}
+
+func TestPublishQuorumWaits(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	replicas := []chan int{make(chan int, 1), make(chan int, 1), make(chan int, 1)}
+	for _, ch := range replicas {
+		ps.Subscribe([]string{"log"}, ch)
+		ch <- 0
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-replicas[0]
+		<-replicas[1]
+		time.Sleep(10 * time.Millisecond)
+		<-replicas[2]
+	}()
+
+	n, err := ps.PublishQuorum(context.Background(), "log", 1, pubsub.Percent(50))
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 deliveries after a quorum of 2, got %d, %v", n, err)
+	}
+}
+
+func TestPublishQuorumError(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ready, full := make(chan int, 1), make(chan int, 1)
+	ps.Subscribe([]string{"log"}, ready)
+	ps.Subscribe([]string{"log"}, full)
+	full <- 0
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	n, err := ps.PublishQuorum(ctx, "log", 1, pubsub.Percent(100))
+	var qerr *pubsub.QuorumError[string]
+	if !errors.As(err, &qerr) || !errors.Is(err, pubsub.ErrQuorum) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a quorum error, got %v", err)
+	}
+	if n != 0 || qerr.Need != 2 || qerr.Ready != 1 {
+		t.Errorf("expected 0 deliveries with 1 of 2 ready, got %d, %+v", n, qerr)
+	}
+	if len(ready) != 0 {
+		t.Error("expected no delivery without a quorum")
+	}
+}
+
+func TestPublishQuorumSlowSubscriber(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ready, full, other := make(chan int, 1), make(chan int, 1), make(chan int, 1)
+	ps.Subscribe([]string{"log"}, ready)
+	ps.Subscribe([]string{"log"}, full)
+	ps.Subscribe([]string{"other"}, other)
+	full <- 0
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ps.PublishQuorum(context.Background(), "log", 1, pubsub.AtLeast(1))
+	}()
+
+	select {
+	case <-ready:
+	case <-time.After(time.Second):
+		t.Fatal("expected the quorum to get the message")
+	}
+
+	// The publish blocks on the full channel without blocking the others.
+	if n, err := ps.PublishWithTimeout("other", 1, time.Second); err != nil || n != 1 {
+		t.Errorf("expected a delivery to another key, got %d, %v", n, err)
+	}
+
+	<-full
+	<-done
+}
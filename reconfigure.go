@@ -0,0 +1,198 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotReconfigurable is returned by Reconfigure for options that can
+// only be set by New.
+var ErrNotReconfigurable = errors.New("pubsub: option can't be changed at runtime")
+
+// Settings are the global settings that Reconfigure can change.
+type Settings struct {
+	BufferSize     int
+	DropPolicy     DropPolicy
+	MemoryBudget   int // messages, zero if unlimited
+	Eviction       EvictionPolicy
+	ByteBudget     int           // bytes, zero if unlimited
+	Retention      int           // messages per key, zero if disabled
+	RetentionAge   time.Duration // zero if unlimited
+	Concurrency    int           // delivery goroutines, zero if unlimited
+	KeyConcurrency int           // delivery goroutines per key, zero if unlimited
+}
+
+// settings returns the settings of the options.
+func (o *options) settings() Settings {
+	return Settings{
+		BufferSize:     o.bufferSize,
+		DropPolicy:     o.dropPolicy,
+		MemoryBudget:   o.budget,
+		Eviction:       o.eviction,
+		ByteBudget:     o.byteBudget,
+		Retention:      o.retention,
+		RetentionAge:   o.retentionAge,
+		Concurrency:    o.concurrency,
+		KeyConcurrency: o.keyConcurrency,
+	}
+}
+
+// Settings returns the current global settings.
+func (ps *PubSub[K, T]) Settings() Settings {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return ps.opts.settings()
+}
+
+// Reconfigure applies options to a running instance atomically, for
+// configuration reloads: publishes see either all the old or all the new
+// settings. The buffer size, drop policy, memory and byte budgets,
+// retention and delivery concurrency can be changed; they apply to
+// publishes and channels created afterwards, and overrides set by
+// ConfigureKey keep precedence. Options that can only be set by New,
+// such as the logger, clock, metrics, shards, Sizer, Cloner or tracer,
+// fail with ErrNotReconfigurable, and invalid values with an error; then
+// nothing is changed. Values follow the rules of New: the options count
+// negative sizes, budgets, retention and concurrency as zero, and every
+// resulting setting is checked again before it is applied.
+//
+// Shrinking a budget or retention doesn't discard messages already held:
+// they are evicted as new messages arrive. After the change, the watchers
+// registered with WatchSettings are called with the old and new settings.
+func (ps *PubSub[K, T]) Reconfigure(opts ...Option) error {
+	// Options setting anything outside Settings are rejected.
+	var only options
+	for _, opt := range opts {
+		opt(&only)
+	}
+	if only.logger != nil || only.clock != nil || only.offsets != nil ||
+		only.sizer != nil || only.cloner != nil || only.tracer != nil ||
+		only.mutations != nil || only.metrics != nil || only.shards != 0 || only.keyStats || only.latencySamples != 0 {
+		return ErrNotReconfigurable
+	}
+
+	ps.settingsWatch.reconfigure.Lock()
+	defer ps.settingsWatch.reconfigure.Unlock()
+
+	ps.mu.Lock()
+	next := ps.opts
+	for _, opt := range opts {
+		opt(&next)
+	}
+
+	if err := next.validate(); err != nil {
+		ps.mu.Unlock()
+		return err
+	}
+
+	old := ps.opts.settings()
+
+	// Budgets and retention are also read under the budget and history
+	// locks, without the main lock.
+	for i := range ps.history {
+		ps.history[i].mu.Lock()
+	}
+	ps.budget.mu.Lock()
+	ps.opts.bufferSize = next.bufferSize
+	ps.opts.dropPolicy = next.dropPolicy
+	ps.opts.budget = next.budget
+	ps.opts.eviction = next.eviction
+	ps.opts.byteBudget = next.byteBudget
+	ps.opts.retention = next.retention
+	ps.opts.retentionAge = next.retentionAge
+	ps.opts.concurrency = next.concurrency
+	ps.opts.keyConcurrency = next.keyConcurrency
+	ps.budget.mu.Unlock()
+	for i := range ps.history {
+		ps.history[i].mu.Unlock()
+	}
+
+	current := ps.opts.settings()
+	ps.mu.Unlock()
+
+	ps.opts.logger.Debug("pubsub: reconfigured", "settings", current)
+	ps.settingsWatch.notify(old, current)
+
+	return nil
+}
+
+// validate checks the values of the reconfigurable options.
+func (o *options) validate() error {
+	if o.dropPolicy < Block || o.dropPolicy > DropOldest {
+		return fmt.Errorf("pubsub: invalid drop policy %d", o.dropPolicy)
+	}
+
+	if o.eviction < EvictOldest || o.eviction > RejectNew {
+		return fmt.Errorf("pubsub: invalid eviction policy %d", o.eviction)
+	}
+
+	for _, v := range []struct {
+		name  string
+		value int64
+	}{
+		{"buffer size", int64(o.bufferSize)},
+		{"memory budget", int64(o.budget)},
+		{"byte budget", int64(o.byteBudget)},
+		{"retention", int64(o.retention)},
+		{"retention age", int64(o.retentionAge)},
+		{"delivery concurrency", int64(o.concurrency)},
+		{"key delivery concurrency", int64(o.keyConcurrency)},
+	} {
+		if v.value < 0 {
+			return fmt.Errorf("pubsub: invalid %s %d", v.name, v.value)
+		}
+	}
+
+	return nil
+}
+
+// settingsWatch holds the watchers of Reconfigure.
+type settingsWatch struct {
+	reconfigure sync.Mutex // serializes Reconfigure, so events are in order
+
+	mu       sync.Mutex
+	watchers map[int]func(old, new Settings)
+	nextID   int
+}
+
+// WatchSettings calls fn after every successful Reconfigure with the old
+// and new settings, for example to log configuration reloads. Calls are
+// made one at a time, in order, by the goroutine calling Reconfigure. The
+// returned function stops the watching.
+func (ps *PubSub[K, T]) WatchSettings(fn func(old, new Settings)) (stop func()) {
+	w := &ps.settingsWatch
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.watchers == nil {
+		w.watchers = make(map[int]func(old, new Settings))
+	}
+
+	id := w.nextID
+	w.nextID++
+	w.watchers[id] = fn
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.watchers, id)
+	}
+}
+
+// notify calls the watchers.
+func (w *settingsWatch) notify(old, current Settings) {
+	w.mu.Lock()
+	watchers := make([]func(old, new Settings), 0, len(w.watchers))
+	for _, fn := range w.watchers {
+		watchers = append(watchers, fn)
+	}
+	w.mu.Unlock()
+
+	for _, fn := range watchers {
+		fn(old, current)
+	}
+}
@@ -0,0 +1,109 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestReconfigure(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(1))
+	ch := make(chan int)
+	ps.Subscribe([]string{"k"}, ch)
+
+	var events []pubsub.Settings
+	ps.WatchSettings(func(old, new pubsub.Settings) {
+		events = append(events, old, new)
+	})
+
+	err := ps.Reconfigure(pubsub.WithDropPolicy(pubsub.DropNewest), pubsub.WithRetention(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for i := range 3 {
+		if n, err := ps.Publish(ctx, "k", i); n != 0 || err != nil {
+			t.Fatalf("expected the message dropped without blocking, got %d, %v", n, err)
+		}
+	}
+
+	if got := len(ps.Retained("k")); got != 3 {
+		t.Errorf("expected 3 retained messages, got %d", got)
+	}
+
+	if len(events) != 2 || events[0].DropPolicy != pubsub.Block || events[1].DropPolicy != pubsub.DropNewest ||
+		events[1].Retention != 3 || ps.Settings() != events[1] {
+		t.Errorf("expected the change reported, got %+v", events)
+	}
+}
+
+func TestReconfigureRejected(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(4))
+	ps.WatchSettings(func(old, new pubsub.Settings) {
+		t.Error("unexpected settings event")
+	})
+
+	err := ps.Reconfigure(pubsub.WithBufferSize(8), pubsub.WithLogger(slog.Default()))
+	if !errors.Is(err, pubsub.ErrNotReconfigurable) {
+		t.Errorf("expected ErrNotReconfigurable, got %v", err)
+	}
+
+	if err := ps.Reconfigure(pubsub.WithBufferSize(8), pubsub.WithDropPolicy(pubsub.DropPolicy(9))); err == nil {
+		t.Error("expected an invalid drop policy to fail")
+	}
+
+	if got := ps.Settings().BufferSize; got != 4 {
+		t.Errorf("expected the settings unchanged, got buffer size %d", got)
+	}
+}
+
+func TestReconfigureNegative(t *testing.T) {
+	opts := []pubsub.Option{
+		pubsub.WithBufferSize(-1),
+		pubsub.WithMemoryBudget(-1, pubsub.EvictOldest),
+		pubsub.WithByteBudget(-1),
+		pubsub.WithRetention(-1),
+		pubsub.WithRetentionAge(-time.Second),
+		pubsub.WithDeliveryConcurrency(-1, -1),
+	}
+
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(4), pubsub.WithRetention(2))
+	if err := ps.Reconfigure(opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same rules as New: negative values count as zero
+	want := pubsub.New[string, int](opts...).Settings()
+	if got := ps.Settings(); got != want {
+		t.Errorf("expected settings %+v, got %+v", want, got)
+	}
+}
+
+func TestReconfigureConcurrent(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithMemoryBudget(10, pubsub.EvictOldest), pubsub.WithRetention(5))
+	ch := make(chan int, 100)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.Pause(ch, 5)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 200 {
+			ps.Publish(context.Background(), "k", i)
+			ps.Retained("k")
+			ps.MemoryUsage()
+		}
+	}()
+
+	for i := range 50 {
+		ps.Reconfigure(pubsub.WithMemoryBudget(i%10+1, pubsub.EvictOldest), pubsub.WithRetention(i%5),
+			pubsub.WithRetentionAge(time.Duration(i)*time.Second), pubsub.WithDeliveryConcurrency(i%3, 0))
+	}
+	<-done
+}
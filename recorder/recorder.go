@@ -0,0 +1,146 @@
+// Package recorder is a flight recorder for PubSub traffic: it taps all
+// publishes into a bounded buffer with timestamps, saves them to a file
+// and replays them into a PubSub instance with the original or scaled
+// timing, so production event sequences can be reproduced locally.
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// Entry is a recorded publish.
+type Entry[K comparable, T any] struct {
+	Time time.Time `json:"time"`
+	Key  K         `json:"key"`
+	Msg  T         `json:"msg"`
+}
+
+// Recorder keeps the most recent publishes of a PubSub instance.
+type Recorder[K comparable, T any] struct {
+	now    func() time.Time
+	remove func()
+
+	mu      sync.Mutex
+	entries []Entry[K, T] // ring buffer
+	next    int           // index of the next entry to write
+	full    bool
+}
+
+// Start starts recording the publishes of ps, keeping the last size of
+// them; size is at least one. Publishes are recorded whether or not they
+// have subscribers, before delivery.
+func Start[K comparable, T any](ps *pubsub.PubSub[K, T], size int) *Recorder[K, T] {
+	r := &Recorder[K, T]{
+		now:     ps.Clock().Now,
+		entries: make([]Entry[K, T], max(size, 1)),
+	}
+	r.remove = ps.Tap(r.record)
+
+	return r
+}
+
+// record adds the publish to the buffer, overwriting the oldest entry
+// when full.
+func (r *Recorder[K, T]) record(key K, msg T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = Entry[K, T]{Time: r.now(), Key: key, Msg: msg}
+	r.next = (r.next + 1) % len(r.entries)
+	r.full = r.full || r.next == 0
+}
+
+// Stop stops recording. The recorded entries remain available.
+func (r *Recorder[K, T]) Stop() {
+	r.remove()
+}
+
+// Entries returns the recorded publishes, oldest first.
+func (r *Recorder[K, T]) Entries() []Entry[K, T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]Entry[K, T](nil), r.entries[:r.next]...)
+	}
+
+	return append(append([]Entry[K, T](nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+// Save writes the recorded publishes to w as JSON lines, oldest first.
+// Keys and messages must be encodable with encoding/json.
+func (r *Recorder[K, T]) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, e := range r.Entries() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Load reads entries written by Save.
+func Load[K comparable, T any](r io.Reader) ([]Entry[K, T], error) {
+	var entries []Entry[K, T]
+	dec := json.NewDecoder(r)
+	for {
+		var e Entry[K, T]
+		if err := dec.Decode(&e); err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+
+		entries = append(entries, e)
+	}
+}
+
+// Replay publishes the entries to ps in order. The delays between
+// entries are those between their recorded times divided by speed:
+// 1 replays in real time, 10 ten times faster. With a speed of zero or
+// less the entries are published without delay. Delays are measured by
+// the clock of ps. Replay stops at the first publish error or when the
+// context is canceled.
+func Replay[K comparable, T any](ctx context.Context, ps *pubsub.PubSub[K, T], entries []Entry[K, T], speed float64) error {
+	for i, e := range entries {
+		if i > 0 && speed > 0 {
+			delay := time.Duration(float64(e.Time.Sub(entries[i-1].Time)) / speed)
+			if err := sleep(ctx, ps.Clock(), delay); err != nil {
+				return err
+			}
+		}
+
+		if _, err := ps.Publish(ctx, e.Key, e.Msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sleep waits for the duration or until the context is canceled.
+func sleep(ctx context.Context, clock pubsub.Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	elapsed := make(chan struct{})
+	timer := clock.AfterFunc(d, func() { close(elapsed) })
+	defer timer.Stop()
+
+	select {
+	case <-elapsed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,83 @@
+package recorder_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub/pstest"
+	"github.com/mdigger/pubsub/recorder"
+)
+
+func TestRecordSaveLoad(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	rec := recorder.Start(ps, 2)
+
+	ctx := context.Background()
+	ps.Publish(ctx, "a", 1)
+	clock.Advance(time.Second)
+	ps.Publish(ctx, "b", 2)
+	clock.Advance(time.Second)
+	ps.Publish(ctx, "a", 3)
+	rec.Stop()
+	ps.Publish(ctx, "a", 4)
+
+	entries := rec.Entries()
+	if len(entries) != 2 || entries[0].Msg != 2 || entries[1].Msg != 3 {
+		t.Fatalf("expected the last two publishes, got %+v", entries)
+	}
+	if d := entries[1].Time.Sub(entries[0].Time); d != time.Second {
+		t.Errorf("expected entries a second apart, got %v", d)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := recorder.Load[string, int](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || loaded[1].Key != "a" || !loaded[1].Time.Equal(entries[1].Time) {
+		t.Errorf("unexpected loaded entries %+v", loaded)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	rec := pstest.Record(t, ps, "a", "b")
+
+	start := time.Unix(0, 0)
+	entries := []recorder.Entry[string, int]{
+		{Time: start, Key: "a", Msg: 1},
+		{Time: start.Add(time.Minute), Key: "b", Msg: 2},
+	}
+
+	done := make(chan error)
+	go func() { done <- recorder.Replay(context.Background(), ps, entries, 2) }()
+
+	rec.ExpectPublished("a", 1)
+	for clock.Timers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(29 * time.Second)
+	rec.ExpectNoMessage(10 * time.Millisecond)
+
+	clock.Advance(time.Second) // a minute at double speed
+	rec.ExpectPublished("b", 2)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]int, 0, 2)
+	for _, m := range rec.Messages() {
+		got = append(got, m.Msg)
+	}
+	if !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("unexpected replayed messages %v", got)
+	}
+}
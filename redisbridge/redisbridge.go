@@ -0,0 +1,283 @@
+// Package redisbridge mirrors PubSub keys to Redis Streams, so in-process
+// consumers and external services share durable streams. Messages
+// published to selected keys are appended to streams with XADD, and
+// entries read from streams with XREADGROUP as a member of a consumer
+// group are published to keys, acknowledged with XACK according to the
+// ack mode. Keys gaining their first local subscriber can be backfilled
+// with recent entries of their stream. Like kafkabridge, the package does
+// not depend on a Redis client: it works with any client implementing the
+// Client interface.
+package redisbridge
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/bridge"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Entry is a stream entry as seen by the bridge. The message is stored in
+// a single field of the entry.
+type Entry struct {
+	Stream string
+	ID     string
+	Data   []byte
+}
+
+// Client is the subset of Redis commands used by the bridge.
+type Client interface {
+	// XAdd appends an entry with the data to the stream.
+	XAdd(ctx context.Context, stream string, data []byte) (id string, err error)
+	// XReadGroup reads new entries of the streams as the consumer of the
+	// group, blocking until at least one is available or the context is
+	// done. The group is expected to exist.
+	XReadGroup(ctx context.Context, group, consumer string, streams ...string) ([]Entry, error)
+	// XAck acknowledges the entries of the stream for the group.
+	XAck(ctx context.Context, stream, group string, ids ...string) error
+}
+
+// History is implemented by clients that can read recent entries of a
+// stream, to backfill keys gaining their first local subscriber.
+type History interface {
+	// XRevRange returns up to count of the last entries of the stream,
+	// newest first.
+	XRevRange(ctx context.Context, stream string, count int) ([]Entry, error)
+}
+
+// AckMode defines when entries read from Redis are acknowledged.
+// Entries that are not acknowledged stay pending in the consumer group
+// and can be claimed again after a failure.
+type AckMode int
+
+const (
+	// AckOnRead acknowledges entries as soon as they are read: at most
+	// once delivery.
+	AckOnRead AckMode = iota
+	// AckOnPublish acknowledges an entry after it was published to all
+	// local subscribers of its key.
+	AckOnPublish
+	// AckOnDelivery is like AckOnPublish, but leaves entries of keys
+	// without local subscribers pending, see pubsub.MustPublish.
+	AckOnDelivery
+)
+
+// Bridge forwards messages between Redis Streams and a PubSub instance.
+//
+// Note: a key should not be both consumed and produced, otherwise
+// messages will loop between Redis and PubSub.
+type Bridge[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+	Client Client
+
+	// Stream returns the stream name of the key.
+	Stream func(K) string
+
+	// Produce lists the keys whose messages are appended to streams.
+	Produce []K
+
+	// Consume lists the keys whose streams are read as Consumer of Group.
+	Consume  []K
+	Group    string
+	Consumer string
+	Ack      AckMode
+
+	// Backfill, if positive and the Client implements History, is the
+	// number of recent entries of a consumed stream published to its key
+	// when the key gains its first local subscriber, before live entries.
+	Backfill int
+
+	// Codec converts messages; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Buffer is the capacity of the channels subscribed to Produce keys.
+	Buffer int
+
+	// OnError, if set, is called for entries that could not be decoded,
+	// encoded or added; they are skipped and the bridge continues.
+	// If OnError is nil, such errors stop the bridge.
+	OnError func(entry Entry, err error)
+}
+
+// Run starts forwarding in both configured directions and blocks until
+// the context is canceled or an unhandled error occurs.
+func (b *Bridge[K, T]) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var backfill *bridge.Backfill[K, T]
+	if history, ok := b.Client.(History); ok && b.Backfill > 0 && len(b.Consume) > 0 {
+		backfill = b.backfiller(history)
+		stop := backfill.Start(ctx)
+		defer stop()
+	}
+
+	var wg sync.WaitGroup
+	if len(b.Consume) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.consume(ctx, backfill))
+		}()
+	}
+
+	for _, key := range b.Produce {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cancel(b.produce(ctx, key))
+		}()
+	}
+
+	wg.Wait()
+
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// backfiller returns the backfill of the consumed keys from the history.
+func (b *Bridge[K, T]) backfiller(history History) *bridge.Backfill[K, T] {
+	consumed := make(map[K]bool, len(b.Consume))
+	for _, key := range b.Consume {
+		consumed[key] = true
+	}
+
+	return &bridge.Backfill[K, T]{
+		PubSub: b.PubSub,
+		Keys:   func(key K) bool { return consumed[key] },
+		Fetch: func(ctx context.Context, key K) ([]T, error) {
+			entries, err := history.XRevRange(ctx, b.Stream(key), b.Backfill)
+			if err != nil {
+				return nil, err
+			}
+
+			msgs := make([]T, 0, len(entries))
+			for i := len(entries) - 1; i >= 0; i-- {
+				msg, err := b.codec().Unmarshal(entries[i].Data)
+				if err != nil {
+					if b.OnError != nil {
+						b.OnError(entries[i], err)
+					}
+					continue
+				}
+
+				msgs = append(msgs, msg)
+			}
+
+			return msgs, nil
+		},
+	}
+}
+
+// consume reads entries from the streams and publishes them to PubSub,
+// after the backfill of their key, if any.
+func (b *Bridge[K, T]) consume(ctx context.Context, backfill *bridge.Backfill[K, T]) error {
+	keys := make(map[string]K, len(b.Consume))
+	streams := make([]string, 0, len(b.Consume))
+	for _, key := range b.Consume {
+		stream := b.Stream(key)
+		keys[stream] = key
+		streams = append(streams, stream)
+	}
+
+	for {
+		entries, err := b.Client.XReadGroup(ctx, b.Group, b.Consumer, streams...)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			key := keys[entry.Stream]
+			if err := backfill.Wait(ctx, key); err != nil {
+				return err
+			}
+
+			if err := b.forward(ctx, key, entry); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// forward publishes a single entry and acknowledges it as configured.
+func (b *Bridge[K, T]) forward(ctx context.Context, key K, entry Entry) error {
+	if b.Ack == AckOnRead {
+		if err := b.Client.XAck(ctx, entry.Stream, b.Group, entry.ID); err != nil {
+			return err
+		}
+	}
+
+	msg, err := b.codec().Unmarshal(entry.Data)
+	if err != nil {
+		if b.OnError == nil {
+			return err
+		}
+
+		b.OnError(entry, err)
+	} else {
+		publish := b.PubSub.Publish
+		if b.Ack == AckOnDelivery {
+			publish = b.PubSub.MustPublish
+		}
+
+		_, err := publish(ctx, key, msg)
+		if errors.Is(err, pubsub.ErrNoSubscribers) {
+			return nil // left pending
+		}
+
+		if err != nil {
+			return err // not acknowledged: stays pending
+		}
+	}
+
+	if b.Ack == AckOnRead {
+		return nil
+	}
+
+	return b.Client.XAck(ctx, entry.Stream, b.Group, entry.ID)
+}
+
+// produce subscribes to the key and appends its messages to the stream.
+func (b *Bridge[K, T]) produce(ctx context.Context, key K) error {
+	ch := make(chan T, b.Buffer)
+	keys := []K{key}
+	b.PubSub.Subscribe(keys, ch)
+	defer b.PubSub.UnsubscribeAndDrain(keys, ch)
+
+	stream := b.Stream(key)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-ch:
+			entry := Entry{Stream: stream}
+			data, err := b.codec().Marshal(msg)
+			if err == nil {
+				entry.Data = data
+				entry.ID, err = b.Client.XAdd(ctx, stream, data)
+			}
+
+			if err != nil {
+				if b.OnError == nil || ctx.Err() != nil {
+					return err
+				}
+
+				b.OnError(entry, err)
+			}
+		}
+	}
+}
+
+// codec returns the configured codec or the default one.
+func (b *Bridge[K, T]) codec() codec.Codec[T] {
+	if b.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return b.Codec
+}
@@ -0,0 +1,176 @@
+package redisbridge_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/redisbridge"
+)
+
+// fakeRedis keeps streams in memory and delivers new entries to one
+// consumer group.
+type fakeRedis struct {
+	mu      sync.Mutex
+	entries chan redisbridge.Entry
+	added   map[string][][]byte
+	acked   []string
+	next    int
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{entries: make(chan redisbridge.Entry, 10), added: make(map[string][][]byte)}
+}
+
+func (r *fakeRedis) XAdd(_ context.Context, stream string, data []byte) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.next++
+	r.added[stream] = append(r.added[stream], data)
+	return strconv.Itoa(r.next), nil
+}
+
+func (r *fakeRedis) XReadGroup(ctx context.Context, _, _ string, _ ...string) ([]redisbridge.Entry, error) {
+	select {
+	case e := <-r.entries:
+		return []redisbridge.Entry{e}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (r *fakeRedis) XAck(_ context.Context, _, _ string, ids ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.acked = append(r.acked, ids...)
+	return nil
+}
+
+func (r *fakeRedis) ackedIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.acked...)
+}
+
+func TestBridgeConsumeAck(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ch := make(chan int, 1)
+	ps.Subscribe([]string{"orders"}, ch)
+
+	redis := newFakeRedis()
+	bridge := &redisbridge.Bridge[string, int]{
+		PubSub:  ps,
+		Client:  redis,
+		Stream:  func(key string) string { return "stream:" + key },
+		Consume: []string{"orders", "idle"},
+		Group:   "svc",
+		Ack:     redisbridge.AckOnDelivery,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	redis.entries <- redisbridge.Entry{Stream: "stream:idle", ID: "1", Data: []byte("1")}
+	redis.entries <- redisbridge.Entry{Stream: "stream:orders", ID: "2", Data: []byte("42")}
+
+	select {
+	case got := <-ch:
+		if got != 42 {
+			t.Errorf("expected 42, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("entry not published")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(redis.ackedIDs()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if acked := redis.ackedIDs(); len(acked) != 1 || acked[0] != "2" {
+		t.Errorf("expected only the delivered entry acknowledged, got %v", acked)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBridgeProduce(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	redis := newFakeRedis()
+	bridge := &redisbridge.Bridge[string, int]{
+		PubSub:  ps,
+		Client:  redis,
+		Stream:  func(key string) string { return "stream:" + key },
+		Produce: []string{"orders"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- bridge.Run(ctx) }()
+
+	for len(ps.Keys()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ps.Publish(ctx, "orders", 7)
+
+	cancel()
+	<-done
+
+	redis.mu.Lock()
+	defer redis.mu.Unlock()
+	if got := redis.added["stream:orders"]; len(got) != 1 || string(got[0]) != "7" {
+		t.Errorf("expected the message added to the stream, got %q", got)
+	}
+}
+
+type historyRedis struct {
+	*fakeRedis
+}
+
+func (r historyRedis) XRevRange(_ context.Context, stream string, count int) ([]redisbridge.Entry, error) {
+	var entries []redisbridge.Entry
+	for i := count; i > 0; i-- {
+		entries = append(entries, redisbridge.Entry{Stream: stream, ID: strconv.Itoa(i), Data: []byte(strconv.Itoa(i))})
+	}
+	return entries, nil
+}
+
+func TestBridgeBackfill(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	redis := historyRedis{newFakeRedis()}
+	bridge := &redisbridge.Bridge[string, int]{
+		PubSub:   ps,
+		Client:   redis,
+		Stream:   func(key string) string { return key },
+		Consume:  []string{"orders"},
+		Backfill: 2,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bridge.Run(ctx)
+
+	ch := make(chan int, 10)
+	for range 100 { // wait for the bridge to watch keys
+		ps.Subscribe([]string{"orders"}, ch)
+		select {
+		case got := <-ch:
+			if got != 1 || <-ch != 2 {
+				t.Fatal("expected the history in order")
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+			ps.Unsubscribe([]string{"orders"}, ch)
+		}
+	}
+
+	t.Fatal("history not backfilled")
+}
@@ -0,0 +1,299 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// WithRetention keeps the last n messages published to each key, whether
+// or not the key has subscribers, so they can be read with Latest and
+// Retained or replayed to new subscribers. Retained messages count
+// against the memory budget. Zero disables retention.
+func WithRetention(n int) Option {
+	return func(o *options) {
+		o.retention = max(n, 0)
+	}
+}
+
+// WithRetentionAge discards retained messages older than the age, as
+// measured by the instance clock. Zero means no age limit.
+func WithRetentionAge(age time.Duration) Option {
+	return func(o *options) {
+		o.retentionAge = max(age, 0)
+	}
+}
+
+// Retained is a message kept by retention.
+type Retained[T any] struct {
+	Seq  uint64    // position in the key's stream, starting at 1
+	Time time.Time // publish time
+	Msg  T
+}
+
+// history holds the retained messages of the keys of a shard.
+type history[K comparable, T any] struct {
+	mu     sync.Mutex
+	keys   map[K]*stream[T]
+	closed bool // set by Close
+}
+
+// stream is the retained tail of a key's messages.
+type stream[T any] struct {
+	seq     uint64 // sequence number of the last message
+	entries []Retained[T]
+	wake    chan struct{} // closed when a message is retained
+	quota   *retainQuota  // of the tenant publishing to the key, if any
+}
+
+// retainQuota limits the bytes retained for the keys of a tenant. The
+// keys may be in different history shards, so it has its own lock.
+type retainQuota struct {
+	mu    sync.Mutex
+	max   int // bytes
+	bytes int // retained
+}
+
+// add counts n more retained bytes; n may be negative.
+func (q *retainQuota) add(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.bytes += n
+}
+
+// fits reports whether n more bytes can be retained.
+func (q *retainQuota) fits(n int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.bytes+n <= q.max
+}
+
+// reserve counts n more retained bytes if they fit, reporting whether
+// they did.
+func (q *retainQuota) reserve(n int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.bytes+n > q.max {
+		return false
+	}
+
+	q.bytes += n
+
+	return true
+}
+
+// streamBytes returns the size of the retained messages of the stream.
+func (ps *PubSub[K, T]) streamBytes(s *stream[T]) int {
+	n := 0
+	for _, e := range s.entries {
+		n += ps.Size(e.Msg)
+	}
+
+	return n
+}
+
+// checkRetainQuota assigns the quota to the key and reports whether the
+// message can be retained within it, once the older messages of the key
+// are discarded if needed. It is true if the key is not retained.
+func (ps *PubSub[K, T]) checkRetainQuota(key K, msg T, q *retainQuota) bool {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	if ps.retention(key) == 0 {
+		return true
+	}
+
+	h := ps.historyOf(key)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stream(key)
+	own := ps.streamBytes(s)
+	if s.quota != q {
+		if s.quota != nil {
+			s.quota.add(-own)
+		}
+		s.quota = q
+		q.add(own)
+	}
+
+	return q.fits(ps.Size(msg) - own)
+}
+
+// stream returns the stream of the key, creating it if needed. The caller
+// must hold the history lock.
+func (h *history[K, T]) stream(key K) *stream[T] {
+	s, ok := h.keys[key]
+	if !ok {
+		if h.keys == nil {
+			h.keys = make(map[K]*stream[T])
+		}
+
+		s = new(stream[T])
+		h.keys[key] = s
+	}
+
+	return s
+}
+
+// close wakes the consumers waiting for messages.
+func (h *history[K, T]) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, s := range h.keys {
+		if s.wake != nil {
+			close(s.wake)
+			s.wake = nil
+		}
+	}
+}
+
+// retain appends the message to the key's history, discarding entries
+// over the limits. The caller must hold the read lock, so retention and
+// delivery happen in the same order.
+func (ps *PubSub[K, T]) retain(key K, msg T) {
+	retention := ps.retention(key)
+	if retention == 0 {
+		return
+	}
+
+	h := ps.historyOf(key)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.stream(key)
+	s.seq++
+	now := ps.opts.clock.Now()
+	ps.expire(s, now)
+
+	for len(s.entries) >= retention {
+		ps.dropRetained(s)
+	}
+
+	size := ps.Size(msg)
+	if q := s.quota; q != nil {
+		for !q.fits(size) && len(s.entries) > 0 {
+			ps.dropRetained(s)
+		}
+		if !q.reserve(size) {
+			return
+		}
+	}
+
+	for !ps.budget.reserve(1, size, &ps.opts) {
+		if ps.opts.eviction != EvictOldest || len(s.entries) == 0 {
+			ps.budget.reject()
+			if s.quota != nil {
+				s.quota.add(-size)
+			}
+			return
+		}
+
+		ps.dropRetained(s)
+		ps.budget.evict()
+	}
+
+	s.entries = append(s.entries, Retained[T]{Seq: s.seq, Time: now, Msg: msg})
+	if s.wake != nil {
+		close(s.wake)
+		s.wake = nil
+	}
+}
+
+// dropRetained discards the oldest retained message of the stream.
+func (ps *PubSub[K, T]) dropRetained(s *stream[T]) {
+	size := ps.Size(s.entries[0].Msg)
+	ps.budget.release(1, size)
+	if s.quota != nil {
+		s.quota.add(-size)
+	}
+	clear(s.entries[:1])
+	s.entries = s.entries[1:]
+}
+
+// purgeRetained discards the retained messages of the key.
+func (ps *PubSub[K, T]) purgeRetained(key K) {
+	h := ps.historyOf(key)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s, ok := h.keys[key]; ok {
+		for len(s.entries) > 0 {
+			ps.dropRetained(s)
+		}
+	}
+}
+
+// expire discards retained messages older than the retention age.
+func (ps *PubSub[K, T]) expire(s *stream[T], now time.Time) {
+	if ps.opts.retentionAge == 0 {
+		return
+	}
+
+	for len(s.entries) > 0 && now.Sub(s.entries[0].Time) > ps.opts.retentionAge {
+		ps.dropRetained(s)
+	}
+}
+
+// retained returns a copy of the key's retained messages. The caller
+// must hold the lock of the history shard of the key.
+func (ps *PubSub[K, T]) retained(key K) []Retained[T] {
+	s, ok := ps.historyOf(key).keys[key]
+	if !ok {
+		return nil
+	}
+
+	ps.expire(s, ps.opts.clock.Now())
+
+	return append([]Retained[T](nil), s.entries...)
+}
+
+// Retained returns the retained messages of the key, oldest first.
+func (ps *PubSub[K, T]) Retained(key K) []Retained[T] {
+	key = ps.resolved(key)
+	h := ps.historyOf(key)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return ps.retained(key)
+}
+
+// Latest returns the last retained message of the key without
+// subscribing, for keys carrying state or configuration. It reports
+// false if no message is retained, for example when retention is off.
+func (ps *PubSub[K, T]) Latest(key K) (T, bool) {
+	key = ps.resolved(key)
+	h := ps.historyOf(key)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.keys[key]
+	if ok {
+		ps.expire(s, ps.opts.clock.Now())
+	}
+
+	if !ok || len(s.entries) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	return s.entries[len(s.entries)-1].Msg, true
+}
+
+// SubscribeWithReplay subscribes the channel to the key and returns the
+// messages retained for it, oldest first. Taking the snapshot and
+// subscribing happen under one lock that excludes publishers, so the
+// returned history and the messages later delivered to the channel
+// continue each other with no gap and no duplicates: the first message
+// received on the channel has the sequence number following the last
+// returned one. Handle the history before reading the channel to rebuild
+// state in order.
+//
+// It is SubscribeFrom with DeliverAll.
+func (ps *PubSub[K, T]) SubscribeWithReplay(key K, ch chan T) ([]Retained[T], error) {
+	return ps.SubscribeFrom(key, ch, DeliverAll)
+}
@@ -0,0 +1,146 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestLatest(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(2))
+	if _, ok := ps.Latest("config"); ok {
+		t.Error("expected no retained message before publishing")
+	}
+
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		ps.Publish(ctx, "config", i) // retained without subscribers
+	}
+
+	if got, ok := ps.Latest("config"); !ok || got != 3 {
+		t.Errorf("expected latest 3, got %d, %v", got, ok)
+	}
+
+	retained := ps.Retained("config")
+	if len(retained) != 2 || retained[0].Msg != 2 || retained[0].Seq != 2 || retained[1].Seq != 3 {
+		t.Errorf("expected the last two messages retained, got %+v", retained)
+	}
+
+	if got := ps.MemoryUsage().Messages; got != 2 {
+		t.Errorf("expected retained messages in the memory usage, got %d", got)
+	}
+}
+
+func TestLatestDisabled(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.Publish(context.Background(), "k", 1)
+
+	if _, ok := ps.Latest("k"); ok {
+		t.Error("expected nothing retained without WithRetention")
+	}
+}
+
+func TestRetentionAge(t *testing.T) {
+	ps, clock := pstest.New[string, int](pubsub.WithRetention(10), pubsub.WithRetentionAge(time.Minute))
+	ps.Publish(context.Background(), "k", 1)
+	clock.Advance(30 * time.Second)
+	ps.Publish(context.Background(), "k", 2)
+	clock.Advance(45 * time.Second)
+
+	if got := ps.Retained("k"); len(got) != 1 || got[0].Msg != 2 {
+		t.Errorf("expected the expired message discarded, got %+v", got)
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := ps.Latest("k"); ok {
+		t.Error("expected all messages expired")
+	}
+
+	if got := ps.MemoryUsage().Messages; got != 0 {
+		t.Errorf("expected expired messages released, got %d", got)
+	}
+}
+
+func TestRetentionBudget(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(10), pubsub.WithMemoryBudget(2, pubsub.EvictOldest))
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		ps.Publish(ctx, "k", i)
+	}
+
+	if got := ps.Retained("k"); len(got) != 2 || got[0].Msg != 2 {
+		t.Errorf("expected the oldest message evicted, got %+v", got)
+	}
+}
+
+func TestSubscribeWithReplay(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(1000))
+	ctx := context.Background()
+
+	const total = 500
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= total; i++ {
+			ps.Publish(ctx, "k", i)
+		}
+	}()
+
+	ch := make(chan int, total)
+	history, err := ps.SubscribeWithReplay("k", ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	next := 1
+	for _, r := range history {
+		if r.Msg != next {
+			t.Fatalf("expected replayed %d, got %d", next, r.Msg)
+		}
+		next++
+	}
+
+	for next <= total {
+		if msg := <-ch; msg != next {
+			t.Fatalf("expected live %d after %d replayed, got %d", next, len(history), msg)
+		}
+		next++
+	}
+
+	select {
+	case msg := <-ch:
+		t.Errorf("unexpected duplicate %d", msg)
+	default:
+	}
+}
+
+func TestPurgeKey(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(10), pubsub.WithMemoryBudget(10, pubsub.EvictOldest))
+	ctx := context.Background()
+	ch, other := make(chan int, 10), make(chan int, 10)
+	ps.SubscribeRef([]string{"k", "other"}, ch)
+	ps.SubscribeRef([]string{"k"}, ch)
+	ps.Subscribe([]string{"other"}, other)
+	ps.SubscribeFunc([]string{"k"}, func(context.Context, string, int) error { return nil })
+	ps.Publish(ctx, "k", 1)
+	ps.Publish(ctx, "k", 2)
+
+	ps.PurgeKey("k")
+
+	if n, _ := ps.Publish(ctx, "k", 3); n != 0 {
+		t.Errorf("expected no subscribers left, got %d deliveries", n)
+	}
+	if got := ps.Retained("k"); len(got) != 1 || got[0].Msg != 3 || got[0].Seq != 3 {
+		t.Errorf("expected only the new message retained, got %+v", got)
+	}
+	if usage := ps.MemoryUsage(); usage.Messages != 1 {
+		t.Errorf("expected the purged messages released, got %+v", usage)
+	}
+	if keys := ps.KeysOf(ch); len(keys) != 1 || keys[0] != "other" {
+		t.Errorf("expected the other key kept, got %v", keys)
+	}
+}
@@ -0,0 +1,199 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Group runs functions in goroutines. *errgroup.Group of
+// golang.org/x/sync implements it.
+type Group interface {
+	Go(fn func() error)
+}
+
+// Worker is a consumer of keys run by Run. Handle is called for each
+// message of the keys; an error stops the worker.
+type Worker[K comparable, T any] struct {
+	Name   string // identifies the worker in errors
+	Keys   []K
+	Handle func(ctx context.Context, key K, msg T) error
+	Replay bool // handle the retained messages of the keys first, see Sync
+}
+
+// Run starts the workers in the group, for a one-call setup of the
+// consumers of a service. Each key of a worker gets a managed
+// subscription, subscribed before Run returns, and its own goroutine
+// calling Handle in publish order; calls for different keys of a worker
+// may be concurrent. Workers with Replay handle the messages retained for
+// their keys first; see Sync. If a subscription fails, the ones already
+// made are closed and the error returned.
+//
+// A worker goroutine returns the error of Handle, wrapped with the name
+// of the worker, and nil when the context is done or the instance is
+// closed, after closing its subscription. Pass the context of
+// errgroup.WithContext, so a failing worker stops the others. For a
+// shutdown without losing messages, call Drain before waiting for the
+// group: it waits until the workers have received the queued messages,
+// and the workers return once they have handled them.
+func (ps *PubSub[K, T]) Run(ctx context.Context, group Group, workers ...Worker[K, T]) error {
+	type start struct {
+		sub     *Subscription[K, T]
+		history []Retained[T]
+	}
+
+	var starts []start
+	for _, w := range workers {
+		for _, key := range w.Keys {
+			var st start
+			var err error
+			if w.Replay {
+				st.sub, st.history, err = ps.replaySubscription(ctx, key)
+			} else {
+				st.sub, err = ps.NewSubscription(ctx, key)
+			}
+			if err != nil {
+				for _, st := range starts {
+					st.sub.Close()
+				}
+				return err
+			}
+			starts = append(starts, st)
+		}
+	}
+
+	for _, w := range workers {
+		for _, key := range w.Keys {
+			st := starts[0]
+			starts = starts[1:]
+
+			var warm *warmup
+			if w.Replay {
+				warm = ps.warmup()
+			}
+			group.Go(func() error {
+				return w.run(ctx, key, st.sub, st.history, warm)
+			})
+		}
+	}
+
+	return nil
+}
+
+// replaySubscription creates a managed subscription to the key and
+// returns it with the retained messages of the key, with no gap nor
+// duplicates as SubscribeWithReplay.
+func (ps *PubSub[K, T]) replaySubscription(ctx context.Context, key K) (*Subscription[K, T], []Retained[T], error) {
+	var history []Retained[T]
+	sub, err := ps.newSubscription([]K{key}, func(ch chan T) (err error) {
+		if err := ps.authorize(ctx, ActionSubscribe, key); err != nil {
+			ps.opts.logger.Warn("pubsub: subscribe denied", "key", key, "error", err)
+			return err
+		}
+		history, err = ps.SubscribeFrom(key, ch, DeliverAll)
+		return err
+	})
+
+	return sub, history, err
+}
+
+// run handles the replayed messages, then the messages of the
+// subscription to the key until the context is done, the instance is
+// closed or Handle fails. warm is nil unless the worker replays.
+func (w Worker[K, T]) run(ctx context.Context, key K, sub *Subscription[K, T], history []Retained[T], warm *warmup) error {
+	defer sub.Close()
+
+	if warm != nil {
+		err := w.replay(ctx, key, history)
+		warm.finish(err)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil // stopped during the replay
+			}
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-sub.C():
+			if !ok { // closed by Close or Drain
+				return nil
+			}
+			if err := w.Handle(ctx, key, msg); err != nil {
+				return fmt.Errorf("pubsub: worker %s: %w", w.Name, err)
+			}
+		}
+	}
+}
+
+// replay handles the retained messages.
+func (w Worker[K, T]) replay(ctx context.Context, key K, history []Retained[T]) error {
+	for _, r := range history {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.Handle(ctx, key, r.Msg); err != nil {
+			return fmt.Errorf("pubsub: worker %s: %w", w.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// warmup tracks the replay of a worker for Sync.
+type warmup struct {
+	done chan struct{} // closed when the replay ended
+	err  error         // set before done is closed
+}
+
+// finish ends the replay with its error.
+func (w *warmup) finish(err error) {
+	w.err = err
+	close(w.done)
+}
+
+// warmup registers the replay of a worker.
+func (ps *PubSub[K, T]) warmup() *warmup {
+	w := &warmup{done: make(chan struct{})}
+
+	ps.mu.Lock()
+	ps.warmups = append(ps.warmups, w)
+	ps.mu.Unlock()
+
+	return w
+}
+
+// Sync is the sync point of a service startup: it blocks until the
+// workers started by Run with Replay have handled all the messages
+// retained for their keys when they subscribed, so consumers of config
+// keys are warm before the service reports ready. It returns the first
+// error of a failed replay, or the context error if the context is done
+// first.
+func (ps *PubSub[K, T]) Sync(ctx context.Context) error {
+	ps.mu.RLock()
+	warmups := slices.Clone(ps.warmups)
+	ps.mu.RUnlock()
+
+	for _, w := range warmups {
+		select {
+		case <-w.done:
+			if w.err != nil {
+				return w.err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// Forget the successful replays.
+	ps.mu.Lock()
+	ps.warmups = slices.DeleteFunc(ps.warmups, func(w *warmup) bool {
+		return slices.Contains(warmups, w)
+	})
+	ps.mu.Unlock()
+
+	return nil
+}
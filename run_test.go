@@ -0,0 +1,189 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// group is a minimal errgroup.Group: Wait returns the first error, which
+// also cancels the context.
+type group struct {
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+	cancel context.CancelFunc
+}
+
+func (g *group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+func (g *group) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
+
+func TestRun(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(4))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := &group{cancel: cancel}
+
+	var orders, payments atomic.Int64
+	err := ps.Run(ctx, g,
+		pubsub.Worker[string, int]{Name: "orders", Keys: []string{"orders"},
+			Handle: func(_ context.Context, _ string, msg int) error {
+				orders.Add(int64(msg))
+				return nil
+			}},
+		pubsub.Worker[string, int]{Name: "payments", Keys: []string{"payments", "refunds"},
+			Handle: func(_ context.Context, key string, msg int) error {
+				if key == "refunds" {
+					msg = -msg
+				}
+				payments.Add(int64(msg))
+				return nil
+			}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		ps.Publish(context.Background(), "orders", i)
+		ps.Publish(context.Background(), "payments", i)
+	}
+	ps.Publish(context.Background(), "refunds", 1)
+
+	if err := ps.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if orders.Load() != 6 || payments.Load() != 5 {
+		t.Errorf("expected totals 6 and 5, got %d and %d", orders.Load(), payments.Load())
+	}
+}
+
+func TestRunError(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(1))
+	defer ps.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := &group{cancel: cancel}
+
+	errBad := errors.New("bad message")
+	err := ps.Run(ctx, g,
+		pubsub.Worker[string, int]{Name: "strict", Keys: []string{"a"},
+			Handle: func(context.Context, string, int) error { return errBad }},
+		pubsub.Worker[string, int]{Name: "idle", Keys: []string{"b"},
+			Handle: func(context.Context, string, int) error { return nil }},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps.Publish(context.Background(), "a", 1)
+
+	// the failure cancels the context, which stops the idle worker
+	if err := g.Wait(); !errors.Is(err, errBad) {
+		t.Errorf("expected the handler error, got %v", err)
+	}
+	if keys := ps.Keys(); len(keys) != 0 {
+		t.Errorf("expected the subscriptions closed, got keys %v", keys)
+	}
+}
+
+func TestRunClosed(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.Close()
+
+	err := ps.Run(context.Background(), &group{}, pubsub.Worker[string, int]{Keys: []string{"k"}})
+	if !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(3))
+	defer ps.Close()
+	for i := 1; i <= 3; i++ {
+		ps.Publish(context.Background(), "config", i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := &group{cancel: cancel}
+
+	var config atomic.Int64
+	release := make(chan struct{})
+	err := ps.Run(ctx, g, pubsub.Worker[string, int]{Name: "config", Keys: []string{"config"}, Replay: true,
+		Handle: func(_ context.Context, _ string, msg int) error {
+			<-release
+			config.Store(int64(msg))
+			return nil
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	short, stop := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer stop()
+	if err := ps.Sync(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the replay to be pending, got %v", err)
+	}
+
+	close(release)
+	if err := ps.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if config.Load() != 3 {
+		t.Errorf("expected the last retained value handled, got %d", config.Load())
+	}
+
+	cancel()
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncError(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(1))
+	defer ps.Close()
+	ps.Publish(context.Background(), "config", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g := &group{cancel: cancel}
+
+	errBad := errors.New("bad config")
+	err := ps.Run(ctx, g, pubsub.Worker[string, int]{Name: "config", Keys: []string{"config"}, Replay: true,
+		Handle: func(context.Context, string, int) error { return errBad }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ps.Sync(context.Background()); !errors.Is(err, errBad) {
+		t.Errorf("expected the replay error, got %v", err)
+	}
+	if err := g.Wait(); !errors.Is(err, errBad) {
+		t.Errorf("expected the replay error, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+)
+
+// Scope is a view of a PubSub instance that transforms keys before
+// subscribing and publishing, so independent modules or tenants can share
+// one instance without key collisions.
+type Scope[K comparable, T any] struct {
+	ps     *PubSub[K, T]
+	mapKey func(K) K
+}
+
+// Scoped returns a view of the PubSub whose methods transform keys with
+// mapKey, for example by adding a prefix. The mapping must be
+// deterministic and should be injective.
+func (ps *PubSub[K, T]) Scoped(mapKey func(K) K) *Scope[K, T] {
+	return &Scope[K, T]{ps: ps, mapKey: mapKey}
+}
+
+// Prefix returns a key mapping for Scoped that adds the prefix to string keys.
+func Prefix(prefix string) func(string) string {
+	return func(key string) string {
+		return prefix + key
+	}
+}
+
+// Scoped returns a nested view: keys are transformed with mapKey first and
+// then with the mapping of this scope.
+func (s *Scope[K, T]) Scoped(mapKey func(K) K) *Scope[K, T] {
+	outer := s.mapKey
+	return &Scope[K, T]{
+		ps:     s.ps,
+		mapKey: func(key K) K { return outer(mapKey(key)) },
+	}
+}
+
+// Key returns the key of the underlying PubSub the scoped key maps to.
+func (s *Scope[K, T]) Key(key K) K {
+	return s.mapKey(key)
+}
+
+// Subscribe subscribes the channel to the scoped keys.
+func (s *Scope[K, T]) Subscribe(keys []K, ch chan T) {
+	s.ps.Subscribe(s.keys(keys), ch)
+}
+
+// Unsubscribe removes the channel subscription from the scoped keys.
+func (s *Scope[K, T]) Unsubscribe(keys []K, ch chan T) {
+	s.ps.Unsubscribe(s.keys(keys), ch)
+}
+
+// Publish sends the message to the subscribers of the scoped key.
+func (s *Scope[K, T]) Publish(ctx context.Context, key K, msg T) (int, error) {
+	return s.ps.Publish(ctx, s.mapKey(key), msg)
+}
+
+// PublishWithTimeout is a convenience method that creates a context with timeout.
+func (s *Scope[K, T]) PublishWithTimeout(key K, msg T, timeout time.Duration) (int, error) {
+	return s.ps.PublishWithTimeout(s.mapKey(key), msg, timeout)
+}
+
+// keys maps the keys to the keys of the underlying PubSub.
+func (s *Scope[K, T]) keys(keys []K) []K {
+	mapped := make([]K, len(keys))
+	for i, key := range keys {
+		mapped[i] = s.mapKey(key)
+	}
+
+	return mapped
+}
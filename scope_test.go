@@ -0,0 +1,50 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestScoped(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	billing := ps.Scoped(pubsub.Prefix("billing/"))
+	shipping := ps.Scoped(pubsub.Prefix("shipping/"))
+
+	billingCh := make(chan string, 1)
+	billing.Subscribe([]string{"events"}, billingCh)
+	shippingCh := make(chan string, 1)
+	shipping.Subscribe([]string{"events"}, shippingCh)
+
+	n, err := billing.Publish(context.Background(), "events", "invoice")
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 delivery, got %d, %v", n, err)
+	}
+	if msg := <-billingCh; msg != "invoice" {
+		t.Errorf("expected invoice, got %q", msg)
+	}
+	if len(shippingCh) != 0 {
+		t.Error("message leaked to another scope")
+	}
+
+	// the underlying instance sees the prefixed key
+	n, _ = ps.Publish(context.Background(), "shipping/events", "parcel")
+	if n != 1 || <-shippingCh != "parcel" {
+		t.Error("expected delivery through the prefixed key")
+	}
+
+	billing.Unsubscribe([]string{"events"}, billingCh)
+	if n, _ := billing.Publish(context.Background(), "events", "late"); n != 0 {
+		t.Errorf("expected no deliveries after unsubscribe, got %d", n)
+	}
+}
+
+func TestNestedScope(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	tenant := ps.Scoped(pubsub.Prefix("tenant1/")).Scoped(pubsub.Prefix("orders/"))
+
+	if key := tenant.Key("created"); key != "tenant1/orders/created" {
+		t.Errorf("unexpected key %q", key)
+	}
+}
@@ -0,0 +1,160 @@
+package pubsub
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// SubscriptionSet groups managed subscriptions sharing a lifecycle, for
+// components managing many of them: Pause, Resume and Close apply to all
+// the subscriptions at once, so no publish sees some of them paused or
+// unsubscribed and others not.
+type SubscriptionSet[K comparable, T any] struct {
+	ps     *PubSub[K, T]
+	mu     sync.Mutex // protects subs and closed
+	subs   []*Subscription[K, T]
+	closed bool
+}
+
+// NewSubscriptionSet returns an empty set of subscriptions of the
+// instance.
+func (ps *PubSub[K, T]) NewSubscriptionSet() *SubscriptionSet[K, T] {
+	return &SubscriptionSet[K, T]{ps: ps}
+}
+
+// Subscribe creates a subscription to the keys with NewSubscription and
+// adds it to the set. It fails with ErrClosed if the set is closed.
+func (s *SubscriptionSet[K, T]) Subscribe(ctx context.Context, keys ...K) (*Subscription[K, T], error) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+
+	sub, err := s.ps.NewSubscription(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	s.Add(sub)
+
+	return sub, nil
+}
+
+// Add adds a subscription of the same PubSub instance to the set. If the
+// set is closed, the subscription is closed instead.
+func (s *SubscriptionSet[K, T]) Add(sub *Subscription[K, T]) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		sub.Close()
+		return
+	}
+
+	s.subs = append(s.subs, sub)
+	s.mu.Unlock()
+}
+
+// Subscriptions returns the subscriptions of the set, in the order they
+// were added.
+func (s *SubscriptionSet[K, T]) Subscriptions() []*Subscription[K, T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.subs)
+}
+
+// Pause pauses all the subscriptions at once; see PubSub.Pause.
+func (s *SubscriptionSet[K, T]) Pause(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ps.mu.Lock()
+	defer s.ps.mu.Unlock()
+
+	for _, sub := range s.subs {
+		s.ps.pause(sub.ch, limit)
+	}
+}
+
+// Resume resumes all the subscriptions at once and returns the messages
+// kept while they were paused, by subscription; see PubSub.Resume.
+func (s *SubscriptionSet[K, T]) Resume() map[*Subscription[K, T]][]T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ps.mu.Lock()
+	defer s.ps.mu.Unlock()
+
+	kept := make(map[*Subscription[K, T]][]T)
+	for _, sub := range s.subs {
+		if msgs := s.ps.resume(sub.ch); len(msgs) > 0 {
+			kept[sub] = msgs
+		}
+	}
+
+	return kept
+}
+
+// Close unsubscribes all the subscriptions at once, then closes each of
+// them, draining their channels as Subscription.Close. Subscriptions added
+// later are closed right away. It is safe to call more than once.
+func (s *SubscriptionSet[K, T]) Close() {
+	s.mu.Lock()
+	subs := s.subs
+	s.subs, s.closed = nil, true
+	s.mu.Unlock()
+
+	if len(subs) == 0 {
+		return
+	}
+
+	// Keep receiving, so a publisher blocked on one of the channels can't
+	// prevent the unsubscribe.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			discard(sub.ch, done)
+		}()
+	}
+
+	s.ps.unsubscribeSet(subs)
+	close(done)
+	wg.Wait()
+
+	for _, sub := range subs {
+		sub.Close()
+	}
+}
+
+// unsubscribeSet removes the subscriptions of the channels under a single
+// lock.
+func (ps *PubSub[K, T]) unsubscribeSet(subs []*Subscription[K, T]) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for _, sub := range subs {
+		ps.resume(sub.ch)
+		ps.remove(sub.keys, sub.ch)
+	}
+}
+
+// discard receives from the channel until done is closed or the channel
+// is.
+func discard[T any](ch chan T, done chan struct{}) {
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubscriptionSet(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(4))
+	set := ps.NewSubscriptionSet()
+
+	a, err := set.Subscribe(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := set.Subscribe(context.Background(), "b")
+	if n := len(set.Subscriptions()); n != 2 {
+		t.Fatalf("expected 2 subscriptions, got %d", n)
+	}
+
+	set.Pause(4)
+	ps.Publish(context.Background(), "a", 1)
+	ps.Publish(context.Background(), "b", 2)
+	if a.Depth() != 0 || b.Depth() != 0 {
+		t.Error("expected all subscriptions paused")
+	}
+
+	kept := set.Resume()
+	if len(kept[a]) != 1 || kept[a][0] != 1 || len(kept[b]) != 1 || kept[b][0] != 2 {
+		t.Errorf("unexpected kept messages %v", kept)
+	}
+	ps.Publish(context.Background(), "a", 3)
+	ps.Publish(context.Background(), "b", 4)
+	if a.Depth() != 1 || b.Depth() != 1 {
+		t.Error("expected all subscriptions resumed")
+	}
+
+	var closed int
+	a.OnClose(func() { closed++ })
+	b.OnClose(func() { closed++ })
+	set.Close()
+	set.Close()
+
+	if closed != 2 {
+		t.Errorf("expected both subscriptions closed, got %d", closed)
+	}
+	if keys := ps.Keys(); len(keys) != 0 {
+		t.Errorf("expected no subscribed keys, got %v", keys)
+	}
+	if _, err := set.Subscribe(context.Background(), "c"); !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+
+	late, _ := ps.NewSubscription(context.Background(), "d")
+	set.Add(late)
+	if keys := ps.Keys(); len(keys) != 0 {
+		t.Errorf("expected a subscription added to a closed set closed, got keys %v", keys)
+	}
+}
+
+func TestSubscriptionSetCloseBlocked(t *testing.T) {
+	ps := pubsub.New[string, int]() // unbuffered: publishes block
+	set := ps.NewSubscriptionSet()
+	set.Subscribe(context.Background(), "k")
+	set.Subscribe(context.Background(), "k")
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		ps.Publish(context.Background(), "k", 1) // nobody reads
+	}()
+
+	set.Close() // must not deadlock with the blocked publish
+	<-published
+}
@@ -0,0 +1,43 @@
+package pubsub
+
+import "hash/maphash"
+
+// WithShards splits the per-key state updated by every publish, the
+// retained messages and the key statistics, into n shards locked
+// independently, so publishes to different keys contend less on them
+// on machines with many cores. Keys are assigned to shards by hash. The
+// default is one shard; n below one means one.
+func WithShards(n int) Option {
+	return func(o *options) {
+		o.shards = max(n, 1)
+	}
+}
+
+// setShards applies the WithShards option.
+func (ps *PubSub[K, T]) setShards() {
+	n := max(ps.opts.shards, 1)
+	ps.history = make([]history[K, T], n)
+	ps.keyStats = make([]keyStats[K], n)
+	if n > 1 {
+		ps.seed = maphash.MakeSeed()
+	}
+}
+
+// shard returns the index of the shard of the key.
+func (ps *PubSub[K, T]) shard(key K) int {
+	if len(ps.history) == 1 {
+		return 0
+	}
+
+	return int(maphash.Comparable(ps.seed, key) % uint64(len(ps.history)))
+}
+
+// historyOf returns the history shard of the key.
+func (ps *PubSub[K, T]) historyOf(key K) *history[K, T] {
+	return &ps.history[ps.shard(key)]
+}
+
+// statsOf returns the key statistics shard of the key.
+func (ps *PubSub[K, T]) statsOf(key K) *keyStats[K] {
+	return &ps.keyStats[ps.shard(key)]
+}
@@ -0,0 +1,39 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestShards(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithShards(4), pubsub.WithRetention(2), pubsub.WithKeyStats())
+
+	for i := range 20 {
+		key := fmt.Sprint("key", i)
+		for n := range 3 {
+			ps.Publish(context.Background(), key, n)
+		}
+	}
+
+	for i := range 20 {
+		key := fmt.Sprint("key", i)
+		if got := ps.Retained(key); len(got) != 2 || got[1].Msg != 2 {
+			t.Errorf("%s: expected the last 2 messages retained, got %v", key, got)
+		}
+		if s := ps.Stats(key); s.Published != 3 || s.Retained != 2 {
+			t.Errorf("%s: expected 3 publishes and 2 retained, got %+v", key, s)
+		}
+	}
+
+	if n := len(ps.AllStats()); n != 20 {
+		t.Errorf("expected the statistics of 20 keys, got %d", n)
+	}
+
+	if err := ps.Reconfigure(pubsub.WithShards(8)); !errors.Is(err, pubsub.ErrNotReconfigurable) {
+		t.Errorf("expected ErrNotReconfigurable, got %v", err)
+	}
+}
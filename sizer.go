@@ -0,0 +1,42 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Sizer returns the size of a message in bytes, for memory budgets and
+// statistics. It should be cheap, as it is called on hot paths.
+type Sizer[T any] func(msg T) int
+
+// WithSizer sets the function measuring messages. T must be the message
+// type of the PubSub instance, otherwise New panics.
+func WithSizer[T any](sizer Sizer[T]) Option {
+	return func(o *options) {
+		o.sizer = sizer
+	}
+}
+
+// Size returns the size of the message reported by the Sizer, or zero if
+// no Sizer is set.
+func (ps *PubSub[K, T]) Size(msg T) int {
+	if ps.sizer == nil {
+		return 0
+	}
+
+	return ps.sizer(msg)
+}
+
+// setSizer applies the WithSizer option.
+func (ps *PubSub[K, T]) setSizer() {
+	if ps.opts.sizer == nil {
+		return
+	}
+
+	sizer, ok := ps.opts.sizer.(Sizer[T])
+	if !ok {
+		panic(fmt.Sprintf("pubsub: WithSizer for %T used with message type %v", ps.opts.sizer, reflect.TypeFor[T]()))
+	}
+
+	ps.sizer = sizer
+}
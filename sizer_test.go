@@ -0,0 +1,62 @@
+package pubsub_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestByteBudget(t *testing.T) {
+	ps := pubsub.New[string, string](
+		pubsub.WithSizer(pubsub.Sizer[string](func(msg string) int { return len(msg) })),
+		pubsub.WithByteBudget(10),
+	)
+	ch := make(chan string)
+	ps.Subscribe([]string{"k"}, ch)
+	ps.Pause(ch, 100)
+
+	ctx := context.Background()
+	ps.Publish(ctx, "k", "aaaa")
+	ps.Publish(ctx, "k", "bbbb")
+	ps.Publish(ctx, "k", "ccccccc") // evicts aaaa and bbbb
+
+	usage := ps.MemoryUsage()
+	if usage.Bytes != 7 || usage.Evicted != 2 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	if got := ps.Resume(ch); !slices.Equal(got, []string{"ccccccc"}) {
+		t.Errorf("expected [ccccccc], got %q", got)
+	}
+	if got := ps.MemoryUsage().Bytes; got != 0 {
+		t.Errorf("expected bytes to be released, got %d", got)
+	}
+}
+
+func TestSizerStats(t *testing.T) {
+	ps := pubsub.New[string, []byte](
+		pubsub.WithBufferSize(2),
+		pubsub.WithSizer(pubsub.Sizer[[]byte](func(msg []byte) int { return len(msg) })),
+	)
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	defer sub.Close()
+
+	ps.Publish(context.Background(), "k", make([]byte, 3))
+	ps.Publish(context.Background(), "k", make([]byte, 5))
+
+	if got := sub.Stats().Bytes; got != 8 {
+		t.Errorf("expected 8 bytes delivered, got %d", got)
+	}
+}
+
+func TestSizerTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a sizer of another type")
+		}
+	}()
+
+	pubsub.New[string, int](pubsub.WithSizer(pubsub.Sizer[string](func(string) int { return 0 })))
+}
@@ -0,0 +1,254 @@
+// Package sse streams PubSub messages to HTTP clients as Server-Sent Events.
+// Each message is encoded with a codec (JSON by default) and sent as
+// a separate event; the event name can be derived from the key the message
+// was published to.
+package sse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// DefaultBuffer is the number of messages kept for a slow client of each key
+// when Handler.Buffer is not set.
+const DefaultBuffer = 64
+
+// Handler is an http.Handler streaming messages for the keys requested
+// by the client.
+type Handler[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// Keys returns the keys requested by the client.
+	// An error is reported to the client as 400 Bad Request.
+	Keys func(r *http.Request) ([]K, error)
+
+	// Event, if set, returns the event name for messages of the key.
+	Event func(key K) string
+
+	// ID, if set, returns the event ID of the message.
+	// Clients send the last received ID in the Last-Event-ID header
+	// when they reconnect.
+	ID func(msg T) string
+
+	// Replay, if set, returns the messages of the key published after
+	// the event with the given ID. It is called when a reconnecting client
+	// sends a Last-Event-ID header, typically backed by retained history.
+	// Replayed messages are sent before live ones; the subscription is made
+	// first, so a message may be sent twice but is never lost.
+	Replay func(key K, lastEventID string) []T
+
+	// Heartbeat is the interval of comment lines sent to keep idle
+	// connections alive. Zero disables heartbeats.
+	Heartbeat time.Duration
+
+	// Retry, if positive, is sent to clients as the reconnection delay.
+	Retry time.Duration
+
+	// Buffer is the number of messages of each key kept for a client that
+	// reads slower than they are published; DefaultBuffer if not positive.
+	// When it is exceeded the oldest message is dropped, so a stalled client
+	// never blocks publishers.
+	Buffer int
+
+	// DisconnectSlow closes the stream of a client exceeding Buffer instead
+	// of dropping messages. The client reconnects with Last-Event-ID and
+	// catches up through Replay.
+	DisconnectSlow bool
+
+	// Codec encodes event data; codec.JSON if nil.
+	// Multi-line output is sent as multiple data lines.
+	Codec codec.Codec[T]
+}
+
+// event is a message together with the key it was published to.
+type event[K comparable, T any] struct {
+	key K
+	msg T
+}
+
+// ServeHTTP subscribes to the requested keys and streams their messages
+// until the client disconnects.
+func (h *Handler[K, T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Keys(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	events, err := h.subscribe(ctx, cancel, &wg, keys)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if h.Retry > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", h.Retry.Milliseconds())
+	}
+
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" && h.Replay != nil {
+		for _, key := range keys {
+			for _, msg := range h.Replay(key, lastID) {
+				if err := h.write(w, key, msg); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	var heartbeat <-chan time.Time
+	if h.Heartbeat > 0 {
+		ticker := time.NewTicker(h.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat:
+			_, err = io.WriteString(w, ":\n\n")
+		case ev := <-events:
+			err = h.write(w, ev.key, ev.msg)
+		}
+
+		if err == nil {
+			err = rc.Flush()
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// subscribe subscribes a channel to each key and merges them into one
+// stream of events. Subscriptions are removed when the context is done.
+// The context carries the principal checked by the PubSub authorizer.
+// Each channel is always read, so publishers never wait for the client:
+// up to Buffer messages per key are queued, and on overflow the oldest one
+// is dropped or, with DisconnectSlow, the stream is cancelled.
+func (h *Handler[K, T]) subscribe(ctx context.Context, cancel context.CancelFunc, wg *sync.WaitGroup, keys []K) (<-chan event[K, T], error) {
+	size := h.Buffer
+	if size <= 0 {
+		size = DefaultBuffer
+	}
+
+	events := make(chan event[K, T])
+	for _, key := range keys {
+		ch := make(chan T, 1)
+		keys := []K{key}
+		if err := h.PubSub.SubscribeContext(ctx, keys, ch); err != nil {
+			return nil, err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer h.PubSub.UnsubscribeAndDrain(keys, ch)
+
+			var queue []T
+			for {
+				var out chan<- event[K, T]
+				var next event[K, T]
+				if len(queue) > 0 {
+					out = events
+					next = event[K, T]{key: key, msg: queue[0]}
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					if len(queue) >= size {
+						if h.DisconnectSlow {
+							cancel()
+							return
+						}
+						queue = pop(queue)
+					}
+					queue = append(queue, msg)
+				case out <- next:
+					queue = pop(queue)
+				}
+			}
+		}()
+	}
+
+	return events, nil
+}
+
+// pop removes the first message of the queue.
+func pop[T any](queue []T) []T {
+	var zero T
+	queue[0] = zero
+	return queue[1:]
+}
+
+var (
+	// field removes the characters that would end an id or event field
+	// early and inject fields of its own; NUL makes clients ignore an id.
+	field = strings.NewReplacer("\r", "", "\n", "", "\x00", "")
+
+	// newline turns every line ending recognized by clients into \n,
+	// so each data line gets its own prefix.
+	newline = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+)
+
+// write sends the message as a single event.
+func (h *Handler[K, T]) write(w io.Writer, key K, msg T) error {
+	data, err := h.codec().Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if h.ID != nil {
+		fmt.Fprintf(&b, "id: %s\n", field.Replace(h.ID(msg)))
+	}
+
+	if h.Event != nil {
+		fmt.Fprintf(&b, "event: %s\n", field.Replace(h.Event(key)))
+	}
+
+	for line := range strings.Lines(newline.Replace(string(data))) {
+		fmt.Fprintf(&b, "data: %s\n", strings.TrimSuffix(line, "\n"))
+	}
+	b.WriteByte('\n')
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// codec returns the configured codec or the default one.
+func (h *Handler[K, T]) codec() codec.Codec[T] {
+	if h.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return h.Codec
+}
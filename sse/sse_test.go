@@ -0,0 +1,200 @@
+package sse_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+	"github.com/mdigger/pubsub/sse"
+)
+
+type note struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+func newServer(ps *pubsub.PubSub[string, note]) *httptest.Server {
+	return httptest.NewServer(&sse.Handler[string, note]{
+		PubSub: ps,
+		Keys: func(r *http.Request) ([]string, error) {
+			return r.URL.Query()["key"], nil
+		},
+		Event: func(key string) string { return key },
+		ID:    func(msg note) string { return msg.ID },
+		Replay: func(key, lastEventID string) []note {
+			if lastEventID == "1" {
+				return []note{{ID: "2", Text: "replayed"}}
+			}
+			return nil
+		},
+	})
+}
+
+// readEvent reads lines up to the end of the next event.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestHandlerStream(t *testing.T) {
+	ps := pubsub.New[string, note]()
+	srv := newServer(ps)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?key=news", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	go func() {
+		for {
+			n, _ := ps.PublishWithTimeout("news", note{ID: "5", Text: "hello"}, time.Second)
+			if n > 0 || ctx.Err() != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	got := readEvent(t, bufio.NewReader(resp.Body))
+	want := "id: 5\nevent: news\ndata: {\"id\":\"5\",\"text\":\"hello\"}"
+	if got != want {
+		t.Errorf("unexpected event:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandlerReplay(t *testing.T) {
+	ps := pubsub.New[string, note]()
+	srv := newServer(ps)
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"?key=news", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	got := readEvent(t, bufio.NewReader(resp.Body))
+	if !strings.Contains(got, `"replayed"`) {
+		t.Errorf("expected replayed event, got:\n%s", got)
+	}
+}
+
+func TestHandlerForbidden(t *testing.T) {
+	ps := pubsub.New[string, note]()
+	ps.SetAuthorizer(pubsub.AuthorizerFunc[string](func(context.Context, pubsub.Action, string) error {
+		return pubsub.ErrForbidden
+	}))
+	srv := newServer(ps)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?key=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandlerSanitize(t *testing.T) {
+	ps := pubsub.New[string, note]()
+	srv := httptest.NewServer(&sse.Handler[string, note]{
+		PubSub: ps,
+		Keys: func(r *http.Request) ([]string, error) {
+			return r.URL.Query()["key"], nil
+		},
+		Event: func(key string) string { return key + "\r\ndata: forged" },
+		ID:    func(msg note) string { return msg.ID },
+		Codec: codec.Funcs[note]{
+			MarshalFunc: func(msg note) ([]byte, error) { return []byte(msg.Text), nil },
+		},
+	})
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?key=news", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	go func() {
+		for {
+			msg := note{ID: "5\nevent: forged", Text: "one\rtwo\r\nthree"}
+			n, _ := ps.PublishWithTimeout("news", msg, time.Second)
+			if n > 0 || ctx.Err() != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	got := readEvent(t, bufio.NewReader(resp.Body))
+	want := "id: 5event: forged\nevent: newsdata: forged\ndata: one\ndata: two\ndata: three"
+	if got != want {
+		t.Errorf("unexpected event:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandlerSlowClient(t *testing.T) {
+	ps := pubsub.New[string, note]()
+	srv := newServer(ps)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?key=news", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if err := ps.WaitForSubscribers(ctx, "news", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client never reads: once the connection buffers are full,
+	// messages must be dropped rather than block the publisher.
+	msg := note{Text: strings.Repeat("x", 64<<10)}
+	for i := range 500 {
+		if n, _ := ps.PublishWithTimeout("news", msg, time.Second); n == 0 {
+			t.Fatalf("publish %d blocked by a stalled client", i)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pubsub
+
+import (
+	"slices"
+	"time"
+)
+
+// Start is the delivery policy of SubscribeFrom: where in the retained
+// history of a key a subscription starts.
+type Start struct {
+	seq  uint64    // first sequence number, if set
+	time time.Time // first publish time, if set
+	none bool      // live messages only
+}
+
+// Delivery policies without parameters.
+var (
+	DeliverAll = Start{}           // all retained messages
+	DeliverNew = Start{none: true} // only messages published after subscribing
+)
+
+// StartAtSeq starts at the message with the sequence number, or the
+// oldest retained one after it.
+func StartAtSeq(seq uint64) Start {
+	return Start{seq: seq}
+}
+
+// StartAt starts at the first message published at or after t.
+func StartAt(t time.Time) Start {
+	return Start{time: t}
+}
+
+// includes reports whether the retained message is replayed.
+func includes[T any](s Start, r Retained[T]) bool {
+	switch {
+	case s.none:
+		return false
+	case s.seq > 0:
+		return r.Seq >= s.seq
+	case !s.time.IsZero():
+		return !r.Time.Before(s.time)
+	default:
+		return true
+	}
+}
+
+// SubscribeFrom subscribes the channel to the key and returns the retained
+// messages selected by the delivery policy, oldest first, with the same
+// no gap, no duplicates guarantee as SubscribeWithReplay.
+func (ps *PubSub[K, T]) SubscribeFrom(key K, ch chan T, start Start) ([]Retained[T], error) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.state != stateOpen {
+		return nil, ErrClosed
+	}
+
+	key = ps.resolve(key)
+	var history []Retained[T]
+	if !start.none {
+		h := ps.historyOf(key)
+		h.mu.Lock()
+		history = ps.retained(key)
+		h.mu.Unlock()
+	}
+
+	i := slices.IndexFunc(history, func(r Retained[T]) bool { return includes(start, r) })
+	if i < 0 {
+		history = nil
+	} else {
+		history = history[i:]
+	}
+
+	ps.add([]K{key}, ch, false)
+
+	return history, nil
+}
@@ -0,0 +1,53 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestSubscribeFrom(t *testing.T) {
+	ps, clock := pstest.New[string, int](pubsub.WithRetention(10))
+	for i := 1; i <= 4; i++ {
+		ps.Publish(context.Background(), "k", i)
+		clock.Advance(time.Minute)
+	}
+
+	tests := []struct {
+		name  string
+		start pubsub.Start
+		first int
+	}{
+		{"all", pubsub.DeliverAll, 1},
+		{"new", pubsub.DeliverNew, 0},
+		{"seq", pubsub.StartAtSeq(3), 3},
+		{"seq after end", pubsub.StartAtSeq(9), 0},
+		{"time", pubsub.StartAt(time.Unix(61, 0)), 3},
+		{"time before start", pubsub.StartAt(time.Unix(-60, 0)), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan int)
+			history, err := ps.SubscribeFrom("k", ch, tt.start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ps.Unsubscribe([]string{"k"}, ch)
+
+			if tt.first == 0 {
+				if len(history) != 0 {
+					t.Errorf("expected no history, got %+v", history)
+				}
+				return
+			}
+
+			if len(history) != 5-tt.first || history[0].Msg != tt.first {
+				t.Errorf("expected history from %d, got %+v", tt.first, history)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Framing separates encoded messages in a byte stream.
+type Framing int
+
+const (
+	// NDJSON writes each message on its own line. The encoded messages
+	// must not contain newlines, as with compact JSON.
+	NDJSON Framing = iota
+	// LengthPrefixed writes each message after its length as a 4-byte
+	// big-endian integer, for binary codecs.
+	LengthPrefixed
+)
+
+// MaxFrameSize limits the size of a message read from a stream.
+const MaxFrameSize = 16 << 20
+
+// ErrFrameTooLarge is returned for messages larger than MaxFrameSize.
+var ErrFrameTooLarge = errors.New("stream: frame too large")
+
+// ErrNewline is reported for encoded messages containing a newline,
+// which can't be framed as NDJSON.
+var ErrNewline = errors.New("stream: message contains a newline")
+
+// writeFrame writes the encoded message with the framing.
+func writeFrame(w io.Writer, framing Framing, data []byte) error {
+	var frame []byte
+	switch framing {
+	case LengthPrefixed:
+		if len(data) > MaxFrameSize {
+			return ErrFrameTooLarge
+		}
+
+		frame = binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(data)), uint32(len(data)))
+		frame = append(frame, data...)
+	default:
+		frame = append(append(make([]byte, 0, len(data)+1), data...), '\n')
+	}
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// nextFrame splits the next encoded message off the buffered data. It
+// returns a nil frame if the data doesn't hold a complete one yet. Empty
+// lines of NDJSON are skipped.
+func nextFrame(buf []byte, framing Framing) (frame, rest []byte, err error) {
+	if framing == LengthPrefixed {
+		if len(buf) < 4 {
+			return nil, buf, nil
+		}
+
+		n := binary.BigEndian.Uint32(buf)
+		if n > MaxFrameSize {
+			return nil, buf, ErrFrameTooLarge
+		}
+
+		if len(buf) < 4+int(n) {
+			return nil, buf, nil
+		}
+
+		return buf[4 : 4+n], buf[4+n:], nil
+	}
+
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			if len(buf) > MaxFrameSize {
+				return nil, buf, ErrFrameTooLarge
+			}
+
+			return nil, buf, nil
+		}
+
+		line := bytes.TrimSpace(buf[:i])
+		buf = buf[i+1:]
+		if len(line) > 0 {
+			return line, buf, nil
+		}
+	}
+}
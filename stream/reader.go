@@ -0,0 +1,126 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Reader reads messages from R and publishes them.
+type Reader[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+	R      io.Reader
+
+	// Key is the key messages are published to, unless Decode is set.
+	Key K
+
+	// Codec decodes messages; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Decode, if set, is used instead of Key and Codec to decode a frame
+	// into the key and message to publish, for records carrying their key
+	// or needing conversion.
+	Decode func(data []byte) (K, T, error)
+
+	// Framing separates the messages; NDJSON by default.
+	Framing Framing
+
+	// Follow, if positive, makes Run keep reading at the end of R, checking
+	// for new data at this interval, to tail a file being appended to.
+	Follow time.Duration
+
+	// OnError, if set, is called for frames that could not be decoded;
+	// they are skipped.
+	OnError func(data []byte, err error)
+}
+
+// readSize is the size of reads from the underlying reader.
+const readSize = 32 << 10
+
+// Run reads and publishes messages until the end of R, or until the
+// context is canceled if Follow is set. It returns nil at the end of R and
+// otherwise the read, framing, publish or context error. A final NDJSON
+// line without a newline is published at the end unless following.
+func (r *Reader[K, T]) Run(ctx context.Context) error {
+	var buf []byte
+	chunk := make([]byte, readSize)
+
+	for {
+		for {
+			frame, rest, err := nextFrame(buf, r.Framing)
+			if err != nil {
+				return err
+			}
+
+			if frame == nil {
+				buf = append(buf[:0], rest...)
+				break
+			}
+
+			buf = rest
+			if err := r.publish(ctx, frame); err != nil {
+				return err
+			}
+		}
+
+		n, err := r.R.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+
+		switch {
+		case err == io.EOF && n > 0:
+		case err == io.EOF && r.Follow > 0:
+			timer := time.NewTimer(r.Follow)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		case err == io.EOF:
+			if line := bytes.TrimSpace(buf); r.Framing == NDJSON && len(line) > 0 {
+				return r.publish(ctx, line)
+			}
+
+			return nil
+		case err != nil:
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// publish decodes the frame and publishes the message.
+func (r *Reader[K, T]) publish(ctx context.Context, data []byte) error {
+	key, msg, err := r.decode(data)
+	if err != nil {
+		if r.OnError != nil {
+			r.OnError(data, err)
+		}
+		return nil
+	}
+
+	_, err = r.PubSub.Publish(ctx, key, msg)
+	return err
+}
+
+// decode converts the frame with the configured decoding.
+func (r *Reader[K, T]) decode(data []byte) (K, T, error) {
+	if r.Decode != nil {
+		return r.Decode(data)
+	}
+
+	c := r.Codec
+	if c == nil {
+		c = codec.JSON[T]{}
+	}
+
+	msg, err := c.Unmarshal(data)
+	return r.Key, msg, err
+}
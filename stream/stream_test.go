@@ -0,0 +1,184 @@
+package stream_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+	"github.com/mdigger/pubsub/stream"
+)
+
+type event struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestWriterNDJSON(t *testing.T) {
+	ps := pubsub.New[string, event]()
+	r, pw := io.Pipe()
+	w := &stream.Writer[string, event]{PubSub: ps, Keys: []string{"a", "b"}, W: pw}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	for len(ps.Keys()) < 2 {
+		runtime.Gosched()
+	}
+
+	go func() {
+		ps.Publish(ctx, "a", event{ID: 1, Name: "one"})
+		ps.Publish(ctx, "b", event{ID: 2, Name: "two"})
+	}()
+
+	lines := bufio.NewScanner(r)
+	for _, want := range []string{`{"id":1,"name":"one"}`, `{"id":2,"name":"two"}`} {
+		if !lines.Scan() || lines.Text() != want {
+			t.Errorf("expected line %s, got %q", want, lines.Text())
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWriterLengthPrefixed(t *testing.T) {
+	var buf bytes.Buffer
+	w := &stream.Writer[string, string]{
+		W:       &buf,
+		Framing: stream.LengthPrefixed,
+		Codec:   codec.Funcs[string]{MarshalFunc: func(s string) ([]byte, error) { return []byte(s), nil }},
+	}
+
+	w.Write("hello")
+	w.Write("")
+
+	want := append(binary.BigEndian.AppendUint32(nil, 5), "hello\x00\x00\x00\x00"...)
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("unexpected frames %q", buf.Bytes())
+	}
+}
+
+func TestWriterNewline(t *testing.T) {
+	var reported error
+	w := &stream.Writer[string, string]{
+		W:       io.Discard,
+		Codec:   codec.Funcs[string]{MarshalFunc: func(s string) ([]byte, error) { return []byte(s), nil }},
+		OnError: func(_ string, err error) { reported = err },
+	}
+
+	if err := w.Write("two\nlines"); err != nil || !errors.Is(reported, stream.ErrNewline) {
+		t.Errorf("expected ErrNewline to be reported, got %v, %v", err, reported)
+	}
+}
+
+func TestReaderNDJSON(t *testing.T) {
+	ps := pubsub.New[string, event]()
+	ch := make(chan event, 10)
+	ps.Subscribe([]string{"events"}, ch)
+
+	var bad []string
+	r := &stream.Reader[string, event]{
+		PubSub:  ps,
+		R:       strings.NewReader("{\"id\":1}\n\n not json\n{\"id\":2}"),
+		Key:     "events",
+		OnError: func(data []byte, _ error) { bad = append(bad, string(data)) },
+	}
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ch) != 2 || (<-ch).ID != 1 || (<-ch).ID != 2 {
+		t.Error("expected both valid records published in order")
+	}
+
+	if len(bad) != 1 || bad[0] != "not json" {
+		t.Errorf("expected the invalid record reported, got %q", bad)
+	}
+}
+
+func TestReaderDecode(t *testing.T) {
+	var buf bytes.Buffer
+	raw := codec.Funcs[string]{MarshalFunc: func(s string) ([]byte, error) { return []byte(s), nil }}
+	w := &stream.Writer[string, string]{W: &buf, Framing: stream.LengthPrefixed, Codec: raw}
+	w.Write("a:1")
+	w.Write("b:2")
+
+	ps := pubsub.New[string, string]()
+	ch := make(chan string, 10)
+	ps.Subscribe([]string{"a", "b"}, ch)
+
+	r := &stream.Reader[string, string]{
+		PubSub:  ps,
+		R:       &buf,
+		Framing: stream.LengthPrefixed,
+		Decode: func(data []byte) (string, string, error) {
+			key, msg, _ := strings.Cut(string(data), ":")
+			return key, msg, nil
+		},
+	}
+
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ch) != 2 || <-ch != "1" || <-ch != "2" {
+		t.Error("expected records published to the keys they carry")
+	}
+}
+
+func TestReaderFollow(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "events.ndjson")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	in, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	ps := pubsub.New[string, event]()
+	ch := make(chan event, 10)
+	ps.Subscribe([]string{"k"}, ch)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	r := &stream.Reader[string, event]{PubSub: ps, R: in, Key: "k", Follow: time.Millisecond}
+	go func() { done <- r.Run(ctx) }()
+
+	f.WriteString(`{"id":1,`) // partial line is kept until completed
+	time.Sleep(5 * time.Millisecond)
+	f.WriteString("\"name\":\"x\"}\n")
+
+	select {
+	case ev := <-ch:
+		if ev.ID != 1 || ev.Name != "x" {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("appended record not published")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
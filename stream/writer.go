@@ -0,0 +1,85 @@
+// Package stream connects PubSub keys to byte streams: Writer writes
+// messages to an io.Writer, such as a file, a socket or standard output,
+// one frame per message, and Reader publishes the messages read from an
+// io.Reader, optionally following a file as it grows. Messages are
+// encoded with a codec (JSON by default) and separated with a Framing.
+package stream
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Writer subscribes to keys and writes their messages to W.
+type Writer[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+	Keys   []K
+	W      io.Writer
+
+	// Codec encodes messages; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Framing separates the messages; NDJSON by default.
+	Framing Framing
+
+	// Buffer is the capacity of the subscription channel.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be encoded
+	// or framed, for example NDJSON messages with ErrNewline; they are
+	// skipped.
+	OnError func(msg T, err error)
+}
+
+// Run writes messages until the context is canceled or a write fails,
+// and returns the context or write error. Messages of all keys are
+// written by one goroutine, in the order they are received.
+func (w *Writer[K, T]) Run(ctx context.Context) error {
+	ch := make(chan T, w.Buffer)
+	if err := w.PubSub.SubscribeContext(ctx, w.Keys, ch); err != nil {
+		return err
+	}
+	defer w.PubSub.UnsubscribeAndDrain(w.Keys, ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-ch:
+			if err := w.Write(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Write writes a single message. Encoding errors are reported to OnError
+// and not returned.
+func (w *Writer[K, T]) Write(msg T) error {
+	data, err := w.codec().Marshal(msg)
+	if err == nil && w.Framing == NDJSON && bytes.IndexByte(data, '\n') >= 0 {
+		err = ErrNewline
+	}
+
+	if err != nil {
+		if w.OnError != nil {
+			w.OnError(msg, err)
+		}
+		return nil
+	}
+
+	return writeFrame(w.W, w.Framing, data)
+}
+
+// codec returns the configured codec or the default one.
+func (w *Writer[K, T]) codec() codec.Codec[T] {
+	if w.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return w.Codec
+}
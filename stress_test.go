@@ -0,0 +1,205 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+// FuzzOperations runs sequences of operations decoded from the input
+// against a PubSub instance and a model of its subscriptions, checking
+// that deliveries and keys match the model.
+func FuzzOperations(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+	f.Add([]byte{0x10, 0x10, 0x30, 0x40, 0x30, 0x50})
+	f.Add([]byte{0x20, 0x21, 0x00, 0x12, 0x33, 0x14, 0x45, 0x56, 0x67})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		const nkeys, nchans = 3, 3
+		ps := pubsub.New[int, int]()
+		chans := make([]chan int, nchans)
+		for i := range chans {
+			chans[i] = make(chan int, len(ops)+1)
+		}
+
+		// model holds the reference counts of the subscriptions.
+		model := make(map[int]map[int]int)
+		closed := false
+
+		for i, op := range ops {
+			key, ch := int(op>>2)%nkeys, int(op>>4)%nchans
+			keys := []int{key}
+
+			switch op % 6 {
+			case 0, 1:
+				ps.Subscribe(keys, chans[ch])
+				if !closed && model[key][ch] == 0 {
+					if model[key] == nil {
+						model[key] = make(map[int]int)
+					}
+					model[key][ch] = 1
+				}
+
+			case 2:
+				ps.SubscribeRef(keys, chans[ch])
+				if !closed {
+					if model[key] == nil {
+						model[key] = make(map[int]int)
+					}
+					model[key][ch]++
+				}
+
+			case 3:
+				ps.Unsubscribe(keys, chans[ch])
+				if refs := model[key][ch]; refs > 1 {
+					model[key][ch]--
+				} else if refs == 1 {
+					delete(model[key], ch)
+					if len(model[key]) == 0 {
+						delete(model, key)
+					}
+				}
+
+			case 4:
+				n, err := ps.Publish(context.Background(), key, i)
+				if closed {
+					if !errors.Is(err, pubsub.ErrClosed) {
+						t.Fatalf("op %d: expected ErrClosed, got %v", i, err)
+					}
+					continue
+				}
+
+				if err != nil || n != len(model[key]) {
+					t.Fatalf("op %d: expected %d deliveries, got %d, %v", i, len(model[key]), n, err)
+				}
+
+				for c, ch := range chans {
+					_, subscribed := model[key][c]
+					select {
+					case msg := <-ch:
+						if !subscribed || msg != i {
+							t.Fatalf("op %d: unexpected message %d on channel %d", i, msg, c)
+						}
+					default:
+						if subscribed {
+							t.Fatalf("op %d: message not delivered to channel %d", i, c)
+						}
+					}
+				}
+
+			case 5:
+				if op&0x80 != 0 {
+					ps.Close()
+					closed = true
+				}
+			}
+
+			var want []int
+			for key := range model {
+				want = append(want, key)
+			}
+
+			got := ps.Keys()
+			slices.Sort(got)
+			slices.Sort(want)
+			if !slices.Equal(got, want) {
+				t.Fatalf("op %d: expected keys %v, got %v", i, want, got)
+			}
+		}
+	})
+}
+
+// TestStress runs random concurrent subscribes, unsubscribes, publishes
+// and managed subscriptions, closing the instance halfway, and checks
+// that each subscriber receives the messages of a publisher in order.
+func TestStress(t *testing.T) {
+	const (
+		keys       = 4
+		publishers = 4
+		churners   = 8
+	)
+
+	ps := pubsub.New[int, [2]int](pubsub.WithBufferSize(16))
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	// check verifies the order of the messages of each publisher.
+	check := func(msgs <-chan [2]int, done <-chan struct{}) {
+		last := make([]int, publishers)
+		for {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				if msg[1] <= last[msg[0]] {
+					t.Errorf("message %d of publisher %d after %d", msg[1], msg[0], last[msg[0]])
+				}
+				last[msg[0]] = msg[1]
+			case <-done:
+				return
+			}
+		}
+	}
+
+	for p := range publishers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seq := 1; ctx.Err() == nil; seq++ {
+				pctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+				_, err := ps.Publish(pctx, rand.IntN(keys), [2]int{p, seq})
+				cancel()
+
+				if errors.Is(err, pubsub.ErrClosed) {
+					return
+				}
+			}
+		}()
+	}
+
+	for c := range churners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				sub := []int{rand.IntN(keys), rand.IntN(keys)}
+				if c%2 == 0 {
+					s, err := ps.NewSubscription(ctx, sub...)
+					if err != nil {
+						return
+					}
+
+					done := make(chan struct{})
+					time.AfterFunc(time.Millisecond, func() { close(done) })
+					check(s.C(), done)
+					s.Close()
+					continue
+				}
+
+				ch := make(chan [2]int, rand.IntN(4))
+				ps.Subscribe(sub, ch)
+				done := make(chan struct{})
+				time.AfterFunc(time.Millisecond, func() { close(done) })
+				check(ch, done)
+				ps.UnsubscribeAndDrain(sub, ch)
+			}
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	ps.Close()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	if keys := ps.Keys(); len(keys) != 0 {
+		t.Errorf("expected all subscriptions removed, got keys %v", keys)
+	}
+}
@@ -0,0 +1,148 @@
+package pubsub
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"strings"
+)
+
+// Wildcard segments of subject patterns.
+const (
+	AnySegment = "*" // matches exactly one segment
+	AnyTail    = ">" // as the last segment, matches one or more segments
+)
+
+// subjectSep ends each segment of a subject path.
+const subjectSep = "\x00"
+
+// Subject is a hierarchical key made of segments, such as
+// NewSubject("orders", "eu", "created"), for structured key schemes
+// without formatting and parsing strings. It is comparable, so it can be
+// the key type of a PubSub, and cheap to compare and hash. Segments may
+// contain any text but NUL and dots, which separate them in the text
+// form; the zero Subject has no segments.
+//
+// As a pattern, a subject may contain wildcard segments: AnySegment
+// matches one segment and a final AnyTail one or more, so "orders.*.created"
+// matches "orders.eu.created" and "orders.>" matches every subject under
+// "orders". SubscribePattern subscribes channels to patterns.
+type Subject struct {
+	path string // each segment followed by subjectSep
+}
+
+// NewSubject returns the subject with the segments. It panics if a
+// segment contains NUL or a dot.
+func NewSubject(segments ...string) Subject {
+	var b strings.Builder
+	for _, s := range segments {
+		if strings.Contains(s, subjectSep) {
+			panic("pubsub: subject segment contains NUL")
+		}
+		if strings.Contains(s, ".") {
+			panic("pubsub: subject segment contains a dot")
+		}
+		b.WriteString(s)
+		b.WriteString(subjectSep)
+	}
+
+	return Subject{path: b.String()}
+}
+
+// ParseSubject returns the subject with the segments of s separated by
+// dots, as printed by String: "orders.eu.created". An empty string is the
+// subject without segments.
+func ParseSubject(s string) Subject {
+	if s == "" {
+		return Subject{}
+	}
+
+	return NewSubject(strings.Split(s, ".")...)
+}
+
+// Len returns the number of segments.
+func (s Subject) Len() int {
+	return strings.Count(s.path, subjectSep)
+}
+
+// All returns an iterator over the segments.
+func (s Subject) All() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		rest := s.path
+		for rest != "" {
+			seg, tail, _ := strings.Cut(rest, subjectSep)
+			if !yield(seg) {
+				return
+			}
+			rest = tail
+		}
+	}
+}
+
+// Segments returns the segments.
+func (s Subject) Segments() []string {
+	segments := make([]string, 0, s.Len())
+	for seg := range s.All() {
+		segments = append(segments, seg)
+	}
+
+	return segments
+}
+
+// Child returns the subject extended with the segments.
+func (s Subject) Child(segments ...string) Subject {
+	return Subject{path: s.path + NewSubject(segments...).path}
+}
+
+// HasPrefix reports whether the subject starts with the segments of the
+// prefix.
+func (s Subject) HasPrefix(prefix Subject) bool {
+	return strings.HasPrefix(s.path, prefix.path)
+}
+
+// Match reports whether the subject matches the pattern, segment by
+// segment, with the wildcards AnySegment and AnyTail. Wildcards in the
+// subject itself are plain segments.
+func (s Subject) Match(pattern Subject) bool {
+	rest, pat := s.path, pattern.path
+	for pat != "" {
+		p, ptail, _ := strings.Cut(pat, subjectSep)
+		if rest == "" {
+			return false
+		}
+		if p == AnyTail && ptail == "" {
+			return true
+		}
+
+		seg, tail, _ := strings.Cut(rest, subjectSep)
+		if p != AnySegment && p != seg {
+			return false
+		}
+		rest, pat = tail, ptail
+	}
+
+	return rest == ""
+}
+
+// String returns the segments separated by dots.
+func (s Subject) String() string {
+	return strings.ReplaceAll(strings.TrimSuffix(s.path, subjectSep), subjectSep, ".")
+}
+
+// MarshalText implements encoding.TextMarshaler with the form of String,
+// so subjects encode as strings in JSON, including as map keys.
+func (s Subject) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseSubject. It
+// fails if the text contains NUL.
+func (s *Subject) UnmarshalText(text []byte) error {
+	if bytes.Contains(text, []byte(subjectSep)) {
+		return errors.New("pubsub: subject contains NUL")
+	}
+
+	*s = ParseSubject(string(text))
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package pubsub_test
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestSubject(t *testing.T) {
+	s := pubsub.NewSubject("orders", "eu", "created")
+	if s != pubsub.ParseSubject("orders.eu.created") {
+		t.Error("expected equal subjects")
+	}
+	if s.Len() != 3 || !slices.Equal(s.Segments(), []string{"orders", "eu", "created"}) {
+		t.Errorf("unexpected segments %v", s.Segments())
+	}
+	if s.String() != "orders.eu.created" {
+		t.Errorf("unexpected string %q", s)
+	}
+	if pubsub.NewSubject("orders").Child("eu", "created") != s {
+		t.Error("expected the child to equal the subject")
+	}
+	if !s.HasPrefix(pubsub.NewSubject("orders", "eu")) || s.HasPrefix(pubsub.NewSubject("order")) {
+		t.Error("unexpected prefix matching")
+	}
+	if (pubsub.Subject{}).Len() != 0 || pubsub.ParseSubject("").Len() != 0 {
+		t.Error("expected no segments")
+	}
+
+	// segments can't contain the separator of the text form
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a segment with a dot")
+		}
+	}()
+	pubsub.NewSubject("a.b")
+}
+
+func TestSubjectMatch(t *testing.T) {
+	tests := []struct {
+		subject, pattern string
+		match            bool
+	}{
+		{"orders.eu.created", "orders.eu.created", true},
+		{"orders.eu.created", "orders.*.created", true},
+		{"orders.eu.created", "*.*.*", true},
+		{"orders.eu.created", "orders.>", true},
+		{"orders.eu.created", ">", true},
+		{"orders.eu.created", "orders.*", false},
+		{"orders.eu.created", "orders.us.created", false},
+		{"orders.eu.created", "orders.eu.created.v1", false},
+		{"orders", "orders.>", false},
+		{"orders.eu", "orders.>.eu", false},
+	}
+
+	for _, tt := range tests {
+		s, p := pubsub.ParseSubject(tt.subject), pubsub.ParseSubject(tt.pattern)
+		if got := s.Match(p); got != tt.match {
+			t.Errorf("%s matching %s: expected %v, got %v", tt.subject, tt.pattern, tt.match, got)
+		}
+	}
+}
+
+func TestSubjectText(t *testing.T) {
+	data, err := json.Marshal(map[pubsub.Subject]pubsub.Subject{
+		pubsub.ParseSubject("orders.eu"): pubsub.ParseSubject("orders.*"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"orders.eu":"orders.*"}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+
+	var m map[pubsub.Subject]pubsub.Subject
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[pubsub.ParseSubject("orders.eu")] != pubsub.ParseSubject("orders.*") {
+		t.Errorf("unexpected subjects %v", m)
+	}
+
+	var s pubsub.Subject
+	if err := s.UnmarshalText([]byte("a\x00b")); err == nil {
+		t.Error("expected an error for NUL")
+	}
+}
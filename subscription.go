@@ -0,0 +1,287 @@
+package pubsub
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Subscription is a subscription managed by the library: it owns its
+// channel and tracks delivery statistics, so operators can see which
+// consumer is falling behind.
+type Subscription[K comparable, T any] struct {
+	ps    *PubSub[K, T]
+	keys  []K // replaced under the lock of ps by UnaliasKey
+	ch    chan T
+	stats *subStats
+	once  sync.Once
+
+	beat     atomic.Bool // heartbeat since the last liveness check
+	mu       sync.Mutex  // protects watchdog, closed and the hooks
+	watchdog Timer
+	closed   bool
+	hooks    []func() // registered with OnClose
+	unhook   func()   // removes the hook of the PubSub instance
+	hooked   bool     // hook of the PubSub instance registered
+	ended    bool     // hooks called
+}
+
+// SubscriptionStats are the delivery statistics of a Subscription.
+type SubscriptionStats struct {
+	Delivered     uint64        // messages sent to the channel
+	Bytes         uint64        // size of the delivered messages, if a Sizer is set
+	Dropped       uint64        // messages not delivered because of the drop policy or a timeout
+	Depth         int           // messages waiting in the channel buffer
+	Capacity      int           // capacity of the channel buffer
+	HighWatermark int           // largest depth seen right after a delivery
+	Lag           time.Duration // time the last delivery waited for room in the buffer
+}
+
+// subStats holds the counters of a managed subscription, updated by
+// publishers under the read lock.
+type subStats struct {
+	delivered atomic.Uint64
+	bytes     atomic.Uint64
+	dropped   atomic.Uint64
+	high      atomic.Int64
+	lag       atomic.Int64
+}
+
+// record accounts a delivery attempt.
+func (s *subStats) record(delivered bool, depth, size int, wait time.Duration) {
+	if !delivered {
+		s.dropped.Add(1)
+		return
+	}
+
+	s.delivered.Add(1)
+	s.bytes.Add(uint64(size))
+	s.lag.Store(int64(wait))
+
+	for {
+		high := s.high.Load()
+		if int64(depth) <= high || s.high.CompareAndSwap(high, int64(depth)) {
+			return
+		}
+	}
+}
+
+// NewSubscription subscribes a new channel, with the buffer size set by
+// WithBufferSize, to the keys. The context is passed to the authorizer
+// as with SubscribeContext. The buffer capacity is reserved in the memory
+// budget; if it doesn't fit, ErrBudgetExceeded is returned.
+// The subscription must be closed when it is no longer used.
+func (ps *PubSub[K, T]) NewSubscription(ctx context.Context, keys ...K) (*Subscription[K, T], error) {
+	return ps.newSubscription(keys, func(ch chan T) error {
+		return ps.SubscribeContext(ctx, keys, ch)
+	})
+}
+
+// newSubscription creates a managed subscription to the keys, whose
+// channel is subscribed by the subscribe function.
+func (ps *PubSub[K, T]) newSubscription(keys []K, subscribe func(ch chan T) error) (*Subscription[K, T], error) {
+	size := ps.bufferSize(keys...)
+	if !ps.budget.reserve(size, 0, &ps.opts) {
+		ps.budget.reject()
+		return nil, ErrBudgetExceeded
+	}
+
+	s := &Subscription[K, T]{
+		ps:    ps,
+		keys:  keys,
+		ch:    make(chan T, size),
+		stats: new(subStats),
+	}
+
+	ps.mu.Lock()
+	if ps.state != stateOpen {
+		ps.mu.Unlock()
+		ps.budget.release(size, 0)
+
+		return nil, ErrClosed
+	}
+
+	if ps.managed == nil {
+		ps.managed = make(map[chan T]*Subscription[K, T])
+	}
+	ps.managed[s.ch] = s
+	ps.mu.Unlock()
+
+	if err := subscribe(s.ch); err != nil {
+		ps.mu.Lock()
+		delete(ps.managed, s.ch)
+		ps.mu.Unlock()
+		ps.budget.release(size, 0)
+
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// C returns the channel messages are delivered to.
+func (s *Subscription[K, T]) C() <-chan T {
+	return s.ch
+}
+
+// Keys returns the keys of the subscription.
+func (s *Subscription[K, T]) Keys() []K {
+	s.ps.mu.RLock()
+	defer s.ps.mu.RUnlock()
+
+	return s.keys
+}
+
+// Depth returns the number of messages waiting to be received.
+func (s *Subscription[K, T]) Depth() int {
+	return len(s.ch)
+}
+
+// Lag returns how long the last delivery had to wait for the consumer to
+// make room in the channel. It grows when the consumer falls behind.
+func (s *Subscription[K, T]) Lag() time.Duration {
+	return time.Duration(s.stats.lag.Load())
+}
+
+// Stats returns the delivery statistics of the subscription.
+func (s *Subscription[K, T]) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		Delivered:     s.stats.delivered.Load(),
+		Bytes:         s.stats.bytes.Load(),
+		Dropped:       s.stats.dropped.Load(),
+		Depth:         len(s.ch),
+		Capacity:      cap(s.ch),
+		HighWatermark: int(s.stats.high.Load()),
+		Lag:           s.Lag(),
+	}
+}
+
+// Pause pauses the subscription; see PubSub.Pause.
+func (s *Subscription[K, T]) Pause(limit int) {
+	s.ps.Pause(s.ch, limit)
+}
+
+// Resume resumes the subscription; see PubSub.Resume.
+func (s *Subscription[K, T]) Resume() []T {
+	return s.ps.Resume(s.ch)
+}
+
+// Heartbeat tells the liveness check set up with KeepAlive that the
+// owner of the subscription is alive.
+func (s *Subscription[K, T]) Heartbeat() {
+	s.beat.Store(true)
+}
+
+// KeepAlive closes the subscription when its owner disappears, for
+// example when the consumer goroutine crashed without closing it. Every
+// interval the subscription is checked: it is kept if Heartbeat was
+// called since the previous check or if probe, when not nil, returns
+// true; otherwise it is closed and the expiration logged. Calling
+// KeepAlive again replaces the previous check.
+func (s *Subscription[K, T]) KeepAlive(interval time.Duration, probe func() bool) {
+	s.Heartbeat() // the first check has a full interval
+
+	var check func()
+	check = func() {
+		if s.beat.Swap(false) || probe != nil && probe() {
+			s.schedule(interval, check)
+			return
+		}
+
+		s.ps.opts.logger.Warn("pubsub: subscription expired", "keys", s.Keys())
+		s.Close()
+	}
+
+	s.schedule(interval, check)
+}
+
+// schedule replaces the pending liveness check, unless the subscription
+// is closed.
+func (s *Subscription[K, T]) schedule(d time.Duration, check func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	if s.watchdog != nil {
+		s.watchdog.Stop()
+	}
+
+	s.watchdog = s.ps.opts.clock.AfterFunc(d, check)
+}
+
+// Close unsubscribes and drains the channel. It is safe to call more
+// than once and after the PubSub instance was closed.
+func (s *Subscription[K, T]) Close() {
+	s.mu.Lock()
+	s.closed = true
+	if s.watchdog != nil {
+		s.watchdog.Stop()
+	}
+	s.mu.Unlock()
+
+	s.once.Do(func() {
+		s.ps.Resume(s.ch)
+		s.ps.UnsubscribeAndDrain(s.Keys(), s.ch)
+
+		s.ps.mu.Lock()
+		delete(s.ps.managed, s.ch)
+		s.ps.disableCredits(s.ch)
+		s.ps.mu.Unlock()
+		s.ps.budget.release(cap(s.ch), 0)
+
+		s.runHooks()
+	})
+}
+
+// OnClose registers fn to be called when the subscription is closed, or
+// when the PubSub instance is closed first, so resources depending on the
+// subscription can tear down. Hooks are called once, in the reverse order
+// of their registration, after the channel was unsubscribed. If the hooks
+// were already called, fn is called right away.
+func (s *Subscription[K, T]) OnClose(fn func()) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		fn()
+		return
+	}
+
+	s.hooks = append(s.hooks, fn)
+	register := !s.hooked
+	s.hooked = true
+	s.mu.Unlock()
+
+	if !register {
+		return
+	}
+
+	// Outside the lock: the hook is called right away if the instance is
+	// closed.
+	unhook := s.ps.OnClose(s.runHooks)
+
+	s.mu.Lock()
+	s.unhook = unhook
+	s.mu.Unlock()
+}
+
+// runHooks calls the hooks registered with OnClose, unless they were
+// called already.
+func (s *Subscription[K, T]) runHooks() {
+	s.mu.Lock()
+	hooks, unhook := s.hooks, s.unhook
+	s.hooks, s.unhook, s.ended = nil, nil, true
+	s.mu.Unlock()
+
+	if unhook != nil {
+		unhook()
+	}
+
+	for _, fn := range slices.Backward(hooks) {
+		fn()
+	}
+}
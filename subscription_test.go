@@ -0,0 +1,156 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestSubscriptionStats(t *testing.T) {
+	ps := pubsub.New[string, int](
+		pubsub.WithBufferSize(2),
+		pubsub.WithDropPolicy(pubsub.DropNewest),
+		pubsub.WithClock(pstest.NewClock(time.Unix(0, 0))), // no lag
+	)
+	sub, err := ps.NewSubscription(context.Background(), "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	ps.Publish(context.Background(), "a", 1)
+	ps.Publish(context.Background(), "b", 2)
+	ps.Publish(context.Background(), "a", 3) // dropped, buffer full
+
+	if d := sub.Depth(); d != 2 {
+		t.Errorf("expected depth 2, got %d", d)
+	}
+
+	<-sub.C()
+	want := pubsub.SubscriptionStats{
+		Delivered:     2,
+		Dropped:       1,
+		Depth:         1,
+		Capacity:      2,
+		HighWatermark: 2,
+	}
+	if got := sub.Stats(); got != want {
+		t.Errorf("unexpected stats %+v, want %+v", got, want)
+	}
+}
+
+func TestSubscriptionLag(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	defer sub.Close()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-sub.C()
+	}()
+
+	ps.Publish(context.Background(), "k", 1)
+	if lag := sub.Lag(); lag < 20*time.Millisecond {
+		t.Errorf("expected lag of at least 20ms, got %v", lag)
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(1))
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	sub.Pause(1)
+	sub.Close()
+	sub.Close()
+
+	if n, _ := ps.Publish(context.Background(), "k", 1); n != 0 {
+		t.Errorf("expected no deliveries after close, got %d", n)
+	}
+}
+
+func TestSubscriptionForbidden(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.SetAuthorizer(pubsub.AuthorizerFunc[string](func(context.Context, pubsub.Action, string) error {
+		return pubsub.ErrForbidden
+	}))
+
+	if _, err := ps.NewSubscription(context.Background(), "k"); !errors.Is(err, pubsub.ErrForbidden) {
+		t.Errorf("expected ErrForbidden, got %v", err)
+	}
+}
+
+func TestSubscriptionKeepAlive(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	sub.KeepAlive(time.Minute, nil)
+
+	clock.Advance(time.Minute) // alive: KeepAlive counts as a heartbeat
+	sub.Heartbeat()
+	clock.Advance(time.Minute)
+	if keys := ps.Keys(); len(keys) != 1 {
+		t.Fatalf("expected the subscription to be alive, got keys %v", keys)
+	}
+
+	clock.Advance(time.Minute) // no heartbeat
+	if keys := ps.Keys(); len(keys) != 0 {
+		t.Errorf("expected the expired subscription to be removed, got keys %v", keys)
+	}
+}
+
+func TestSubscriptionKeepAliveProbe(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	alive := true
+	sub.KeepAlive(time.Second, func() bool { return alive })
+
+	for range 3 {
+		clock.Advance(time.Second)
+	}
+	if len(ps.Keys()) != 1 {
+		t.Fatal("expected the probe to keep the subscription alive")
+	}
+
+	alive = false
+	clock.Advance(time.Second)
+	if len(ps.Keys()) != 0 {
+		t.Error("expected the subscription to expire")
+	}
+
+	sub.Close()
+	if clock.Timers() != 0 {
+		t.Error("expected no pending checks after close")
+	}
+}
+
+func TestSubscriptionOnClose(t *testing.T) {
+	ps := pubsub.New[string, int]()
+
+	sub, _ := ps.NewSubscription(context.Background(), "k")
+	var calls []int
+	sub.OnClose(func() { calls = append(calls, 1) })
+	sub.OnClose(func() { calls = append(calls, 2) })
+	sub.Close()
+	sub.Close()
+	if !slices.Equal(calls, []int{2, 1}) {
+		t.Errorf("expected hooks in reverse order once, got %v", calls)
+	}
+
+	// closing the instance calls the hooks of open subscriptions
+	other, _ := ps.NewSubscription(context.Background(), "k")
+	var n int
+	other.OnClose(func() { n++ })
+	ps.Close()
+	other.Close()
+	if n != 1 {
+		t.Errorf("expected the hook called once, got %d", n)
+	}
+
+	other.OnClose(func() { n++ })
+	if n != 2 {
+		t.Error("expected a hook registered after close to be called right away")
+	}
+}
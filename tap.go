@@ -0,0 +1,61 @@
+package pubsub
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// tap is a registered publish observer.
+type tap[K comparable, T any] struct {
+	fn func(K, T)
+}
+
+// taps is the copy-on-write list of publish observers.
+type taps[K comparable, T any] struct {
+	mu   sync.Mutex // serializes changes
+	list atomic.Pointer[[]*tap[K, T]]
+}
+
+// Tap calls fn synchronously for every authorized Publish, before the
+// message is delivered and whether or not the key has subscribers, for
+// example to record or trace traffic. fn must be fast and must not
+// publish. The returned function removes the tap.
+func (ps *PubSub[K, T]) Tap(fn func(key K, msg T)) (remove func()) {
+	t := &tap[K, T]{fn: fn}
+	ps.taps.update(func(list []*tap[K, T]) []*tap[K, T] {
+		return append(slices.Clone(list), t)
+	})
+
+	return func() {
+		ps.taps.update(func(list []*tap[K, T]) []*tap[K, T] {
+			return slices.DeleteFunc(slices.Clone(list), func(x *tap[K, T]) bool { return x == t })
+		})
+	}
+}
+
+// update replaces the list with the result of fn.
+func (t *taps[K, T]) update(fn func([]*tap[K, T]) []*tap[K, T]) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var list []*tap[K, T]
+	if p := t.list.Load(); p != nil {
+		list = *p
+	}
+
+	list = fn(list)
+	t.list.Store(&list)
+}
+
+// call passes the published message to the taps.
+func (t *taps[K, T]) call(key K, msg T) {
+	p := t.list.Load()
+	if p == nil {
+		return
+	}
+
+	for _, tap := range *p {
+		tap.fn(key, msg)
+	}
+}
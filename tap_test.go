@@ -0,0 +1,26 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestTap(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var seen []int
+	remove := ps.Tap(func(key string, msg int) {
+		if key == "k" {
+			seen = append(seen, msg)
+		}
+	})
+
+	ps.Publish(context.Background(), "k", 1) // tapped without subscribers
+	remove()
+	ps.Publish(context.Background(), "k", 2)
+
+	if len(seen) != 1 || seen[0] != 1 {
+		t.Errorf("expected only the first publish to be tapped, got %v", seen)
+	}
+}
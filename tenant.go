@@ -0,0 +1,239 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Quota violations reported by Tenant. ErrRateLimited is also returned
+// for keys rate limited with ConfigureKey.
+var (
+	ErrTooManyKeys        = errors.New("pubsub: tenant key limit exceeded")
+	ErrTooManySubscribers = errors.New("pubsub: tenant subscriber limit exceeded")
+	ErrRateLimited        = errors.New("pubsub: publish rate exceeded")
+	ErrRetentionQuota     = errors.New("pubsub: tenant retained bytes limit exceeded")
+)
+
+// Quota limits the resources a tenant may use. Zero values mean no limit.
+type Quota struct {
+	MaxKeys        int     // distinct keys with subscriptions
+	MaxSubscribers int     // subscriptions, counted per channel and key
+	PublishRate    float64 // sustained publishes per second
+	PublishBurst   int     // publishes allowed at once; 1 if zero
+
+	// MaxRetainedBytes limits the size of the messages retained for the
+	// keys the tenant publishes to, as measured by the Sizer set with
+	// WithSizer, without which it has no effect. The oldest messages of
+	// a key are discarded to make room for a new one; a message that
+	// can't fit even then is rejected.
+	MaxRetainedBytes int
+
+	// OnViolation, if set, is called with the error of every rejected
+	// operation, for example to log or meter abusive tenants.
+	OnViolation func(err error)
+}
+
+// Tenant is a scoped view of a PubSub instance enforcing a quota.
+// All subscriptions of the tenant must be made through it.
+type Tenant[K comparable, T any] struct {
+	scope *Scope[K, T]
+	quota Quota
+
+	mu    sync.Mutex
+	subs  map[K]map[chan T]struct{} // scoped key subscriptions
+	count int                       // total subscriptions
+	rate  bucket                    // publish rate
+	kept  *retainQuota              // nil if retention is unlimited
+}
+
+// Tenant returns a view of the scope limited by the quota.
+func (s *Scope[K, T]) Tenant(quota Quota) *Tenant[K, T] {
+	if quota.PublishBurst <= 0 {
+		quota.PublishBurst = 1
+	}
+
+	t := &Tenant[K, T]{
+		scope: s,
+		quota: quota,
+		subs:  make(map[K]map[chan T]struct{}),
+		rate:  newBucket(quota.PublishRate, quota.PublishBurst, s.ps.opts.clock.Now()),
+	}
+	if quota.MaxRetainedBytes > 0 {
+		t.kept = &retainQuota{max: quota.MaxRetainedBytes}
+	}
+
+	return t
+}
+
+// Subscribe subscribes the channel to the keys, unless the subscription
+// would exceed the quota. The subscription is all or nothing. It fails
+// with ErrClosed if the instance is draining or closed. Subscriptions
+// removed without the tenant, by UnsubscribeAll or PurgeKey, stop
+// counting against the quota.
+func (t *Tenant[K, T]) Subscribe(keys []K, ch chan T) error {
+	t.mu.Lock()
+	t.prune()
+
+	newKeys, newSubs := 0, 0
+	seen := make(map[K]struct{}, len(keys))
+	for _, key := range keys {
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		subs, exists := t.subs[key]
+		if !exists {
+			newKeys++
+		}
+
+		if _, subscribed := subs[ch]; !subscribed {
+			newSubs++
+		}
+	}
+
+	var err error
+	switch {
+	case t.quota.MaxKeys > 0 && len(t.subs)+newKeys > t.quota.MaxKeys:
+		err = ErrTooManyKeys
+	case t.quota.MaxSubscribers > 0 && t.count+newSubs > t.quota.MaxSubscribers:
+		err = ErrTooManySubscribers
+	}
+	if err != nil {
+		t.mu.Unlock()
+		return t.violation(err)
+	}
+
+	defer t.mu.Unlock()
+
+	if err := t.scope.ps.SubscribeContext(context.Background(), t.scope.keys(keys), ch); err != nil {
+		return err
+	}
+
+	for key := range seen {
+		if _, exists := t.subs[key]; !exists {
+			t.subs[key] = make(map[chan T]struct{})
+		}
+		t.subs[key][ch] = struct{}{}
+	}
+	t.count += newSubs
+
+	return nil
+}
+
+// prune forgets the subscriptions removed without the tenant. The caller
+// must hold the tenant lock.
+func (t *Tenant[K, T]) prune() {
+	ps := t.scope.ps
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	for key, subs := range t.subs {
+		scoped := ps.resolve(t.scope.Key(key))
+		for ch := range subs {
+			if _, subscribed := ps.channelKeys[ch][scoped]; !subscribed {
+				delete(subs, ch)
+				t.count--
+			}
+		}
+
+		if len(subs) == 0 {
+			delete(t.subs, key)
+		}
+	}
+}
+
+// Unsubscribe removes the channel subscription from the keys.
+func (t *Tenant[K, T]) Unsubscribe(keys []K, ch chan T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range keys {
+		if subs, exists := t.subs[key]; exists {
+			if _, subscribed := subs[ch]; subscribed {
+				delete(subs, ch)
+				t.count--
+			}
+
+			if len(subs) == 0 {
+				delete(t.subs, key)
+			}
+		}
+	}
+
+	t.scope.Unsubscribe(keys, ch)
+}
+
+// Publish sends the message to the subscribers of the key, unless the
+// tenant exceeded its publish rate or the message can't be retained
+// within its quota.
+func (t *Tenant[K, T]) Publish(ctx context.Context, key K, msg T) (int, error) {
+	if err := t.allow(); err != nil {
+		return 0, err
+	}
+
+	if t.kept != nil && !t.scope.ps.checkRetainQuota(t.scope.Key(key), msg, t.kept) {
+		return 0, t.violation(ErrRetentionQuota)
+	}
+
+	return t.scope.Publish(ctx, key, msg)
+}
+
+// allow takes a token from the publish rate bucket.
+func (t *Tenant[K, T]) allow() error {
+	if t.quota.PublishRate <= 0 {
+		return nil
+	}
+
+	t.mu.Lock()
+	ok := t.rate.take(t.scope.ps.opts.clock.Now())
+	t.mu.Unlock()
+
+	if !ok {
+		return t.violation(ErrRateLimited)
+	}
+
+	return nil
+}
+
+// violation reports the error to the quota callback and returns it. The
+// caller must not hold the tenant lock, so the callback may use the
+// tenant.
+func (t *Tenant[K, T]) violation(err error) error {
+	if t.quota.OnViolation != nil {
+		t.quota.OnViolation(err)
+	}
+
+	return err
+}
+
+// bucket is a token bucket limiting the rate of operations. The caller
+// synchronizes access.
+type bucket struct {
+	rate   float64 // tokens per second
+	burst  int     // maximum tokens
+	tokens float64
+	last   time.Time // last refill
+}
+
+// newBucket returns a full bucket.
+func newBucket(rate float64, burst int, now time.Time) bucket {
+	return bucket{rate: rate, burst: burst, tokens: float64(burst), last: now}
+}
+
+// take refills the bucket and takes a token, reporting whether one was
+// left.
+func (b *bucket) take(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	b.tokens = min(b.tokens, float64(b.burst))
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
@@ -0,0 +1,155 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestTenantSubscriptionLimits(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var violations int
+	tenant := ps.Scoped(pubsub.Prefix("t1/")).Tenant(pubsub.Quota{
+		MaxKeys:        2,
+		MaxSubscribers: 3,
+		OnViolation:    func(error) { violations++ },
+	})
+
+	ch1, ch2 := make(chan int), make(chan int)
+	if err := tenant.Subscribe([]string{"a", "b"}, ch1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenant.Subscribe([]string{"c"}, ch2); !errors.Is(err, pubsub.ErrTooManyKeys) {
+		t.Errorf("expected ErrTooManyKeys, got %v", err)
+	}
+	if err := tenant.Subscribe([]string{"a"}, ch2); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenant.Subscribe([]string{"b"}, ch2); !errors.Is(err, pubsub.ErrTooManySubscribers) {
+		t.Errorf("expected ErrTooManySubscribers, got %v", err)
+	}
+	if violations != 2 {
+		t.Errorf("expected 2 violations, got %d", violations)
+	}
+
+	tenant.Unsubscribe([]string{"a", "b"}, ch1)
+	if err := tenant.Subscribe([]string{"c"}, ch2); err != nil {
+		t.Errorf("expected room after unsubscribe, got %v", err)
+	}
+}
+
+func TestTenantPublishRate(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	tenant := ps.Scoped(pubsub.Prefix("t1/")).Tenant(pubsub.Quota{
+		PublishRate:  0.001, // practically no refill during the test
+		PublishBurst: 2,
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := tenant.Publish(ctx, "a", i); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	if _, err := tenant.Publish(ctx, "a", 3); !errors.Is(err, pubsub.ErrRateLimited) {
+		t.Errorf("expected ErrRateLimited, got %v", err)
+	}
+}
+
+func TestTenantRetainedBytes(t *testing.T) {
+	ps := pubsub.New[string, string](pubsub.WithRetention(10),
+		pubsub.WithSizer(func(msg string) int { return len(msg) }))
+	var violations int
+	tenant := ps.Scoped(pubsub.Prefix("t1/")).Tenant(pubsub.Quota{
+		MaxRetainedBytes: 6,
+		OnViolation:      func(error) { violations++ },
+	})
+
+	ctx := context.Background()
+	for _, msg := range []string{"aa", "bb", "cc", "dddd"} {
+		if _, err := tenant.Publish(ctx, "a", msg); err != nil {
+			t.Fatalf("publish %s: %v", msg, err)
+		}
+	}
+
+	// The oldest messages of the key made room for the last one.
+	if got := ps.Retained("t1/a"); len(got) != 2 || got[0].Msg != "cc" || got[1].Msg != "dddd" {
+		t.Errorf("expected cc and dddd retained, got %v", got)
+	}
+
+	if _, err := tenant.Publish(ctx, "b", "e"); !errors.Is(err, pubsub.ErrRetentionQuota) {
+		t.Errorf("expected ErrRetentionQuota, got %v", err)
+	}
+	if violations != 1 {
+		t.Errorf("expected 1 violation, got %d", violations)
+	}
+
+	ps.PurgeKey("t1/a")
+	if _, err := tenant.Publish(ctx, "b", "e"); err != nil {
+		t.Errorf("expected room after purge, got %v", err)
+	}
+}
+
+func TestTenantUsage(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var tenant *pubsub.Tenant[string, int]
+	tenant = ps.Scoped(pubsub.Prefix("t1/")).Tenant(pubsub.Quota{
+		MaxSubscribers: 1,
+		OnViolation: func(error) {
+			tenant.Unsubscribe([]string{"a"}, nil) // must not deadlock
+		},
+	})
+
+	ch1, ch2 := make(chan int), make(chan int)
+	if err := tenant.Subscribe([]string{"a"}, ch1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenant.Subscribe([]string{"b"}, ch2); !errors.Is(err, pubsub.ErrTooManySubscribers) {
+		t.Errorf("expected ErrTooManySubscribers, got %v", err)
+	}
+
+	// Subscriptions removed without the tenant free the quota.
+	ps.UnsubscribeAll(ch1)
+	if err := tenant.Subscribe([]string{"b"}, ch2); err != nil {
+		t.Errorf("expected room after UnsubscribeAll, got %v", err)
+	}
+	ps.PurgeKey("t1/b")
+
+	// Failed subscriptions don't count.
+	ps.Close()
+	if err := tenant.Subscribe([]string{"c"}, ch1); !errors.Is(err, pubsub.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestTenantRetainedBytesShards(t *testing.T) {
+	ps := pubsub.New[string, string](pubsub.WithRetention(2), pubsub.WithShards(8),
+		pubsub.WithSizer(func(msg string) int { return len(msg) }))
+	tenant := ps.Scoped(pubsub.Prefix("t1/")).Tenant(pubsub.Quota{MaxRetainedBytes: 64})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				tenant.Publish(context.Background(), fmt.Sprint(i), "msg")
+			}
+		}()
+	}
+	wg.Wait()
+
+	var bytes int
+	for i := range 8 {
+		for _, r := range ps.Retained(fmt.Sprint("t1/", i)) {
+			bytes += len(r.Msg)
+		}
+	}
+	if bytes > 64 {
+		t.Errorf("expected at most 64 bytes retained, got %d", bytes)
+	}
+}
@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"context"
+	"time"
+)
+
+// Topic is a single stream of messages: a PubSub without the key
+// dimension, for the common case of one event stream.
+// It shares the semantics of PubSub for a single key.
+type Topic[T any] struct {
+	ps *PubSub[struct{}, T]
+}
+
+// topicKey is the only key of a Topic.
+var topicKey = []struct{}{{}}
+
+// NewTopic creates and returns a new Topic instance configured with the options.
+func NewTopic[T any](opts ...Option) *Topic[T] {
+	return &Topic[T]{ps: New[struct{}, T](opts...)}
+}
+
+// Subscribe adds a channel to receive messages published to the topic.
+// If the channel is already subscribed, this is a no-op.
+func (t *Topic[T]) Subscribe(ch chan T) {
+	t.ps.Subscribe(topicKey, ch)
+}
+
+// Unsubscribe removes a channel from receiving messages of the topic.
+func (t *Topic[T]) Unsubscribe(ch chan T) {
+	t.ps.Unsubscribe(topicKey, ch)
+}
+
+// Publish sends a message to all subscribed channels, with the same
+// blocking and context semantics as PubSub.Publish.
+func (t *Topic[T]) Publish(ctx context.Context, msg T) (int, error) {
+	return t.ps.Publish(ctx, struct{}{}, msg)
+}
+
+// PublishWithTimeout is a convenience method that creates a context with timeout.
+func (t *Topic[T]) PublishWithTimeout(msg T, timeout time.Duration) (int, error) {
+	return t.ps.PublishWithTimeout(struct{}{}, msg, timeout)
+}
+
+// Next waits for the next message published to the topic.
+func (t *Topic[T]) Next(ctx context.Context) (T, error) {
+	return t.ps.Next(ctx, struct{}{})
+}
+
+// Latest returns the most recent message retained for the topic.
+func (t *Topic[T]) Latest() (T, bool) {
+	return t.ps.Latest(struct{}{})
+}
+
+// Retained returns the messages retained for the topic, oldest first.
+func (t *Topic[T]) Retained() []Retained[T] {
+	return t.ps.Retained(struct{}{})
+}
+
+// SubscribeWithReplay subscribes the channel and returns the retained
+// messages published before it, with the semantics of
+// PubSub.SubscribeWithReplay.
+func (t *Topic[T]) SubscribeWithReplay(ch chan T) ([]Retained[T], error) {
+	return t.ps.SubscribeWithReplay(struct{}{}, ch)
+}
@@ -0,0 +1,57 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestTopic(t *testing.T) {
+	topic := pubsub.NewTopic[string]()
+	ch1, ch2 := make(chan string, 1), make(chan string, 1)
+	topic.Subscribe(ch1)
+	topic.Subscribe(ch2)
+
+	n, err := topic.Publish(context.Background(), "hello")
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 deliveries, got %d, %v", n, err)
+	}
+	if <-ch1 != "hello" || <-ch2 != "hello" {
+		t.Error("unexpected message")
+	}
+
+	topic.Unsubscribe(ch1)
+	if n, _ := topic.PublishWithTimeout("bye", time.Second); n != 1 {
+		t.Errorf("expected 1 delivery, got %d", n)
+	}
+}
+
+func TestTopicRetention(t *testing.T) {
+	topic := pubsub.NewTopic[string](pubsub.WithRetention(2))
+	if _, ok := topic.Latest(); ok {
+		t.Error("expected nothing retained")
+	}
+
+	for _, msg := range []string{"a", "b", "c"} {
+		topic.Publish(context.Background(), msg)
+	}
+
+	if msg, ok := topic.Latest(); !ok || msg != "c" {
+		t.Errorf("expected c, got %q, %v", msg, ok)
+	}
+	if got := topic.Retained(); len(got) != 2 || got[0].Msg != "b" || got[1].Msg != "c" {
+		t.Errorf("expected b, c retained, got %v", got)
+	}
+
+	ch := make(chan string, 1)
+	history, err := topic.SubscribeWithReplay(ch)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("expected 2 replayed messages, got %v, %v", history, err)
+	}
+	topic.Publish(context.Background(), "d")
+	if msg := <-ch; msg != "d" {
+		t.Errorf("expected d, got %q", msg)
+	}
+}
@@ -0,0 +1,212 @@
+package pubsub
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// GraphFormat is a graph description language written by WriteTopology.
+type GraphFormat int
+
+const (
+	DOT     GraphFormat = iota // Graphviz DOT
+	Mermaid                    // Mermaid flowchart
+)
+
+// component is a part of the topology drawn by WriteTopology: a derived
+// key or an annotated component, such as a bridge.
+type component[K comparable] struct {
+	name    string
+	in, out []K
+}
+
+// Annotate records an external component, such as a bridge or a worker
+// pool, consuming the keys in and publishing to the keys out, so it is
+// drawn by WriteTopology. It doesn't affect delivery. The returned
+// function removes the annotation.
+func (ps *PubSub[K, T]) Annotate(name string, in, out []K) (remove func()) {
+	c := &component[K]{name: name, in: in, out: out}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.components = append(ps.components, c)
+
+	return func() {
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+
+		ps.components = slices.DeleteFunc(ps.components, func(x *component[K]) bool { return x == c })
+	}
+}
+
+// WriteTopology writes the current topology as a graph in the format, to
+// document and debug event graphs: keys, the channels and handlers
+// subscribed to them, derived keys and annotated components. Messages
+// flow along the edges. Keys are labeled with fmt.Sprint.
+func (ps *PubSub[K, T]) WriteTopology(w io.Writer, format GraphFormat) error {
+	g := ps.graph()
+
+	var b strings.Builder
+	switch format {
+	case Mermaid:
+		b.WriteString("flowchart LR\n")
+		for _, n := range g.nodes {
+			fmt.Fprintf(&b, "  %s%s\n", n.id, mermaidShape(n))
+		}
+		for _, e := range g.edges {
+			fmt.Fprintf(&b, "  %s --> %s\n", e[0], e[1])
+		}
+
+	default:
+		b.WriteString("digraph pubsub {\n  rankdir=LR;\n")
+		for _, n := range g.nodes {
+			fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", n.id, dotQuote(n.label), dotShapes[n.kind])
+		}
+		for _, e := range g.edges {
+			fmt.Fprintf(&b, "  %s -> %s;\n", e[0], e[1])
+		}
+		b.WriteString("}\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Kinds of graph nodes.
+const (
+	nodeKey = iota
+	nodeChannel
+	nodeHandler
+	nodeComponent
+)
+
+// dotShapes are the DOT shapes of the node kinds.
+var dotShapes = [...]string{"ellipse", "box", "box", "component"}
+
+// graphNode is a node of a topology graph.
+type graphNode struct {
+	id    string
+	label string
+	kind  int
+}
+
+// topology is a snapshot of the topology as a graph.
+type topology struct {
+	nodes []graphNode
+	edges [][2]string
+}
+
+// graph takes a snapshot of the topology.
+func (ps *PubSub[K, T]) graph() topology {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	var g topology
+
+	// Collect the keys first, in name order, for a stable output.
+	names := make(map[K]string)
+	addKey := func(key K) {
+		if _, ok := names[key]; !ok {
+			names[key] = fmt.Sprint(key)
+		}
+	}
+	for key := range ps.subscribers {
+		addKey(key)
+	}
+	for key := range ps.handlers {
+		addKey(key)
+	}
+	for _, c := range ps.components {
+		for _, key := range slices.Concat(c.in, c.out) {
+			addKey(key)
+		}
+	}
+
+	keys := make([]K, 0, len(names))
+	for key := range names {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b K) int { return cmp.Compare(names[a], names[b]) })
+
+	ids := make(map[K]string, len(keys))
+	for i, key := range keys {
+		ids[key] = fmt.Sprintf("k%d", i)
+		g.nodes = append(g.nodes, graphNode{ids[key], names[key], nodeKey})
+	}
+
+	channels := make(map[chan T]string)
+	handlers := make(map[*handler[K, T]]string)
+	for _, key := range keys {
+		for ch := range ps.subscribers[key].channels() {
+			id, ok := channels[ch]
+			if !ok {
+				id = fmt.Sprintf("c%d", len(channels))
+				channels[ch] = id
+				g.nodes = append(g.nodes, graphNode{id, ps.channelLabel(ch), nodeChannel})
+			}
+			g.edges = append(g.edges, [2]string{ids[key], id})
+		}
+
+		for _, h := range ps.handlers[key] {
+			id, ok := handlers[h]
+			if !ok {
+				id = fmt.Sprintf("h%d", len(handlers))
+				handlers[h] = id
+				g.nodes = append(g.nodes, graphNode{id, "handler", nodeHandler})
+			}
+			g.edges = append(g.edges, [2]string{ids[key], id})
+		}
+	}
+
+	for i, c := range ps.components {
+		id := fmt.Sprintf("p%d", i)
+		g.nodes = append(g.nodes, graphNode{id, c.name, nodeComponent})
+		for _, key := range c.in {
+			g.edges = append(g.edges, [2]string{ids[key], id})
+		}
+		for _, key := range c.out {
+			g.edges = append(g.edges, [2]string{id, ids[key]})
+		}
+	}
+
+	return g
+}
+
+// channelLabel describes a subscribed channel. The caller must hold the
+// read lock.
+func (ps *PubSub[K, T]) channelLabel(ch chan T) string {
+	kind := "chan"
+	if _, managed := ps.managed[ch]; managed {
+		kind = "subscription"
+	}
+
+	label := fmt.Sprintf("%s %d/%d", kind, len(ch), cap(ch))
+	if _, paused := ps.paused[ch]; paused {
+		label += " paused"
+	}
+
+	return label
+}
+
+// dotQuote quotes a DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// mermaidShape returns the Mermaid shape and label of the node.
+func mermaidShape(n graphNode) string {
+	label := `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(n.label) + `"`
+
+	switch n.kind {
+	case nodeKey:
+		return "([" + label + "])"
+	case nodeComponent:
+		return "[[" + label + "]]"
+	default:
+		return "[" + label + "]"
+	}
+}
@@ -0,0 +1,61 @@
+package pubsub_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func newTopology() *pubsub.PubSub[string, int] {
+	ps := pubsub.New[string, int]()
+	ps.Subscribe([]string{"orders", "payments"}, make(chan int, 4))
+	ps.SubscribeFunc([]string{"payments"}, func(context.Context, string, int) error { return nil })
+	ps.Derive("big", []string{"orders"}, func(n int) (int, bool) { return n, n > 100 })
+	ps.Annotate(`kafka "in"`, nil, []string{"orders"})
+
+	return ps
+}
+
+func TestWriteTopologyDOT(t *testing.T) {
+	var b strings.Builder
+	if err := newTopology().WriteTopology(&b, pubsub.DOT); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph pubsub {
+  rankdir=LR;
+  k0 [label="big", shape=ellipse];
+  k1 [label="orders", shape=ellipse];
+  k2 [label="payments", shape=ellipse];
+  c0 [label="chan 0/4", shape=box];
+  h0 [label="handler", shape=box];
+  p0 [label="derive", shape=component];
+  p1 [label="kafka \"in\"", shape=component];
+  k1 -> c0;
+  k2 -> c0;
+  k2 -> h0;
+  k1 -> p0;
+  p0 -> k0;
+  p1 -> k1;
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("unexpected DOT:\n%s", got)
+	}
+}
+
+func TestWriteTopologyMermaid(t *testing.T) {
+	var b strings.Builder
+	if err := newTopology().WriteTopology(&b, pubsub.Mermaid); err != nil {
+		t.Fatal(err)
+	}
+
+	got := b.String()
+	for _, want := range []string{"flowchart LR\n", `k1(["orders"])`, `p1[["kafka #quot;in#quot;"]]`, "k1 --> c0"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package pubsub
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MessageID returns the ID of a message to trace, or an empty string if
+// the message isn't traced.
+type MessageID[T any] func(msg T) string
+
+// WithTracer records the journey of the messages whose ID returned by id
+// is not empty: when they were published and, for each subscribed channel
+// and handler, when and with what outcome they were delivered, and how
+// many messages were queued in the channel then. The journeys of the last
+// size traced messages, at least one, are kept and returned by Trace, to
+// answer where an event went during an incident. Returning an ID only for
+// some messages samples them. T must be the message type of the PubSub
+// instance, otherwise New panics.
+//
+// Messages published by Publish, MustPublish and PublishWait are traced.
+// Tracing a message allocates; the others are not affected.
+func WithTracer[T any](id MessageID[T], size int) Option {
+	return func(o *options) {
+		o.tracer = id
+		o.traceSize = max(size, 1)
+	}
+}
+
+// Trace is the journey of a traced message.
+type Trace[K comparable, T any] struct {
+	ID        string
+	Key       K
+	Published time.Time
+	Events    []TraceEvent[T] // in the order of the deliveries
+	Err       error           // returned by the publish
+}
+
+// TraceEvent is the delivery of a traced message to a subscriber.
+type TraceEvent[T any] struct {
+	Time       time.Time
+	Subscriber chan T // nil for handlers registered with SubscribeFunc
+	Outcome    Outcome
+	Queued     int   // messages waiting in the channel after the delivery
+	Err        error // context error if TimedOut, handler error if HandlerFailed
+}
+
+// tracer keeps the journeys of the last traced messages.
+type tracer[K comparable, T any] struct {
+	id     MessageID[T]
+	mu     sync.Mutex
+	traces map[string]*trace[K, T]
+	ring   []*trace[K, T] // oldest first
+	size   int
+}
+
+// trace is a journey being recorded.
+type trace[K comparable, T any] struct {
+	ps *PubSub[K, T]
+	Trace[K, T]
+}
+
+// setTracer applies the WithTracer option.
+func (ps *PubSub[K, T]) setTracer() {
+	if ps.opts.tracer == nil {
+		return
+	}
+
+	id, ok := ps.opts.tracer.(MessageID[T])
+	if !ok {
+		panic(fmt.Sprintf("pubsub: WithTracer for %T used with message type %v", ps.opts.tracer, reflect.TypeFor[T]()))
+	}
+
+	ps.tracer = &tracer[K, T]{
+		id:     id,
+		traces: make(map[string]*trace[K, T]),
+		size:   ps.opts.traceSize,
+	}
+}
+
+// trace starts the journey of the message, or returns nil if it isn't
+// traced.
+func (ps *PubSub[K, T]) trace(key K, msg T) *trace[K, T] {
+	t := ps.tracer
+	if t == nil {
+		return nil
+	}
+
+	id := t.id(msg)
+	if id == "" {
+		return nil
+	}
+
+	tr := &trace[K, T]{ps: ps, Trace: Trace[K, T]{ID: id, Key: key, Published: ps.opts.clock.Now()}}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.ring) == t.size {
+		if oldest := t.ring[0]; t.traces[oldest.ID] == oldest {
+			delete(t.traces, oldest.ID)
+		}
+		t.ring = slices.Delete(t.ring, 0, 1)
+	}
+	t.ring = append(t.ring, tr)
+	t.traces[id] = tr // a message published again has a new journey
+
+	return tr
+}
+
+// channel returns the function recording deliveries to channels, or nil
+// if tr is nil.
+func (tr *trace[K, T]) channel() func(ch chan T, o Outcome, err error) {
+	if tr == nil {
+		return nil
+	}
+
+	return tr.record
+}
+
+// handler returns the function recording calls of handlers, or nil if tr
+// is nil.
+func (tr *trace[K, T]) handler() func(o Outcome, err error) {
+	if tr == nil {
+		return nil
+	}
+
+	return func(o Outcome, err error) {
+		tr.record(nil, o, err)
+	}
+}
+
+// record adds a delivery to the journey. It may be called concurrently.
+func (tr *trace[K, T]) record(ch chan T, o Outcome, err error) {
+	e := TraceEvent[T]{Time: tr.ps.opts.clock.Now(), Subscriber: ch, Outcome: o, Queued: len(ch), Err: err}
+
+	tr.ps.tracer.mu.Lock()
+	defer tr.ps.tracer.mu.Unlock()
+
+	tr.Events = append(tr.Events, e)
+}
+
+// end records the result of the publish; tr may be nil.
+func (tr *trace[K, T]) end(err error) {
+	if tr == nil {
+		return
+	}
+
+	tr.ps.tracer.mu.Lock()
+	defer tr.ps.tracer.mu.Unlock()
+
+	tr.Err = err
+}
+
+// Trace returns the journey of the last traced message with the ID, if it
+// is still kept. It reports false if no Tracer is set.
+func (ps *PubSub[K, T]) Trace(id string) (Trace[K, T], bool) {
+	t := ps.tracer
+	if t == nil {
+		return Trace[K, T]{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tr, ok := t.traces[id]
+	if !ok {
+		return Trace[K, T]{}, false
+	}
+
+	journey := tr.Trace
+	journey.Events = slices.Clone(journey.Events)
+
+	return journey, true
+}
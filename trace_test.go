@@ -0,0 +1,97 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+type event struct {
+	ID   int
+	Body string
+}
+
+func TestTrace(t *testing.T) {
+	id := func(e event) string {
+		if e.ID == 0 {
+			return "" // not traced
+		}
+		return strconv.Itoa(e.ID)
+	}
+	ps := pubsub.New[string, event](
+		pubsub.WithTracer(id, 2),
+		pubsub.WithDropPolicy(pubsub.DropNewest),
+	)
+
+	fast := make(chan event, 2)
+	full := make(chan event) // nobody reads
+	ps.Subscribe([]string{"orders"}, fast)
+	ps.Subscribe([]string{"orders"}, full)
+	errFailed := errors.New("failed")
+	ps.SubscribeFunc([]string{"orders"}, func(context.Context, string, event) error { return errFailed })
+
+	ps.Publish(context.Background(), "orders", event{ID: 1})
+	ps.Publish(context.Background(), "orders", event{Body: "untraced"})
+
+	tr, ok := ps.Trace("1")
+	if !ok {
+		t.Fatal("expected the trace of message 1")
+	}
+	if tr.ID != "1" || tr.Key != "orders" || tr.Published.IsZero() {
+		t.Errorf("unexpected trace %+v", tr)
+	}
+	if !errors.Is(tr.Err, errFailed) {
+		t.Errorf("expected the publish error, got %v", tr.Err)
+	}
+
+	outcomes := make(map[chan event]pubsub.Outcome)
+	var handler pubsub.TraceEvent[event]
+	for _, e := range tr.Events {
+		if e.Subscriber == nil {
+			handler = e
+		} else {
+			outcomes[e.Subscriber] = e.Outcome
+			if e.Subscriber == fast && e.Queued != 1 {
+				t.Errorf("expected 1 queued message, got %d", e.Queued)
+			}
+		}
+	}
+	if len(tr.Events) != 3 || outcomes[fast] != pubsub.Delivered || outcomes[full] != pubsub.Dropped {
+		t.Errorf("unexpected journey %+v", tr.Events)
+	}
+	if handler.Outcome != pubsub.HandlerFailed || !errors.Is(handler.Err, errFailed) {
+		t.Errorf("unexpected handler event %+v", handler)
+	}
+
+	// only the last 2 traced messages are kept
+	ps.Publish(context.Background(), "orders", event{ID: 2})
+	ps.Publish(context.Background(), "orders", event{ID: 3})
+	if _, ok := ps.Trace("1"); ok {
+		t.Error("expected the oldest trace evicted")
+	}
+	if _, ok := ps.Trace("3"); !ok {
+		t.Error("expected the trace of message 3")
+	}
+}
+
+func TestTraceDisabled(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.Publish(context.Background(), "k", 1)
+
+	if _, ok := ps.Trace("1"); ok {
+		t.Error("expected no trace without a tracer")
+	}
+}
+
+func TestTracerType(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a mismatched message type")
+		}
+	}()
+
+	pubsub.New[string, int](pubsub.WithTracer(func(string) string { return "" }, 1))
+}
@@ -0,0 +1,263 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTxFull is returned by PublishTx when the messages don't fit into the
+// buffers of the subscribers.
+var ErrTxFull = errors.New("pubsub: transaction doesn't fit subscriber buffers")
+
+// PublishTx publishes a set of messages to their keys atomically: either
+// all of them are placed into the buffers of all subscribers and retained,
+// or none is, so consumers never see a partial multi-key update, as long
+// as only the instance sends to the subscriber channels. No other
+// publish is interleaved with the transaction, and each subscriber
+// receives the messages destined to it in the given order.
+//
+// PublishTx never blocks on subscribers: if a subscriber channel, the
+// buffer of a paused one, or the credits and backlog of a Subscription
+// with flow control don't have room for all of its messages, or the
+// messages kept in buffers and retained would exceed the memory budget,
+// it fails with ErrTxFull. Unbuffered subscriber channels thus always
+// make it fail. Messages are authorized, converted and validated first;
+// an error of any of them aborts the whole transaction.
+//
+// The room is checked under a lock excluding the other publishes, so only
+// a channel also written to from outside the instance can lose it before
+// the messages are sent. Then the transaction fails with ErrTxFull but is
+// partially delivered: the messages kept in pause buffers and backlogs are
+// taken back, those already sent into channels can't be, and PublishTx
+// returns their number. Don't send to subscriber channels directly if
+// transactions must be all or nothing.
+//
+// Handlers registered with SubscribeFunc are not part of the transaction:
+// once it succeeded, they are called for each message in order, as by
+// Publish, and their errors are joined. It returns the total number of
+// deliveries.
+func (ps *PubSub[K, T]) PublishTx(ctx context.Context, msgs ...Keyed[K, T]) (int, error) {
+	msgs = append([]Keyed[K, T](nil), msgs...)
+	handlers := make([][]*handler[K, T], len(msgs))
+	for i, m := range msgs {
+		key, msg, hs, err := ps.prepare(ctx, m.Key, m.Msg)
+		if err != nil {
+			return 0, err
+		}
+
+		msgs[i].Key, msgs[i].Msg, handlers[i] = key, msg, hs
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered, err := ps.commit(ctx, msgs)
+	if err != nil {
+		return delivered, err
+	}
+
+	for _, m := range msgs {
+		ps.taps.call(m.Key, m.Msg)
+	}
+
+	var errs []error
+	for i, m := range msgs {
+		if len(handlers[i]) == 0 {
+			continue
+		}
+
+		n, err := ps.call(ctx, m.Key, m.Msg, handlers[i])
+		delivered += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// commit delivers and retains the messages of a transaction if they all
+// fit, and returns the number of deliveries, or of the messages left in
+// channels if it failed after sending some.
+func (ps *PubSub[K, T]) commit(ctx context.Context, msgs []Keyed[K, T]) (int, error) {
+	defer ps.watch.dispatch()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if ps.state != stateOpen {
+		return 0, ErrClosed
+	}
+
+	// Nobody else can send to the channels nor take budget while the lock
+	// is held, so room checked now is still there when sending.
+	need := make(map[chan T]int)
+	sizes := make(map[chan T]int)
+	for _, m := range msgs {
+		targets, _ := ps.targets(m.Key)
+		for ch := range targets {
+			need[ch]++
+			sizes[ch] += ps.Size(m.Msg)
+		}
+	}
+
+	var kept, keptSize int
+	direct := make(map[chan T]int, len(need))
+	for ch, n := range need {
+		if l := ps.limits[ch]; l != nil {
+			n = min(n, int(l.left.Load()))
+		}
+
+		d, k := ps.capacity(ch)
+		if n > d+k {
+			return 0, ErrTxFull
+		}
+
+		if n > d {
+			kept += n - d
+			keptSize += sizes[ch] // at most
+		}
+		direct[ch] = d
+	}
+
+	for _, m := range msgs {
+		if ps.retention(m.Key) > 0 {
+			kept++
+			keptSize += ps.Size(m.Msg)
+		}
+	}
+
+	if !ps.budget.fits(kept, keptSize, &ps.opts) {
+		return 0, ErrTxFull
+	}
+
+	var (
+		delivered int
+		counts    = make([]int, len(msgs))
+		sent      = make(map[chan T]int, len(need))
+		spent     []*limit[K, T]
+	)
+	for i, m := range msgs {
+		targets, _ := ps.targets(m.Key)
+		for ch := range targets {
+			l := ps.limits[ch]
+			taken, last := l.take()
+			if !taken {
+				continue
+			}
+
+			if ok, _ := ps.deliver(ctx, ch, m.Msg, DropNewest); !ok {
+				// Only a send from outside the instance can take the room:
+				// the messages kept in buffers are taken back, those
+				// already in channels can't be.
+				l.giveBack()
+				return ps.rollback(sent, direct, spent), ErrTxFull
+			}
+
+			sent[ch]++
+			counts[i]++
+			if last {
+				spent = append(spent, l)
+			}
+		}
+	}
+
+	for i, m := range msgs {
+		ps.retain(m.Key, m.Msg)
+		ps.recordPublish(m.Key, counts[i], 0)
+		delivered += counts[i]
+	}
+
+	for _, l := range spent {
+		ps.expireLimit(l)
+	}
+
+	return delivered, nil
+}
+
+// rollback takes back the messages of a failed transaction kept for the
+// channels beyond those sent directly, and returns the number of those
+// sent directly. The caller must hold the lock.
+func (ps *PubSub[K, T]) rollback(sent, direct map[chan T]int, spent []*limit[K, T]) int {
+	var delivered int
+	for ch, n := range sent {
+		delivered += min(n, direct[ch])
+		n -= direct[ch]
+		if n <= 0 {
+			continue
+		}
+
+		ps.unkeep(ch, n)
+		if l := ps.limits[ch]; l != nil {
+			for range n {
+				l.giveBack()
+			}
+		}
+	}
+
+	for _, l := range spent {
+		if l.left.Load() == 0 {
+			ps.expireLimit(l)
+		}
+	}
+
+	return delivered
+}
+
+// room returns the number of messages the channel, its pause buffer or
+// credit backlog can take without blocking or dropping. The caller must
+// hold the lock.
+func (ps *PubSub[K, T]) room(ch chan T) int {
+	direct, kept := ps.capacity(ch)
+	return direct + kept
+}
+
+// capacity returns the number of messages the channel can take without
+// blocking or dropping: sent to it directly, then kept in its pause buffer
+// or credit backlog. The caller must hold the lock.
+func (ps *PubSub[K, T]) capacity(ch chan T) (direct, kept int) {
+	if b, paused := ps.paused[ch]; paused {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		return 0, b.limit - len(b.msgs)
+	}
+
+	c, credited := ps.credited[ch]
+	if !credited {
+		return cap(ch) - len(ch), 0
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flush(ch)
+	free := cap(ch) - len(ch)
+	backlog := c.backlog.limit - len(c.backlog.msgs)
+	switch {
+	case c.n == 0 || len(c.backlog.msgs) > 0:
+		return 0, backlog
+	case c.n <= free: // the next ones are kept once the credits are spent
+		return c.n, backlog
+	default: // the next one would block
+		return free, 0
+	}
+}
+
+// unkeep discards the n newest messages kept for the channel in its pause
+// buffer or credit backlog. The caller must hold the lock.
+func (ps *PubSub[K, T]) unkeep(ch chan T, n int) {
+	b, paused := ps.paused[ch]
+	if !paused {
+		c, credited := ps.credited[ch]
+		if !credited {
+			return
+		}
+		b = &c.backlog
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removeNewest(min(n, len(b.msgs)))
+}
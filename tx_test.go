@@ -0,0 +1,172 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestPublishTx(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithRetention(10))
+	a, b := make(chan int, 2), make(chan int, 1)
+	ps.Subscribe([]string{"debit", "credit"}, a)
+	ps.Subscribe([]string{"credit"}, b)
+
+	n, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "debit", Msg: -10},
+		pubsub.Keyed[string, int]{Key: "credit", Msg: 10},
+	)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 deliveries, got %d, %v", n, err)
+	}
+
+	if <-a != -10 || <-a != 10 || <-b != 10 {
+		t.Error("unexpected messages")
+	}
+
+	if v, _ := ps.Latest("debit"); v != -10 {
+		t.Errorf("expected the message retained, got %d", v)
+	}
+}
+
+func TestPublishTxFull(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	a, b := make(chan int, 2), make(chan int, 1)
+	ps.Subscribe([]string{"debit"}, a)
+	ps.Subscribe([]string{"credit"}, b)
+	b <- 0 // no room left
+
+	_, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "debit", Msg: -10},
+		pubsub.Keyed[string, int]{Key: "credit", Msg: 10},
+	)
+	if !errors.Is(err, pubsub.ErrTxFull) {
+		t.Fatalf("expected ErrTxFull, got %v", err)
+	}
+
+	if len(a) != 0 {
+		t.Error("expected no message published")
+	}
+}
+
+func TestPublishTxInvalid(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.SetValidator(pubsub.ValidatorFunc[string, int](func(_ string, msg int) error {
+		if msg == 0 {
+			return errors.New("zero")
+		}
+		return nil
+	}))
+
+	a := make(chan int, 2)
+	ps.Subscribe([]string{"a", "b"}, a)
+
+	_, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "a", Msg: 1},
+		pubsub.Keyed[string, int]{Key: "b", Msg: 0},
+	)
+	if !errors.Is(err, pubsub.ErrInvalid) || len(a) != 0 {
+		t.Errorf("expected the whole transaction rejected, got %v with %d delivered", err, len(a))
+	}
+}
+
+func TestPublishTxCredits(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithBufferSize(4))
+	sub, err := ps.NewSubscription(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	sub.EnableCredits(0, 0) // no credit and no backlog: nothing fits
+
+	b := make(chan int, 1)
+	ps.Subscribe([]string{"b"}, b)
+
+	n, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "a", Msg: 1},
+		pubsub.Keyed[string, int]{Key: "b", Msg: 2},
+	)
+	if !errors.Is(err, pubsub.ErrTxFull) || n != 0 {
+		t.Fatalf("expected ErrTxFull, got %d, %v", n, err)
+	}
+	if len(b) != 0 {
+		t.Error("expected no partial delivery")
+	}
+
+	sub.EnableCredits(1, 1) // one sent, one kept
+	if n, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "a", Msg: 1},
+		pubsub.Keyed[string, int]{Key: "a", Msg: 2},
+		pubsub.Keyed[string, int]{Key: "b", Msg: 3},
+	); err != nil || n != 3 {
+		t.Fatalf("expected 3 deliveries, got %d, %v", n, err)
+	}
+}
+
+func TestPublishTxBudget(t *testing.T) {
+	ps := pubsub.New[string, int](pubsub.WithMemoryBudget(1, pubsub.EvictOldest))
+	a, b := make(chan int, 2), make(chan int, 2)
+	ps.Subscribe([]string{"a"}, a)
+	ps.Subscribe([]string{"b"}, b)
+	ps.Pause(a, 2)
+
+	// The paused buffer has room for both, the budget only for one.
+	_, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "a", Msg: 1},
+		pubsub.Keyed[string, int]{Key: "a", Msg: 2},
+		pubsub.Keyed[string, int]{Key: "b", Msg: 3},
+	)
+	if !errors.Is(err, pubsub.ErrTxFull) {
+		t.Fatalf("expected ErrTxFull, got %v", err)
+	}
+	if len(b) != 0 || len(ps.Resume(a)) != 0 {
+		t.Error("expected no partial delivery")
+	}
+}
+
+func TestPublishTxPartial(t *testing.T) {
+	a, b := make(chan int, 2), make(chan int, 1)
+	var calls int
+	ps := pubsub.New[string, int](pubsub.WithRetention(1), pubsub.WithSizer(func(int) int {
+		// Fill b from outside the instance after the room is checked,
+		// while the retained sizes are computed.
+		if calls++; calls == 3 {
+			b <- 0
+		}
+		return 1
+	}))
+	ps.Subscribe([]string{"a"}, a)
+	ps.Subscribe([]string{"b"}, b)
+
+	n, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "a", Msg: 1},
+		pubsub.Keyed[string, int]{Key: "b", Msg: 2},
+	)
+	if !errors.Is(err, pubsub.ErrTxFull) || n != 1 || len(a) != 1 {
+		t.Errorf("expected ErrTxFull with the message sent to a counted, got %d, %v", n, err)
+	}
+}
+
+func TestPublishTxHandlers(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	var got []int
+	ps.SubscribeFunc([]string{"a", "b"}, func(_ context.Context, _ string, msg int) error {
+		got = append(got, msg)
+		return nil
+	})
+	var tapped int
+	ps.Tap(func(string, int) { tapped++ })
+
+	n, err := ps.PublishTx(context.Background(),
+		pubsub.Keyed[string, int]{Key: "a", Msg: 1},
+		pubsub.Keyed[string, int]{Key: "b", Msg: 2},
+	)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 deliveries, got %d, %v", n, err)
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 || tapped != 2 {
+		t.Errorf("expected the handlers called in order and 2 taps, got %v, %d", got, tapped)
+	}
+}
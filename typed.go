@@ -0,0 +1,56 @@
+package pubsub
+
+import "sync"
+
+// On registers a handler for messages of type E published to the key of
+// a heterogeneous PubSub. Messages of other types are ignored, so
+// handlers for different event types can share a key while staying
+// type-safe:
+//
+//	pubsub.On(bus, "orders", func(e OrderCreated) { ... })
+//	pubsub.On(bus, "orders", func(e OrderShipped) { ... })
+//
+// The handler is called from a dedicated goroutine, one message at a time
+// in publish order. The returned function unregisters the handler and
+// waits for a running call to return; it must not be called from the
+// handler itself.
+func On[E any, K comparable](ps *PubSub[K, any], key K, handler func(E)) (off func()) {
+	return ps.handle([]K{key}, ps.bufferSize(key), func(msg any) {
+		if e, ok := msg.(E); ok {
+			handler(e)
+		}
+	})
+}
+
+// handle subscribes a channel with the given buffer to the keys and calls
+// the handler for each message from a dedicated goroutine. The returned
+// function removes the subscription and waits for the goroutine to exit.
+func (ps *PubSub[K, T]) handle(keys []K, buffer int, handler func(T)) (off func()) {
+	ch := make(chan T, buffer)
+	ps.Subscribe(keys, ch)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ps.UnsubscribeAndDrain(keys, ch)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case msg := <-ch:
+				handler(msg)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+type orderCreated struct{ ID int }
+type orderShipped struct{ ID int }
+
+func TestOn(t *testing.T) {
+	bus := pubsub.New[string, any]()
+	created := make(chan orderCreated, 1)
+	shipped := make(chan orderShipped, 1)
+
+	offCreated := pubsub.On(bus, "orders", func(e orderCreated) { created <- e })
+	defer offCreated()
+	offShipped := pubsub.On(bus, "orders", func(e orderShipped) { shipped <- e })
+
+	ctx := context.Background()
+	bus.Publish(ctx, "orders", orderCreated{ID: 1})
+	bus.Publish(ctx, "orders", "ignored")
+	bus.Publish(ctx, "orders", orderShipped{ID: 2})
+
+	select {
+	case e := <-created:
+		if e.ID != 1 {
+			t.Errorf("unexpected created event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("created event not handled")
+	}
+
+	select {
+	case e := <-shipped:
+		if e.ID != 2 {
+			t.Errorf("unexpected shipped event %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("shipped event not handled")
+	}
+
+	offShipped()
+	if n, _ := bus.Publish(ctx, "orders", orderShipped{ID: 3}); n != 1 {
+		t.Errorf("expected only the created handler to remain, got %d deliveries", n)
+	}
+}
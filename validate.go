@@ -0,0 +1,132 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalid is matched by the errors of publishes rejected by the
+// validator.
+var ErrInvalid = errors.New("pubsub: invalid message")
+
+// ValidationError is returned by Publish when the validator rejects the
+// message. Err is the error of the validator.
+type ValidationError[K comparable] struct {
+	Key          K
+	DeadLettered bool // the message was published to the dead letter key
+	Err          error
+}
+
+func (e *ValidationError[K]) Error() string {
+	return fmt.Sprintf("pubsub: invalid message for key %v: %v", e.Key, e.Err)
+}
+
+// Unwrap returns ErrInvalid and the error of the validator.
+func (e *ValidationError[K]) Unwrap() []error {
+	return []error{ErrInvalid, e.Err}
+}
+
+// Validator checks that a message published to a key has the expected
+// shape, for example against a JSON Schema or a protobuf descriptor of
+// the key. A nil error accepts the message.
+type Validator[K comparable, T any] interface {
+	Validate(key K, msg T) error
+}
+
+// ValidatorFunc adapts a function to the Validator interface.
+type ValidatorFunc[K comparable, T any] func(key K, msg T) error
+
+func (f ValidatorFunc[K, T]) Validate(key K, msg T) error {
+	return f(key, msg)
+}
+
+// Schemas is a Validator dispatching to validators registered per key,
+// a schema registry for buses carrying different message shapes, such as
+// PubSub[string, any]. Messages of keys without a registered validator
+// are accepted. It is safe for concurrent use.
+type Schemas[K comparable, T any] struct {
+	mu         sync.RWMutex
+	validators map[K]Validator[K, T]
+}
+
+// Register sets the validator of the key; nil removes it.
+func (s *Schemas[K, T]) Register(key K, v Validator[K, T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v == nil {
+		delete(s.validators, key)
+		return
+	}
+
+	if s.validators == nil {
+		s.validators = make(map[K]Validator[K, T])
+	}
+	s.validators[key] = v
+}
+
+// Validate validates the message with the validator of the key.
+func (s *Schemas[K, T]) Validate(key K, msg T) error {
+	s.mu.RLock()
+	v, ok := s.validators[key]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return v.Validate(key, msg)
+}
+
+// validation is the validation configuration of an instance.
+type validation[K comparable, T any] struct {
+	validator  Validator[K, T]
+	deadLetter K
+	dead       bool // deadLetter is set
+}
+
+// SetValidator sets the validator consulted by Publish after
+// authorization, so producers can't silently break consumers. Rejected
+// messages are not delivered and Publish returns a *ValidationError[K].
+// A nil validator accepts everything.
+func (ps *PubSub[K, T]) SetValidator(v Validator[K, T]) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.validation.validator = v
+}
+
+// SetDeadLetter makes Publish deliver messages rejected by the validator
+// to the key instead of dropping them, for inspection or repair. The
+// messages are published unchanged and not validated again.
+func (ps *PubSub[K, T]) SetDeadLetter(key K) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	ps.validation.deadLetter = key
+	ps.validation.dead = true
+}
+
+// validate consults the validator, if any, and dead-letters rejected
+// messages.
+func (ps *PubSub[K, T]) validate(ctx context.Context, v validation[K, T], key K, msg T) error {
+	if v.validator == nil {
+		return nil
+	}
+
+	err := v.validator.Validate(key, msg)
+	if err == nil {
+		return nil
+	}
+
+	ps.opts.logger.Warn("pubsub: invalid message", "key", key, "error", err)
+	verr := &ValidationError[K]{Key: key, Err: err}
+	if v.dead {
+		_, derr := ps.fanout(ctx, v.deadLetter, msg, false, nil)
+		verr.DeadLettered = derr == nil
+	}
+
+	return verr
+}
@@ -0,0 +1,67 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestValidator(t *testing.T) {
+	ps := pubsub.New[string, any]()
+	schemas := new(pubsub.Schemas[string, any])
+	schemas.Register("orders", pubsub.ValidatorFunc[string, any](func(_ string, msg any) error {
+		if _, ok := msg.(map[string]any)["id"]; !ok {
+			return errors.New("missing id")
+		}
+		return nil
+	}))
+	ps.SetValidator(schemas)
+
+	ch := make(chan any, 2)
+	ps.Subscribe([]string{"orders", "other"}, ch)
+
+	ctx := context.Background()
+	_, err := ps.Publish(ctx, "orders", map[string]any{"name": "x"})
+	var verr *pubsub.ValidationError[string]
+	if !errors.Is(err, pubsub.ErrInvalid) || !errors.As(err, &verr) || verr.Key != "orders" || verr.DeadLettered {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+
+	if n, err := ps.Publish(ctx, "orders", map[string]any{"id": 1}); n != 1 || err != nil {
+		t.Errorf("expected a valid message delivered, got %d, %v", n, err)
+	}
+
+	if n, _ := ps.Publish(ctx, "other", "anything"); n != 1 {
+		t.Error("expected keys without a schema to accept anything")
+	}
+
+	if len(ch) != 2 {
+		t.Errorf("expected only accepted messages delivered, got %d", len(ch))
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ps.SetValidator(pubsub.ValidatorFunc[string, int](func(_ string, msg int) error {
+		if msg < 0 {
+			return errors.New("negative")
+		}
+		return nil
+	}))
+	ps.SetDeadLetter("dead")
+
+	dead := make(chan int, 1)
+	ps.Subscribe([]string{"dead"}, dead)
+
+	_, err := ps.Publish(context.Background(), "amounts", -5)
+	var verr *pubsub.ValidationError[string]
+	if !errors.As(err, &verr) || !verr.DeadLettered {
+		t.Fatalf("expected a dead-lettered validation error, got %v", err)
+	}
+
+	if got := <-dead; got != -5 {
+		t.Errorf("expected -5 dead-lettered, got %d", got)
+	}
+}
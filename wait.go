@@ -0,0 +1,93 @@
+package pubsub
+
+import "context"
+
+// Next subscribes to the key, waits for the next message published to it
+// and unsubscribes. Only messages published after the call are received.
+// If the context is canceled first, the context error is returned; if the
+// subscription is denied or the instance is closed, that error is returned
+// immediately.
+func (ps *PubSub[K, T]) Next(ctx context.Context, key K) (T, error) {
+	keys := []K{key}
+	ch := make(chan T, max(ps.bufferSize(key), 1))
+	if err := ps.SubscribeContext(ctx, keys, ch); err != nil {
+		var zero T
+		return zero, err
+	}
+	defer ps.UnsubscribeAndDrain(keys, ch)
+
+	select {
+	case msg := <-ch:
+		return msg, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// WaitFor subscribes to the key and waits for the first message for which
+// match returns true, then unsubscribes. Messages that don't match are
+// discarded. Errors are returned as by Next.
+func (ps *PubSub[K, T]) WaitFor(ctx context.Context, key K, match func(T) bool) (T, error) {
+	keys := []K{key}
+	ch := make(chan T, max(ps.bufferSize(key), 1))
+	if err := ps.SubscribeContext(ctx, keys, ch); err != nil {
+		var zero T
+		return zero, err
+	}
+	defer ps.UnsubscribeAndDrain(keys, ch)
+
+	for {
+		select {
+		case msg := <-ch:
+			if match(msg) {
+				return msg, nil
+			}
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// WaitForSubscribers blocks until at least n channels and handlers are
+// subscribed to the key, or channels to patterns matching it, so startup
+// code can hold off publishing until its consumers are attached. It
+// returns ErrClosed if the instance is draining or closed first, and the
+// context error if the context is canceled first.
+func (ps *PubSub[K, T]) WaitForSubscribers(ctx context.Context, key K, n int) error {
+	for {
+		ps.mu.Lock()
+		key := ps.resolve(key)
+		if _, channels := ps.targets(key); channels+len(ps.handlers[key]) >= n {
+			ps.mu.Unlock()
+			return nil
+		}
+
+		if ps.state != stateOpen {
+			ps.mu.Unlock()
+			return ErrClosed
+		}
+
+		if ps.changed == nil {
+			ps.changed = make(chan struct{})
+		}
+		changed := ps.changed
+		ps.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// subscribersChanged wakes the WaitForSubscribers calls. The caller must
+// hold the lock.
+func (ps *PubSub[K, T]) subscribersChanged() {
+	if ps.changed != nil {
+		close(ps.changed)
+		ps.changed = nil
+	}
+}
@@ -0,0 +1,120 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+func TestNext(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	go func() {
+		for {
+			n, _ := ps.Publish(ctx, "ready", "yes")
+			if n > 0 || ctx.Err() != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	msg, err := ps.Next(ctx, "ready")
+	if err != nil || msg != "yes" {
+		t.Fatalf("expected yes, got %q, %v", msg, err)
+	}
+
+	if n, _ := ps.PublishWithTimeout("ready", "again", 10*time.Millisecond); n != 0 {
+		t.Errorf("expected no subscribers after Next, got %d", n)
+	}
+}
+
+func TestNextTimeout(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := ps.Next(ctx, "never"); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestNextClosed(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	ps.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := ps.Next(ctx, "never"); err != pubsub.ErrClosed {
+		t.Errorf("Next: expected ErrClosed, got %v", err)
+	}
+	if _, err := ps.WaitFor(ctx, "never", func(string) bool { return true }); err != pubsub.ErrClosed {
+		t.Errorf("WaitFor: expected ErrClosed, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("expected an immediate return")
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	go func() {
+		for i := 0; ctx.Err() == nil; i++ {
+			ps.Publish(ctx, "state", i)
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	msg, err := ps.WaitFor(ctx, "state", func(n int) bool { return n >= 5 })
+	if err != nil || msg < 5 {
+		t.Fatalf("expected a state >= 5, got %d, %v", msg, err)
+	}
+}
+
+func TestWaitForSubscribers(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- ps.WaitForSubscribers(ctx, "jobs", 2) }()
+
+	ps.Subscribe([]string{"jobs"}, make(chan int, 1))
+	select {
+	case err := <-done:
+		t.Fatalf("expected to wait for the second subscriber, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	ps.SubscribeFunc([]string{"jobs"}, func(context.Context, string, int) error { return nil })
+	if err := <-done; err != nil {
+		t.Fatalf("expected the subscribers to be ready, got %v", err)
+	}
+
+	if err := ps.WaitForSubscribers(ctx, "jobs", 1); err != nil {
+		t.Errorf("expected no wait for existing subscribers, got %v", err)
+	}
+}
+
+func TestWaitForSubscribersCanceled(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := ps.WaitForSubscribers(ctx, "jobs", 1); err != context.DeadlineExceeded {
+		t.Errorf("expected the context error, got %v", err)
+	}
+
+	go ps.Close()
+	if err := ps.WaitForSubscribers(context.Background(), "jobs", 1); err != pubsub.ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
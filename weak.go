@@ -0,0 +1,33 @@
+package pubsub
+
+import "runtime"
+
+// SubscribeWeak subscribes the channel to the keys for as long as owner
+// is reachable: when the garbage collector finds owner unreachable, the
+// subscription is removed and the channel drained. It is a safety net
+// for long-lived instances hosting code that may forget to unsubscribe,
+// not a replacement for unsubscribing; collection may happen late or,
+// for objects that are never collected, not at all.
+//
+// The owner typically holds the channel; the PubSub instance must not
+// hold references to owner, or it will never become unreachable. The
+// returned function unsubscribes immediately and cancels the cleanup.
+func SubscribeWeak[K comparable, T, O any](ps *PubSub[K, T], owner *O, keys []K, ch chan T) (unsubscribe func()) {
+	ps.Subscribe(keys, ch)
+
+	cleanup := runtime.AddCleanup(owner, func(sub weakSub[K, T]) {
+		go sub.ps.UnsubscribeAndDrain(sub.keys, sub.ch)
+	}, weakSub[K, T]{ps, keys, ch})
+
+	return func() {
+		cleanup.Stop()
+		ps.Unsubscribe(keys, ch)
+	}
+}
+
+// weakSub is the subscription removed by the cleanup of SubscribeWeak.
+type weakSub[K comparable, T any] struct {
+	ps   *PubSub[K, T]
+	keys []K
+	ch   chan T
+}
@@ -0,0 +1,43 @@
+package pubsub_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+)
+
+type plugin struct {
+	events chan int
+	state  [64]byte // make sure the object is heap allocated
+}
+
+func TestSubscribeWeak(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	func() {
+		p := &plugin{events: make(chan int, 1)}
+		pubsub.SubscribeWeak(ps, p, []string{"k"}, p.events)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(ps.Keys()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not removed after the owner was collected")
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscribeWeakUnsubscribe(t *testing.T) {
+	ps := pubsub.New[string, int]()
+	p := &plugin{events: make(chan int, 1)}
+	unsubscribe := pubsub.SubscribeWeak(ps, p, []string{"k"}, p.events)
+
+	unsubscribe()
+	if len(ps.Keys()) != 0 {
+		t.Error("expected the subscription to be removed")
+	}
+	runtime.KeepAlive(p)
+}
@@ -0,0 +1,275 @@
+// Package webhook delivers PubSub messages to HTTP endpoints.
+// Each message is encoded with a codec (JSON by default) and POSTed to
+// the configured URL, with retries and exponential backoff, optional HMAC
+// signing against replays and a limit on the number of concurrent
+// requests.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+)
+
+// Headers set on every request.
+const (
+	KeyHeader       = "X-Pubsub-Key"       // key the message was published to
+	TimestampHeader = "X-Pubsub-Timestamp" // Unix time of the attempt, in seconds
+	SignatureHeader = "X-Pubsub-Signature" // "sha256=" + hex HMAC, see Verify
+)
+
+// DefaultTolerance is the maximum age of a signed request accepted by
+// Verify when its tolerance is zero.
+const DefaultTolerance = 5 * time.Minute
+
+// Default delivery settings used when the corresponding fields are zero.
+const (
+	DefaultMaxAttempts = 3
+	DefaultBackoff     = 100 * time.Millisecond
+	DefaultMaxBackoff  = 10 * time.Second
+)
+
+// StatusError is returned for responses with a non-2xx status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("webhook: unexpected status %d", e.StatusCode)
+}
+
+// temporary reports whether the request may succeed if retried.
+func (e *StatusError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Sender subscribes to keys and POSTs their messages to a URL.
+type Sender[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+	Keys   []K
+	URL    string
+
+	// Client is used to send requests; http.DefaultClient if nil.
+	Client *http.Client
+
+	// Codec encodes request bodies; codec.JSON if nil.
+	Codec codec.Codec[T]
+
+	// Header contains additional headers sent with every request.
+	Header http.Header
+
+	// Secret, if set, is used to sign requests with HMAC-SHA256: the
+	// signature covers the timestamp, the key and the body, so receivers
+	// checking it with Verify reject replays of old requests and requests
+	// moved to another key.
+	Secret []byte
+
+	// MaxAttempts limits delivery attempts per message.
+	// Network errors, 429 and 5xx responses are retried; other
+	// responses are not.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry; it doubles on each
+	// following retry up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	// Concurrency limits the number of requests in flight; 1 if zero.
+	// With a limit above one, messages may be delivered out of order.
+	Concurrency int
+
+	// Buffer is the capacity of the subscription channels.
+	Buffer int
+
+	// OnError, if set, is called for messages that could not be delivered.
+	OnError func(key K, msg T, err error)
+}
+
+// Run delivers messages until the context is canceled, then waits for
+// requests in flight to finish.
+func (s *Sender[K, T]) Run(ctx context.Context) error {
+	limit := s.Concurrency
+	if limit <= 0 {
+		limit = 1
+	}
+
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for _, key := range s.Keys {
+		keys := []K{key}
+		ch := make(chan T, s.Buffer)
+		s.PubSub.Subscribe(keys, ch)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.PubSub.UnsubscribeAndDrain(keys, ch)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					select {
+					case sem <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer func() { <-sem }()
+
+						if err := s.Send(ctx, key, msg); err != nil && s.OnError != nil {
+							s.OnError(key, msg, err)
+						}
+					}()
+				}
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// Send delivers a single message, retrying as configured.
+func (s *Sender[K, T]) Send(ctx context.Context, key K, msg T) error {
+	c := s.codec()
+	body, err := c.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	attempts := s.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
+
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+
+	maxBackoff := s.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = s.post(ctx, key, c.ContentType(), body)
+		if err == nil || attempt >= attempts {
+			return err
+		}
+
+		if se, ok := err.(*StatusError); ok && !se.temporary() {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// post makes a single delivery attempt.
+func (s *Sender[K, T]) post(ctx context.Context, key K, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	for name, values := range s.Header {
+		req.Header[name] = values
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(KeyHeader, fmt.Sprint(key))
+	req.Header.Set(TimestampHeader, timestamp)
+
+	if s.Secret != nil {
+		req.Header.Set(SignatureHeader, sign(s.Secret, timestamp, req.Header.Get(KeyHeader), body))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body) // so the connection can be reused
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// codec returns the configured codec or the default one.
+func (s *Sender[K, T]) codec() codec.Codec[T] {
+	if s.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return s.Codec
+}
+
+// sign returns the signature header value of a request.
+func sign(secret []byte, timestamp, key string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "\n" + key + "\n"))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the request with the header and body was signed
+// with the secret, for its key and timestamp, at most tolerance ago or
+// ahead; DefaultTolerance if zero. Receivers use it to authenticate
+// requests; those needing to reject replays within the tolerance also
+// remember the signatures they accepted during that time.
+func Verify(secret, body []byte, header http.Header, tolerance time.Duration) bool {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	timestamp := header.Get(TimestampHeader)
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if age := time.Since(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+
+	expected := sign(secret, timestamp, header.Get(KeyHeader), body)
+
+	return hmac.Equal([]byte(expected), []byte(header.Get(SignatureHeader)))
+}
@@ -0,0 +1,123 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/webhook"
+)
+
+func TestSenderRetriesAndSigns(t *testing.T) {
+	secret := []byte("s3cret")
+	var calls atomic.Int32
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !webhook.Verify(secret, body, r.Header, time.Minute) {
+			t.Error("invalid signature")
+		}
+		if key := r.Header.Get(webhook.KeyHeader); key != "orders" {
+			t.Errorf("unexpected key header %q", key)
+		}
+		received <- string(body)
+	}))
+	defer srv.Close()
+
+	ps := pubsub.New[string, map[string]int]()
+	sender := &webhook.Sender[string, map[string]int]{
+		PubSub:  ps,
+		Keys:    []string{"orders"},
+		URL:     srv.URL,
+		Secret:  secret,
+		Backoff: time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sender.Run(ctx)
+
+	for {
+		n, _ := ps.PublishWithTimeout("orders", map[string]int{"id": 1}, time.Second)
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case body := <-received:
+		if body != `{"id":1}` {
+			t.Errorf("unexpected body %s", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 attempts, got %d", n)
+	}
+}
+
+func TestSendPermanentError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	sender := &webhook.Sender[string, string]{URL: srv.URL, Backoff: time.Millisecond}
+	err := sender.Send(context.Background(), "key", "msg")
+
+	var se *webhook.StatusError
+	if !errors.As(err, &se) || se.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status error 400, got %v", err)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected a single attempt, got %d", n)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	headers := make(chan http.Header, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	sender := &webhook.Sender[string, string]{URL: srv.URL, Secret: secret}
+	if err := sender.Send(context.Background(), "orders", "m"); err != nil {
+		t.Fatal(err)
+	}
+	header := <-headers
+	body := []byte(`"m"`)
+
+	if !webhook.Verify(secret, body, header, 0) {
+		t.Fatal("expected a valid signature")
+	}
+
+	moved := header.Clone()
+	moved.Set(webhook.KeyHeader, "payments")
+	if webhook.Verify(secret, body, moved, 0) {
+		t.Error("expected a request moved to another key rejected")
+	}
+
+	old := header.Clone()
+	old.Set(webhook.TimestampHeader, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	if webhook.Verify(secret, body, old, 0) {
+		t.Error("expected an old request rejected")
+	}
+}
@@ -0,0 +1,173 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Window is the time window of Aggregate.
+type Window struct {
+	Size  time.Duration // span of messages aggregated together
+	Slide time.Duration // interval between aggregates
+}
+
+// Tumbling returns a window aggregating consecutive, non-overlapping
+// spans of the duration.
+func Tumbling(size time.Duration) Window {
+	return Window{Size: size, Slide: size}
+}
+
+// Sliding returns a window aggregating the messages of the last size
+// duration every slide.
+func Sliding(size, slide time.Duration) Window {
+	return Window{Size: size, Slide: slide}
+}
+
+// Count is a reducer of Aggregate counting the messages.
+func Count[T any](n int, _ T) int {
+	return n + 1
+}
+
+// Aggregate collects the messages published to the keys of src over the
+// window and publishes their aggregate to dst, on the key mapped with
+// mapKey, for example the number of messages per minute with Count. Each
+// aggregate is reduced with reduce from the zero value of A over the
+// messages of the key received during the last window size, in order,
+// every window slide, as measured by the clock of src. Keys mapped to the
+// same key of dst are aggregated together, their messages reduced in the
+// order received. Keys without messages in the window are skipped.
+//
+// Messages are collected by handlers registered with SubscribeFunc, so a
+// message belongs to the windows in progress when its Publish returns.
+// Aggregates are published with ctx. Aggregation stops when ctx is
+// canceled or the returned function is called. It panics if the window
+// size or slide is not positive.
+func Aggregate[K, K2 comparable, T, A any](
+	ctx context.Context,
+	src *PubSub[K, T], dst *PubSub[K2, A], keys []K, w Window,
+	mapKey func(K) K2, reduce func(acc A, msg T) A,
+) (stop func()) {
+	if w.Size <= 0 || w.Slide <= 0 {
+		panic("pubsub: invalid aggregation window")
+	}
+
+	a := &aggregation[K, K2, T, A]{
+		ctx:     ctx,
+		clock:   src.opts.clock,
+		dst:     dst,
+		window:  w,
+		mapKey:  mapKey,
+		reduce:  reduce,
+		entries: make(map[K2][]timed[T]),
+	}
+
+	remove := src.SubscribeFunc(keys, a.add)
+
+	a.mu.Lock()
+	a.timer = a.clock.AfterFunc(w.Slide, a.tick)
+	a.mu.Unlock()
+
+	stopped := context.AfterFunc(ctx, a.stop)
+
+	return func() {
+		stopped()
+		remove()
+		a.stop()
+	}
+}
+
+// timed is a message with the time it was received.
+type timed[T any] struct {
+	time time.Time
+	msg  T
+}
+
+// aggregation is the state of an Aggregate call.
+type aggregation[K, K2 comparable, T, A any] struct {
+	ctx    context.Context
+	clock  Clock
+	dst    *PubSub[K2, A]
+	window Window
+	mapKey func(K) K2
+	reduce func(A, T) A
+
+	mu      sync.Mutex
+	entries map[K2][]timed[T] // messages of the window size by mapped key
+	timer   Timer
+	stopped bool
+}
+
+// add collects a message.
+func (a *aggregation[K, K2, T, A]) add(_ context.Context, key K, msg T) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.stopped {
+		k2 := a.mapKey(key)
+		a.entries[k2] = append(a.entries[k2], timed[T]{a.clock.Now(), msg})
+	}
+
+	return nil
+}
+
+// tick publishes the aggregates of the window ending now.
+func (a *aggregation[K, K2, T, A]) tick() {
+	a.mu.Lock()
+	if a.stopped {
+		a.mu.Unlock()
+		return
+	}
+
+	now := a.clock.Now()
+	start := now.Add(-a.window.Size)
+	out := make(map[K2]A, len(a.entries))
+	for key, entries := range a.entries {
+		i := 0
+		for i < len(entries) && entries[i].time.Before(start) {
+			i++
+		}
+
+		entries = entries[i:]
+		if len(entries) == 0 {
+			delete(a.entries, key)
+			continue
+		}
+		a.entries[key] = entries
+
+		// The window is [start, now): messages received right now belong
+		// to the next one.
+		var (
+			acc A
+			n   int
+		)
+		for _, e := range entries {
+			if !e.time.Before(now) {
+				break
+			}
+			acc = a.reduce(acc, e.msg)
+			n++
+		}
+
+		if n > 0 {
+			out[key] = acc
+		}
+	}
+
+	a.timer = a.clock.AfterFunc(a.window.Slide, a.tick)
+	a.mu.Unlock()
+
+	for key, acc := range out {
+		a.dst.Publish(a.ctx, key, acc)
+	}
+}
+
+// stop stops the aggregation.
+func (a *aggregation[K, K2, T, A]) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.stopped = true
+	a.entries = nil
+	a.timer.Stop()
+}
@@ -0,0 +1,102 @@
+package pubsub_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/pstest"
+)
+
+func TestAggregateTumbling(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	counts := pubsub.New[string, int]()
+	ch := make(chan int, 10)
+	counts.Subscribe([]string{"clicks/count"}, ch)
+
+	stop := pubsub.Aggregate(context.Background(), ps, counts, []string{"clicks"},
+		pubsub.Tumbling(time.Minute), func(key string) string { return key + "/count" }, pubsub.Count[int])
+	defer stop()
+
+	for range 3 {
+		ps.Publish(context.Background(), "clicks", 1)
+	}
+	clock.Advance(time.Minute)
+
+	ps.Publish(context.Background(), "clicks", 1)
+	clock.Advance(time.Minute)
+	clock.Advance(time.Minute) // empty window, skipped
+
+	if got := <-ch; got != 3 {
+		t.Errorf("expected 3 in the first window, got %d", got)
+	}
+	if got := <-ch; got != 1 {
+		t.Errorf("expected 1 in the second window, got %d", got)
+	}
+	if len(ch) != 0 {
+		t.Errorf("expected no aggregates of empty windows, got %d", <-ch)
+	}
+}
+
+func TestAggregateSliding(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	ch := make(chan int, 10)
+	ps.Subscribe([]string{"sum"}, ch)
+
+	stop := pubsub.Aggregate(context.Background(), ps, ps, []string{"n"},
+		pubsub.Sliding(2*time.Second, time.Second), func(string) string { return "sum" },
+		func(acc, n int) int { return acc + n })
+	defer stop()
+
+	for _, n := range []int{1, 2, 4} {
+		ps.Publish(context.Background(), "n", n)
+		clock.Advance(time.Second)
+	}
+	clock.Advance(time.Second)
+
+	for _, want := range []int{1, 3, 6, 4} {
+		if got := <-ch; got != want {
+			t.Errorf("expected %d, got %d", want, got)
+		}
+	}
+}
+
+func TestAggregateMergesMappedKeys(t *testing.T) {
+	ps, clock := pstest.New[string, string]()
+	ch := make(chan string, 10)
+	out := pubsub.New[string, string]()
+	out.Subscribe([]string{"all"}, ch)
+
+	stop := pubsub.Aggregate(context.Background(), ps, out, []string{"a", "b"},
+		pubsub.Tumbling(time.Minute), func(string) string { return "all" },
+		func(acc, msg string) string { return acc + msg })
+	defer stop()
+
+	for _, key := range []string{"a", "b", "a", "b"} {
+		ps.Publish(context.Background(), key, key)
+	}
+	clock.Advance(time.Minute)
+
+	if got := <-ch; got != "abab" {
+		t.Errorf("expected abab, got %q", got)
+	}
+	if len(ch) != 0 {
+		t.Errorf("expected one aggregate, got %q", <-ch)
+	}
+}
+
+func TestAggregateStop(t *testing.T) {
+	ps, clock := pstest.New[string, int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := pubsub.Aggregate(ctx, ps, ps, []string{"n"},
+		pubsub.Tumbling(time.Second), func(string) string { return "count" }, pubsub.Count[int])
+
+	cancel()
+	time.Sleep(10 * time.Millisecond) // let the context stop the aggregation
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("expected the timer stopped, got %d timers", n)
+	}
+
+	stop()
+}
@@ -0,0 +1,362 @@
+// Package wsgateway exposes a PubSub instance to remote clients over
+// WebSocket. Clients subscribe to keys, unsubscribe and publish messages
+// using a small JSON protocol of Frame values, one frame per WebSocket
+// text message.
+package wsgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/codec"
+	"github.com/mdigger/pubsub/internal/websocket"
+)
+
+// Frame operations.
+const (
+	OpSubscribe   = "subscribe"   // client: subscribe to Keys
+	OpUnsubscribe = "unsubscribe" // client: unsubscribe from Keys
+	OpPublish     = "publish"     // client: publish Data to Keys
+	OpMessage     = "message"     // server: Data was published to Keys[0]
+	OpAck         = "ack"         // server: request with ID succeeded
+	OpError       = "error"       // server: request with ID failed
+	OpKeys        = "keys"        // client: list keys with subscribers, returned in Keys of the ack
+	OpStats       = "stats"       // client: get Stats, returned in Data of the ack
+)
+
+// DefaultQueueSize is the send queue capacity used when QueueSize is zero.
+const DefaultQueueSize = 64
+
+// DefaultPublishTimeout is the publish time limit used when PublishTimeout
+// is zero.
+const DefaultPublishTimeout = 5 * time.Second
+
+// Errors returned to clients and passed to OnClose.
+var (
+	// ErrSlowClient is the reason a connection is closed when its send
+	// queue overflows.
+	ErrSlowClient = errors.New("wsgateway: slow client evicted")
+	// ErrInspectDisabled is returned for keys and stats requests when
+	// Inspect is not set.
+	ErrInspectDisabled = errors.New("wsgateway: inspection disabled")
+)
+
+// Stats is the reply to the stats operation.
+type Stats struct {
+	Clients int                `json:"clients"` // connected clients
+	Keys    int                `json:"keys"`    // keys with subscribers
+	Memory  pubsub.MemoryUsage `json:"memory"`
+}
+
+// Frame is a single protocol message exchanged with clients.
+// ID is an optional client-chosen request identifier echoed in the ack
+// or error reply; requests without an ID are not acknowledged.
+type Frame[K comparable] struct {
+	Op        string          `json:"op"`
+	ID        string          `json:"id,omitempty"`
+	Keys      []K             `json:"keys,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Delivered int             `json:"delivered,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// Gateway is an http.Handler serving WebSocket clients.
+type Gateway[K comparable, T any] struct {
+	PubSub *pubsub.PubSub[K, T]
+
+	// QueueSize is the capacity of the per-connection send queue.
+	// A client whose queue overflows is disconnected.
+	QueueSize int
+
+	// WriteTimeout limits the time spent writing a frame to a client.
+	// Zero means no limit.
+	WriteTimeout time.Duration
+
+	// PublishTimeout limits the time spent publishing a client message,
+	// during which the connection reads no further requests;
+	// DefaultPublishTimeout if zero. A negative value means no limit.
+	PublishTimeout time.Duration
+
+	// Codec converts messages to and from the Data field of frames;
+	// codec.JSON if nil. Its output must be valid JSON.
+	Codec codec.Codec[T]
+
+	// OnClose, if set, is called when a client connection is closed,
+	// with ErrSlowClient if the client was evicted.
+	OnClose func(r *http.Request, err error)
+
+	// CheckOrigin reports whether a WebSocket handshake may proceed. If
+	// nil, only requests without an Origin header or from the same host
+	// are accepted, so other sites can't connect with the cookies of the
+	// user, from which the request context may carry the principal.
+	CheckOrigin func(r *http.Request) bool
+
+	// Inspect enables the keys and stats operations. They expose the
+	// names of all active keys to every client, so enable them only for
+	// trusted clients, for example on an internal listener.
+	Inspect bool
+
+	clients atomic.Int64
+}
+
+// codec returns the configured codec or the default one.
+func (gw *Gateway[K, T]) codec() codec.Codec[T] {
+	if gw.Codec == nil {
+		return codec.JSON[T]{}
+	}
+
+	return gw.Codec
+}
+
+// queueSize returns the configured queue size or the default one.
+func (gw *Gateway[K, T]) queueSize() int {
+	if gw.QueueSize <= 0 {
+		return DefaultQueueSize
+	}
+
+	return gw.QueueSize
+}
+
+// publishTimeout returns the configured publish timeout or the default one.
+func (gw *Gateway[K, T]) publishTimeout() time.Duration {
+	if gw.PublishTimeout == 0 {
+		return DefaultPublishTimeout
+	}
+
+	return gw.PublishTimeout
+}
+
+// client is the state of a single connection.
+type client[K comparable, T any] struct {
+	gw     *Gateway[K, T]
+	conn   *websocket.Conn
+	queue  chan []byte
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu   sync.Mutex
+	subs map[K]context.CancelFunc // per-key forwarder cancelation
+	wg   sync.WaitGroup
+}
+
+// ServeHTTP upgrades the connection and serves the client until it
+// disconnects or is evicted.
+func (gw *Gateway[K, T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r, gw.CheckOrigin)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(r.Context())
+	c := &client[K, T]{
+		gw:     gw,
+		conn:   conn,
+		queue:  make(chan []byte, gw.queueSize()),
+		ctx:    ctx,
+		cancel: cancel,
+		subs:   make(map[K]context.CancelFunc),
+	}
+
+	gw.clients.Add(1)
+	defer gw.clients.Add(-1)
+
+	c.wg.Add(1)
+	go c.writeLoop()
+
+	c.readLoop()
+	cancel(nil)
+	conn.Close()
+	c.wg.Wait()
+
+	if gw.OnClose != nil {
+		err := context.Cause(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		gw.OnClose(r, err)
+	}
+}
+
+// readLoop handles client requests until the connection fails.
+func (c *client[K, T]) readLoop() {
+	go func() {
+		<-c.ctx.Done()
+		c.conn.Close() // unblock ReadMessage on eviction
+	}()
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req Frame[K]
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.reply(Frame[K]{Op: OpError, Error: err.Error()})
+			continue
+		}
+
+		ack, err := c.handle(req)
+		switch {
+		case err != nil:
+			c.reply(Frame[K]{Op: OpError, ID: req.ID, Error: err.Error()})
+		case req.ID != "":
+			ack.Op, ack.ID = OpAck, req.ID
+			c.reply(ack)
+		}
+	}
+}
+
+// handle executes a single client request and returns the fields of
+// the acknowledgement.
+func (c *client[K, T]) handle(req Frame[K]) (Frame[K], error) {
+	switch req.Op {
+	case OpSubscribe:
+		for _, key := range req.Keys {
+			if err := c.subscribe(key); err != nil {
+				return Frame[K]{}, err
+			}
+		}
+		return Frame[K]{}, nil
+
+	case OpUnsubscribe:
+		c.mu.Lock()
+		for _, key := range req.Keys {
+			if stop, ok := c.subs[key]; ok {
+				stop()
+				delete(c.subs, key)
+			}
+		}
+		c.mu.Unlock()
+		return Frame[K]{}, nil
+
+	case OpPublish:
+		msg, err := c.gw.codec().Unmarshal(req.Data)
+		if err != nil {
+			return Frame[K]{}, err
+		}
+
+		ctx := c.ctx
+		if timeout := c.gw.publishTimeout(); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		var total int
+		for _, key := range req.Keys {
+			delivered, err := c.gw.PubSub.Publish(ctx, key, msg)
+			total += delivered
+			if err != nil {
+				return Frame[K]{}, err
+			}
+		}
+		return Frame[K]{Delivered: total}, nil
+
+	case OpKeys:
+		if !c.gw.Inspect {
+			return Frame[K]{}, ErrInspectDisabled
+		}
+		return Frame[K]{Keys: c.gw.PubSub.Keys()}, nil
+
+	case OpStats:
+		if !c.gw.Inspect {
+			return Frame[K]{}, ErrInspectDisabled
+		}
+		data, err := json.Marshal(Stats{
+			Clients: int(c.gw.clients.Load()),
+			Keys:    len(c.gw.PubSub.Keys()),
+			Memory:  c.gw.PubSub.MemoryUsage(),
+		})
+		return Frame[K]{Data: data}, err
+
+	default:
+		return Frame[K]{}, errors.New("wsgateway: unknown operation " + req.Op)
+	}
+}
+
+// subscribe starts forwarding messages of the key to the client.
+// The request context carries the principal checked by the PubSub
+// authorizer.
+func (c *client[K, T]) subscribe(key K) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.subs[key]; ok {
+		return nil
+	}
+
+	ch := make(chan T, c.gw.queueSize())
+	keys := []K{key}
+	if err := c.gw.PubSub.SubscribeContext(c.ctx, keys, ch); err != nil {
+		return err
+	}
+
+	ctx, stop := context.WithCancel(c.ctx)
+	c.subs[key] = stop
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.gw.PubSub.UnsubscribeAndDrain(keys, ch)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg := <-ch:
+				data, err := c.gw.codec().Marshal(msg)
+				if err != nil {
+					c.reply(Frame[K]{Op: OpError, Keys: keys, Error: err.Error()})
+					continue
+				}
+
+				c.reply(Frame[K]{Op: OpMessage, Keys: keys, Data: data})
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reply queues the frame for sending. If the queue is full the client
+// is evicted.
+func (c *client[K, T]) reply(f Frame[K]) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+
+	select {
+	case c.queue <- data:
+	default:
+		c.cancel(ErrSlowClient)
+	}
+}
+
+// writeLoop sends queued frames to the client.
+func (c *client[K, T]) writeLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case data := <-c.queue:
+			if c.gw.WriteTimeout > 0 {
+				c.conn.SetWriteDeadline(time.Now().Add(c.gw.WriteTimeout))
+			}
+
+			if err := c.conn.WriteMessage(websocket.OpText, data); err != nil {
+				c.cancel(err)
+				return
+			}
+		}
+	}
+}
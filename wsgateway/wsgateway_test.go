@@ -0,0 +1,156 @@
+package wsgateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mdigger/pubsub"
+	"github.com/mdigger/pubsub/internal/websocket"
+	"github.com/mdigger/pubsub/wsgateway"
+)
+
+func dial(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, f wsgateway.Frame[string]) {
+	t.Helper()
+	data, _ := json.Marshal(f)
+	if err := conn.WriteMessage(websocket.OpText, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) wsgateway.Frame[string] {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f wsgateway.Frame[string]
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestGateway(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	srv := httptest.NewServer(&wsgateway.Gateway[string, string]{PubSub: ps})
+	defer srv.Close()
+
+	conn := dial(t, srv)
+	defer conn.Close()
+
+	send(t, conn, wsgateway.Frame[string]{Op: wsgateway.OpSubscribe, ID: "1", Keys: []string{"chat"}})
+	if f := receive(t, conn); f.Op != wsgateway.OpAck || f.ID != "1" {
+		t.Fatalf("expected ack, got %+v", f)
+	}
+
+	// a client publish reaches both local and remote subscribers
+	local := make(chan string, 1)
+	ps.Subscribe([]string{"chat"}, local)
+	send(t, conn, wsgateway.Frame[string]{
+		Op: wsgateway.OpPublish, ID: "2", Keys: []string{"chat"}, Data: json.RawMessage(`"hi"`),
+	})
+
+	if msg := <-local; msg != "hi" {
+		t.Errorf("expected local message %q, got %q", "hi", msg)
+	}
+
+	var acked, received bool
+	for !acked || !received {
+		f := receive(t, conn)
+		switch f.Op {
+		case wsgateway.OpAck:
+			acked = f.ID == "2" && f.Delivered == 2
+		case wsgateway.OpMessage:
+			received = f.Keys[0] == "chat" && string(f.Data) == `"hi"`
+		default:
+			t.Fatalf("unexpected frame %+v", f)
+		}
+	}
+
+	send(t, conn, wsgateway.Frame[string]{Op: "bogus", ID: "3"})
+	if f := receive(t, conn); f.Op != wsgateway.OpError || f.ID != "3" {
+		t.Errorf("expected error, got %+v", f)
+	}
+}
+
+func TestGatewayEvictsSlowClient(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	closed := make(chan error, 1)
+	srv := httptest.NewServer(&wsgateway.Gateway[string, string]{
+		PubSub:    ps,
+		QueueSize: 1,
+		OnClose:   func(_ *http.Request, err error) { closed <- err },
+	})
+	defer srv.Close()
+
+	conn := dial(t, srv)
+	defer conn.Close()
+
+	send(t, conn, wsgateway.Frame[string]{Op: wsgateway.OpSubscribe, Keys: []string{"flood"}})
+
+	// the client never reads, so the queue eventually overflows
+	for {
+		ps.PublishWithTimeout("flood", strings.Repeat("x", 1<<16), 10*time.Millisecond)
+		select {
+		case err := <-closed:
+			if !errors.Is(err, wsgateway.ErrSlowClient) {
+				t.Errorf("expected ErrSlowClient, got %v", err)
+			}
+			return
+		default:
+		}
+	}
+}
+
+func TestGatewayInspect(t *testing.T) {
+	ps := pubsub.New[string, string]()
+	ps.Subscribe([]string{"orders"}, make(chan string, 1))
+
+	closed := httptest.NewServer(&wsgateway.Gateway[string, string]{PubSub: ps})
+	defer closed.Close()
+
+	conn := dial(t, closed)
+	defer conn.Close()
+
+	send(t, conn, wsgateway.Frame[string]{Op: wsgateway.OpKeys, ID: "1"})
+	if f := receive(t, conn); f.Op != wsgateway.OpError {
+		t.Fatalf("expected an error with inspection disabled, got %+v", f)
+	}
+
+	srv := httptest.NewServer(&wsgateway.Gateway[string, string]{PubSub: ps, Inspect: true})
+	defer srv.Close()
+
+	conn = dial(t, srv)
+	defer conn.Close()
+
+	send(t, conn, wsgateway.Frame[string]{Op: wsgateway.OpKeys, ID: "2"})
+	if f := receive(t, conn); f.Op != wsgateway.OpAck || len(f.Keys) != 1 || f.Keys[0] != "orders" {
+		t.Fatalf("expected the orders key, got %+v", f)
+	}
+
+	send(t, conn, wsgateway.Frame[string]{Op: wsgateway.OpStats, ID: "3"})
+	f := receive(t, conn)
+	var stats wsgateway.Stats
+	if err := json.Unmarshal(f.Data, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Clients != 1 || stats.Keys != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}